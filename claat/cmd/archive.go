@@ -0,0 +1,236 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/googlecodelabs/tools/claat/nodes"
+	"github.com/googlecodelabs/tools/claat/outfs"
+	"github.com/googlecodelabs/tools/claat/types"
+	"github.com/googlecodelabs/tools/claat/util"
+)
+
+// archivesFromCode is the value of CmdExportOptions.Archives which makes
+// export build step archives from tagged code blocks of the codelab.
+// Any other non-empty value is a source directory.
+const archivesFromCode = "code"
+
+// Labels of the buttons linking step archives.
+const (
+	starterButtonLabel  = "Download starter code"
+	solutionButtonLabel = "Download solution"
+)
+
+// writeArchives builds a zip archive of the project state after each step
+// which changes it, stores the archives in directory dir of fsys and links
+// them from the steps with Download buttons.
+//
+// The project state is either extracted from tagged code blocks,
+// as in ExtractCodelab, or read from step-N subdirectories of a source dir
+// as written by "claat extract -snapshots", where step-0 is the optional
+// initial state.
+//
+// The written archives are added to the asset manifest in ctx.
+// It does nothing if ctx.Archives is empty.
+func writeArchives(fsys outfs.FS, dir string, clab *types.Codelab, ctx *types.Context) error {
+	if ctx.Archives == "" {
+		return nil
+	}
+	cps, err := checkpoints(clab.Steps, ctx.Archives, ctx.Env)
+	if err != nil {
+		return err
+	}
+	var mod time.Time
+	if ctx.Updated != nil {
+		mod = time.Time(*ctx.Updated)
+	}
+	written := map[int]bool{}
+	archive := func(n int) error {
+		if written[n] {
+			return nil
+		}
+		written[n] = true
+		b, err := cps[n].zip(mod)
+		if err != nil {
+			return err
+		}
+		if err := fsys.WriteFile(path.Join(dir, archiveURL(n)), b); err != nil {
+			return err
+		}
+		ctx.Manifest = append(ctx.Manifest, types.NewAsset(archiveURL(n), "application/zip", "", b))
+		return nil
+	}
+	for i, step := range clab.Steps {
+		n := i + 1
+		starter, solution := cps[n-1], cps[n]
+		if solution == nil || solution.equal(starter) {
+			continue
+		}
+		if len(starter) > 0 {
+			if err := archive(n - 1); err != nil {
+				return err
+			}
+			btn := archiveButton(archiveURL(n-1), starterButtonLabel)
+			step.Content.Nodes = append([]nodes.Node{btn}, step.Content.Nodes...)
+		}
+		if err := archive(n); err != nil {
+			return err
+		}
+		step.Content.Append(archiveButton(archiveURL(n), solutionButtonLabel))
+	}
+	sort.Slice(ctx.Manifest, func(i, j int) bool { return ctx.Manifest[i].Path < ctx.Manifest[j].Path })
+	return nil
+}
+
+// archivesSource returns archives source src as it is recorded in the
+// codelab metadata. A directory is made absolute, so that the codelab
+// can be updated from any working directory.
+func archivesSource(src string) (string, error) {
+	if src == "" || src == archivesFromCode {
+		return src, nil
+	}
+	return filepath.Abs(src)
+}
+
+// checkpoints returns project states keyed by the number of completed steps.
+// A missing key means the state is unknown.
+func checkpoints(steps []*types.Step, src, env string) (map[int]project, error) {
+	cps := make(map[int]project, len(steps)+1)
+	if src == archivesFromCode {
+		proj := project{}
+		for i, step := range steps {
+			if err := proj.extract(step, env); err != nil {
+				return nil, fmt.Errorf("step %d: %v", i+1, err)
+			}
+			cps[i+1] = proj.clone()
+		}
+		return cps, nil
+	}
+	for n := 0; n <= len(steps); n++ {
+		d := filepath.Join(src, snapshotDirname(n))
+		if _, err := os.Stat(d); os.IsNotExist(err) {
+			continue
+		}
+		proj, err := readProject(d)
+		if err != nil {
+			return nil, err
+		}
+		cps[n] = proj
+	}
+	return cps, nil
+}
+
+// archiveFilename returns the name of the archive with the project state
+// after step n, where n is 1-based.
+func archiveFilename(n int) string {
+	return snapshotDirname(n) + ".zip"
+}
+
+// archiveURL returns URL of the archive with the project state after step n,
+// relative to the codelab dir.
+func archiveURL(n int) string {
+	return path.Join(util.ZipDirname, archiveFilename(n))
+}
+
+// archiveButton returns a paragraph with a Download button linking url.
+func archiveButton(url, label string) nodes.Node {
+	btn := nodes.NewButtonNode(true, true, true, nodes.NewTextNode(nodes.NewTextNodeOptions{Value: label}))
+	p := nodes.NewListNode(nodes.NewURLNode(url, btn))
+	p.MutateBlock(true)
+	return p
+}
+
+// readProject reads all files in dir, recursively.
+func readProject(dir string) (project, error) {
+	p := project{}
+	err := filepath.Walk(dir, func(f string, fi os.FileInfo, err error) error {
+		if err != nil || fi.IsDir() {
+			return err
+		}
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, f)
+		if err != nil {
+			return err
+		}
+		p[filepath.ToSlash(rel)] = b
+		return nil
+	})
+	return p, err
+}
+
+// clone returns a copy of p.
+// File contents are shared since they are never modified in place.
+func (p project) clone() project {
+	c := make(project, len(p))
+	for name, b := range p {
+		c[name] = b
+	}
+	return c
+}
+
+// equal reports whether p and q contain the same files.
+func (p project) equal(q project) bool {
+	if len(p) != len(q) {
+		return false
+	}
+	for name, b := range p {
+		b2, ok := q[name]
+		if !ok || !bytes.Equal(b, b2) {
+			return false
+		}
+	}
+	return true
+}
+
+// zip returns a zip archive of all files of p,
+// with modification time set to mod.
+func (p project) zip(mod time.Time) ([]byte, error) {
+	names := make([]string, 0, len(p))
+	for name := range p {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range names {
+		w, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     name,
+			Method:   zip.Deflate,
+			Modified: mod,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(p[name]); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
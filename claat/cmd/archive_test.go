@@ -0,0 +1,221 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd_test
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googlecodelabs/tools/claat/cmd"
+	"github.com/googlecodelabs/tools/claat/types"
+)
+
+func TestExportCodelabArchives(t *testing.T) {
+	tests := []struct {
+		name string
+		// src files of the archives source dir, or nil to use code blocks
+		src   map[string]string
+		want  map[string]map[string]string
+		links []string
+	}{
+		{
+			name: "Code",
+			want: map[string]map[string]string{
+				"step-1.zip": {
+					"README.txt": "Say hello.\n",
+					"go.mod":     "module example.com/hello\n",
+					"main.go":    "package main\n\nfunc main() {\n}\n",
+				},
+				"step-2.zip": {
+					"README.txt": "Say hello.\n",
+					"go.mod":     "module example.com/hello\n",
+					"main.go":    "package main\n\nfunc main() {\n\tprintln(\"hello, world\")\n}\n",
+				},
+			},
+			links: []string{"zip/step-1.zip", "zip/step-1.zip", "zip/step-2.zip"},
+		},
+		{
+			name: "Dir",
+			src: map[string]string{
+				"step-0/README": "start here\n",
+				"step-1/README": "done\n",
+				"step-2/README": "done\n",
+			},
+			want: map[string]map[string]string{
+				"step-0.zip": {"README": "start here\n"},
+				"step-1.zip": {"README": "done\n"},
+			},
+			links: []string{"zip/step-0.zip", "zip/step-1.zip"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tmp, err := ioutil.TempDir("", "TestExportCodelabArchives-*")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(tmp)
+
+			archives := "code"
+			if tc.src != nil {
+				archives = filepath.Join(tmp, "src")
+				for name, content := range tc.src {
+					f := filepath.Join(archives, filepath.FromSlash(name))
+					if err := os.MkdirAll(filepath.Dir(f), 0755); err != nil {
+						t.Fatal(err)
+					}
+					if err := ioutil.WriteFile(f, []byte(content), 0644); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			out := filepath.Join(tmp, "out")
+			meta, err := cmd.ExportCodelab("testdata/extract.md", nil, cmd.CmdExportOptions{
+				Archives: archives,
+				Expenv:   "web",
+				Output:   out,
+				Tmplout:  "html",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			dir := filepath.Join(out, meta.ID)
+			zips, err := filepath.Glob(filepath.Join(dir, "zip", "*.zip"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := map[string]map[string]string{}
+			for _, z := range zips {
+				got[filepath.Base(z)] = readZip(t, z)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("ExportCodelab() archives got diff (-want +got):\n%s", diff)
+			}
+
+			b, err := ioutil.ReadFile(filepath.Join(dir, "index.html"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var links []string
+			for _, s := range strings.Split(string(b), `<a href="`)[1:] {
+				if strings.HasPrefix(s, "zip/") {
+					links = append(links, s[:strings.IndexByte(s, '"')])
+				}
+			}
+			if diff := cmp.Diff(tc.links, links); diff != "" {
+				t.Errorf("ExportCodelab() archive links got diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUpdateCodelabArchivesDir(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "TestUpdateCodelabArchivesDir-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	src, err := filepath.Abs("testdata/extract.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	readme := filepath.Join(tmp, "src", "step-1", "README")
+	if err := os.MkdirAll(filepath.Dir(readme), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(readme, []byte("done\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// export with a relative archives dir, and update from another dir
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(tmp); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	exportDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta, err := cmd.ExportCodelab(src, nil, cmd.CmdExportOptions{
+		Archives: "src",
+		Expenv:   "web",
+		Output:   "out",
+		Tmplout:  "html",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(wd); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(tmp, "out", meta.ID)
+	b, err := ioutil.ReadFile(filepath.Join(dir, "codelab.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cm types.ContextMeta
+	if err := json.Unmarshal(b, &cm); err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(exportDir, "src"); cm.Archives != want {
+		t.Errorf("codelab.json archives = %q, want %q", cm.Archives, want)
+	}
+
+	if err := ioutil.WriteFile(readme, []byte("updated\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmd.UpdateCodelab(dir, cmd.CmdUpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	got := readZip(t, filepath.Join(dir, "zip", "step-1.zip"))
+	if want := map[string]string{"README": "updated\n"}; !cmp.Equal(got, want) {
+		t.Errorf("UpdateCodelab() step-1.zip = %v, want %v", got, want)
+	}
+}
+
+func readZip(t *testing.T, name string) map[string]string {
+	t.Helper()
+	zr, err := zip.OpenReader(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	files := map[string]string{}
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = string(b)
+	}
+	return files
+}
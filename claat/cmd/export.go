@@ -15,6 +15,7 @@
 package cmd
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -23,27 +24,54 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"time"
 
 	"github.com/googlecodelabs/tools/claat/fetch"
+	"github.com/googlecodelabs/tools/claat/i18n"
+	"github.com/googlecodelabs/tools/claat/outfs"
 	"github.com/googlecodelabs/tools/claat/render"
+	"github.com/googlecodelabs/tools/claat/transform"
 	"github.com/googlecodelabs/tools/claat/types"
 	"github.com/googlecodelabs/tools/claat/util"
 )
 
 // Options type to make the CmdExport signature succinct.
 type CmdExportOptions struct {
+	// Archives is the source of per-step code archives: "code" to extract
+	// them from tagged code blocks, or a directory with step-N subdirectories,
+	// which is recorded in the codelab metadata as an absolute path.
+	// Leave empty to not generate archives.
+	Archives string
+	// AssetHosts are hosts of remote downloads, such as PDFs or zip archives,
+	// which are copied into the codelab assets dir when linked.
+	// A "*." prefix matches any subdomain.
+	AssetHosts []string
 	// AuthToken is the token to use for the Drive API.
 	AuthToken string
+	// Backup keeps the previous version of each exported codelab
+	// in a hidden sibling directory, e.g. ".codelab-id.bak".
+	Backup bool
 	// Expenv is the codelab environment to export to.
 	Expenv string
 	// ExtraVars is extra template variables.
 	ExtraVars map[string]string
+	// Images is the image mode: fetch.ImagesSlurp, fetch.ImagesKeep
+	// or fetch.ImagesRewrite. The default is fetch.ImagesSlurp.
+	Images string
+	// ImageURL is the template of image URLs in fetch.ImagesRewrite mode,
+	// e.g. "https://cdn.example.com/{{.ID}}/{{.Hash}}{{.Ext}}".
+	ImageURL string
 	// GlobalGA is the global Google Analytics account to use.
 	GlobalGA string
-	// Output is the output directory, or "-" for stdout.
+	// Output is the output directory, "-" for stdout, or a zip or tar.gz
+	// archive file, e.g. "site.zip", which replaces any existing file.
 	Output string
+	// OutputFS is the file system to export codelabs into, instead of Output,
+	// e.g. an outfs.MemFS. Codelabs are stored as in an output directory,
+	// but previous versions and other translations are not looked up.
+	OutputFS outfs.FS
 	// PassMetadata are the extra metadata fields to pass along.
 	PassMetadata map[string]bool
 	// Prefix is a URL prefix to prepend when using HTML format.
@@ -52,6 +80,30 @@ type CmdExportOptions struct {
 	Srcs []string
 	// Tmplout is the output format.
 	Tmplout string
+	// Transforms are names of registered transforms to apply
+	// to the codelab before rendering, in order.
+	Transforms []string
+	// Plugins are paths to executables which modify the codelab
+	// after Transforms, in order. See runPlugin for the protocol.
+	Plugins []string
+	// DefaultLang is the language of codelabs exported directly into
+	// their ID directory. Codelabs in other languages are exported
+	// as translations into a subdirectory, e.g. "codelab-id/ja".
+	// The default is types.DefaultLang.
+	DefaultLang string
+	// Math enables parsing of $...$ and $$...$$ math expressions
+	// in codelab text, which are rendered as MathML.
+	Math bool
+	// Diagrams enables pre-rendering of diagrams into SVG images
+	// with the dot and mmdc commands, which must be installed.
+	Diagrams bool
+	// ResponsiveImages enables processing of codelab images:
+	// metadata is stripped, and large images are downscaled to their
+	// display width at 1x and 2x pixel density, with WebP variants.
+	ResponsiveImages bool
+	// MaxAssetSize is the maximum size of an image or another asset
+	// in bytes. The default is fetch.DefaultMaxAssetSize.
+	MaxAssetSize int64
 }
 
 // CmdExport is the "claat export ..." subcommand.
@@ -63,26 +115,59 @@ func CmdExport(opts CmdExportOptions) int {
 	}
 	type result struct {
 		src  string
+		ec   *exportedCodelab
 		meta *types.Meta
 		err  error
 	}
 	srcs := util.Unique(opts.Srcs)
+	out, err := openOutput(opts)
+	if err != nil {
+		log.Printf("%s: %v", opts.Output, err)
+		return 1
+	}
+
+	// all codelabs are fetched before writing any of them,
+	// so that translations exported together link to each other
 	ch := make(chan *result, len(srcs))
 	for _, src := range srcs {
 		go func(src string) {
-			meta, err := ExportCodelab(src, nil, opts)
-			ch <- &result{src, meta, err}
+			ec, err := prepareCodelab(src, nil, out, opts, nil)
+			ch <- &result{src: src, ec: ec, err: err}
 		}(src)
 	}
+	var prepared []*exportedCodelab
+	langs := make(map[string][]string) // languages by codelab ID
 	for range srcs {
 		res := <-ch
 		if res.err != nil {
 			exitCode = 1
 			log.Printf(reportErr, res.src, res.err)
-		} else if !isStdout(opts.Output) {
+			continue
+		}
+		prepared = append(prepared, res.ec)
+		m := &res.ec.clab.Meta
+		langs[m.ID] = append(langs[m.ID], langOrDefault(m.Lang, res.ec.ctx.DefaultLanguage()))
+	}
+
+	for _, ec := range prepared {
+		go func(ec *exportedCodelab) {
+			meta, err := ec.write(out, opts, langs[ec.clab.ID])
+			ch <- &result{src: ec.src, meta: meta, err: err}
+		}(ec)
+	}
+	for range prepared {
+		res := <-ch
+		if res.err != nil {
+			exitCode = 1
+			log.Printf(reportErr, res.src, res.err)
+		} else if !out.isStdout() {
 			log.Printf(reportOk, res.meta.ID)
 		}
 	}
+	if err := out.Close(); err != nil {
+		exitCode = 1
+		log.Printf("%s: %v", opts.Output, err)
+	}
 	return exitCode
 }
 
@@ -91,167 +176,427 @@ func CmdExport(opts CmdExportOptions) int {
 //
 // Stored results include codelab content formatted in tmplout, its assets
 // and metadata in JSON format.
+// Translations, i.e. codelabs with lang metadata other than
+// opts.DefaultLang, are stored in a subdirectory of the codelab
+// named after their language, e.g. "codelab-id/ja".
 //
 // There's a special case where basedir has a value of "-", in which
 // nothing is stored on disk and the only output, codelab formatted content,
-// is printed to stdout.
+// is printed to stdout. The results can also be stored in an archive
+// or opts.OutputFS instead, see CmdExportOptions.
 //
 // An alternate http.RoundTripper may be specified if desired. Leave null for default.
 func ExportCodelab(src string, rt http.RoundTripper, opts CmdExportOptions) (*types.Meta, error) {
+	out, err := openOutput(opts)
+	if err != nil {
+		return nil, err
+	}
+	var meta *types.Meta
+	ec, err := prepareCodelab(src, rt, out, opts, nil)
+	if err == nil {
+		meta, err = ec.write(out, opts, nil)
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// exportedCodelab is a fetched and transformed codelab,
+// ready to be written to the output.
+type exportedCodelab struct {
+	src   string
+	clab  *types.Codelab
+	ctx   *types.Context
+	files []*pluginFile     // additional plugin output
+	i18n  *i18n.MergeResult // translation of the codelab, if any
+	stage string            // staging dir in the output dir, if any
+	mem   *outfs.MemFS      // staging file system, if the output is not a dir
+}
+
+// staged returns the file system ec is staged in, or nil for stdout.
+func (ec *exportedCodelab) staged() outfs.FS {
+	switch {
+	case ec.stage != "":
+		return outfs.Dir(ec.stage)
+	case ec.mem != nil:
+		return ec.mem
+	}
+	return nil
+}
+
+// prepareCodelab fetches and parses codelab src, downloading its images
+// into a staging area of out, and applies transforms and plugins.
+// If cat is not nil, the codelab is translated with it before transforms.
+func prepareCodelab(src string, rt http.RoundTripper, out *output, opts CmdExportOptions, cat *i18n.Catalog) (ec *exportedCodelab, err error) {
+	// the codelab is exported into a staging dir first,
+	// which replaces the previous version in write;
+	// other outputs are staged in memory
+	var stage string
+	var mem *outfs.MemFS
+	switch {
+	case out.dir != "":
+		if stage, err = newStagingDir(out.dir); err != nil {
+			return nil, err
+		}
+		defer func() {
+			if err != nil {
+				os.RemoveAll(stage)
+			}
+		}()
+	case out.fs != nil:
+		mem = outfs.NewMemFS()
+	}
 	f, err := fetch.NewFetcher(opts.AuthToken, opts.PassMetadata, rt)
 	if err != nil {
 		return nil, err
 	}
-	clab, err := f.SlurpCodelab(src, opts.Output)
+	f.DefaultLang = opts.DefaultLang
+	f.Math = opts.Math
+	f.Diagrams = opts.Diagrams
+	f.ResponsiveImages = opts.ResponsiveImages
+	f.MaxAssetSize = opts.MaxAssetSize
+	f.AssetHosts = opts.AssetHosts
+	f.Images = opts.Images
+	f.ImageURL = opts.ImageURL
+	if cat != nil {
+		f.Lang = cat.TargetLang
+	}
+	archives, err := archivesSource(opts.Archives)
 	if err != nil {
 		return nil, err
 	}
 
-	// codelab export context
+	// codelab export context; the update timestamp and the manifest
+	// are set once the codelab and its assets are fetched
+	ctx := &types.Context{
+		Env:              opts.Expenv,
+		Format:           opts.Tmplout,
+		Prefix:           opts.Prefix,
+		MainGA:           opts.GlobalGA,
+		Archives:         archives,
+		Transforms:       opts.Transforms,
+		Plugins:          opts.Plugins,
+		DefaultLang:      opts.DefaultLang,
+		Math:             opts.Math,
+		Diagrams:         opts.Diagrams,
+		ResponsiveImages: opts.ResponsiveImages,
+		MaxAssetSize:     opts.MaxAssetSize,
+		AssetHosts:       opts.AssetHosts,
+		Images:           opts.Images,
+		ImageURL:         opts.ImageURL,
+	}
+	// translate, transform and pipe the codelab through plugins
+	// before its assets are stored
+	var merged *i18n.MergeResult
+	var files []*pluginFile
+	f.Transform = func(clab *types.Codelab) error {
+		if cat != nil {
+			merged = i18n.Merge(clab, cat)
+		}
+		if err := transform.Apply(clab, opts.Transforms...); err != nil {
+			return err
+		}
+		var err error
+		files, err = runPlugins(opts.Plugins, clab, ctx)
+		return err
+	}
+	ec = &exportedCodelab{src: src, stage: stage, mem: mem}
+	clab, err := f.SlurpCodelabFS(src, ec.staged())
+	if err != nil {
+		return nil, err
+	}
 	lastmod := types.ContextTime(clab.Mod)
 	clab.Meta.Source = src
-	meta := &clab.Meta
+	ctx.Updated = &lastmod
+	ctx.Manifest = clab.Manifest
+	ec.clab, ec.ctx, ec.files, ec.i18n = clab.Codelab, ctx, files, merged
+	return ec, nil
+}
 
-	dir := opts.Output // output dir or stdout
-	if !isStdout(dir) {
-		dir = codelabDir(dir, meta)
-	}
-	// write codelab and its metadata to disk
-	return meta, writeCodelab(dir, clab.Codelab, opts.ExtraVars, &types.Context{
-		Env:     opts.Expenv,
-		Format:  opts.Tmplout,
-		Prefix:  opts.Prefix,
-		MainGA:  opts.GlobalGA,
-		Updated: &lastmod,
-	})
+// write stores the codelab in out.
+// The langs argument are languages of other translations
+// of the codelab exported at the same time.
+//
+// The codelab is written into its staging area, which then replaces
+// the previous version of the codelab dir, or is copied into the output
+// file system. On failure, the previous version is kept and the staging
+// area is removed.
+func (ec *exportedCodelab) write(out *output, opts CmdExportOptions, langs []string) (*types.Meta, error) {
+	meta := &ec.clab.Meta
+	lang := ec.ctx.DefaultLanguage()
+	fsys := ec.staged()
+	if fsys == nil {
+		return meta, writeCodelab(nil, "", ec.clab, opts.ExtraVars, ec.ctx)
+	}
+	if ec.stage != "" {
+		defer os.RemoveAll(ec.stage)
+	}
+
+	rel, err := meta.Dir(lang)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.ToSlash(rel)
+	meta.Locales = codelabLocales(out.dir, meta, lang, langs)
+	// step archives are stored next to images, if any
+	if err := writeArchives(fsys, dir, ec.clab, ec.ctx); err != nil {
+		return nil, err
+	}
+	// write codelab and its metadata
+	if err := writeCodelab(fsys, dir, ec.clab, opts.ExtraVars, ec.ctx); err != nil {
+		return nil, err
+	}
+	if err := writePluginFiles(fsys, dir, ec.files); err != nil {
+		return nil, err
+	}
+	if ec.mem != nil {
+		return meta, ec.mem.CopyTo(out.fs)
+	}
+
+	commitMu.Lock()
+	defer commitMu.Unlock()
+	staged := filepath.Join(ec.stage, filepath.FromSlash(dir))
+	if err := commitDir(staged, filepath.Join(out.dir, rel), opts.Backup); err != nil {
+		return nil, err
+	}
+	if err := updateLocales(out.dir, meta, lang, langs); err != nil {
+		return nil, err
+	}
+	return meta, nil
 }
 
 func ExportCodelabMemory(src io.ReadCloser, w io.Writer, opts CmdExportOptions) (*types.Meta, error) {
 	m := fetch.NewMemoryFetcher(opts.PassMetadata)
+	m.Math = opts.Math
 	clab, err := m.SlurpCodelab(src)
 	if err != nil {
 		return nil, err
 	}
+	if err := transform.Apply(clab.Codelab, opts.Transforms...); err != nil {
+		return nil, err
+	}
 
 	// codelab export context
 	lastmod := types.ContextTime(clab.Mod)
 	meta := &clab.Meta
 	ctx := &types.Context{
-		Env:     opts.Expenv,
-		Format:  opts.Tmplout,
-		Prefix:  opts.Prefix,
-		MainGA:  opts.GlobalGA,
-		Updated: &lastmod,
+		Env:        opts.Expenv,
+		Format:     opts.Tmplout,
+		Prefix:     opts.Prefix,
+		MainGA:     opts.GlobalGA,
+		Updated:    &lastmod,
+		Transforms: opts.Transforms,
 	}
 
 	return meta, writeCodelabWriter(w, clab.Codelab, opts.ExtraVars, ctx)
 }
 
-func writeCodelabWriter(w io.Writer, clab *types.Codelab, extraVars map[string]string, ctx *types.Context) error {
-	// main content file(s)
-	data := &struct {
-		render.Context
-		Current *types.Step
-		StepNum int
-		Prev    bool
-		Next    bool
-	}{Context: render.Context{
-		Env:      ctx.Env,
-		Prefix:   ctx.Prefix,
-		Format:   ctx.Format,
-		GlobalGA: ctx.MainGA,
-		Updated:  time.Time(*ctx.Updated).Format(time.RFC3339),
-		Meta:     &clab.Meta,
-		Steps:    clab.Steps,
-		Extra:    extraVars,
-	}}
+// ExportCodelabFS exports codelab source src in memory, in any output
+// format, and returns the exported files as they would be stored in
+// an output directory: codelab content, images and metadata, e.g.
+// "codelab-id/index.html". Nothing is read from or written to disk,
+// except for themes specified by path in opts.Tmplout.
+//
+// The typ argument is the source type, the name of a registered parser,
+// e.g. "md" or "gdoc"; markdown is assumed if it is empty. Imports, images,
+// code snippets and linked files of the codelab are opened with resolve,
+// see fetch.MemoryFetcher.
+// Output options are ignored. Plugins, remote asset hosts, pre-rendered
+// diagrams and step archives from a directory, which need the disk,
+// the network or external commands, are rejected.
+func ExportCodelabFS(src io.Reader, typ string, resolve fetch.Resolver, opts CmdExportOptions) (*types.Meta, *outfs.MemFS, error) {
+	if opts.Archives != "" && opts.Archives != archivesFromCode {
+		return nil, nil, fmt.Errorf("step archives from %s are not supported in memory", opts.Archives)
+	}
+	if len(opts.AssetHosts) > 0 {
+		return nil, nil, errors.New("assets from remote hosts are not supported in memory")
+	}
+	if len(opts.Plugins) > 0 {
+		return nil, nil, errors.New("plugins are not supported in memory")
+	}
+	if opts.Diagrams {
+		return nil, nil, errors.New("pre-rendered diagrams are not supported in memory")
+	}
+	m := fetch.NewMemoryFetcher(opts.PassMetadata)
+	m.Type = typ
+	m.Resolver = resolve
+	m.DefaultLang = opts.DefaultLang
+	m.Math = opts.Math
+	m.ResponsiveImages = opts.ResponsiveImages
+	m.MaxAssetSize = opts.MaxAssetSize
+	m.Images = opts.Images
+	m.ImageURL = opts.ImageURL
+	m.Transform = func(clab *types.Codelab) error {
+		return transform.Apply(clab, opts.Transforms...)
+	}
+	mem := outfs.NewMemFS()
+	clab, err := m.SlurpCodelabFS(ioutil.NopCloser(src), mem)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	if ctx.Format == "offline" {
-		return errors.New("exporting codelab offline is not supported for In-Memory Export")
+	// codelab export context
+	lastmod := types.ContextTime(clab.Mod)
+	ctx := &types.Context{
+		Env:              opts.Expenv,
+		Format:           opts.Tmplout,
+		Prefix:           opts.Prefix,
+		MainGA:           opts.GlobalGA,
+		Updated:          &lastmod,
+		Archives:         opts.Archives,
+		Transforms:       opts.Transforms,
+		DefaultLang:      opts.DefaultLang,
+		Math:             opts.Math,
+		ResponsiveImages: opts.ResponsiveImages,
+		MaxAssetSize:     opts.MaxAssetSize,
+		Images:           opts.Images,
+		ImageURL:         opts.ImageURL,
+		Manifest:         clab.Manifest,
+	}
+	meta := &clab.Meta
+	lang := ctx.DefaultLanguage()
+	meta.Locales = codelabLocales("", meta, lang, nil)
+	rel, err := meta.Dir(lang)
+	if err != nil {
+		return nil, nil, err
+	}
+	dir := filepath.ToSlash(rel)
+	if err := writeArchives(mem, dir, clab.Codelab, ctx); err != nil {
+		return nil, nil, err
 	}
+	if err := writeCodelab(mem, dir, clab.Codelab, opts.ExtraVars, ctx); err != nil {
+		return nil, nil, err
+	}
+	return meta, mem, nil
+}
 
-	return render.Execute(w, ctx.Format, data)
+func writeCodelabWriter(w io.Writer, clab *types.Codelab, extraVars map[string]string, ctx *types.Context) error {
+	f, err := render.LookupFormat(ctx.Format)
+	if err != nil {
+		return err
+	}
+	if f.PerStep() {
+		return fmt.Errorf("exporting codelab in %s format is not supported for In-Memory Export", ctx.Format)
+	}
+	var buf bytes.Buffer
+	if err := f.Render(&buf, newPage(clab, extraVars, ctx)); err != nil {
+		return err
+	}
+	_, err = buf.WriteTo(w)
+	return err
 }
 
 // writeCodelab stores codelab main content in ctx.Format and its metadata
-// in JSON format on disk.
+// in JSON format in directory dir of fsys, or prints the content
+// to stdout if fsys is nil.
 // extraVars is extra variables to pass into the template context.
-func writeCodelab(dir string, clab *types.Codelab, extraVars map[string]string, ctx *types.Context) error {
+func writeCodelab(fsys outfs.FS, dir string, clab *types.Codelab, extraVars map[string]string, ctx *types.Context) error {
+	f, err := render.LookupFormat(ctx.Format)
+	if err != nil {
+		return err
+	}
 	// output to stdout does not include metadata
-	if !isStdout(dir) {
-		// make sure codelab dir exists
-		if err := os.MkdirAll(dir, 0755); err != nil {
-			return err
-		}
+	if fsys != nil {
 		// codelab metadata
 		cm := &types.ContextMeta{Context: *ctx, Meta: clab.Meta}
-		f := filepath.Join(dir, metaFilename)
-		if err := writeMeta(f, cm); err != nil {
+		if err := writeMeta(fsys, path.Join(dir, metaFilename), cm); err != nil {
 			return err
 		}
 	}
 
 	// main content file(s)
-	data := &struct {
-		render.Context
-		Current *types.Step
-		StepNum int
-		Prev    bool
-		Next    bool
-	}{Context: render.Context{
-		Env:      ctx.Env,
-		Prefix:   ctx.Prefix,
-		Format:   ctx.Format,
-		GlobalGA: ctx.MainGA,
-		Updated:  time.Time(*ctx.Updated).Format(time.RFC3339),
-		Meta:     &clab.Meta,
-		Steps:    clab.Steps,
-		Extra:    extraVars,
-	}}
-	if ctx.Format != "offline" {
-		w := os.Stdout
-		if !isStdout(dir) {
-			ext := ctx.Format
-			if ext != "md" {
-				ext = "html"
-			}
-			f, err := os.Create(filepath.Join(dir, "index."+ext))
-			if err != nil {
-				return err
-			}
-			w = f
-			defer f.Close()
+	data := newPage(clab, extraVars, ctx)
+	if err := writeFormatFiles(fsys, dir, f, clab, data); err != nil {
+		return err
+	}
+
+	// theme assets are not printed to stdout
+	if sf, ok := f.(render.StaticFormat); ok && fsys != nil {
+		if src := sf.StaticDir(); src != "" {
+			return copyDir(fsys, path.Join(dir, filepath.Base(src)), src)
 		}
-		return render.Execute(w, ctx.Format, data)
+	}
+	return nil
+}
+
+// writeFormatFiles renders clab in format f, into a single file
+// or a file per step.
+func writeFormatFiles(fsys outfs.FS, dir string, f render.Format, clab *types.Codelab, data *render.Page) error {
+	if !f.PerStep() {
+		return writeFormatFile(fsys, dir, f, 0, data)
 	}
 	for i, step := range clab.Steps {
 		data.Current = step
 		data.StepNum = i + 1
 		data.Prev = i > 0
 		data.Next = i < len(clab.Steps)-1
-		w := os.Stdout
-		if !isStdout(dir) {
-			name := "index.html"
-			if i > 0 {
-				name = fmt.Sprintf("step-%d.html", i+1)
-			}
-			f, err := os.Create(filepath.Join(dir, name))
-			if err != nil {
-				return err
-			}
-			w = f
-			defer f.Close()
-		}
-		if err := render.Execute(w, ctx.Format, data); err != nil {
+		if err := writeFormatFile(fsys, dir, f, i+1, data); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-// writeMeta writes codelab metadata to a local disk location
-// specified by path.
-func writeMeta(path string, cm *types.ContextMeta) error {
+// copyDir copies regular files of local directory src into directory dst
+// of fsys, recursively.
+func copyDir(fsys outfs.FS, dst, src string) error {
+	return filepath.Walk(src, func(p string, fi os.FileInfo, err error) error {
+		if err != nil || !fi.Mode().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		return fsys.WriteFile(path.Join(dst, filepath.ToSlash(rel)), b)
+	})
+}
+
+// writeFormatFile renders output file of step n in format f into directory
+// dir of fsys, or prints it to stdout if fsys is nil.
+// The file is rendered in memory first, so that a rendering error
+// does not leave a truncated file behind.
+func writeFormatFile(fsys outfs.FS, dir string, f render.Format, n int, data *render.Page) error {
+	var buf bytes.Buffer
+	if err := f.Render(&buf, data); err != nil {
+		return err
+	}
+	if fsys == nil {
+		_, err := buf.WriteTo(os.Stdout)
+		return err
+	}
+	return fsys.WriteFile(path.Join(dir, f.Filename(n)), buf.Bytes())
+}
+
+// newPage creates rendering context of clab.
+func newPage(clab *types.Codelab, extraVars map[string]string, ctx *types.Context) *render.Page {
+	var locales []*render.Locale
+	if len(clab.Locales) > 0 {
+		locales = render.NewLocales(clab.Locales, clab.Lang, ctx.DefaultLanguage())
+	}
+	return &render.Page{Context: render.Context{
+		Env:      ctx.Env,
+		Prefix:   ctx.Prefix,
+		Format:   ctx.Format,
+		GlobalGA: ctx.MainGA,
+		Updated:  time.Time(*ctx.Updated).Format(time.RFC3339),
+		Meta:     &clab.Meta,
+		Steps:    clab.Steps,
+		Extra:    extraVars,
+		Locales:  locales,
+	}}
+}
+
+// writeMeta writes codelab metadata to file name of fsys.
+func writeMeta(fsys outfs.FS, name string, cm *types.ContextMeta) error {
 	if cm.Context.Format == "htmlElements" {
 		cm.Context.Format = "html"
 	}
@@ -260,5 +605,5 @@ func writeMeta(path string, cm *types.ContextMeta) error {
 		return err
 	}
 	b = append(b, '\n')
-	return ioutil.WriteFile(path, b, 0644)
+	return fsys.WriteFile(name, b)
 }
@@ -16,16 +16,31 @@
 package cmd_test
 
 import (
+	"archive/tar"
+	"archive/zip"
 	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
 	"io/ioutil"
 	"os"
 	"path"
 	"reflect"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/googlecodelabs/tools/claat/cmd"
+	"github.com/googlecodelabs/tools/claat/fetch"
+	"github.com/googlecodelabs/tools/claat/nodes"
+	"github.com/googlecodelabs/tools/claat/outfs"
+	"github.com/googlecodelabs/tools/claat/transform"
+	"github.com/googlecodelabs/tools/claat/types"
 )
 
 func TestExportCodelabMemory(t *testing.T) {
@@ -138,3 +153,978 @@ func filterIgnoredLinePrefix(content string) string {
 
 	return strings.Join(processedContent, "\n")
 }
+
+func init() {
+	transform.Register("test-banner", func(clab *types.Codelab) error {
+		s := &types.Step{Title: "Before you begin", Content: nodes.NewListNode(nodes.NewTextNode(nodes.NewTextNodeOptions{Value: "Banner"}))}
+		clab.Steps = append([]*types.Step{s}, clab.Steps...)
+		return nil
+	})
+	transform.Register("test-drop-last-step", func(clab *types.Codelab) error {
+		clab.Steps = clab.Steps[:len(clab.Steps)-1]
+		return nil
+	})
+}
+
+func TestExportCodelabTransforms(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "TestExportCodelabTransforms-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	opts := cmd.CmdExportOptions{
+		Expenv:     "web",
+		Output:     tmp,
+		Tmplout:    "md",
+		Transforms: []string{"test-banner"},
+	}
+	meta, err := cmd.ExportCodelab("testdata/simple-2-steps.md", nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(path.Join(tmp, meta.ID, "index.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	banner := strings.Index(string(b), "## Before you begin")
+	first := strings.Index(string(b), "## Step 1")
+	if banner < 0 || banner > first {
+		t.Errorf("index.md does not start with the banner step:\n%s", b)
+	}
+	b, err = ioutil.ReadFile(path.Join(tmp, meta.ID, "codelab.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"test-banner"`) {
+		t.Errorf("codelab.json does not record transforms:\n%s", b)
+	}
+
+	opts.Transforms = []string{"no-such-transform"}
+	if _, err := cmd.ExportCodelab("testdata/simple-2-steps.md", nil, opts); err == nil {
+		t.Error("ExportCodelab with an unknown transform returned nil error")
+	}
+}
+
+func TestExportCodelabTransformsBeforeImages(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "TestExportCodelabTransformsBeforeImages-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	for i, name := range []string{"kept.png", "dropped.png"} {
+		var img bytes.Buffer
+		if err := png.Encode(&img, image.NewGray(image.Rect(0, 0, i+1, i+1))); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path.Join(tmp, name), img.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	src := path.Join(tmp, "codelab.md")
+	md := "id: transformed\n\n# Transformed\n\n## Step 1\n\n![kept](kept.png)\n\n## Step 2\n\n![dropped](dropped.png)\n"
+	if err := ioutil.WriteFile(src, []byte(md), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := cmd.CmdExportOptions{
+		Expenv:     "web",
+		Output:     path.Join(tmp, "out"),
+		Tmplout:    "md",
+		Transforms: []string{"test-drop-last-step"},
+	}
+	meta, err := cmd.ExportCodelab(src, nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// images of the removed step are neither stored nor in the manifest
+	files, err := ioutil.ReadDir(path.Join(opts.Output, meta.ID, "img"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("img dir has %d files, want 1", len(files))
+	}
+	b, err := ioutil.ReadFile(path.Join(opts.Output, meta.ID, "codelab.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cm types.ContextMeta
+	if err := json.Unmarshal(b, &cm); err != nil {
+		t.Fatal(err)
+	}
+	if len(cm.Manifest) != 1 {
+		t.Errorf("codelab.json manifest = %+v, want one image", cm.Manifest)
+	}
+}
+
+func TestExportCodelabPerStep(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "TestExportCodelabPerStep-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	opts := cmd.CmdExportOptions{
+		Expenv:  "web",
+		Output:  tmp,
+		Tmplout: "offline",
+	}
+	meta, err := cmd.ExportCodelab("testdata/simple-2-steps.md", nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"index.html", "step-2.html"} {
+		b, err := ioutil.ReadFile(path.Join(tmp, meta.ID, name))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if !strings.Contains(string(b), "Content") {
+			t.Errorf("%s does not contain step content:\n%s", name, b)
+		}
+	}
+	src, err := os.Open("testdata/simple-2-steps.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = cmd.ExportCodelabMemory(src, ioutil.Discard, opts)
+	if err == nil || !strings.Contains(err.Error(), "offline") {
+		t.Errorf("ExportCodelabMemory in offline format returned error %v, want unsupported format", err)
+	}
+}
+
+func TestExportCodelabTheme(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "TestExportCodelabTheme-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	theme := path.Join(tmp, "theme")
+	files := map[string]string{
+		"head.tmpl":              `{{define "head"}}<link rel="stylesheet" href="static/theme.css">{{end}}`,
+		"static/theme.css":       "body {}",
+		"static/fonts/font.woff": "font",
+	}
+	for name, content := range files {
+		p := path.Join(theme, name)
+		if err := os.MkdirAll(path.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out := path.Join(tmp, "out")
+	opts := cmd.CmdExportOptions{
+		Expenv:  "web",
+		Output:  out,
+		Tmplout: theme,
+	}
+	meta, err := cmd.ExportCodelab("testdata/simple-2-steps.md", nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(path.Join(out, meta.ID, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `href="static/theme.css"`) {
+		t.Errorf("index.html does not contain the theme head:\n%s", b)
+	}
+	for _, name := range []string{"static/theme.css", "static/fonts/font.woff"} {
+		b, err := ioutil.ReadFile(path.Join(out, meta.ID, name))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if string(b) != files[name] {
+			t.Errorf("%s = %q, want %q", name, b, files[name])
+		}
+	}
+}
+
+func TestExportCodelabTemplateError(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "TestExportCodelabTemplateError-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	tmpl := path.Join(tmp, "broken.html")
+	if err := ioutil.WriteFile(tmpl, []byte("<h1>{{.Meta.Title}}</h1>{{.Meta.Missing}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := path.Join(tmp, "out")
+	opts := cmd.CmdExportOptions{
+		Expenv:  "web",
+		Output:  out,
+		Tmplout: "html",
+	}
+	meta, err := cmd.ExportCodelab("testdata/simple-2-steps.md", nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	index := path.Join(out, meta.ID, "index.html")
+	want, err := ioutil.ReadFile(index)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts.Tmplout = tmpl
+	if _, err := cmd.ExportCodelab("testdata/simple-2-steps.md", nil, opts); err == nil {
+		t.Fatal("ExportCodelab with a broken template returned nil error")
+	}
+	got, err := ioutil.ReadFile(index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("index.html changed after a template error:\n%s", got)
+	}
+}
+
+func TestExportTranslations(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "TestExportTranslations-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	code := cmd.CmdExport(cmd.CmdExportOptions{
+		DefaultLang: "en",
+		Expenv:      "web",
+		Output:      tmp,
+		Srcs:        []string{"testdata/simple-2-steps.md", "testdata/simple-2-steps-ja.md"},
+		Tmplout:     "html",
+	})
+	if code != 0 {
+		t.Fatalf("CmdExport() = %d, want 0", code)
+	}
+
+	b, err := ioutil.ReadFile(path.Join(tmp, "example", "ja", "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		`<html lang="ja" dir="ltr">`,
+		`<link rel="alternate" hreflang="en" href="../">`,
+		`<link rel="alternate" hreflang="ja" href="../ja/">`,
+	} {
+		if !strings.Contains(string(b), s) {
+			t.Errorf("example/ja/index.html does not contain %s", s)
+		}
+	}
+	b, err = ioutil.ReadFile(path.Join(tmp, "example", "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := `<link rel="alternate" hreflang="ja" href="./ja/">`; !strings.Contains(string(b), s) {
+		t.Errorf("example/index.html does not contain %s", s)
+	}
+
+	for _, dir := range []string{"example", "example/ja"} {
+		b, err := ioutil.ReadFile(path.Join(tmp, dir, "codelab.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var meta types.Meta
+		if err := json.Unmarshal(b, &meta); err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{"en", "ja"}; !reflect.DeepEqual(meta.Locales, want) {
+			t.Errorf("%s/codelab.json locales = %q, want %q", dir, meta.Locales, want)
+		}
+	}
+}
+
+func TestExportTranslationLater(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "TestExportTranslationLater-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	opts := cmd.CmdExportOptions{Expenv: "web", Output: tmp, Tmplout: "md"}
+	meta, err := cmd.ExportCodelab("testdata/simple-2-steps.md", nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Locales != nil {
+		t.Errorf("meta.Locales = %q, want nil", meta.Locales)
+	}
+	if _, err := cmd.ExportCodelab("testdata/simple-2-steps-ja.md", nil, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	// the original codelab metadata records the new translation
+	b, err := ioutil.ReadFile(path.Join(tmp, "example", "codelab.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cm types.ContextMeta
+	if err := json.Unmarshal(b, &cm); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"en", "ja"}; !reflect.DeepEqual(cm.Locales, want) {
+		t.Errorf("example/codelab.json locales = %q, want %q", cm.Locales, want)
+	}
+}
+
+func TestExportResponsiveImages(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "TestExportResponsiveImages-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	// a screenshot-like image: flat with a few lines
+	m := image.NewNRGBA(image.Rect(0, 0, 600, 400))
+	for i := range m.Pix {
+		m.Pix[i] = 0xff
+	}
+	for y := 100; y < 300; y += 20 {
+		for x := 50; x < 550; x++ {
+			m.SetNRGBA(x, y, color.NRGBA{0x20, 0x21, 0x24, 0xff})
+		}
+	}
+	var img bytes.Buffer
+	if err := png.Encode(&img, m); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path.Join(tmp, "shot.png"), img.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	src := path.Join(tmp, "codelab.md")
+	md := "id: responsive\n\n# Responsive\n\n## Step 1\n\n<img src=\"shot.png\" alt=\"shot\" width=\"200\">\n"
+	if err := ioutil.WriteFile(src, []byte(md), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := cmd.CmdExportOptions{
+		Expenv:           "web",
+		Output:           path.Join(tmp, "out"),
+		Tmplout:          "html",
+		ResponsiveImages: true,
+	}
+	meta, err := cmd.ExportCodelab(src, nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(path.Join(opts.Output, meta.ID, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		`<picture><source type="image/webp" srcset="img/`,
+		`-200w.webp 1x, img/`,
+		`-400w.webp 2x">`,
+		`width="200" height="133"`,
+	} {
+		if !strings.Contains(string(b), s) {
+			t.Errorf("index.html does not contain %s", s)
+		}
+	}
+	files, err := ioutil.ReadDir(path.Join(opts.Output, meta.ID, "img"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 4 {
+		t.Errorf("img dir has %d files, want 4 (PNG and WebP at 1x and 2x)", len(files))
+	}
+	b, err = ioutil.ReadFile(path.Join(opts.Output, meta.ID, "codelab.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"responsive_images": true`) {
+		t.Errorf("codelab.json does not record responsive images:\n%s", b)
+	}
+}
+
+func TestExportAssets(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "TestExportAssets-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	if err := ioutil.WriteFile(path.Join(tmp, "data.zip"), []byte("a,b\n1,2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	src := path.Join(tmp, "codelab.md")
+	md := "id: assets\n\n# Assets\n\n## Step 1\n\nGet [the data](data.zip) or read [the docs](https://example.com/docs).\n"
+	if err := ioutil.WriteFile(src, []byte(md), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := cmd.CmdExportOptions{
+		Expenv:  "web",
+		Output:  path.Join(tmp, "out"),
+		Tmplout: "html",
+	}
+	meta, err := cmd.ExportCodelab(src, nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(path.Join(opts.Output, meta.ID, "codelab.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cm types.ContextMeta
+	if err := json.Unmarshal(b, &cm); err != nil {
+		t.Fatal(err)
+	}
+	if len(cm.Manifest) != 1 || !strings.HasSuffix(cm.Manifest[0].Path, "-data.zip") {
+		t.Fatalf("codelab.json manifest = %+v, want one data.zip", cm.Manifest)
+	}
+	a := cm.Manifest[0]
+	data, err := ioutil.ReadFile(path.Join(opts.Output, meta.ID, a.Path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "a,b\n1,2\n" {
+		t.Errorf("%s = %q", a.Path, data)
+	}
+	if want := types.NewAsset(a.Path, a.Type, "data.zip", data); !reflect.DeepEqual(a, want) {
+		t.Errorf("manifest entry = %+v, want %+v", a, want)
+	}
+	b, err = ioutil.ReadFile(path.Join(opts.Output, meta.ID, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{`href="` + a.Path + `"`, `href="https://example.com/docs"`} {
+		if !strings.Contains(string(b), s) {
+			t.Errorf("index.html does not contain %s", s)
+		}
+	}
+}
+
+func TestExportImageModes(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "TestExportImageModes-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewGray(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path.Join(tmp, "local.png"), img.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	src := path.Join(tmp, "codelab.md")
+	md := "id: images\n\n# Images\n\n## Step 1\n\n![local](local.png)\n"
+	if err := ioutil.WriteFile(src, []byte(md), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		images, url string
+		src         string // prefix of the exported image src
+		files       int    // number of files in the img dir
+	}{
+		{images: "slurp", src: `src="img/`, files: 1},
+		{images: "keep", src: `src="local.png"`, files: 0},
+		{images: "rewrite", url: "https://cdn.example.com/{{.ID}}/{{.Hash}}{{.Ext}}", src: `src="https://cdn.example.com/images/`, files: 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.images, func(t *testing.T) {
+			opts := cmd.CmdExportOptions{
+				Expenv:   "web",
+				Output:   path.Join(tmp, tc.images),
+				Tmplout:  "html",
+				Images:   tc.images,
+				ImageURL: tc.url,
+			}
+			meta, err := cmd.ExportCodelab(src, nil, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			dir := path.Join(opts.Output, meta.ID)
+			b, err := ioutil.ReadFile(path.Join(dir, "index.html"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(b), tc.src) {
+				t.Errorf("index.html does not contain %s", tc.src)
+			}
+			// the img dir is only created for slurped images
+			files, err := ioutil.ReadDir(path.Join(dir, "img"))
+			if err != nil && !os.IsNotExist(err) {
+				t.Fatal(err)
+			}
+			if len(files) != tc.files {
+				t.Errorf("img dir has %d files, want %d", len(files), tc.files)
+			}
+			b, err = ioutil.ReadFile(path.Join(dir, "codelab.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var cm types.ContextMeta
+			if err := json.Unmarshal(b, &cm); err != nil {
+				t.Fatal(err)
+			}
+			if cm.Images != tc.images || cm.ImageURL != tc.url {
+				t.Errorf("codelab.json images = %q, %q; want %q, %q", cm.Images, cm.ImageURL, tc.images, tc.url)
+			}
+		})
+	}
+
+	opts := cmd.CmdExportOptions{Output: path.Join(tmp, "bad"), Tmplout: "html", Images: "rewrite"}
+	if _, err := cmd.ExportCodelab(src, nil, opts); err == nil {
+		t.Error("ExportCodelab(rewrite without image URL) returned nil error")
+	}
+}
+
+func TestExportStaging(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "TestExportStaging-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	for i, name := range []string{"a.png", "b.png"} {
+		var img bytes.Buffer
+		if err := png.Encode(&img, image.NewGray(image.Rect(0, 0, i+1, 1))); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path.Join(tmp, name), img.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	src := path.Join(tmp, "codelab.md")
+	export := func(img string, backup bool) error {
+		t.Helper()
+		md := "id: staged\n\n# Staged\n\n## Step 1\n\n![image](" + img + ")\n"
+		if err := ioutil.WriteFile(src, []byte(md), 0644); err != nil {
+			t.Fatal(err)
+		}
+		opts := cmd.CmdExportOptions{
+			Expenv:  "web",
+			Output:  path.Join(tmp, "out"),
+			Tmplout: "html",
+			Backup:  backup,
+		}
+		_, err := cmd.ExportCodelab(src, nil, opts)
+		return err
+	}
+	dir := path.Join(tmp, "out", "staged")
+	readManifest := func() []*types.Asset {
+		t.Helper()
+		b, err := ioutil.ReadFile(path.Join(dir, "codelab.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var cm types.ContextMeta
+		if err := json.Unmarshal(b, &cm); err != nil {
+			t.Fatal(err)
+		}
+		return cm.Manifest
+	}
+	noStaging := func() {
+		t.Helper()
+		fis, err := ioutil.ReadDir(path.Join(tmp, "out"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, fi := range fis {
+			if strings.HasPrefix(fi.Name(), ".claat-stage-") {
+				t.Errorf("staging dir %s is not removed", fi.Name())
+			}
+		}
+	}
+
+	if err := export("a.png", false); err != nil {
+		t.Fatal(err)
+	}
+	first := readManifest()
+	index, err := ioutil.ReadFile(path.Join(dir, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// files placed by hand are not owned by the export
+	for _, name := range []string{"notes.txt", "img/hand.png"} {
+		if err := ioutil.WriteFile(path.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a failed export keeps the previous version
+	if err := export("missing.png", false); err == nil {
+		t.Fatal("ExportCodelab with a missing image returned nil error")
+	}
+	got, err := ioutil.ReadFile(path.Join(dir, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, index) {
+		t.Errorf("index.html changed after a failed export:\n%s", got)
+	}
+	noStaging()
+
+	// translations are moved into the new version
+	if err := os.MkdirAll(path.Join(dir, "fr"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path.Join(dir, "fr", "codelab.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := export("b.png", true); err != nil {
+		t.Fatal(err)
+	}
+	second := readManifest()
+	if len(first) != 1 || len(second) != 1 || first[0].Path == second[0].Path {
+		t.Fatalf("manifests = %+v and %+v, want a different image each", first, second)
+	}
+	if _, err := os.Stat(path.Join(dir, first[0].Path)); !os.IsNotExist(err) {
+		t.Errorf("stale image %s is not removed: %v", first[0].Path, err)
+	}
+	for _, name := range []string{"notes.txt", "img/hand.png", "fr/codelab.json", second[0].Path} {
+		if _, err := os.Stat(path.Join(dir, name)); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	got, err = ioutil.ReadFile(path.Join(tmp, "out", ".staged.bak", "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, index) {
+		t.Errorf("backup index.html differs from the previous version:\n%s", got)
+	}
+	noStaging()
+
+	// all images of codelabs exported without a manifest are owned
+	b, err := ioutil.ReadFile(path.Join(dir, "codelab.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var legacy map[string]interface{}
+	if err := json.Unmarshal(b, &legacy); err != nil {
+		t.Fatal(err)
+	}
+	delete(legacy, "manifest")
+	if b, err = json.Marshal(legacy); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path.Join(dir, "codelab.json"), b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := export("a.png", false); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"img/hand.png", second[0].Path} {
+		if _, err := os.Stat(path.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("image %s of a codelab without a manifest is not removed: %v", name, err)
+		}
+	}
+	for _, name := range []string{"notes.txt", "fr/codelab.json", first[0].Path} {
+		if _, err := os.Stat(path.Join(dir, name)); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	noStaging()
+}
+
+func TestUpdateEmptyManifest(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "TestUpdateEmptyManifest-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	src := path.Join(tmp, "codelab.md")
+	md := "id: kept\n\n# Kept\n\n## Step 1\n\n![image](local.png)\n"
+	if err := ioutil.WriteFile(src, []byte(md), 0644); err != nil {
+		t.Fatal(err)
+	}
+	opts := cmd.CmdExportOptions{
+		Expenv:  "web",
+		Output:  tmp,
+		Tmplout: "html",
+		Images:  "keep",
+	}
+	if _, err := cmd.ExportCodelab(src, nil, opts); err != nil {
+		t.Fatal(err)
+	}
+	dir := path.Join(tmp, "kept")
+	b, err := ioutil.ReadFile(path.Join(dir, "codelab.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var legacy map[string]interface{}
+	if err := json.Unmarshal(b, &legacy); err != nil {
+		t.Fatal(err)
+	}
+	if m, ok := legacy["manifest"].([]interface{}); !ok || len(m) != 0 {
+		t.Fatalf("codelab.json manifest = %#v, want an empty list", legacy["manifest"])
+	}
+
+	// files placed by hand in the images dir are not owned by an export
+	// with an empty manifest
+	if err := os.MkdirAll(path.Join(dir, "img"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path.Join(dir, "img", "hand.png"), []byte("hand"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmd.UpdateCodelab(dir, cmd.CmdUpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path.Join(dir, "img", "hand.png")); err != nil {
+		t.Errorf("hand-placed image: %v", err)
+	}
+}
+
+func TestExportOutputs(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "TestExportOutputs-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewGray(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path.Join(tmp, "local.png"), img.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	src := path.Join(tmp, "codelab.md")
+	md := "id: outputs\n\n# Outputs\n\n## Step 1\n\n![local](local.png)\n"
+	if err := ioutil.WriteFile(src, []byte(md), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mem := outfs.NewMemFS()
+	opts := cmd.CmdExportOptions{
+		Expenv:   "web",
+		OutputFS: mem,
+		Tmplout:  "html",
+	}
+	if _, err := cmd.ExportCodelab(src, nil, opts); err != nil {
+		t.Fatal(err)
+	}
+	want := mem.Names()
+	if len(want) != 3 || want[0] != "outputs/codelab.json" || !strings.HasPrefix(want[1], "outputs/img/") || want[2] != "outputs/index.html" {
+		t.Fatalf("OutputFS files = %v, want codelab.json, an image and index.html", want)
+	}
+
+	for _, name := range []string{"site.zip", "site.tar.gz"} {
+		opts := cmd.CmdExportOptions{
+			Expenv:  "web",
+			Output:  path.Join(tmp, name),
+			Tmplout: "html",
+			Srcs:    []string{src},
+		}
+		if code := cmd.CmdExport(opts); code != 0 {
+			t.Fatalf("CmdExport(%s) = %d", name, code)
+		}
+		files, err := readArchive(opts.Output)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		var got []string
+		for name := range files {
+			got = append(got, name)
+		}
+		sort.Strings(got)
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("%s files differ (-want +got): %s", name, diff)
+		}
+		b, _ := mem.ReadFile(want[1])
+		if !bytes.Equal(files[want[1]], b) {
+			t.Errorf("%s: %s differs from OutputFS", name, want[1])
+		}
+	}
+
+	// nothing but the archives is written to disk
+	fis, err := ioutil.ReadDir(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, fi := range fis {
+		names = append(names, fi.Name())
+	}
+	if diff := cmp.Diff([]string{"codelab.md", "local.png", "site.tar.gz", "site.zip"}, names); diff != "" {
+		t.Errorf("output dir differs (-want +got): %s", diff)
+	}
+}
+
+// readArchive returns contents of files in a zip or tar.gz archive file.
+func readArchive(file string) (map[string][]byte, error) {
+	res := make(map[string][]byte)
+	if strings.HasSuffix(file, ".zip") {
+		zr, err := zip.OpenReader(file)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		for _, f := range zr.File {
+			r, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			b, err := ioutil.ReadAll(r)
+			r.Close()
+			if err != nil {
+				return nil, err
+			}
+			res[f.Name] = b
+		}
+		return res, nil
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gr)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return res, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		b, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		res[h.Name] = b
+	}
+}
+
+func TestExportCodelabFS(t *testing.T) {
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewGray(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	resources := map[string]string{
+		"intro.md":    "Imported intro\n\n![local](local.png)\n",
+		"local.png":   img.String(),
+		"hello/go.go": "package main\n\n// [START main]\nfunc main() {}\n// [END main]\n",
+		"data.zip":    "a,b\n1,2\n",
+	}
+	var (
+		mu       sync.Mutex
+		resolved []string
+	)
+	// images are resolved concurrently
+	resolve := func(ref string) (io.ReadCloser, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		resolved = append(resolved, ref)
+		s, ok := resources[ref]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+		return ioutil.NopCloser(strings.NewReader(s)), nil
+	}
+	md := "id: memory\n\n# Memory\n\n## Step 1\n\n<<intro.md>>\n\n## Step 2\n\n<<snippet hello/go.go#main>>\n\n" +
+		"Get [the data](data.zip), [the slides](slides.pdf) and read [the next page](next.html).\n"
+	opts := cmd.CmdExportOptions{
+		Expenv:  "web",
+		Tmplout: "offline",
+	}
+	meta, mem, err := cmd.ExportCodelabFS(strings.NewReader(md), "md", resolve, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.ID != "memory" {
+		t.Errorf("meta.ID = %q, want memory", meta.ID)
+	}
+	files := mem.Files()
+	for _, name := range []string{"memory/codelab.json", "memory/index.html", "memory/step-2.html"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("%s is not exported; files: %v", name, mem.Names())
+		}
+	}
+	var cm types.ContextMeta
+	if err := json.Unmarshal(files["memory/codelab.json"], &cm); err != nil {
+		t.Fatal(err)
+	}
+	bySource := make(map[string]*types.Asset)
+	for _, a := range cm.Manifest {
+		bySource[a.Source] = a
+	}
+	imgAsset, fileAsset := bySource["local.png"], bySource["data.zip"]
+	if len(cm.Manifest) != 2 || imgAsset == nil || imgAsset.Type != "image/png" || fileAsset == nil {
+		t.Fatalf("manifest = %+v, want a PNG image and the linked file", cm.Manifest)
+	}
+	if b := files[path.Join("memory", imgAsset.Path)]; !bytes.Equal(b, img.Bytes()) {
+		t.Errorf("%s = %q, want the resolved image", imgAsset.Path, b)
+	}
+	if b := files[path.Join("memory", fileAsset.Path)]; string(b) != resources["data.zip"] {
+		t.Errorf("%s = %q, want the resolved file", fileAsset.Path, b)
+	}
+	index := string(files["memory/index.html"])
+	for _, s := range []string{"Imported intro", `src="` + imgAsset.Path + `"`} {
+		if !strings.Contains(index, s) {
+			t.Errorf("index.html does not contain %s", s)
+		}
+	}
+	step := string(files["memory/step-2.html"])
+	for _, s := range []string{"func main() {}", `href="` + fileAsset.Path + `"`, `href="slides.pdf"`, `href="next.html"`} {
+		if !strings.Contains(step, s) {
+			t.Errorf("step-2.html does not contain %s:\n%s", s, step)
+		}
+	}
+	mu.Lock()
+	sort.Strings(resolved)
+	got := resolved
+	resolved = nil
+	mu.Unlock()
+	// pages are not resolved, and unknown downloads are kept as links
+	if diff := cmp.Diff([]string{"data.zip", "hello/go.go", "intro.md", "local.png", "slides.pdf"}, got); diff != "" {
+		t.Errorf("resolved refs differ (-want +got): %s", diff)
+	}
+
+	// without a resolver, only the codelab itself is exported
+	md = "id: plain\n\n# Plain\n\n## Step 1\n\n<<intro.md>>\n\n![remote](https://example.com/a.png)\n"
+	_, mem, err = cmd.ExportCodelabFS(strings.NewReader(md), "", nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"plain/codelab.json", "plain/index.html"}, mem.Names()); diff != "" {
+		t.Errorf("files differ (-want +got): %s", diff)
+	}
+
+	// images can be rewritten to another host
+	ropts := opts
+	ropts.Images = fetch.ImagesRewrite
+	ropts.ImageURL = "https://cdn.example.com/{{.ID}}/{{.Name}}"
+	_, mem, err = cmd.ExportCodelabFS(strings.NewReader("id: cdn\n\n# CDN\n\n## S\n\n![a](local.png)\n"), "md", resolve, ropts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index := string(mem.Files()["cdn/index.html"]); !strings.Contains(index, `src="https://cdn.example.com/cdn/`) {
+		t.Errorf("index.html does not contain the rewritten image URL:\n%s", index)
+	}
+
+	// options which need the disk, network or external commands are rejected
+	for _, o := range []cmd.CmdExportOptions{
+		{Tmplout: "html", AssetHosts: []string{"example.com"}},
+		{Tmplout: "html", Plugins: []string{"plugin"}},
+		{Tmplout: "html", Diagrams: true},
+		{Tmplout: "html", Archives: "steps"},
+	} {
+		if _, _, err := cmd.ExportCodelabFS(strings.NewReader(md), "md", resolve, o); err == nil {
+			t.Errorf("ExportCodelabFS(%+v) returned nil error", o)
+		}
+	}
+
+	// unresolved references are errors
+	delete(resources, "local.png")
+	if _, _, err := cmd.ExportCodelabFS(strings.NewReader("id: x\n\n# X\n\n## S\n\n![a](local.png)\n"), "md", resolve, opts); err == nil {
+		t.Error("ExportCodelabFS with a missing image returned nil error")
+	}
+}
@@ -0,0 +1,349 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/googlecodelabs/tools/claat/fetch"
+	"github.com/googlecodelabs/tools/claat/nodes"
+	"github.com/googlecodelabs/tools/claat/render"
+	"github.com/googlecodelabs/tools/claat/types"
+	"github.com/googlecodelabs/tools/claat/util"
+)
+
+// Options type to make the CmdExtract signature succinct.
+type CmdExtractOptions struct {
+	// AuthToken is the token to use for the Drive API.
+	AuthToken string
+	// Expenv is the codelab environment to extract code for.
+	Expenv string
+	// Output is the output directory.
+	Output string
+	// PassMetadata are the extra metadata fields to pass along.
+	PassMetadata map[string]bool
+	// Snapshots makes extract write the project state after each step
+	// in a separate directory, instead of only the final state.
+	Snapshots bool
+	// Srcs is the sources to extract code from.
+	Srcs []string
+}
+
+// CmdExtract is the "claat extract ..." subcommand.
+// It returns a process exit code.
+func CmdExtract(opts CmdExtractOptions) int {
+	var exitCode int
+	if len(opts.Srcs) == 0 {
+		log.Fatalf("Need at least one source. Try '-h' for options.")
+	}
+	if isStdout(opts.Output) {
+		log.Fatalf("Extract needs an output directory. Try '-h' for options.")
+	}
+	type result struct {
+		src  string
+		meta *types.Meta
+		err  error
+	}
+	srcs := util.Unique(opts.Srcs)
+	ch := make(chan *result, len(srcs))
+	for _, src := range srcs {
+		go func(src string) {
+			meta, err := ExtractCodelab(src, nil, opts)
+			ch <- &result{src, meta, err}
+		}(src)
+	}
+	for range srcs {
+		res := <-ch
+		if res.err != nil {
+			exitCode = 1
+			log.Printf(reportErr, res.src, res.err)
+		} else {
+			log.Printf(reportOk, res.meta.ID)
+		}
+	}
+	return exitCode
+}
+
+// ExtractCodelab fetches codelab src from either local disk or remote,
+// and writes its tagged code blocks into a project tree in a dir
+// ancestored by output.
+//
+// A code block is tagged when its first line is a marker of the form
+// "file: path" or "patch: path", optionally written as a comment,
+// e.g. "// file: main.go". A file marker replaces the whole file content
+// with the rest of the block, while a patch marker applies the rest of
+// the block as a unified diff to a file created in one of the previous blocks.
+// A block annotated with a file name, such as "```go main.go" in markdown,
+// and without a marker replaces the whole file content with the block.
+//
+// An alternate http.RoundTripper may be specified if desired. Leave null for default.
+func ExtractCodelab(src string, rt http.RoundTripper, opts CmdExtractOptions) (*types.Meta, error) {
+	f, err := fetch.NewFetcher(opts.AuthToken, opts.PassMetadata, rt)
+	if err != nil {
+		return nil, err
+	}
+	// no need to slurp images
+	clab, err := f.SlurpCodelab(src, stdout)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := codelabDir(opts.Output, &clab.Meta, types.DefaultLang)
+	if err != nil {
+		return nil, err
+	}
+	proj := project{}
+	for i, step := range clab.Steps {
+		if err := proj.extract(step, opts.Expenv); err != nil {
+			return nil, fmt.Errorf("step %d: %v", i+1, err)
+		}
+		if opts.Snapshots {
+			if err := proj.write(filepath.Join(dir, snapshotDirname(i+1))); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if !opts.Snapshots {
+		if err := proj.write(dir); err != nil {
+			return nil, err
+		}
+	}
+	return &clab.Meta, nil
+}
+
+// snapshotDirname returns directory name of the project state
+// after step n, where n is 1-based.
+func snapshotDirname(n int) string {
+	return "step-" + strconv.Itoa(n)
+}
+
+// codeMarkerRegexp matches the first line of a tagged code block.
+// The marker can be wrapped in a line or block comment of most languages.
+var codeMarkerRegexp = regexp.MustCompile(`^\s*(?://|#|--|;|/\*|<!--)?\s*(file|patch):\s*(\S+?)\s*(?:\*/|-->)?\s*$`)
+
+// codeMarker parses a tagged code block v.
+// It returns the marker kind, either "file" or "patch", the file path
+// and the rest of the block.
+// The returned kind is empty if v is not tagged.
+func codeMarker(v string) (kind, name, body string) {
+	v = strings.TrimLeft(v, "\n")
+	line := v
+	if i := strings.IndexByte(v, '\n'); i >= 0 {
+		line, body = v[:i], v[i+1:]
+	}
+	m := codeMarkerRegexp.FindStringSubmatch(line)
+	if m == nil {
+		return "", "", ""
+	}
+	return m[1], m[2], body
+}
+
+// project is a set of files extracted from code blocks,
+// keyed by a slash-separated path relative to the project root.
+type project map[string][]byte
+
+// extract applies all tagged code blocks of step to p,
+// skipping content which does not match env.
+func (p project) extract(step *types.Step, env string) error {
+	if !render.MatchEnv(step.Tags, env) {
+		return nil
+	}
+	for _, cn := range codeBlocks(step.Content.Nodes, env) {
+		kind, name, body := codeMarker(cn.Value)
+		if kind == "" && cn.File != "" {
+			// annotated block, e.g. "```go main.go" in markdown
+			kind, name, body = "file", cn.File, strings.TrimLeft(cn.Value, "\n")
+		}
+		if kind == "" {
+			continue
+		}
+		name, err := projectPath(name)
+		if err != nil {
+			return err
+		}
+		switch kind {
+		case "file":
+			p[name] = []byte(body)
+		case "patch":
+			orig, ok := p[name]
+			if !ok {
+				return fmt.Errorf("%s: patch of a file which does not exist", name)
+			}
+			b, err := applyPatch(orig, body)
+			if err != nil {
+				return fmt.Errorf("%s: %v", name, err)
+			}
+			p[name] = b
+		}
+	}
+	return nil
+}
+
+// write stores all files of p in dir, creating subdirectories as needed.
+func (p project) write(dir string) error {
+	names := make([]string, 0, len(p))
+	for name := range p {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		f := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(f), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(f, p[name], 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// projectPath cleans up name and makes sure it stays within a project root.
+func projectPath(name string) (string, error) {
+	p := path.Clean(filepath.ToSlash(name))
+	if path.IsAbs(p) || p == "." || p == ".." || strings.HasPrefix(p, "../") {
+		return "", fmt.Errorf("%s: file path must be relative to the project root", name)
+	}
+	return p, nil
+}
+
+// codeBlocks returns code blocks of n which match env, recursively,
+// skipping containers which do not match env along with their content.
+// Inline code is parsed into text nodes, so it is never returned.
+func codeBlocks(n []nodes.Node, env string) []*nodes.CodeNode {
+	var codes []*nodes.CodeNode
+	nodes.Inspect(n, func(n nodes.Node) bool {
+		if n == nil || !render.MatchEnv(n.Env(), env) {
+			return false
+		}
+		if cn, ok := n.(*nodes.CodeNode); ok {
+			codes = append(codes, cn)
+		}
+		return true
+	})
+	return codes
+}
+
+// hunkHeaderRegexp matches a unified diff hunk header, e.g. "@@ -1,3 +1,4 @@".
+var hunkHeaderRegexp = regexp.MustCompile(`^@@ -(\d+)(?:,\d+)? \+\d+(?:,\d+)? @@`)
+
+// applyPatch applies unified diff to orig.
+// File headers ("---" and "+++" lines) are optional and ignored.
+// Hunks are located by their context, so line numbers of the hunk headers
+// need not be exact.
+func applyPatch(orig []byte, diff string) ([]byte, error) {
+	lines := strings.SplitAfter(string(orig), "\n")
+	if n := len(lines); n > 0 && lines[n-1] == "" {
+		lines = lines[:n-1]
+	}
+	type hunk struct {
+		start    int
+		old, new []string
+	}
+	var hunks []*hunk
+	var cur *hunk
+	for _, l := range strings.SplitAfter(diff, "\n") {
+		if m := hunkHeaderRegexp.FindStringSubmatch(l); m != nil {
+			start, _ := strconv.Atoi(m[1])
+			cur = &hunk{start: start - 1}
+			hunks = append(hunks, cur)
+			continue
+		}
+		if cur == nil || l == "" {
+			// file headers and anything else before the first hunk
+			continue
+		}
+		text := l[1:]
+		if text == "" || text[len(text)-1] != '\n' {
+			text += "\n"
+		}
+		switch l[0] {
+		case ' ':
+			cur.old = append(cur.old, text)
+			cur.new = append(cur.new, text)
+		case '-':
+			cur.old = append(cur.old, text)
+		case '+':
+			cur.new = append(cur.new, text)
+		case '\n':
+			// blank context line with trailing whitespace trimmed by the author
+			cur.old = append(cur.old, "\n")
+			cur.new = append(cur.new, "\n")
+		case '\\':
+			// "\ No newline at end of file"
+		default:
+			return nil, fmt.Errorf("invalid patch line: %q", l)
+		}
+	}
+	if len(hunks) == 0 {
+		return nil, fmt.Errorf("patch contains no hunks")
+	}
+	for i, h := range hunks {
+		at := findLines(lines, h.old, h.start)
+		if at < 0 {
+			return nil, fmt.Errorf("hunk #%d does not apply", i+1)
+		}
+		res := make([]string, 0, len(lines)-len(h.old)+len(h.new))
+		res = append(res, lines[:at]...)
+		res = append(res, h.new...)
+		res = append(res, lines[at+len(h.old):]...)
+		lines = res
+		// adjust positions of the following hunks
+		for _, next := range hunks[i+1:] {
+			next.start += len(h.new) - len(h.old)
+		}
+	}
+	return []byte(strings.Join(lines, "")), nil
+}
+
+// findLines returns index of the first occurrence of sub in lines
+// closest to hint, or -1 if lines do not contain sub.
+func findLines(lines, sub []string, hint int) int {
+	match := func(at int) bool {
+		if at < 0 || at+len(sub) > len(lines) {
+			return false
+		}
+		for i, s := range sub {
+			if strings.TrimRight(lines[at+i], " \t\r\n") != strings.TrimRight(s, " \t\r\n") {
+				return false
+			}
+		}
+		return true
+	}
+	if hint > len(lines) {
+		hint = len(lines)
+	}
+	if hint < 0 {
+		hint = 0
+	}
+	for d := 0; d <= len(lines); d++ {
+		if match(hint - d) {
+			return hint - d
+		}
+		if match(hint + d) {
+			return hint + d
+		}
+	}
+	return -1
+}
@@ -0,0 +1,96 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googlecodelabs/tools/claat/cmd"
+)
+
+func TestExtractCodelab(t *testing.T) {
+	tests := []struct {
+		name      string
+		snapshots bool
+		want      map[string]string
+	}{
+		{
+			name: "Final",
+			want: map[string]string{
+				"main.go":    "package main\n\nfunc main() {\n\tprintln(\"hello, world\")\n}\n",
+				"go.mod":     "module example.com/hello\n",
+				"README.txt": "Say hello.\n",
+			},
+		},
+		{
+			name:      "Snapshots",
+			snapshots: true,
+			want: map[string]string{
+				"step-1/main.go":    "package main\n\nfunc main() {\n}\n",
+				"step-1/go.mod":     "module example.com/hello\n",
+				"step-1/README.txt": "Say hello.\n",
+				"step-2/main.go":    "package main\n\nfunc main() {\n\tprintln(\"hello, world\")\n}\n",
+				"step-2/go.mod":     "module example.com/hello\n",
+				"step-2/README.txt": "Say hello.\n",
+				"step-3/main.go":    "package main\n\nfunc main() {\n\tprintln(\"hello, world\")\n}\n",
+				"step-3/go.mod":     "module example.com/hello\n",
+				"step-3/README.txt": "Say hello.\n",
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tmp, err := ioutil.TempDir("", "TestExtractCodelab-*")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(tmp)
+
+			meta, err := cmd.ExtractCodelab("testdata/extract.md", nil, cmd.CmdExtractOptions{
+				Expenv:    "web",
+				Output:    tmp,
+				Snapshots: tc.snapshots,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := map[string]string{}
+			root := filepath.Join(tmp, meta.ID)
+			err = filepath.Walk(root, func(p string, fi os.FileInfo, err error) error {
+				if err != nil || fi.IsDir() {
+					return err
+				}
+				b, err := ioutil.ReadFile(p)
+				if err != nil {
+					return err
+				}
+				rel, _ := filepath.Rel(root, p)
+				got[filepath.ToSlash(rel)] = string(b)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("ExtractCodelab() project got diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
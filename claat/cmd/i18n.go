@@ -0,0 +1,239 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/googlecodelabs/tools/claat/fetch"
+	"github.com/googlecodelabs/tools/claat/i18n"
+	"github.com/googlecodelabs/tools/claat/types"
+)
+
+// Translation catalog formats.
+const (
+	catalogXLIFF = "xliff"
+	catalogPO    = "po"
+)
+
+// Options type to make the CmdI18n signature succinct.
+type CmdI18nOptions struct {
+	// Export are the options of exporting merged translations.
+	// Its Output is also the output directory of extracted catalogs.
+	Export CmdExportOptions
+	// Format is the format of extracted catalogs, "xliff" or "po".
+	Format string
+	// Lang is the target language of extracted catalogs, if known.
+	Lang string
+}
+
+// CmdI18n is the "claat i18n ..." subcommand.
+// The first of args is the i18n subcommand, "extract" or "merge",
+// followed by its arguments.
+// It returns a process exit code.
+func CmdI18n(args []string, opts CmdI18nOptions) int {
+	if len(args) == 0 {
+		log.Fatalf("Need i18n subcommand. Try '-h' for options.")
+	}
+	switch args[0] {
+	case "extract":
+		return CmdI18nExtract(args[1:], opts)
+	case "merge":
+		return CmdI18nMerge(args[1:], opts)
+	}
+	log.Fatalf("Unknown i18n subcommand. Try '-h' for options.")
+	return 1
+}
+
+// CmdI18nExtract is the "claat i18n extract ..." subcommand.
+// It writes a translation catalog of each of srcs into opts.Export.Output.
+// It returns a process exit code.
+func CmdI18nExtract(srcs []string, opts CmdI18nOptions) int {
+	var exitCode int
+	if len(srcs) == 0 {
+		log.Fatalf("Need at least one source. Try '-h' for options.")
+	}
+	for _, src := range srcs {
+		name, err := ExtractCatalog(src, nil, opts)
+		if err != nil {
+			exitCode = 1
+			log.Printf(reportErr, src, err)
+			continue
+		}
+		if !isStdout(opts.Export.Output) {
+			log.Printf(reportOk, name)
+		}
+	}
+	return exitCode
+}
+
+// ExtractCatalog fetches and parses codelab src, and writes a catalog
+// of its translatable segments in opts.Format into a file in
+// opts.Export.Output, or to stdout. The file is named after the codelab ID,
+// followed by opts.Lang if not empty, e.g. "codelab-id.ja.xlf".
+// It returns the name of the written file.
+//
+// An alternate http.RoundTripper may be specified if desired. Leave null for default.
+func ExtractCatalog(src string, rt http.RoundTripper, opts CmdI18nOptions) (string, error) {
+	f, err := fetch.NewFetcher(opts.Export.AuthToken, opts.Export.PassMetadata, rt)
+	if err != nil {
+		return "", err
+	}
+	f.Math = opts.Export.Math
+	f.Diagrams = opts.Export.Diagrams
+	// no need to slurp images
+	clab, err := f.SlurpCodelab(src, stdout)
+	if err != nil {
+		return "", err
+	}
+	cat := i18n.Extract(clab.Codelab, langOrDefault(opts.Export.DefaultLang, types.DefaultLang))
+	cat.TargetLang = opts.Lang
+
+	var buf bytes.Buffer
+	name := cat.ID
+	if opts.Lang != "" {
+		name += "." + opts.Lang
+	}
+	switch opts.Format {
+	case catalogXLIFF, "":
+		name += ".xlf"
+		err = i18n.WriteXLIFF(&buf, cat)
+	case catalogPO:
+		name += ".po"
+		err = i18n.WritePO(&buf, cat)
+	default:
+		err = fmt.Errorf("unknown catalog format %q", opts.Format)
+	}
+	if err != nil {
+		return "", err
+	}
+	if isStdout(opts.Export.Output) {
+		_, err := buf.WriteTo(os.Stdout)
+		return name, err
+	}
+	if err := os.MkdirAll(opts.Export.Output, 0755); err != nil {
+		return "", err
+	}
+	name = filepath.Join(opts.Export.Output, name)
+	return name, ioutil.WriteFile(name, buf.Bytes(), 0644)
+}
+
+// CmdI18nMerge is the "claat i18n merge ..." subcommand.
+// The first of args is a codelab source, followed by translation catalogs
+// of the codelab. Each catalog is merged with the source and exported
+// as a translation of the codelab.
+// It returns a process exit code.
+func CmdI18nMerge(args []string, opts CmdI18nOptions) int {
+	var exitCode int
+	if len(args) < 2 {
+		log.Fatalf("Need a source and at least one translation. Try '-h' for options.")
+	}
+	src := args[0]
+	out, err := openOutput(opts.Export)
+	if err != nil {
+		log.Printf("%s: %v", opts.Export.Output, err)
+		return 1
+	}
+	for _, file := range args[1:] {
+		res, err := mergeCatalog(src, file, nil, out, opts.Export)
+		if err != nil {
+			exitCode = 1
+			log.Printf(reportErr, file, err)
+			continue
+		}
+		for _, err := range res.Errors {
+			log.Printf("%s: %v", file, err)
+		}
+		log.Printf("%s: %d translated, %d fuzzy, %d untranslated", file, res.Exact, res.Fuzzy, res.Untranslated)
+	}
+	if err := out.Close(); err != nil {
+		exitCode = 1
+		log.Printf("%s: %v", opts.Export.Output, err)
+	}
+	return exitCode
+}
+
+// MergeCatalog translates codelab src with the catalog in file,
+// in XLIFF or PO format depending on the file extension, and exports
+// the result as a translation into the catalog target language.
+// The catalog file is recorded in the codelab metadata, so that
+// UpdateCodelab merges it with the updated source again.
+//
+// An alternate http.RoundTripper may be specified if desired. Leave null for default.
+func MergeCatalog(src, file string, rt http.RoundTripper, opts CmdExportOptions) (*i18n.MergeResult, error) {
+	out, err := openOutput(opts)
+	if err != nil {
+		return nil, err
+	}
+	res, err := mergeCatalog(src, file, rt, out, opts)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// mergeCatalog is MergeCatalog writing into out,
+// which may be shared by several merged catalogs.
+func mergeCatalog(src, file string, rt http.RoundTripper, out *output, opts CmdExportOptions) (*i18n.MergeResult, error) {
+	cat, err := readCatalog(file)
+	if err != nil {
+		return nil, err
+	}
+	ec, err := prepareCodelab(src, rt, out, opts, cat)
+	if err != nil {
+		return nil, err
+	}
+	// recorded for update to merge the catalog again
+	ec.ctx.Catalog = file
+	if _, err := ec.write(out, opts, nil); err != nil {
+		return nil, err
+	}
+	return ec.i18n, nil
+}
+
+// readCatalog reads a translation catalog from file.
+// Files with .po or .pot extension are read as PO, others as XLIFF.
+// The catalog must have a target language.
+func readCatalog(file string) (*i18n.Catalog, error) {
+	r, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var cat *i18n.Catalog
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".po", ".pot":
+		cat, err = i18n.ReadPO(r)
+	default:
+		cat, err = i18n.ReadXLIFF(r)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cat.TargetLang == "" {
+		return nil, fmt.Errorf("%s: no target language", file)
+	}
+	return cat, nil
+}
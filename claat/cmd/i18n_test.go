@@ -0,0 +1,149 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/googlecodelabs/tools/claat/cmd"
+	"github.com/googlecodelabs/tools/claat/i18n"
+)
+
+func TestI18nExtractMerge(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "TestI18nExtractMerge-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	opts := cmd.CmdI18nOptions{
+		Export: cmd.CmdExportOptions{Expenv: "web", Output: tmp, Tmplout: "md"},
+		Format: "po",
+		Lang:   "fr",
+	}
+	name, err := cmd.ExtractCatalog("testdata/simple-2-steps.md", nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(tmp, "example.fr.po"); name != want {
+		t.Errorf("ExtractCatalog() = %q, want %q", name, want)
+	}
+
+	// translate the catalog
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cat, err := i18n.ReadPO(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sources []string
+	for _, s := range cat.Segments {
+		sources = append(sources, s.Source)
+		s.Target = strings.Replace(s.Source, "Step", "Étape", 1)
+	}
+	if want := "Sample Codelab|Create a CodeLab Using Markdown|Step 1|Duration 00:01:00|Content 1|Step 2|Duration 00:02:00|Content 2"; strings.Join(sources, "|") != want {
+		t.Errorf("catalog sources = %q, want %q", sources, want)
+	}
+	f, err = os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = i18n.WritePO(f, cat)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := cmd.MergeCatalog("testdata/simple-2-steps.md", name, nil, opts.Export)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Exact != 8 || res.Untranslated != 0 || len(res.Errors) != 0 {
+		t.Errorf("MergeCatalog() = %+v, want 8 exact translations", res)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(tmp, "example", "fr", "index.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"lang: fr", "## Étape 1", "## Étape 2"} {
+		if !strings.Contains(string(b), s) {
+			t.Errorf("example/fr/index.md does not contain %q:\n%s", s, b)
+		}
+	}
+
+	// update merges the catalog again, instead of replacing
+	// the translation with the untranslated source
+	meta, err := cmd.UpdateCodelab(filepath.Join(tmp, "example", "fr"), cmd.CmdUpdateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Lang != "fr" {
+		t.Errorf("UpdateCodelab() lang = %q, want fr", meta.Lang)
+	}
+	b, err = ioutil.ReadFile(filepath.Join(tmp, "example", "fr", "index.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "## Étape 1") {
+		t.Errorf("updated example/fr/index.md is not translated:\n%s", b)
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "example", "index.md")); !os.IsNotExist(err) {
+		t.Errorf("update wrote the untranslated source into example/: %v", err)
+	}
+}
+
+func TestI18nMergeArchive(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "TestI18nMergeArchive-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	opts := cmd.CmdI18nOptions{
+		Export: cmd.CmdExportOptions{Expenv: "web", Output: tmp, Tmplout: "md"},
+		Format: "po",
+	}
+	args := []string{"testdata/simple-2-steps.md"}
+	for _, lang := range []string{"fr", "de"} {
+		opts.Lang = lang
+		name, err := cmd.ExtractCatalog("testdata/simple-2-steps.md", nil, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		args = append(args, name)
+	}
+
+	// all catalogs are merged into the same archive
+	opts.Export.Output = filepath.Join(tmp, "site.zip")
+	if code := cmd.CmdI18nMerge(args, opts); code != 0 {
+		t.Fatalf("CmdI18nMerge() = %d, want 0", code)
+	}
+	files, err := readArchive(opts.Export.Output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"example/fr/index.md", "example/de/index.md"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("%s does not contain %s", opts.Export.Output, name)
+		}
+	}
+}
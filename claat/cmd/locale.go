@@ -0,0 +1,111 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/googlecodelabs/tools/claat/outfs"
+	"github.com/googlecodelabs/tools/claat/types"
+	"github.com/googlecodelabs/tools/claat/util"
+)
+
+// langOrDefault returns lang, or defaultLang if lang is empty.
+func langOrDefault(lang, defaultLang string) string {
+	if lang == "" {
+		return defaultLang
+	}
+	return lang
+}
+
+// exportedLocales returns languages of all translations of codelab id
+// found in base dir, by the directory of their metadata file.
+// Nothing is found if base is empty, i.e. the output is not a directory.
+func exportedLocales(base, id, defaultLang string) map[string]string {
+	root := filepath.Join(base, id)
+	res := make(map[string]string)
+	if base == "" {
+		return res
+	}
+	if cm, err := readMeta(filepath.Join(root, metaFilename)); err == nil {
+		res[langOrDefault(cm.Lang, defaultLang)] = root
+	}
+	fis, err := ioutil.ReadDir(root)
+	if err != nil {
+		return res
+	}
+	for _, fi := range fis {
+		if !fi.IsDir() || isHidden(fi.Name()) {
+			continue
+		}
+		dir := filepath.Join(root, fi.Name())
+		if _, err := os.Stat(filepath.Join(dir, metaFilename)); err == nil {
+			res[fi.Name()] = dir
+		}
+	}
+	return res
+}
+
+// codelabLocales returns sorted languages of all translations of codelab m:
+// the ones already exported into base dir, m itself and langs
+// exported along with it.
+// It returns nil if m has no lang metadata and no translations.
+func codelabLocales(base string, m *types.Meta, defaultLang string, langs []string) []string {
+	own := langOrDefault(m.Lang, defaultLang)
+	all := append([]string{own}, langs...)
+	for lang := range exportedLocales(base, m.ID, defaultLang) {
+		all = append(all, lang)
+	}
+	all = util.Unique(all)
+	if m.Lang == "" && len(all) == 1 {
+		return nil
+	}
+	sort.Strings(all)
+	return all
+}
+
+// updateLocales records locales of m in metadata files of its translations
+// previously exported into base dir. Translations listed in langs, exported
+// along with m, are skipped.
+//
+// Rendered content of the translations is not changed: their language
+// switcher is updated the next time they are exported or updated.
+func updateLocales(base string, m *types.Meta, defaultLang string, langs []string) error {
+	if len(m.Locales) == 0 {
+		return nil
+	}
+	skip := map[string]bool{strings.ToLower(langOrDefault(m.Lang, defaultLang)): true}
+	for _, lang := range langs {
+		skip[strings.ToLower(lang)] = true
+	}
+	for lang, dir := range exportedLocales(base, m.ID, defaultLang) {
+		if skip[strings.ToLower(lang)] {
+			continue
+		}
+		cm, err := readMeta(filepath.Join(dir, metaFilename))
+		if err != nil {
+			return err
+		}
+		cm.Locales = m.Locales
+		if err := writeMeta(outfs.Dir(dir), metaFilename, cm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/googlecodelabs/tools/claat/outfs"
+)
+
+// output is the destination of exported codelabs.
+type output struct {
+	// dir is the output directory, if exporting to a directory on disk.
+	// Codelabs are staged next to their final location and replace
+	// the previous version once fully exported, see commitDir.
+	dir string
+	// fs is the output file system, if exporting to an archive
+	// or CmdExportOptions.OutputFS. Codelabs are staged in memory
+	// and copied into fs once fully exported.
+	// Both dir and fs are empty for stdout.
+	fs outfs.FS
+	// close completes the output, if needed.
+	close func() error
+}
+
+// openOutput opens the output of opts: opts.OutputFS if not nil,
+// or opts.Output, which is "-" for stdout, a zip or tar.gz archive
+// file name, as in outfs.IsArchive, or a directory.
+// The caller must call Close once all codelabs are written.
+func openOutput(opts CmdExportOptions) (*output, error) {
+	switch {
+	case opts.OutputFS != nil:
+		return &output{fs: opts.OutputFS}, nil
+	case isStdout(opts.Output):
+		return &output{}, nil
+	case outfs.IsArchive(opts.Output):
+		return openArchive(opts.Output)
+	case opts.Output == "":
+		return &output{dir: "."}, nil
+	}
+	return &output{dir: opts.Output}, nil
+}
+
+// openArchive creates archive file name. It is written to a temporary file
+// first, which replaces any existing file on Close.
+func openArchive(name string) (*output, error) {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return nil, err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(name), stagingPrefix)
+	if err != nil {
+		return nil, err
+	}
+	a := outfs.NewArchive(f, name)
+	finish := func() error {
+		err := a.Close()
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err == nil {
+			err = os.Chmod(f.Name(), 0644)
+		}
+		if err == nil {
+			err = os.Rename(f.Name(), name)
+		}
+		if err != nil {
+			os.Remove(f.Name())
+		}
+		return err
+	}
+	return &output{fs: a, close: finish}, nil
+}
+
+// isStdout reports whether o is stdout.
+func (o *output) isStdout() bool {
+	return o.dir == "" && o.fs == nil
+}
+
+// Close completes the output, e.g. writes the end of an archive.
+func (o *output) Close() error {
+	if o.close == nil {
+		return nil
+	}
+	return o.close()
+}
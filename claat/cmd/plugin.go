@@ -0,0 +1,137 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/googlecodelabs/tools/claat/outfs"
+	"github.com/googlecodelabs/tools/claat/types"
+)
+
+// pluginProtocolVersion is the version of the plugin protocol.
+// It is incremented on incompatible changes.
+const pluginProtocolVersion = 1
+
+// pluginRequest is written to a plugin's stdin as JSON.
+type pluginRequest struct {
+	Version int            `json:"version"`
+	Context *types.Context `json:"context"`
+	Codelab *types.Codelab `json:"codelab"`
+}
+
+// pluginResponse is read from a plugin's stdout as JSON.
+type pluginResponse struct {
+	// Codelab is the modified codelab, or nil to keep it unchanged.
+	Codelab *types.Codelab `json:"codelab,omitempty"`
+	// Files are additional output files.
+	Files []*pluginFile `json:"files,omitempty"`
+	// Error, if not empty, fails the export.
+	Error string `json:"error,omitempty"`
+}
+
+// pluginFile is an additional output file produced by a plugin.
+type pluginFile struct {
+	Name    string `json:"name"`    // Slash-separated path relative to the codelab dir
+	Content []byte `json:"content"` // File content, base64 encoded in JSON
+}
+
+// runPlugins pipes clab through each of the plugin executables in order,
+// replacing it with the codelab returned by the plugin, if any.
+// It returns additional output files of all plugins.
+// Plugins are run before images and other assets of clab are stored,
+// so ctx has no update timestamp and manifest.
+func runPlugins(plugins []string, clab *types.Codelab, ctx *types.Context) ([]*pluginFile, error) {
+	var files []*pluginFile
+	for _, p := range plugins {
+		ff, err := runPlugin(p, clab, ctx)
+		if err != nil {
+			return nil, fmt.Errorf("plugin %s: %v", p, err)
+		}
+		files = append(files, ff...)
+	}
+	return files, nil
+}
+
+func runPlugin(plugin string, clab *types.Codelab, ctx *types.Context) ([]*pluginFile, error) {
+	req, err := json.Marshal(&pluginRequest{
+		Version: pluginProtocolVersion,
+		Context: ctx,
+		Codelab: clab,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(plugin)
+	cmd.Stdin = bytes.NewReader(req)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%v: %s", err, msg)
+		}
+		return nil, err
+	}
+
+	var res pluginResponse
+	if err := json.Unmarshal(stdout.Bytes(), &res); err != nil {
+		return nil, fmt.Errorf("invalid response: %v", err)
+	}
+	if res.Error != "" {
+		return nil, fmt.Errorf("%s", res.Error)
+	}
+	for _, f := range res.Files {
+		if !validPluginFile(f.Name) {
+			return nil, fmt.Errorf("invalid output file name %q", f.Name)
+		}
+	}
+	if res.Codelab != nil {
+		// the codelab dir is named after the id and lang,
+		// and images are stored in it after plugins run
+		if res.Codelab.ID != clab.ID || res.Codelab.Lang != clab.Lang {
+			return nil, errors.New("codelab id and lang must not be changed")
+		}
+		// the source is owned by claat, for the update command to work
+		res.Codelab.Source = clab.Source
+		*clab = *res.Codelab
+		clab.Quiz = types.NewQuiz(clab.Steps)
+	}
+	return res.Files, nil
+}
+
+// validPluginFile reports whether name is a relative path inside
+// the codelab dir, other than the metadata file.
+func validPluginFile(name string) bool {
+	p := path.Clean(name)
+	return name != "" && !path.IsAbs(p) && p != "." && p != ".." &&
+		!strings.HasPrefix(p, "../") && p != metaFilename
+}
+
+// writePluginFiles writes files produced by plugins to the codelab dir.
+func writePluginFiles(fsys outfs.FS, dir string, files []*pluginFile) error {
+	for _, f := range files {
+		if err := fsys.WriteFile(path.Join(dir, f.Name), f.Content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/googlecodelabs/tools/claat/cmd"
+	"github.com/googlecodelabs/tools/claat/types"
+)
+
+// TestPluginProcess is not a real test. It is run as a plugin
+// by TestExportCodelabPlugin.
+func TestPluginProcess(t *testing.T) {
+	if os.Getenv("CLAAT_TEST_PLUGIN") != "1" {
+		return
+	}
+	var req struct {
+		Version int
+		Context *types.Context
+		Codelab *types.Codelab
+	}
+	if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	req.Codelab.Title += " (" + req.Context.Format + ")"
+	req.Codelab.Steps = req.Codelab.Steps[:1]
+	json.NewEncoder(os.Stdout).Encode(map[string]interface{}{
+		"codelab": req.Codelab,
+		"files": []map[string]interface{}{
+			{"name": "extra/steps.txt", "content": []byte(req.Codelab.Steps[0].Title)},
+		},
+	})
+	os.Exit(0)
+}
+
+func TestExportCodelabPlugin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin scripts require a POSIX shell")
+	}
+	tmp, err := ioutil.TempDir("", "TestExportCodelabPlugin-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	script := func(name, body string) string {
+		p := filepath.Join(tmp, name)
+		if err := ioutil.WriteFile(p, []byte("#!/bin/sh\n"+body+"\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	plugin := script("plugin", fmt.Sprintf("CLAAT_TEST_PLUGIN=1 exec %q -test.run='^TestPluginProcess$'", os.Args[0]))
+	failing := script("failing", "echo boom >&2\nexit 1")
+	invalid := script("invalid", `echo '{"files": [{"name": "../escape"}]}'`)
+	renaming := script("renaming", `echo '{"codelab": {"id": "renamed"}}'`)
+
+	out := filepath.Join(tmp, "out")
+	opts := cmd.CmdExportOptions{
+		Expenv:  "web",
+		Output:  out,
+		Tmplout: "md",
+		Plugins: []string{plugin},
+	}
+	meta, err := cmd.ExportCodelab("testdata/simple-2-steps.md", nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Sample Codelab (md)"; meta.Title != want {
+		t.Errorf("meta.Title = %q, want %q", meta.Title, want)
+	}
+	if meta.Source != "testdata/simple-2-steps.md" {
+		t.Errorf("meta.Source = %q, want testdata/simple-2-steps.md", meta.Source)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(out, meta.ID, "index.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(b); !strings.Contains(s, "Step 1") || strings.Contains(s, "Step 2") {
+		t.Errorf("index.md is not modified by the plugin:\n%s", s)
+	}
+	b, err = ioutil.ReadFile(filepath.Join(out, meta.ID, "extra", "steps.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "Step 1" {
+		t.Errorf("extra/steps.txt = %q, want %q", b, "Step 1")
+	}
+
+	// images are stored after plugins run, so only those of the step
+	// kept by the plugin are stored
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewGray(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(tmp, "a.png"), img.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join(tmp, "images.md")
+	md := "id: plugin-images\n\n# Images\n\n## Step 1\n\n![a](a.png)\n\n## Step 2\n\n![b](missing.png)\n"
+	if err := ioutil.WriteFile(src, []byte(md), 0644); err != nil {
+		t.Fatal(err)
+	}
+	opts.Tmplout = "html"
+	if _, err := cmd.ExportCodelab(src, nil, opts); err != nil {
+		t.Fatal(err)
+	}
+	b, err = ioutil.ReadFile(filepath.Join(out, "plugin-images", "codelab.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cm types.ContextMeta
+	if err := json.Unmarshal(b, &cm); err != nil {
+		t.Fatal(err)
+	}
+	if len(cm.Manifest) != 1 || cm.Manifest[0].Source != "a.png" {
+		t.Errorf("codelab.json manifest = %+v, want a.png only", cm.Manifest)
+	}
+
+	for _, p := range []string{failing, invalid, renaming} {
+		opts.Plugins = []string{p}
+		_, err := cmd.ExportCodelab("testdata/simple-2-steps.md", nil, opts)
+		if err == nil {
+			t.Errorf("ExportCodelab with plugin %s returned nil error", filepath.Base(p))
+		}
+		if p == failing && !strings.Contains(fmt.Sprint(err), "boom") {
+			t.Errorf("ExportCodelab error %q does not include plugin stderr", err)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/googlecodelabs/tools/claat/util"
+)
+
+// stagingPrefix is the name prefix of temporary directories in which
+// codelabs are exported, before they replace the previous version.
+const stagingPrefix = ".claat-stage-"
+
+// commitMu serializes replacing of codelab dirs with staged ones,
+// since translations are stored inside the dir of their codelab.
+var commitMu sync.Mutex
+
+// newStagingDir creates a temporary staging directory in base,
+// which is the parent directory of exported codelabs.
+// The caller is responsible for removing it.
+func newStagingDir(base string) (string, error) {
+	if err := os.MkdirAll(base, 0755); err != nil {
+		return "", err
+	}
+	return ioutil.TempDir(base, stagingPrefix)
+}
+
+// backupDir returns the path of the backup of codelab dir,
+// a hidden sibling directory.
+func backupDir(dir string) string {
+	return filepath.Join(filepath.Dir(dir), "."+filepath.Base(dir)+".bak")
+}
+
+// isHidden reports whether file name is hidden, such as a staging dir
+// or a backup of a translation.
+func isHidden(name string) bool {
+	return strings.HasPrefix(name, ".")
+}
+
+// commitDir replaces codelab dir with the fully exported staged dir,
+// keeping the previous version in backupDir(dir) if backup is true.
+// The caller must hold commitMu.
+//
+// Files of the previous version which the export does not own, i.e. are
+// not listed in its manifest, such as files placed there by hand, are
+// carried over unless the new version has them too. Without a manifest,
+// e.g. for codelabs exported by older versions, all images are owned.
+// Subdirectories with translations, and hidden ones, are moved into
+// the staged dir before it replaces dir.
+//
+// Replacing is best-effort rather than atomic: dir is renamed to its
+// backup and staged is renamed to dir, so dir is briefly missing
+// in between. If staged cannot be renamed, the previous version is
+// restored along with its subdirectories.
+func commitDir(staged, dir string, backup bool) error {
+	owned := ownedFiles(dir)
+	_, err := os.Stat(dir)
+	exists := err == nil
+	var nested []string
+	if exists {
+		if nested, err = carryOver(staged, dir, owned); err != nil {
+			return err
+		}
+	}
+
+	// move translations into the new version first, so that they
+	// are never missing from the committed dir
+	var moved []string
+	restore := func() {
+		for _, rel := range moved {
+			os.Rename(filepath.Join(staged, rel), filepath.Join(dir, rel))
+		}
+	}
+	for _, rel := range nested {
+		target := filepath.Join(staged, rel)
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			restore()
+			return err
+		}
+		if err := os.Rename(filepath.Join(dir, rel), target); err != nil {
+			restore()
+			return err
+		}
+		moved = append(moved, rel)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		restore()
+		return err
+	}
+	bak := backupDir(dir)
+	if err := os.RemoveAll(bak); err != nil {
+		restore()
+		return err
+	}
+	if exists {
+		if err := os.Rename(dir, bak); err != nil {
+			restore()
+			return err
+		}
+	}
+	if err := os.Rename(staged, dir); err != nil {
+		if exists {
+			os.Rename(bak, dir)
+			restore()
+		}
+		return err
+	}
+	if !exists || backup {
+		return nil
+	}
+	return os.RemoveAll(bak)
+}
+
+// ownedFiles returns a function reporting whether a file of codelab dir,
+// relative to dir, is owned by its previous export: listed in the manifest
+// of dir, even if it is empty, or stored in the images dir if dir was exported
+// before manifests were recorded.
+func ownedFiles(dir string) func(rel string) bool {
+	cm, err := readMeta(filepath.Join(dir, metaFilename))
+	if err != nil || cm.Manifest == nil {
+		return func(rel string) bool {
+			return strings.HasPrefix(rel, util.ImgDirname+string(filepath.Separator))
+		}
+	}
+	owned := make(map[string]bool)
+	for _, a := range cm.Manifest {
+		if a.IsLocal() {
+			owned[filepath.FromSlash(a.Path)] = true
+		}
+	}
+	return func(rel string) bool {
+		return owned[rel]
+	}
+}
+
+// carryOver copies files of codelab dir into staged, unless they are owned
+// by the previous export or staged already has them. It returns paths
+// of subdirectories to move, relative to dir: those containing codelab
+// metadata, e.g. translations, and hidden ones.
+func carryOver(staged, dir string, owned func(rel string) bool) ([]string, error) {
+	var nested []string
+	err := filepath.Walk(dir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil || p == dir {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			if _, err := os.Stat(filepath.Join(p, metaFilename)); err == nil || isHidden(fi.Name()) {
+				nested = append(nested, rel)
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		target := filepath.Join(staged, rel)
+		if _, err := os.Lstat(target); err == nil || owned(rel) {
+			return nil
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if fi.Mode()&os.ModeSymlink != 0 {
+			link, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		}
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(target, b, fi.Mode().Perm())
+	})
+	return nested, err
+}
@@ -0,0 +1,53 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"log"
+
+	"github.com/googlecodelabs/tools/claat/render"
+)
+
+// CmdTemplate is the "claat template ..." subcommand.
+// The first of args is the template subcommand, currently only "check",
+// followed by output formats, which are usually paths to template files
+// or theme directories.
+// It returns a process exit code.
+func CmdTemplate(args []string) int {
+	if len(args) == 0 || args[0] != "check" {
+		log.Fatalf("Unknown template subcommand. Try '-h' for options.")
+	}
+	return CmdTemplateCheck(args[1:])
+}
+
+// CmdTemplateCheck is the "claat template check ..." subcommand.
+// It renders a sample codelab with every kind of content in each of formats,
+// reporting errors with template line numbers.
+// It returns a process exit code.
+func CmdTemplateCheck(formats []string) int {
+	var exitCode int
+	if len(formats) == 0 {
+		log.Fatalf("Need at least one template. Try '-h' for options.")
+	}
+	for _, f := range formats {
+		if err := render.Check(f); err != nil {
+			exitCode = 1
+			log.Printf(reportErr, f, err)
+			continue
+		}
+		log.Printf(reportOk, f)
+	}
+	return exitCode
+}
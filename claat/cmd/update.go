@@ -27,6 +27,9 @@ import (
 	"time"
 
 	"github.com/googlecodelabs/tools/claat/fetch"
+	"github.com/googlecodelabs/tools/claat/i18n"
+	"github.com/googlecodelabs/tools/claat/outfs"
+	"github.com/googlecodelabs/tools/claat/transform"
 	"github.com/googlecodelabs/tools/claat/types"
 	"github.com/googlecodelabs/tools/claat/util"
 )
@@ -35,6 +38,9 @@ import (
 type CmdUpdateOptions struct {
 	// AuthToken is the token to use for the Drive API.
 	AuthToken string
+	// Backup keeps the previous version of each updated codelab
+	// in a hidden sibling directory, e.g. ".codelab-id.bak".
+	Backup bool
 	// ExtraVars is extra template variables.
 	ExtraVars map[string]string
 	// GlobalGA is the global Google Analytics account to use.
@@ -71,7 +77,7 @@ func CmdUpdate(opts CmdUpdateOptions) int {
 			// random sleep up to 1 sec
 			// to reduce number of rate limit errors
 			time.Sleep(time.Duration(rand.Intn(1000)) * time.Millisecond)
-			meta, err := updateCodelab(d, opts)
+			meta, err := UpdateCodelab(d, opts)
 			ch <- &result{d, meta, err}
 		}(d)
 	}
@@ -89,10 +95,11 @@ func CmdUpdate(opts CmdUpdateOptions) int {
 	return exitCode
 }
 
-// updateCodelab reads metadata from a dir/codelab.json file,
-// re-exports the codelab just like it normally would in exportCodelab,
-// and removes assets (images) which are not longer in use.
-func updateCodelab(dir string, opts CmdUpdateOptions) (*types.Meta, error) {
+// UpdateCodelab reads metadata from a dir/codelab.json file,
+// re-exports the codelab just like it normally would in ExportCodelab,
+// and removes assets of the previous export which are no longer in use.
+// Translations made with MergeCatalog are merged with their catalog again.
+func UpdateCodelab(dir string, opts CmdUpdateOptions) (*types.Meta, error) {
 	// get stored codelab metadata and fail early if we can't
 	meta, err := readMeta(filepath.Join(dir, metaFilename))
 	if err != nil {
@@ -107,46 +114,112 @@ func updateCodelab(dir string, opts CmdUpdateOptions) (*types.Meta, error) {
 	}
 
 	// fetch and parse codelab source
+	lang := meta.Context.DefaultLanguage()
 	f, err := fetch.NewFetcher(opts.AuthToken, opts.PassMetadata, nil)
 	if err != nil {
 		return nil, err
 	}
+	f.DefaultLang = lang
+	f.Math = meta.Context.Math
+	f.Diagrams = meta.Context.Diagrams
+	f.ResponsiveImages = meta.Context.ResponsiveImages
+	f.MaxAssetSize = meta.Context.MaxAssetSize
+	f.AssetHosts = meta.Context.AssetHosts
+	f.Images = meta.Context.Images
+	f.ImageURL = meta.Context.ImageURL
+	var cat *i18n.Catalog
+	if meta.Context.Catalog != "" {
+		if cat, err = readCatalog(meta.Context.Catalog); err != nil {
+			return nil, err
+		}
+		f.Lang = cat.TargetLang
+	}
+	// translate, transform and pipe the codelab through plugins
+	// before its assets are stored; plugins get the context without
+	// the update timestamp and the manifest of the previous export
+	meta.Context.Updated = nil
+	meta.Context.Manifest = nil
+	var files []*pluginFile
+	f.Transform = func(clab *types.Codelab) error {
+		if cat != nil {
+			i18n.Merge(clab, cat)
+		}
+		if err := transform.Apply(clab, meta.Context.Transforms...); err != nil {
+			return err
+		}
+		var err error
+		files, err = runPlugins(meta.Context.Plugins, clab, &meta.Context)
+		return err
+	}
 	basedir := filepath.Join(dir, "..")
-	clab, err := f.SlurpCodelab(meta.Source, basedir)
+	if meta.IsTranslation(lang) {
+		// translations are stored in a subdirectory of the codelab
+		basedir = filepath.Join(basedir, "..")
+	}
+	// the codelab is exported into a staging dir first,
+	// which replaces the previous version once fully written
+	stage, err := newStagingDir(basedir)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(stage)
+	clab, err := f.SlurpCodelab(meta.Source, stage)
 	if err != nil {
 		return nil, err
 	}
 	updated := types.ContextTime(clab.Mod)
 	meta.Context.Updated = &updated
+	meta.Context.Manifest = clab.Manifest
+
+	rel, err := clab.Meta.Dir(lang)
+	if err != nil {
+		return nil, err
+	}
+	newdir := filepath.Join(basedir, rel)
+	staged := filepath.Join(stage, rel)
+	clab.Meta.Locales = codelabLocales(basedir, &clab.Meta, lang, nil)
 
-	newdir := codelabDir(basedir, &clab.Meta)
-	imgdir := filepath.Join(newdir, util.ImgDirname)
+	// write step archives, codelab and its metadata
+	stagedFS := outfs.Dir(staged)
+	if err := writeArchives(stagedFS, ".", clab.Codelab, &meta.Context); err != nil {
+		return nil, err
+	}
+	if err := writeCodelab(stagedFS, ".", clab.Codelab, opts.ExtraVars, &meta.Context); err != nil {
+		return nil, err
+	}
+	if err := writePluginFiles(stagedFS, ".", files); err != nil {
+		return nil, err
+	}
 
-	// write codelab and its metadata
-	if err := writeCodelab(newdir, clab.Codelab, opts.ExtraVars, &meta.Context); err != nil {
+	// replace the previous version, removing files of its manifest
+	// which are no longer in use; other files, e.g. those placed
+	// in img/ by hand, are kept
+	commitMu.Lock()
+	defer commitMu.Unlock()
+	if err := commitDir(staged, newdir, opts.Backup); err != nil {
+		return nil, err
+	}
+	if err := updateLocales(basedir, &clab.Meta, lang, nil); err != nil {
 		return nil, err
 	}
 
-	// cleanup:
-	// - remove original dir if codelab ID has changed and so has the output dir
-	// - otherwise, remove images which are not in imgs
-	old := codelabDir(basedir, &meta.Meta)
-	if old != newdir {
-		return &meta.Meta, os.RemoveAll(old)
+	// remove original dir if codelab ID or language has changed and so has
+	// the output dir, unless the new dir is inside the original one
+	old, err := codelabDir(basedir, &meta.Meta, lang)
+	if err != nil {
+		return nil, err
 	}
-	visit := func(p string, fi os.FileInfo, err error) error {
-		if err != nil || p == imgdir {
-			return err
-		}
-		if fi.IsDir() {
-			return filepath.SkipDir
-		}
-		if _, ok := clab.Imgs[filepath.Base(p)]; !ok {
-			return os.Remove(p)
+	if old == newdir || strings.HasPrefix(newdir, old+string(filepath.Separator)) {
+		return &meta.Meta, nil
+	}
+	if opts.Backup {
+		bak := backupDir(old)
+		if err := os.RemoveAll(bak); err != nil {
+			return nil, err
 		}
-		return nil
+		return &meta.Meta, os.Rename(old, bak)
 	}
-	return &meta.Meta, filepath.Walk(imgdir, visit)
+	return &meta.Meta, os.RemoveAll(old)
 }
 
 // scanPaths looks for codelab metadata files in roots, recursively.
@@ -177,12 +250,19 @@ func scanPaths(roots []string) ([]string, error) {
 }
 
 // walkPath walks root dir recursively, looking for metaFilename files.
+// Hidden directories, such as backups of codelabs, are skipped.
 func walkPath(root string) ([]string, error) {
 	var dirs []string
 	err := filepath.Walk(root, func(p string, fi os.FileInfo, err error) error {
-		if err != nil || fi.IsDir() {
+		if err != nil {
 			return err
 		}
+		if fi.IsDir() {
+			if p != root && isHidden(fi.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
 		if filepath.Base(p) == metaFilename {
 			dirs = append(dirs, filepath.Dir(p))
 		}
@@ -47,6 +47,11 @@ func isStdout(filename string) bool {
 
 // codelabDir returns codelab root directory.
 // The base argument is codelab parent directory.
-func codelabDir(base string, m *types.Meta) string {
-	return filepath.Join(base, m.ID)
+// Translations are stored in a subdirectory named after their language.
+func codelabDir(base string, m *types.Meta, defaultLang string) (string, error) {
+	dir, err := m.Dir(defaultLang)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, dir), nil
 }
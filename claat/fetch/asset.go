@@ -0,0 +1,187 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fetch
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc64"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/googlecodelabs/tools/claat/nodes"
+	"github.com/googlecodelabs/tools/claat/outfs"
+	"github.com/googlecodelabs/tools/claat/util"
+)
+
+// downloadExts are file extensions of links which are copied into
+// the codelab: local files, and remote ones if their host is allowed.
+// See Fetcher.AssetHosts.
+var downloadExts = map[string]bool{
+	".7z":  true,
+	".bz2": true,
+	".gz":  true,
+	".jar": true,
+	".pdf": true,
+	".tar": true,
+	".tgz": true,
+	".xz":  true,
+	".zip": true,
+}
+
+// slurpAssets copies downloads linked from n into dir of out and rewrites
+// the links. Downloads are links to files with one of downloadExts or
+// Download buttons: local files relative to the codelab src, or to the
+// imported fragment containing the link, and files of allowed remote hosts.
+// Other links, e.g. to pages or other codelabs, are kept as is.
+// Names of the created files are added to assets, along with their source.
+func (f *Fetcher) slurpAssets(src string, out outfs.FS, dir string, n []nodes.Node, assets map[string]string) error {
+	srcs := make(map[*nodes.URLNode]string)
+	for _, imp := range nodes.ImportNodes(n) {
+		for _, un := range nodes.URLNodes(imp.Content.Nodes) {
+			srcs[un] = imp.URL
+		}
+	}
+	files := make(map[string]string) // slurped file names by source
+	var errStr string
+	for _, un := range nodes.URLNodes(n) {
+		s, ok := srcs[un]
+		if !ok {
+			s = src
+		}
+		u := f.assetURL(s, un)
+		if u == nil {
+			continue
+		}
+		frag := u.Fragment
+		u.Fragment = ""
+		file, ok := files[u.String()]
+		if !ok {
+			var err error
+			if file, err = f.slurpAsset(out, dir, u); err != nil {
+				if f.resolve != nil && errors.Is(err, os.ErrNotExist) {
+					// not a file known to the resolver, e.g. a link to a page
+					continue
+				}
+				errStr += fmt.Sprintf("%s: %v\n", un.URL, err)
+				continue
+			}
+			files[u.String()] = file
+			source := u.String()
+			if u.Host == "" {
+				// do not record absolute paths of the local machine
+				source = strings.SplitN(un.URL, "#", 2)[0]
+			}
+			assets[file] = source
+		}
+		un.URL = (&url.URL{Path: path.Join(util.AssetDirname, file), Fragment: frag}).String()
+	}
+	if len(errStr) > 0 {
+		return errors.New(errStr)
+	}
+	return nil
+}
+
+// assetURL returns the URL of a file linked by un, which should be copied
+// into the codelab, or nil if the link is kept as is.
+// Local files are returned as absolute file paths, or as written
+// in the codelab if it is read from memory.
+func (f *Fetcher) assetURL(src string, un *nodes.URLNode) *url.URL {
+	u, err := url.Parse(un.URL)
+	if err != nil || un.URL == "" || strings.HasPrefix(un.URL, "#") {
+		return nil
+	}
+	// relative links in remote codelabs point to remote files
+	if srcURL, err := url.Parse(src); err == nil && srcURL.Host != "" {
+		u = srcURL.ResolveReference(u)
+	}
+	// pages, e.g. of other codelabs, are linked rather than copied
+	if !un.IsDownload() && !downloadExts[strings.ToLower(path.Ext(u.Path))] {
+		return nil
+	}
+	if u.Host == "" {
+		if u.Scheme != "" || u.Path == "" || path.IsAbs(u.Path) {
+			return nil
+		}
+		if f.resolve != nil {
+			return &url.URL{Path: u.Path, Fragment: u.Fragment}
+		}
+		p, err := restrictPathToParent(filepath.FromSlash(u.Path), filepath.Dir(src))
+		if err != nil {
+			return nil
+		}
+		if fi, err := os.Stat(p); err != nil || !fi.Mode().IsRegular() {
+			return nil
+		}
+		return &url.URL{Path: p, Fragment: u.Fragment}
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || !f.allowedAssetHost(u.Hostname()) {
+		return nil
+	}
+	return u
+}
+
+// allowedAssetHost reports whether remote assets can be copied from host.
+// Entries of f.AssetHosts starting with "*." also match any subdomain.
+func (f *Fetcher) allowedAssetHost(host string) bool {
+	host = strings.ToLower(host)
+	for _, h := range f.AssetHosts {
+		h = strings.ToLower(h)
+		if h == host || strings.HasPrefix(h, "*.") && strings.HasSuffix(host, h[1:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// slurpAsset copies local file or downloads remote file u into dir of out.
+// It returns the name of the created file.
+func (f *Fetcher) slurpAsset(out outfs.FS, dir string, u *url.URL) (string, error) {
+	var b []byte
+	var err error
+	switch {
+	case u.Host == "" && f.resolve != nil:
+		b, err = f.readResolved(u.Path)
+	case u.Host == "":
+		b, err = f.readLocalBytes(u.Path)
+	default:
+		b, err = f.slurpRemoteBytes(u.String(), 5)
+	}
+	if err != nil {
+		return "", err
+	}
+	crc := crc64.Checksum(b, f.crcTable)
+	file := fmt.Sprintf("%x-%s", crc, assetName(path.Base(u.Path)))
+	return file, out.WriteFile(path.Join(dir, file), b)
+}
+
+// assetName returns a file name based on name, which is safe to use
+// in a URL path, or "download" if nothing remains of name.
+func assetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9', r == '.', r == '_', r == '-':
+			return r
+		}
+		return '-'
+	}, name)
+	if strings.Trim(name, ".-") == "" {
+		return "download"
+	}
+	return name
+}
@@ -0,0 +1,178 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fetch
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/googlecodelabs/tools/claat/nodes"
+	"github.com/googlecodelabs/tools/claat/outfs"
+)
+
+func TestSlurpAssets(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("remote " + r.URL.Path))
+	}))
+	defer srv.Close()
+	host := srv.URL + "/files"
+
+	tmp, err := ioutil.TempDir("", "TestSlurpAssets-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	src := filepath.Join(tmp, "src", "codelab.md")
+	if err := os.MkdirAll(filepath.Join(tmp, "src", "docs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string]string{
+		"src/docs/guide.pdf": "local guide",
+		"src/docs/notes.txt": "local notes",
+		"src/other.md":       "# Other codelab",
+		"src/page.html":      "<script>alert(1)</script>",
+		"src/image.svg":      "<svg/>",
+		"secret.txt":         "outside",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(tmp, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	text := nodes.NewTextNode(nodes.NewTextNodeOptions{Value: "file"})
+	download := func(u string) *nodes.URLNode {
+		return nodes.NewURLNode(u, nodes.NewButtonNode(true, true, true, text))
+	}
+	tests := []struct {
+		in   *nodes.URLNode
+		file string // slurped file name, or empty if kept as is
+		data string
+	}{
+		{in: nodes.NewURLNode("docs/guide.pdf#page=2", text), file: "guide.pdf", data: "local guide"},
+		{in: nodes.NewURLNode("docs/guide.pdf", text), file: "guide.pdf", data: "local guide"},
+		{in: nodes.NewURLNode("docs/missing.pdf", text)},
+		{in: nodes.NewURLNode("../secret.txt", text)},
+		{in: download("../secret.txt")},
+		{in: download("docs/notes.txt"), file: "notes.txt", data: "local notes"},
+		{in: nodes.NewURLNode("docs/notes.txt", text)},
+		{in: nodes.NewURLNode("other.md", text)},
+		{in: nodes.NewURLNode("page.html", text)},
+		{in: nodes.NewURLNode("image.svg", text)},
+		{in: nodes.NewURLNode("#step-2", text)},
+		{in: nodes.NewURLNode("mailto:someone@example.com", text)},
+		{in: nodes.NewURLNode(host+"/slides.PDF", text), file: "slides.PDF", data: "remote /files/slides.PDF"},
+		{in: download(host + "/sample?v=2"), file: "sample", data: "remote /files/sample"},
+		{in: nodes.NewURLNode(host+"/page.html", text)},
+		{in: nodes.NewURLNode("https://example.com/code.zip", text)},
+	}
+	var in []nodes.Node
+	for _, tc := range tests {
+		in = append(in, tc.in)
+	}
+	// links of imported fragments are relative to the fragment
+	fragLink := nodes.NewURLNode("guide.pdf", text)
+	imp := nodes.NewImportNode(filepath.Join(tmp, "src", "docs", "part.md"))
+	imp.Content.Nodes = []nodes.Node{fragLink}
+	in = append(in, imp)
+	f, err := NewFetcher("", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(srv.URL)
+	f.AssetHosts = []string{"*.example.org", u.Hostname()}
+	out := outfs.NewMemFS()
+	assets := make(map[string]string)
+	if err := f.slurpAssets(src, out, "out/assets", in, assets); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, tc := range tests {
+		got := tc.in.URL
+		if tc.file == "" {
+			if strings.HasPrefix(got, "assets/") {
+				t.Errorf("link %d rewritten to %q, want kept", i, got)
+			}
+			continue
+		}
+		name := strings.TrimPrefix(strings.SplitN(got, "#", 2)[0], "assets/")
+		if !strings.HasPrefix(got, "assets/") || !strings.HasSuffix(name, "-"+tc.file) {
+			t.Errorf("link %d = %q, want assets/<crc>-%s", i, got, tc.file)
+			continue
+		}
+		b, err := out.ReadFile("out/assets/" + name)
+		if err != nil {
+			t.Errorf("link %d: %v", i, err)
+			continue
+		}
+		if string(b) != tc.data {
+			t.Errorf("link %d file = %q, want %q", i, b, tc.data)
+		}
+		if _, ok := assets[name]; !ok {
+			t.Errorf("link %d: %s not in assets %v", i, name, assets)
+		}
+	}
+	if !strings.HasSuffix(tests[0].in.URL, "#page=2") {
+		t.Errorf("link fragment is lost: %q", tests[0].in.URL)
+	}
+	if tests[0].in.URL != tests[1].in.URL+"#page=2" {
+		t.Errorf("same file is copied twice: %q and %q", tests[0].in.URL, tests[1].in.URL)
+	}
+	if fragLink.URL != tests[1].in.URL {
+		t.Errorf("fragment link = %q, want %q", fragLink.URL, tests[1].in.URL)
+	}
+	if len(assets) != 4 {
+		t.Errorf("assets = %v, want 4 files", assets)
+	}
+}
+
+func TestAllowedAssetHost(t *testing.T) {
+	f := &Fetcher{AssetHosts: []string{"storage.example.com", "*.Example.org"}}
+	tests := map[string]bool{
+		"storage.example.com":  true,
+		"STORAGE.example.com":  true,
+		"example.com":          false,
+		"cdn.example.org":      true,
+		"a.b.example.org":      true,
+		"example.org":          false,
+		"evilexample.org":      false,
+		"storage.example.com.": false,
+	}
+	for host, want := range tests {
+		if got := f.allowedAssetHost(host); got != want {
+			t.Errorf("allowedAssetHost(%q) = %t, want %t", host, got, want)
+		}
+	}
+}
+
+func TestAssetName(t *testing.T) {
+	tests := map[string]string{
+		"guide.pdf":        "guide.pdf",
+		"my guide (1).pdf": "my-guide--1-.pdf",
+		"データ.zip":          "---.zip",
+		"/":                "download",
+		"..":               "download",
+	}
+	for in, want := range tests {
+		if got := assetName(in); got != want {
+			t.Errorf("assetName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fetch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/crc64"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/googlecodelabs/tools/claat/nodes"
+	"github.com/googlecodelabs/tools/claat/outfs"
+	"github.com/googlecodelabs/tools/claat/util"
+)
+
+// diagramCommands are commands which read diagram source from stdin
+// and write the rendered SVG image to stdout, keyed by diagram kind.
+var diagramCommands = map[string][]string{
+	nodes.DiagramDot:     {"dot", "-Tsvg"},
+	nodes.DiagramMermaid: {"mmdc", "--input", "-", "--output", "-", "--outputFormat", "svg", "--quiet"},
+}
+
+// slurpDiagrams pre-renders diagrams of n into SVG images stored in dir of out,
+// and sets the diagram image URLs. It fails if the command of a diagram kind
+// is not installed, so that exports do not depend on the machine silently.
+// Names of the created files are added to images,
+// along with the diagram kind and source checksum.
+func (f *Fetcher) slurpDiagrams(out outfs.FS, dir string, n []nodes.Node, images map[string]string) error {
+	var errStr string
+	for _, dn := range nodes.DiagramNodes(n) {
+		cmd := diagramCommands[dn.Kind]
+		if len(cmd) == 0 {
+			continue
+		}
+		if _, err := exec.LookPath(cmd[0]); err != nil {
+			errStr += fmt.Sprintf("%s diagram: %v\n", dn.Kind, err)
+			continue
+		}
+		b, err := renderDiagram(cmd, dn.Value)
+		if err != nil {
+			errStr += fmt.Sprintf("%s diagram: %v\n", dn.Kind, err)
+			continue
+		}
+		// diagram source comes from the codelab and may contain links
+		// with script URLs, e.g. of dot URL attributes or mermaid click callbacks
+		if b, err = sanitizeSVG(b); err != nil {
+			errStr += fmt.Sprintf("%s diagram: %v\n", dn.Kind, err)
+			continue
+		}
+		file := fmt.Sprintf("%x.svg", crc64.Checksum(b, f.crcTable))
+		if err := out.WriteFile(path.Join(dir, file), b); err != nil {
+			return err
+		}
+		dn.Img = path.Join(util.ImgDirname, file)
+		images[file] = diagramSource(dn, f.crcTable)
+	}
+	if len(errStr) > 0 {
+		return errors.New(errStr)
+	}
+	return nil
+}
+
+// diagramSource returns the manifest source of the image pre-rendered
+// from diagram n: its kind and a checksum of the diagram source.
+func diagramSource(n *nodes.DiagramNode, tab *crc64.Table) string {
+	return fmt.Sprintf("diagram:%s:%x", n.Kind, crc64.Checksum([]byte(n.Value), tab))
+}
+
+// renderDiagram runs command cmd with diagram source src on stdin
+// and returns its output.
+func renderDiagram(cmd []string, src string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	c := exec.Command(cmd[0], cmd[1:]...)
+	c.Stdin = strings.NewReader(src)
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+	if err := c.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %v: %s", cmd[0], err, msg)
+		}
+		return nil, fmt.Errorf("%s: %v", cmd[0], err)
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,67 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fetch
+
+import (
+	"os/exec"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/googlecodelabs/tools/claat/nodes"
+	"github.com/googlecodelabs/tools/claat/outfs"
+)
+
+func TestSlurpDiagrams(t *testing.T) {
+	if _, err := exec.LookPath("cat"); err != nil {
+		t.Skip("cat command is not available")
+	}
+	defer func(cmds map[string][]string) { diagramCommands = cmds }(diagramCommands)
+	diagramCommands = map[string][]string{
+		nodes.DiagramDot:     {"cat"},
+		nodes.DiagramMermaid: {"claat-test-no-such-command"},
+	}
+
+	f, err := NewFetcher("", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const svg = `<svg xmlns="http://www.w3.org/2000/svg"><a href="javascript:alert(1)"><text>A</text></a></svg>`
+	dot := nodes.NewDiagramNode(nodes.DiagramDot, svg)
+	mermaid := nodes.NewDiagramNode(nodes.DiagramMermaid, "graph TD; A-->B")
+	out := outfs.NewMemFS()
+	images := map[string]string{}
+	err = f.slurpDiagrams(out, "img", []nodes.Node{nodes.NewListNode(dot, mermaid)}, images)
+	if err == nil || !strings.Contains(err.Error(), "mermaid diagram") {
+		t.Errorf("slurpDiagrams error = %v; want an error of the mermaid diagram whose command is not installed", err)
+	}
+	if mermaid.Img != "" {
+		t.Errorf("mermaid.Img = %q; want empty when the command is not installed", mermaid.Img)
+	}
+	file := path.Base(dot.Img)
+	if dot.Img != "img/"+file || path.Ext(file) != ".svg" {
+		t.Fatalf("dot.Img = %q; want img/<hash>.svg", dot.Img)
+	}
+	if src := images[file]; !strings.HasPrefix(src, "diagram:dot:") {
+		t.Errorf("images[%q] = %q; want diagram:dot:<crc>", file, src)
+	}
+	b, err := out.ReadFile("img/" + file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "javascript:") || !strings.Contains(string(b), "<text>A</text>") {
+		t.Errorf("rendered diagram = %q; want sanitized SVG", b)
+	}
+}
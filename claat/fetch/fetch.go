@@ -14,6 +14,7 @@
 package fetch
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -25,12 +26,14 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/googlecodelabs/tools/claat/fetch/drive/auth"
 	"github.com/googlecodelabs/tools/claat/nodes"
+	"github.com/googlecodelabs/tools/claat/outfs"
 	"github.com/googlecodelabs/tools/claat/parser"
 	"github.com/googlecodelabs/tools/claat/types"
 	"github.com/googlecodelabs/tools/claat/util"
@@ -46,9 +49,6 @@ const (
 
 	// driveAPI is a base URL for Drive API
 	driveAPI = "https://www.googleapis.com/drive/v3"
-
-	// Minimum image size in bytes for extension detection.
-	minImageSize = 11
 )
 
 // TODO: create an enum for use with "nometa" for readability's sake
@@ -68,12 +68,56 @@ type resource struct {
 // and modified timestamp fields.
 type codelab struct {
 	*types.Codelab
-	Typ  srcType           //  source type
-	Mod  time.Time         // last modified timestamp
-	Imgs map[string]string // Slurped local image paths
+	Typ      srcType           //  source type
+	Mod      time.Time         // last modified timestamp
+	Imgs     map[string]string // Slurped local image paths
+	Assets   map[string]string // Files copied into the assets dir and their sources
+	Manifest []*types.Asset    // Slurped images and assets, sorted by path
 }
 
+// Resolver opens a resource referenced by a codelab read from memory:
+// an imported fragment, an image or a code snippet file.
+// The ref argument is the URL or path as written in the codelab.
+// The caller closes the returned reader.
+// Links to files are resolved too; links for which the resolver returns
+// an error matching os.ErrNotExist, e.g. to other pages, are kept as is.
+// A resolver may be called concurrently, e.g. for images, so it must be
+// safe for concurrent use.
+type Resolver func(ref string) (io.ReadCloser, error)
+
+// MemoryFetcher parses codelabs read from memory. Unlike Fetcher,
+// it never accesses the disk or network; resources referenced
+// by the codelab are opened with its Resolver.
 type MemoryFetcher struct {
+	// Type is the codelab source type, the name of a registered parser.
+	// The default is SrcMarkdown.
+	Type string
+	// Resolver opens imported fragments, images, code snippets
+	// and linked files. If nil, imports and code snippets are left empty,
+	// links are kept as is, and only embedded images, such as data URLs,
+	// are slurped.
+	Resolver Resolver
+	// DefaultLang is the language of codelabs stored directly in their ID
+	// directory. The default is types.DefaultLang.
+	DefaultLang string
+	// Lang, if not empty, overrides the language of the codelab.
+	Lang string
+	// Math enables parsing of math expressions, as in Fetcher.
+	Math bool
+	// ResponsiveImages enables processing of slurped images,
+	// as in Fetcher.
+	ResponsiveImages bool
+	// MaxAssetSize is the maximum size of images, code snippets
+	// and linked files in bytes. The default is DefaultMaxAssetSize.
+	MaxAssetSize int64
+	// Images and ImageURL set how codelab images are handled, as in Fetcher.
+	// Without a Resolver, only embedded images are affected.
+	Images   string
+	ImageURL string
+	// Transform, if not nil, modifies parsed codelabs before their
+	// images are stored, as in Fetcher.
+	Transform func(clab *types.Codelab) error
+
 	passMetadata map[string]bool
 }
 
@@ -83,35 +127,171 @@ func NewMemoryFetcher(pm map[string]bool) *MemoryFetcher {
 	}
 }
 
+// SlurpCodelab parses codelab source rc, without storing its images.
 func (m *MemoryFetcher) SlurpCodelab(rc io.ReadCloser) (*codelab, error) {
-	r := &resource{
-		body: rc,
-		typ:  SrcMarkdown,
-		mod:  time.Now(),
-	}
-	defer r.body.Close()
+	return m.SlurpCodelabFS(rc, nil)
+}
 
+// SlurpCodelabFS parses codelab source rc and resolves its imports
+// and code snippets. Images and linked files are stored in out,
+// in a codelab directory relative to its root, as in Fetcher.SlurpCodelabFS.
+// Diagrams are not pre-rendered, which would run external commands,
+// so they are shown as their source.
+// Images are not stored and URLs are kept as is if out is nil.
+func (m *MemoryFetcher) SlurpCodelabFS(rc io.ReadCloser, out outfs.FS) (*codelab, error) {
+	defer rc.Close()
+	typ := srcType(m.Type)
+	if typ == SrcInvalid {
+		typ = SrcMarkdown
+	}
 	opts := *parser.NewOptions()
 	opts.PassMetadata = m.passMetadata
+	opts.Math = m.Math
 
-	clab, err := parser.Parse(string(r.typ), r.body, opts)
+	clab, err := parser.Parse(string(typ), rc, opts)
 	if err != nil {
 		return nil, err
 	}
+	if m.Lang != "" {
+		clab.Lang = m.Lang
+	}
+
+	// resources are read with the resolver only
+	f := &Fetcher{
+		DefaultLang:      m.DefaultLang,
+		ResponsiveImages: m.ResponsiveImages,
+		MaxAssetSize:     m.MaxAssetSize,
+		Images:           m.Images,
+		ImageURL:         m.ImageURL,
+		crcTable:         crc64.MakeTable(crc64.ECMA),
+		passMetadata:     m.passMetadata,
+		resolve:          m.Resolver,
+	}
+	imageURL, err := f.checkImageMode()
+	if err != nil {
+		return nil, err
+	}
+	var content []nodes.Node
+	if m.Resolver != nil {
+		// parse imports as fragments of the same source type
+		for _, st := range clab.Steps {
+			for _, n := range nodes.ImportNodes(st.Content.Nodes) {
+				b, err := f.readResolved(n.URL)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %v", n.URL, err)
+				}
+				frag, err := parser.ParseFragment(string(typ), bytes.NewReader(b), opts)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %v", n.URL, err)
+				}
+				n.Content.Nodes = frag
+			}
+			content = append(content, st.Content.Nodes...)
+		}
+		if err := f.slurpSnippets("", content); err != nil {
+			return nil, err
+		}
+	} else {
+		// without a resolver, only embedded images can be read
+		f.keepLinked = true
+		for _, st := range clab.Steps {
+			content = append(content, st.Content.Nodes...)
+		}
+	}
+
+	if m.Transform != nil {
+		if err := m.Transform(clab); err != nil {
+			return nil, err
+		}
+		// transforms may drop steps or nodes
+		content = nil
+		for _, st := range clab.Steps {
+			content = append(content, st.Content.Nodes...)
+		}
+	}
+
+	images := make(map[string]string)
+	manifest := []*types.Asset{}
+	if out != nil {
+		rec := newRecorder(out)
+		dir, err := codelabDir("", &clab.Meta, m.DefaultLang)
+		if err != nil {
+			return nil, err
+		}
+		dir = filepath.ToSlash(dir)
+		if err := f.slurpImages("", rec, path.Join(dir, util.ImgDirname), content, images); err != nil {
+			return nil, err
+		}
+		assets := make(map[string]string)
+		if m.Resolver != nil {
+			if err := f.slurpAssets("", rec, path.Join(dir, util.AssetDirname), content, assets); err != nil {
+				return nil, err
+			}
+		}
+		if imageURL != nil {
+			if err := rewriteImages(imageURL, clab.ID, content, images); err != nil {
+				return nil, err
+			}
+		}
+		if manifest, err = rec.manifest(dir, images, assets); err != nil {
+			return nil, err
+		}
+	}
+	clab.Quiz = types.NewQuiz(clab.Steps)
 
 	return &codelab{
-		Codelab: clab,
-		Typ:     r.typ,
-		Mod:     r.mod,
+		Codelab:  clab,
+		Typ:      typ,
+		Mod:      time.Now(),
+		Imgs:     images,
+		Manifest: manifest,
 	}, nil
 }
 
 type Fetcher struct {
+	// DefaultLang is the language of codelabs stored directly in their ID
+	// directory. Assets of translations into other languages are stored in
+	// a per-language subdirectory. The default is types.DefaultLang.
+	DefaultLang string
+	// Lang, if not empty, overrides the language of fetched codelabs,
+	// e.g. for codelabs translated after fetching.
+	Lang string
+	// Math enables parsing of $...$ and $$...$$ math expressions
+	// in codelab text.
+	Math bool
+	// Diagrams enables pre-rendering of diagrams into SVG images
+	// with the dot and mmdc commands, which must be installed.
+	Diagrams bool
+	// ResponsiveImages enables processing of slurped images:
+	// stripping of metadata, downscaling to their display width at 1x
+	// and 2x pixel density, and WebP variants. See processImage.
+	ResponsiveImages bool
+	// MaxAssetSize is the maximum size of images and other assets
+	// in bytes. The default is DefaultMaxAssetSize.
+	MaxAssetSize int64
+	// AssetHosts are hosts of remote files, such as PDFs or zip archives,
+	// which are downloaded into the codelab when linked. A "*." prefix
+	// matches any subdomain. Linked local files are always allowed.
+	AssetHosts []string
+	// Images is the image mode, one of ImagesSlurp, ImagesKeep
+	// or ImagesRewrite. The default is ImagesSlurp.
+	Images string
+	// ImageURL is a text/template of image URLs in ImagesRewrite mode,
+	// e.g. "https://cdn.example.com/{{.ID}}/{{.Hash}}{{.Ext}}".
+	// See imageURLData for the available fields.
+	ImageURL string
+	// Transform, if not nil, modifies fetched codelabs, including their
+	// imported fragments and code snippets, before images and other
+	// assets are stored. The manifest reflects the transformed codelab.
+	Transform func(clab *types.Codelab) error
+
 	authHelper   *auth.Helper
 	authToken    string
 	crcTable     *crc64.Table
 	passMetadata map[string]bool
 	roundTripper http.RoundTripper
+	resolve      Resolver // opens all resources instead of disk and network, if set
+	keepLinked   bool     // keeps URLs of all images which are not embedded
 }
 
 // NewFetcher creates an instance of Fetcher.
@@ -130,9 +310,25 @@ func NewFetcher(at string, pm map[string]bool, rt http.RoundTripper) (*Fetcher,
 // It returns parsed codelab and its source type.
 //
 // The function will also fetch and parse fragments included
-// with nodes.ImportNode.
+// with nodes.ImportNode. Codelab assets are stored in a codelab directory
+// under output, unless output is "-", meaning stdout.
 func (f *Fetcher) SlurpCodelab(src string, output string) (*codelab, error) {
-	_, err := os.Stat(src)
+	var out outfs.FS
+	if !isStdout(output) {
+		out = outfs.Dir(output)
+	}
+	return f.SlurpCodelabFS(src, out)
+}
+
+// SlurpCodelabFS is like SlurpCodelab, but stores codelab assets in out,
+// in a codelab directory relative to its root.
+// Assets are not stored and image URLs are kept as is if out is nil.
+func (f *Fetcher) SlurpCodelabFS(src string, out outfs.FS) (*codelab, error) {
+	imageURL, err := f.checkImageMode()
+	if err != nil {
+		return nil, err
+	}
+	_, err = os.Stat(src)
 	// Only setup oauth if this source is not a local file.
 	if os.IsNotExist(err) {
 		if f.authHelper == nil {
@@ -150,26 +346,15 @@ func (f *Fetcher) SlurpCodelab(src string, output string) (*codelab, error) {
 
 	opts := *parser.NewOptions()
 	opts.PassMetadata = f.passMetadata
+	opts.Math = f.Math
 
 	clab, err := parser.Parse(string(res.typ), res.body, opts)
 	if err != nil {
 		return nil, err
 	}
-	images := make(map[string]string)
-	dir := codelabDir(output, &clab.Meta)
-	imgDir := filepath.Join(dir, util.ImgDirname)
-	if !isStdout(output) {
-		// download or copy codelab assets to disk, and rewrite image URLs
-		var nodes []nodes.Node
-		for _, step := range clab.Steps {
-			nodes = append(nodes, step.Content.Nodes...)
-		}
-		err := f.SlurpImages(src, imgDir, nodes, images)
-		if err != nil {
-			return nil, err
-		}
+	if f.Lang != "" {
+		clab.Lang = f.Lang
 	}
-
 	// fetch imports and parse them as fragments
 	var imports []*nodes.ImportNode
 	for _, st := range clab.Steps {
@@ -179,18 +364,11 @@ func (f *Fetcher) SlurpCodelab(src string, output string) (*codelab, error) {
 	defer close(ch)
 	for _, imp := range imports {
 		go func(n *nodes.ImportNode) {
-			frag, err := f.slurpFragment(n.URL)
+			frag, err := f.slurpFragment(src, n.URL)
 			if err != nil {
 				ch <- fmt.Errorf("%s: %v", n.URL, err)
 				return
 			}
-			if !isStdout(output) {
-				// download or copy codelab assets to disk, and rewrite image URLs
-				err = f.SlurpImages(gdocID(n.URL), imgDir, frag, images)
-				if err != nil {
-					return
-				}
-			}
 			n.Content.Nodes = frag
 			ch <- nil
 		}(imp)
@@ -201,47 +379,154 @@ func (f *Fetcher) SlurpCodelab(src string, output string) (*codelab, error) {
 		}
 	}
 
+	// read code snippets, including those of imported fragments
+	var content []nodes.Node
+	for _, st := range clab.Steps {
+		content = append(content, st.Content.Nodes...)
+	}
+	if err := f.slurpSnippets(src, content); err != nil {
+		return nil, err
+	}
+
+	// transform the codelab before storing any of its assets,
+	// so that only assets of the transformed codelab are stored
+	if f.Transform != nil {
+		if err := f.Transform(clab); err != nil {
+			return nil, err
+		}
+		content = nil
+		for _, st := range clab.Steps {
+			content = append(content, st.Content.Nodes...)
+		}
+	}
+
+	images := make(map[string]string)
+	dir, err := codelabDir("", &clab.Meta, f.DefaultLang)
+	if err != nil {
+		return nil, err
+	}
+	dir = filepath.ToSlash(dir)
+	imgDir := path.Join(dir, util.ImgDirname)
+	var rec *recorder
+	if out != nil {
+		rec = newRecorder(out)
+		// download or copy codelab images, and rewrite their URLs;
+		// images of imported fragments are relative to the fragment
+		srcs := make(map[*nodes.ImageNode]string)
+		for _, imp := range nodes.ImportNodes(content) {
+			for _, n := range nodes.ImageNodes(imp.Content.Nodes) {
+				srcs[n] = gdocID(imp.URL)
+			}
+		}
+		bySrc := make(map[string][]*nodes.ImageNode)
+		for _, n := range nodes.ImageNodes(content) {
+			s, ok := srcs[n]
+			if !ok {
+				s = src
+			}
+			bySrc[s] = append(bySrc[s], n)
+		}
+		for s, imgs := range bySrc {
+			if err := f.slurpImageNodes(s, rec, imgDir, imgs, images); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	assets := make(map[string]string)
+	// the manifest is empty rather than nil without any files,
+	// which tells the export apart from those predating manifests
+	m := []*types.Asset{}
+	if rec != nil {
+		// pre-render diagrams next to images
+		if f.Diagrams {
+			if err := f.slurpDiagrams(rec, imgDir, content, images); err != nil {
+				return nil, err
+			}
+		}
+		// copy linked files and rewrite their URLs
+		if err := f.slurpAssets(src, rec, path.Join(dir, util.AssetDirname), content, assets); err != nil {
+			return nil, err
+		}
+		if imageURL != nil {
+			if err := rewriteImages(imageURL, clab.ID, content, images); err != nil {
+				return nil, err
+			}
+		}
+		if m, err = rec.manifest(dir, images, assets); err != nil {
+			return nil, err
+		}
+	}
+
+	clab.Quiz = types.NewQuiz(clab.Steps)
+
 	v := &codelab{
-		Codelab: clab,
-		Typ:     res.typ,
-		Mod:     res.mod,
-		Imgs:    images,
+		Codelab:  clab,
+		Typ:      res.typ,
+		Mod:      res.mod,
+		Imgs:     images,
+		Assets:   assets,
+		Manifest: m,
 	}
 	return v, nil
 }
 
+// SlurpImages downloads or copies images of n into directory dir
+// and rewrites their URLs. Names of the created files are added to images,
+// along with their source.
 func (f *Fetcher) SlurpImages(src, dir string, n []nodes.Node, images map[string]string) error {
-	// make sure img dir exists
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return err
-	}
+	return f.slurpImages(src, outfs.Dir(dir), ".", n, images)
+}
+
+// slurpImages is like SlurpImages, but stores the images in directory dir of out.
+func (f *Fetcher) slurpImages(src string, out outfs.FS, dir string, n []nodes.Node, images map[string]string) error {
+	return f.slurpImageNodes(src, out, dir, nodes.ImageNodes(n), images)
+}
 
+// slurpImageNodes is like slurpImages, for image nodes relative to src.
+func (f *Fetcher) slurpImageNodes(src string, out outfs.FS, dir string, imgs []*nodes.ImageNode, images map[string]string) error {
 	type res struct {
-		url, file string
-		err       error
+		url   string
+		files []string
+		err   error
 	}
 
 	ch := make(chan *res, 100)
 	defer close(ch)
 	var count int
-	imageNodes := nodes.ImageNodes(n)
+	var imageNodes []*nodes.ImageNode
+	for _, imageNode := range imgs {
+		if !f.keepImage(imageNode) {
+			imageNodes = append(imageNodes, imageNode)
+		}
+	}
 	count += len(imageNodes)
 	for _, imageNode := range imageNodes {
 		go func(imageNode *nodes.ImageNode) {
 			url := imageNode.Src
-			file, err := f.slurpBytes(src, dir, url, imageNode.Bytes)
-			if err == nil {
-				imageNode.Src = filepath.Join(util.ImgDirname, file)
+			var files []string
+			var err error
+			if f.ResponsiveImages {
+				files, err = f.slurpResponsive(src, out, dir, imageNode)
+			} else {
+				var file string
+				file, err = f.slurpBytes(src, out, dir, url, imageNode.Bytes)
+				if err == nil {
+					imageNode.Src = filepath.Join(util.ImgDirname, file)
+				}
+				files = []string{file}
 			}
-			ch <- &res{url, file, err}
+			ch <- &res{url, files, err}
 		}(imageNode)
 	}
 	var errStr string
 	for i := 0; i < count; i++ {
 		r := <-ch
-		images[r.file] = r.url
+		for _, file := range r.files {
+			images[file] = r.url
+		}
 		if r.err != nil {
-			errStr += fmt.Sprintf("%s => %s: %v\n", r.url, r.file, r.err)
+			errStr += fmt.Sprintf("%s => %s: %v\n", r.url, strings.Join(r.files, ", "), r.err)
 		}
 	}
 	if len(errStr) > 0 {
@@ -251,24 +536,70 @@ func (f *Fetcher) SlurpImages(src, dir string, n []nodes.Node, images map[string
 	return nil
 }
 
-func (f *Fetcher) slurpBytes(codelabSrc, dir, imgURL string, imgBytes []byte) (string, error) {
+func (f *Fetcher) slurpBytes(codelabSrc string, out outfs.FS, dir, imgURL string, imgBytes []byte) (string, error) {
+	b, ext, err := f.readImage(codelabSrc, imgURL, imgBytes)
+	if err != nil {
+		return "", err
+	}
+
+	// Generate image file from slurped bytes.
+	crc := crc64.Checksum(b, f.crcTable)
+	file := fmt.Sprintf("%x%s", crc, ext)
+	return file, out.WriteFile(path.Join(dir, file), b)
+}
+
+// slurpResponsive is like slurpBytes, but processes the image of n
+// for responsive display, writing all of its variants into dir of out.
+// It updates n with the processed image and returns names of written files.
+func (f *Fetcher) slurpResponsive(codelabSrc string, out outfs.FS, dir string, n *nodes.ImageNode) ([]string, error) {
+	b, ext, err := f.readImage(codelabSrc, n.Src, n.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	crc := crc64.Checksum(b, f.crcTable)
+	img := processImage(b, fmt.Sprintf("%x", crc), ext, n.Width)
+	var files []string
+	for i, file := range img.files {
+		if err := out.WriteFile(path.Join(dir, file.name), file.data); err != nil {
+			return files, err
+		}
+		files = append(files, file.name)
+		src := filepath.Join(util.ImgDirname, file.name)
+		if i == 0 {
+			n.Src = src
+			continue
+		}
+		n.Variants = append(n.Variants, &nodes.ImageVariant{Src: src, Type: file.typ, Density: file.density})
+	}
+	n.PixelWidth, n.PixelHeight = img.width, img.height
+	return files, nil
+}
+
+// readImage reads image bytes of imgURL, relative to codelabSrc,
+// unless imgBytes are already known. It also returns the image file extension,
+// detected from the image content. SVG images are sanitized.
+func (f *Fetcher) readImage(codelabSrc, imgURL string, imgBytes []byte) ([]byte, string, error) {
 	// images can be data URLs, local in Markdown cases or remote.
 	// Only proceed a simple copy on local reference.
 	var b []byte
-	var ext string
 	var err error
 
 	if len(imgBytes) > 0 {
 		// Slurp bytes from image URL data.
 		b = imgBytes
-		if ext, err = imgExtFromBytes(b); err != nil {
-			return "", fmt.Errorf("Error reading image type: %v", err)
+		if int64(len(b)) > f.maxAssetSize() {
+			return nil, "", errAssetSize(f.maxAssetSize())
+		}
+	} else if f.resolve != nil {
+		// Slurp bytes from the caller, for codelabs read from memory.
+		if b, err = f.readResolved(imgURL); err != nil {
+			return nil, "", err
 		}
 	} else {
 		// Slurp bytes from local or remote URL.
 		u, err := url.Parse(imgURL)
 		if err != nil {
-			return "", err
+			return nil, "", err
 		}
 
 		// If the codelab source is being downloaded from the network, then we should interpret
@@ -280,31 +611,44 @@ func (f *Fetcher) slurpBytes(codelabSrc, dir, imgURL string, imgBytes []byte) (s
 
 		if u.Host == "" {
 			if imgURL, err = restrictPathToParent(imgURL, filepath.Dir(codelabSrc)); err != nil {
-				return "", err
+				return nil, "", err
 			}
-			if b, err = ioutil.ReadFile(imgURL); err != nil {
-				return "", err
+			if b, err = f.readLocalBytes(imgURL); err != nil {
+				return nil, "", err
 			}
-			ext = filepath.Ext(imgURL)
 		} else {
 			if b, err = f.slurpRemoteBytes(u.String(), 5); err != nil {
-				return "", fmt.Errorf("Error downloading image at %s: %v", u.String(), err)
-			}
-			if ext, err = imgExtFromBytes(b); err != nil {
-				return "", fmt.Errorf("Error reading image type at %s: %v", u.String(), err)
+				return nil, "", fmt.Errorf("Error downloading image at %s: %v", u.String(), err)
 			}
+			imgURL = u.String()
 		}
 	}
 
-	// Generate image file from slurped bytes.
-	crc := crc64.Checksum(b, f.crcTable)
-	file := fmt.Sprintf("%x%s", crc, ext)
-	dst := filepath.Join(dir, file)
-	return file, ioutil.WriteFile(dst, b, 0644)
+	typ, err := sniffImage(b)
+	if err != nil {
+		if len(imgBytes) > 0 {
+			return nil, "", fmt.Errorf("Error reading image type: %v", err)
+		}
+		return nil, "", fmt.Errorf("Error reading image type at %s: %v", imgURL, err)
+	}
+	if typ == mimeSVG {
+		if b, err = sanitizeSVG(b); err != nil {
+			return nil, "", err
+		}
+	}
+	return b, imageExts[typ], nil
 }
 
-func (f *Fetcher) slurpFragment(url string) ([]nodes.Node, error) {
-	res, err := f.fetch(url)
+// slurpFragment fetches and parses a fragment imported by codelab src.
+// Fragments in local git repositories must be within the codelab dir.
+func (f *Fetcher) slurpFragment(src, url string) ([]nodes.Node, error) {
+	var res *resource
+	var err error
+	if isGitRef(url) {
+		res, err = f.fetchGit(url, localDir(src))
+	} else {
+		res, err = f.fetch(url)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -312,14 +656,18 @@ func (f *Fetcher) slurpFragment(url string) ([]nodes.Node, error) {
 
 	opts := *parser.NewOptions()
 	opts.PassMetadata = f.passMetadata
+	opts.Math = f.Math
 
 	return parser.ParseFragment(string(res.typ), res.body, opts)
 }
 
-// fetch retrieves codelab doc either from local disk
-// or a remote location.
+// fetch retrieves codelab doc either from local disk,
+// a remote location or a remote git repository.
 // The caller is responsible for closing returned stream.
 func (f *Fetcher) fetch(name string) (*resource, error) {
+	if isGitRef(name) {
+		return f.fetchGit(name, "")
+	}
 	fi, err := os.Stat(name)
 	if os.IsNotExist(err) {
 		return f.fetchRemote(name, false)
@@ -422,12 +770,61 @@ func (f *Fetcher) fetchDriveFile(id string, nometa bool) (*resource, error) {
 }
 
 func (f *Fetcher) slurpRemoteBytes(url string, n int) ([]byte, error) {
-	res, err := retryGet(f.authHelper.DriveClient(), url, n)
+	var client *http.Client
+	if f.authHelper != nil {
+		client = f.authHelper.DriveClient()
+	}
+	res, err := retryGet(client, url, n)
 	if err != nil {
 		return nil, err
 	}
 	defer res.Body.Close()
-	return ioutil.ReadAll(res.Body)
+	return readAllLimit(res.Body, f.maxAssetSize())
+}
+
+// readResolved reads resource ref opened with f.resolve,
+// up to the maximum asset size.
+func (f *Fetcher) readResolved(ref string) ([]byte, error) {
+	r, err := f.resolve(ref)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return readAllLimit(r, f.maxAssetSize())
+}
+
+// readLocalBytes reads local file name, up to the maximum asset size.
+func (f *Fetcher) readLocalBytes(name string) ([]byte, error) {
+	r, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return readAllLimit(r, f.maxAssetSize())
+}
+
+// maxAssetSize returns the maximum size of assets read by f.
+func (f *Fetcher) maxAssetSize() int64 {
+	if f.MaxAssetSize <= 0 {
+		return DefaultMaxAssetSize
+	}
+	return f.MaxAssetSize
+}
+
+// readAllLimit reads r until EOF, failing if it has more than max bytes.
+func readAllLimit(r io.Reader, max int64) ([]byte, error) {
+	b, err := ioutil.ReadAll(io.LimitReader(r, max+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > max {
+		return nil, errAssetSize(max)
+	}
+	return b, nil
+}
+
+func errAssetSize(max int64) error {
+	return fmt.Errorf("asset is larger than the maximum size of %d bytes", max)
 }
 
 // retryGet tries to GET specified url up to n times.
@@ -474,12 +871,24 @@ func retryGet(client *http.Client, url string, n int) (*http.Response, error) {
 		// this is neither a rate limit error, nor a server error:
 		// retrying is useless
 		if !rateLimit && res.StatusCode < http.StatusInternalServerError {
-			return nil, fmt.Errorf("fetch %s: %s; %s", url, res.Status, b)
+			return nil, fmt.Errorf("fetch %s: %s; %s", url, res.Status, errorBody(b))
 		}
 	}
 	return nil, fmt.Errorf("%s: failed after %d retries", url, n)
 }
 
+// errorBody summarizes error response body b for an error message.
+// HTML error pages are reduced to their title.
+func errorBody(b []byte) string {
+	if !strings.HasPrefix(http.DetectContentType(b), "text/html") {
+		return string(b)
+	}
+	if title := htmlTitle(b); title != "" {
+		return fmt.Sprintf("HTML page %q", title)
+	}
+	return "HTML page"
+}
+
 func gdocID(url string) string {
 	const s = "/document/d/"
 	if i := strings.Index(url, s); i >= 0 {
@@ -522,20 +931,14 @@ func isStdout(filename string) bool {
 
 // codelabDir returns codelab root directory.
 // The base argument is codelab parent directory.
-func codelabDir(base string, m *types.Meta) string {
-	return filepath.Join(base, m.ID)
-}
-
-func imgExtFromBytes(b []byte) (string, error) {
-	if len(b) < minImageSize {
-		return "", fmt.Errorf("error parsing image - response \"%s\" is too small (< %d bytes)", b, minImageSize)
+// Translations are stored in a subdirectory named after their language.
+func codelabDir(base string, m *types.Meta, defaultLang string) (string, error) {
+	if defaultLang == "" {
+		defaultLang = types.DefaultLang
 	}
-	ext := ".png"
-	switch {
-	case string(b[6:10]) == "JFIF":
-		ext = ".jpeg"
-	case string(b[0:3]) == "GIF":
-		ext = ".gif"
+	dir, err := m.Dir(defaultLang)
+	if err != nil {
+		return "", err
 	}
-	return ext, nil
+	return filepath.Join(base, dir), nil
 }
@@ -111,11 +111,19 @@ func TestImgExtFromBytes(t *testing.T) {
 		wantExt string
 		wantErr bool
 	}{
-		{[]byte("012345JFIF0"), ".jpeg", false},
-		{[]byte("GIF34567890"), ".gif", false},
-		{[]byte("SOMETHINGELSE"), ".png", false},
-		{[]byte("GIF345JFIF0"), ".jpeg", false},
-		{[]byte("toosmall"), "", true},
+		{[]byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00"), ".jpeg", false},
+		{[]byte("\xff\xd8\xff\xe1\x00\x10Exif\x00"), ".jpeg", false},
+		{[]byte("GIF89a"), ".gif", false},
+		{[]byte("\x89PNG\r\n\x1a\n"), ".png", false},
+		{[]byte("RIFF\x00\x00\x00\x00WEBPVP8L"), ".webp", false},
+		{[]byte("\x00\x00\x00\x1cftypavif\x00\x00\x00\x00avifmif1miaf"), ".avif", false},
+		{[]byte("\x00\x00\x00\x18ftypmif1\x00\x00\x00\x00mif1avif"), ".avif", false},
+		{[]byte("\x00\x00\x00\x18ftypisom\x00\x00\x00\x00isommp41"), "", true},
+		{[]byte(`<?xml version="1.0"?><!-- c --><svg xmlns="http://www.w3.org/2000/svg"/>`), ".svg", false},
+		{[]byte(`<?xml version="1.0"?><html/>`), "", true},
+		{[]byte("<!DOCTYPE html><html><title>Sign in</title></html>"), "", true},
+		{[]byte("SOMETHINGELSE"), "", true},
+		{[]byte(""), "", true},
 	}
 	for _, tc := range tests {
 		t.Run(fmt.Sprintf("bytes: %s", tc.bytes), func(t *testing.T) {
@@ -0,0 +1,198 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fetch
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// gitPrefix is the prefix of references to files in git repositories,
+// e.g. "git+https://github.com/org/repo.git//app/main.go?ref=v1.0".
+// The repository URL and the file path within the repository are
+// separated by "//", and the optional ref query parameter names
+// a branch or a tag; the default branch is used without it.
+// Repositories are cloned over https or ssh, or from a local path
+// or file URL within the directory of a local codelab.
+const gitPrefix = "git+"
+
+// isGitRef reports whether ref is a reference to a file in a git repository.
+func isGitRef(ref string) bool {
+	return strings.HasPrefix(ref, gitPrefix)
+}
+
+// parseGitRef splits git file reference ref into the repository URL,
+// the slash-separated file path within the repository and the ref to check out.
+func parseGitRef(ref string) (repo, file, rev string, err error) {
+	u, err := url.Parse(strings.TrimPrefix(ref, gitPrefix))
+	if err != nil {
+		return "", "", "", err
+	}
+	i := strings.Index(u.Path, "//")
+	if i < 0 {
+		return "", "", "", fmt.Errorf("%s: no // between the repository and the file path", ref)
+	}
+	file = path.Clean(u.Path[i+2:])
+	if file == "." || file == ".." || strings.HasPrefix(file, "../") {
+		return "", "", "", fmt.Errorf("%s: file path must be within the repository", ref)
+	}
+	rev = u.Query().Get("ref")
+	u.Path = u.Path[:i]
+	u.RawPath = ""
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String(), file, rev, nil
+}
+
+// resolveGitRef returns a reference to the file at relative path p,
+// resolved against the file of git reference base in the same repository
+// and at the same ref.
+func resolveGitRef(base, p string) (string, error) {
+	repo, file, rev, err := parseGitRef(base)
+	if err != nil {
+		return "", err
+	}
+	ref := gitPrefix + repo + "//" + path.Join(path.Dir(file), p)
+	if rev != "" {
+		ref += "?" + url.Values{"ref": {rev}}.Encode()
+	}
+	return ref, nil
+}
+
+// gitRepo returns the repository of a git file reference to clone.
+// Remote repositories must be https or ssh URLs.
+// Local repositories must be within directory parent,
+// and are not allowed at all if parent is empty.
+func gitRepo(repo, parent string) (string, error) {
+	u, err := url.Parse(repo)
+	if err != nil {
+		return "", err
+	}
+	switch u.Scheme {
+	case "https", "ssh":
+		return repo, nil
+	case "", "file":
+		if parent == "" {
+			return "", fmt.Errorf("%s: local repositories are allowed only in local codelabs", repo)
+		}
+		if u.Host != "" {
+			return "", fmt.Errorf("%s: file URL with a host", repo)
+		}
+		return restrictPathToParent(filepath.FromSlash(u.Path), parent)
+	}
+	return "", fmt.Errorf("%s: unsupported repository scheme %q; use https or ssh", repo, u.Scheme)
+}
+
+// localDir returns the directory of codelab src if it is a local file,
+// or an empty string otherwise.
+func localDir(src string) string {
+	if src == "" || isGitRef(src) {
+		return ""
+	}
+	if fi, err := os.Stat(src); err != nil || fi.IsDir() {
+		return ""
+	}
+	return filepath.Dir(src)
+}
+
+// fragmentRoot returns the directory local git repositories referenced
+// by fragment src must be in, imported by a codelab whose repositories
+// must be in root. Fragments of local repositories share root of the codelab,
+// while those of remote repositories may not reference local ones.
+func fragmentRoot(src, root string) string {
+	if !isGitRef(src) {
+		return localDir(src)
+	}
+	repo, _, _, err := parseGitRef(src)
+	if err != nil {
+		return ""
+	}
+	if u, err := url.Parse(repo); err != nil || (u.Scheme != "" && u.Scheme != "file") {
+		return ""
+	}
+	return root
+}
+
+// fetchGit retrieves a file referenced by ref from a git repository.
+// Local repositories must be within directory parent, see gitRepo.
+func (f *Fetcher) fetchGit(ref, parent string) (*resource, error) {
+	b, err := f.readGit(ref, parent)
+	if err != nil {
+		return nil, err
+	}
+	return &resource{
+		body: ioutil.NopCloser(bytes.NewReader(b)),
+		typ:  SrcMarkdown,
+		mod:  time.Now(),
+	}, nil
+}
+
+// readGit reads a file referenced by ref from a git repository,
+// up to the maximum asset size. The repository is cloned shallowly
+// into a temporary directory with the git command.
+// Local repositories must be within directory parent, see gitRepo.
+func (f *Fetcher) readGit(ref, parent string) ([]byte, error) {
+	repo, file, rev, err := parseGitRef(ref)
+	if err != nil {
+		return nil, err
+	}
+	if repo, err = gitRepo(repo, parent); err != nil {
+		return nil, err
+	}
+	dir, err := ioutil.TempDir("", "claat-git-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	args := []string{"clone", "--quiet", "--depth", "1", "--no-checkout"}
+	if rev != "" {
+		args = append(args, "--branch", rev)
+	}
+	if _, err := runGit("", append(args, "--", repo, dir)...); err != nil {
+		return nil, err
+	}
+	b, err := runGit(dir, "show", "HEAD:"+file)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > f.maxAssetSize() {
+		return nil, errAssetSize(f.maxAssetSize())
+	}
+	return b, nil
+}
+
+// runGit runs the git command with args in dir and returns its output.
+func runGit(dir string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	c := exec.Command("git", args...)
+	c.Dir = dir
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+	if err := c.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %v: %s", args[0], err, msg)
+		}
+		return nil, fmt.Errorf("git %s: %v", args[0], err)
+	}
+	return stdout.Bytes(), nil
+}
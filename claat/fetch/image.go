@@ -0,0 +1,378 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fetch
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"math"
+)
+
+// jpegQuality is the quality of re-encoded JPEG images.
+const jpegQuality = 90
+
+// maxImagePixels is the maximum number of pixels of processed images.
+// Larger images are not decoded, since their size in memory
+// can be much larger than the size of the image data.
+var maxImagePixels int64 = 50 << 20
+
+// imageFile is a file of a processed image.
+type imageFile struct {
+	name    string
+	data    []byte
+	typ     string  // MIME type of variants in a different format
+	density float32 // pixel density of the file relative to the 1x image
+}
+
+// processedImage is an image prepared for responsive display.
+type processedImage struct {
+	width, height int          // intrinsic size of the 1x image, if known
+	files         []*imageFile // 1x image first, followed by its variants
+}
+
+// processImage prepares image data b for display at the given width
+// in CSS pixels, or at its intrinsic size if width is zero.
+// Files are named after base, followed by their width unless the file
+// is the original image.
+//
+// Metadata is stripped from PNG and JPEG images, and images larger than
+// width are downscaled to 1x and 2x pixel density. Each size is also encoded
+// as WebP, lossless for PNG images and lossy at jpegQuality for JPEG images,
+// and the WebP variants are kept if they are all smaller than their
+// counterparts in the original format.
+// Other formats, images larger than maxImagePixels, and images which
+// cannot be decoded, are returned as is, with ext.
+func processImage(b []byte, base, ext string, width float32) *processedImage {
+	orig := &processedImage{files: []*imageFile{{name: base + ext, data: b}}}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(b))
+	if err != nil || format != "png" && format != "jpeg" {
+		return orig
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > maxImagePixels {
+		return orig
+	}
+	ext = "." + format
+	orient := 1
+	switch format {
+	case "jpeg":
+		b, orient = stripJPEG(b)
+	case "png":
+		b = stripPNG(b)
+	}
+	m, _, err := image.Decode(bytes.NewReader(b))
+	if err != nil {
+		return orig
+	}
+	src := orientImage(m, orient)
+	w, h := cfg.Width, cfg.Height
+	if orient >= 5 {
+		w, h = h, w
+	}
+
+	// widths of the 1x image and variants with higher density, if any
+	w1 := w
+	if width > 0 && int(math.Ceil(float64(width))) < w {
+		w1 = int(math.Ceil(float64(width)))
+	}
+	sizes := []int{w1}
+	switch {
+	case 2*w1 <= w:
+		sizes = append(sizes, 2*w1)
+	case w1 < w:
+		sizes = append(sizes, w)
+	}
+
+	res := &processedImage{width: w1, height: scaledHeight(w, h, w1)}
+	var webp []*imageFile
+	keepWebP := true
+	for _, sw := range sizes {
+		density := float32(math.Round(float64(sw)/float64(w1)*100) / 100)
+		var dst image.Image = src
+		if sw != w {
+			dst = resizeImage(src, sw, scaledHeight(w, h, sw))
+		}
+		f := &imageFile{name: base + ext, data: b, density: density}
+		if dst != src || orient != 1 {
+			var buf bytes.Buffer
+			if format == "png" {
+				enc := png.Encoder{CompressionLevel: png.BestCompression}
+				err = enc.Encode(&buf, dst)
+			} else {
+				err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: jpegQuality})
+			}
+			if err != nil {
+				return orig
+			}
+			f.name = fmt.Sprintf("%s-%dw%s", base, sw, ext)
+			f.data = buf.Bytes()
+		}
+		res.files = append(res.files, f)
+
+		if !keepWebP {
+			continue
+		}
+		var buf bytes.Buffer
+		if format == "png" {
+			err = encodeWebP(&buf, dst)
+		} else {
+			err = encodeLossyWebP(&buf, dst, jpegQuality)
+		}
+		if err != nil || buf.Len() >= len(f.data) {
+			keepWebP = false
+			continue
+		}
+		webp = append(webp, &imageFile{
+			name:    fmt.Sprintf("%s-%dw.webp", base, sw),
+			data:    buf.Bytes(),
+			typ:     "image/webp",
+			density: density,
+		})
+	}
+	if keepWebP {
+		res.files = append(res.files, webp...)
+	}
+	return res
+}
+
+// scaledHeight returns the height of a w by h image scaled to width sw.
+func scaledHeight(w, h, sw int) int {
+	sh := int(math.Round(float64(h) * float64(sw) / float64(w)))
+	if sh < 1 {
+		sh = 1
+	}
+	return sh
+}
+
+// stripJPEG removes Exif, XMP and IPTC metadata segments from JPEG data b,
+// keeping color profiles. It also returns the image orientation
+// from Exif metadata, or 1 if unknown.
+// Malformed data is returned as is.
+func stripJPEG(b []byte) ([]byte, int) {
+	if len(b) < 4 || b[0] != 0xff || b[1] != 0xd8 {
+		return b, 1
+	}
+	out := append(make([]byte, 0, len(b)), b[:2]...)
+	orient := 1
+	for i := 2; i+4 <= len(b); {
+		if b[i] != 0xff {
+			return b, 1
+		}
+		marker := b[i+1]
+		switch {
+		case marker == 0xff:
+			// fill byte
+			i++
+			continue
+		case marker == 0xda:
+			// start of scan, followed by entropy coded data
+			return append(out, b[i:]...), orient
+		}
+		end := i + 2 + int(binary.BigEndian.Uint16(b[i+2:]))
+		if end < i+4 || end > len(b) {
+			return b, 1
+		}
+		switch marker {
+		case 0xe1: // APP1, Exif or XMP
+			if d := b[i+4 : end]; bytes.HasPrefix(d, []byte("Exif\x00\x00")) {
+				orient = exifOrientation(d[6:])
+			}
+		case 0xed: // APP13, IPTC
+		default:
+			out = append(out, b[i:end]...)
+		}
+		i = end
+	}
+	return b, 1
+}
+
+// exifOrientation returns the orientation tag value of the first IFD
+// of Exif TIFF data b, or 1 if unknown.
+func exifOrientation(b []byte) int {
+	if len(b) < 8 {
+		return 1
+	}
+	var bo binary.ByteOrder
+	switch string(b[:2]) {
+	case "II":
+		bo = binary.LittleEndian
+	case "MM":
+		bo = binary.BigEndian
+	default:
+		return 1
+	}
+	off := int(bo.Uint32(b[4:]))
+	if off < 8 || off+2 > len(b) {
+		return 1
+	}
+	for i, n := 0, int(bo.Uint16(b[off:])); i < n; i++ {
+		e := off + 2 + 12*i
+		if e+12 > len(b) {
+			break
+		}
+		if bo.Uint16(b[e:]) == 0x0112 {
+			if v := int(bo.Uint16(b[e+8:])); v >= 1 && v <= 8 {
+				return v
+			}
+			break
+		}
+	}
+	return 1
+}
+
+// stripPNG removes Exif, text and timestamp chunks from PNG data b.
+// Malformed data is returned as is.
+func stripPNG(b []byte) []byte {
+	const sig = "\x89PNG\r\n\x1a\n"
+	if !bytes.HasPrefix(b, []byte(sig)) {
+		return b
+	}
+	out := append(make([]byte, 0, len(b)), sig...)
+	for i := len(sig); i < len(b); {
+		if i+12 > len(b) {
+			return b
+		}
+		end := i + 12 + int(binary.BigEndian.Uint32(b[i:]))
+		if end < i+12 || end > len(b) {
+			return b
+		}
+		switch string(b[i+4 : i+8]) {
+		case "eXIf", "tEXt", "zTXt", "iTXt", "tIME":
+		default:
+			out = append(out, b[i:end]...)
+		}
+		i = end
+	}
+	return out
+}
+
+// orientImage returns m as premultiplied RGBA, transformed according to
+// Exif orientation value orient.
+func orientImage(m image.Image, orient int) *image.RGBA {
+	b := m.Bounds()
+	src := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(src, src.Rect, m, b.Min, draw.Src)
+	if orient < 2 || orient > 8 {
+		return src
+	}
+	w, h := b.Dx(), b.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	if orient >= 5 {
+		dst = image.NewRGBA(image.Rect(0, 0, h, w))
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orient {
+			case 2: // flip horizontal
+				dx, dy = w-1-x, y
+			case 3: // rotate 180
+				dx, dy = w-1-x, h-1-y
+			case 4: // flip vertical
+				dx, dy = x, h-1-y
+			case 5: // transpose
+				dx, dy = y, x
+			case 6: // rotate 90 clockwise
+				dx, dy = h-1-y, x
+			case 7: // transverse
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotate 90 counterclockwise
+				dx, dy = y, w-1-x
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):][:4], src.Pix[src.PixOffset(x, y):])
+		}
+	}
+	return dst
+}
+
+// boxWeight is the weight of source pixel i in a destination pixel.
+type boxWeight struct {
+	i int
+	w float32
+}
+
+// boxWeights returns weights of source pixels covered by each destination
+// pixel, when n source pixels are scaled to m pixels.
+func boxWeights(n, m int) [][]boxWeight {
+	scale := float64(n) / float64(m)
+	ws := make([][]boxWeight, m)
+	for d := range ws {
+		lo, hi := float64(d)*scale, float64(d+1)*scale
+		for i := int(lo); i < n && float64(i) < hi; i++ {
+			if c := math.Min(hi, float64(i+1)) - math.Max(lo, float64(i)); c > 0 {
+				ws[d] = append(ws[d], boxWeight{i, float32(c / scale)})
+			}
+		}
+	}
+	return ws
+}
+
+// resizeImage downscales src to w by h pixels, averaging the area
+// of src covered by each pixel.
+func resizeImage(src *image.RGBA, w, h int) *image.RGBA {
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	xw, yw := boxWeights(sw, w), boxWeights(sh, h)
+
+	// horizontal pass into a w by sh buffer
+	tmp := make([]float32, 4*w*sh)
+	for y := 0; y < sh; y++ {
+		row := src.Pix[src.PixOffset(0, y):]
+		for x, ws := range xw {
+			t := tmp[4*(y*w+x):][:4]
+			for _, wt := range ws {
+				p := row[4*wt.i:][:4]
+				for k := range t {
+					t[k] += wt.w * float32(p[k])
+				}
+			}
+		}
+	}
+
+	// vertical pass
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y, ws := range yw {
+		for x := 0; x < w; x++ {
+			var c [4]float32
+			for _, wt := range ws {
+				t := tmp[4*(wt.i*w+x):][:4]
+				for k := range c {
+					c[k] += wt.w * t[k]
+				}
+			}
+			p := dst.Pix[dst.PixOffset(x, y):][:4]
+			p[3] = clamp8(c[3], 255)
+			for k := 0; k < 3; k++ {
+				// premultiplied colors cannot exceed alpha
+				p[k] = clamp8(c[k], p[3])
+			}
+		}
+	}
+	return dst
+}
+
+// clamp8 rounds v to the nearest integer in [0, hi].
+func clamp8(v float32, hi uint8) uint8 {
+	switch {
+	case v <= 0:
+		return 0
+	case v+0.5 >= float32(hi):
+		return hi
+	}
+	return uint8(v + 0.5)
+}
@@ -0,0 +1,285 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fetch
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func encodePNG(t *testing.T, m image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, m); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// pngChunk returns a PNG chunk of type typ with data.
+func pngChunk(typ, data string) []byte {
+	b := make([]byte, 8, 12+len(data))
+	binary.BigEndian.PutUint32(b, uint32(len(data)))
+	copy(b[4:], typ)
+	b = append(b, data...)
+	crc := make([]byte, 4)
+	binary.BigEndian.PutUint32(crc, crc32.ChecksumIEEE(b[4:]))
+	return append(b, crc...)
+}
+
+// exifSegment returns a JPEG APP1 segment with Exif orientation.
+func exifSegment(orient uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01")
+	tiff = append(tiff, 0x01, 0x12, 0x00, 0x03, 0, 0, 0, 1, byte(orient>>8), byte(orient), 0, 0)
+	tiff = append(tiff, 0, 0, 0, 0)
+	data := append([]byte("Exif\x00\x00"), tiff...)
+	return append([]byte{0xff, 0xe1, byte((len(data) + 2) >> 8), byte(len(data) + 2)}, data...)
+}
+
+func TestStripPNG(t *testing.T) {
+	orig := encodePNG(t, image.NewGray(image.Rect(0, 0, 4, 4)))
+	// insert metadata chunks after IHDR
+	ihdr := 8 + 12 + 13
+	b := append([]byte(nil), orig[:ihdr]...)
+	b = append(b, pngChunk("tEXt", "Author\x00someone")...)
+	b = append(b, pngChunk("eXIf", "MM\x00\x2a")...)
+	b = append(b, orig[ihdr:]...)
+
+	got := stripPNG(b)
+	if !bytes.Equal(got, orig) {
+		t.Errorf("stripPNG() = %q, want %q", got, orig)
+	}
+	if _, err := png.Decode(bytes.NewReader(got)); err != nil {
+		t.Errorf("png.Decode: %v", err)
+	}
+	// malformed data is kept
+	if got := stripPNG(b[:len(b)-3]); len(got) != len(b)-3 {
+		t.Errorf("stripPNG(truncated) len = %d, want %d", len(got), len(b)-3)
+	}
+}
+
+func TestStripJPEG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, 8, 8)), nil); err != nil {
+		t.Fatal(err)
+	}
+	orig := buf.Bytes()
+	b := append([]byte(nil), orig[:2]...)
+	b = append(b, exifSegment(6)...)
+	b = append(b, orig[2:]...)
+
+	got, orient := stripJPEG(b)
+	if orient != 6 {
+		t.Errorf("stripJPEG() orientation = %d, want 6", orient)
+	}
+	if !bytes.Equal(got, orig) {
+		t.Errorf("stripJPEG() did not remove Exif segment")
+	}
+	if _, orient := stripJPEG(orig); orient != 1 {
+		t.Errorf("stripJPEG(no Exif) orientation = %d, want 1", orient)
+	}
+}
+
+func TestOrientImage(t *testing.T) {
+	// 2x1 image: red, green
+	m := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	red, green := color.RGBA{R: 0xff, A: 0xff}, color.RGBA{G: 0xff, A: 0xff}
+	m.Set(0, 0, red)
+	m.Set(1, 0, green)
+
+	tests := []struct {
+		orient int
+		size   image.Point
+		first  color.RGBA // at 0, 0
+	}{
+		{1, image.Pt(2, 1), red},
+		{2, image.Pt(2, 1), green},
+		{6, image.Pt(1, 2), red},
+		{8, image.Pt(1, 2), green},
+	}
+	for _, tc := range tests {
+		got := orientImage(m, tc.orient)
+		if got.Rect.Size() != tc.size {
+			t.Errorf("orientImage(%d) size = %v, want %v", tc.orient, got.Rect.Size(), tc.size)
+			continue
+		}
+		if c := got.RGBAAt(0, 0); c != tc.first {
+			t.Errorf("orientImage(%d) at 0, 0 = %v, want %v", tc.orient, c, tc.first)
+		}
+	}
+}
+
+func TestResizeImage(t *testing.T) {
+	// 4x2 black and white stripes average to gray
+	m := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for x := 0; x < 4; x++ {
+		c := color.RGBA{A: 0xff}
+		if x%2 == 0 {
+			c = color.RGBA{0xff, 0xff, 0xff, 0xff}
+		}
+		m.Set(x, 0, c)
+		m.Set(x, 1, c)
+	}
+	got := resizeImage(m, 2, 1)
+	want := []uint8{0x80, 0x80, 0x80, 0xff, 0x80, 0x80, 0x80, 0xff}
+	if !bytes.Equal(got.Pix, want) {
+		t.Errorf("resizeImage() = %v, want %v", got.Pix, want)
+	}
+	// fractional scale keeps a uniform image intact
+	u := image.NewRGBA(image.Rect(0, 0, 7, 5))
+	for i := range u.Pix {
+		u.Pix[i] = 0x40
+	}
+	for i, v := range resizeImage(u, 3, 2).Pix {
+		if v != 0x40 {
+			t.Fatalf("resizeImage(uniform) Pix[%d] = %#x, want 0x40", i, v)
+		}
+	}
+}
+
+// screenshotImage returns a w by h image of a gradient bar
+// above flat background with text-like marks.
+func screenshotImage(w, h int) *image.NRGBA {
+	m := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.NRGBA{0xfa, 0xfa, 0xfa, 0xff}
+			switch {
+			case y < h/5:
+				c = color.NRGBA{0x1a, uint8(0x73 + x*0x40/w), 0xe8, 0xff}
+			case (y/12)%2 == 0 && (x*7+y*3)%11 < 4:
+				c = color.NRGBA{0x20, 0x21, 0x24, 0xff}
+			}
+			m.SetNRGBA(x, y, c)
+		}
+	}
+	return m
+}
+
+func TestProcessImage(t *testing.T) {
+	screenshot := encodePNG(t, screenshotImage(400, 300))
+	var photo bytes.Buffer
+	if err := jpeg.Encode(&photo, screenshotImage(400, 300), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	type file struct {
+		Name    string
+		Type    string
+		Density float32
+	}
+	tests := []struct {
+		name  string
+		b     []byte
+		ext   string
+		width float32
+		files []file
+		size  image.Point
+	}{
+		{
+			name:  "Downscale",
+			b:     screenshot,
+			width: 150,
+			files: []file{
+				{"abc-150w.png", "", 1},
+				{"abc-300w.png", "", 2},
+				{"abc-150w.webp", "image/webp", 1},
+				{"abc-300w.webp", "image/webp", 2},
+			},
+			size: image.Pt(150, 113),
+		},
+		{
+			name:  "Original2x",
+			b:     screenshot,
+			width: 250,
+			files: []file{
+				{"abc-250w.png", "", 1},
+				{"abc.png", "", 1.6},
+				{"abc-250w.webp", "image/webp", 1},
+				{"abc-400w.webp", "image/webp", 1.6},
+			},
+			size: image.Pt(250, 188),
+		},
+		{
+			name: "Intrinsic",
+			b:    screenshot,
+			files: []file{
+				{"abc.png", "", 1},
+				{"abc-400w.webp", "image/webp", 1},
+			},
+			size: image.Pt(400, 300),
+		},
+		{
+			name:  "JPEG",
+			b:     photo.Bytes(),
+			ext:   ".jpg",
+			width: 150,
+			files: []file{
+				{"abc-150w.jpeg", "", 1},
+				{"abc-300w.jpeg", "", 2},
+				{"abc-150w.webp", "image/webp", 1},
+				{"abc-300w.webp", "image/webp", 2},
+			},
+			size: image.Pt(150, 113),
+		},
+		{
+			name:  "Unknown",
+			b:     []byte("<svg></svg>"),
+			ext:   ".svg",
+			width: 100,
+			files: []file{{"abc.svg", "", 0}},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ext := tc.ext
+			if ext == "" {
+				ext = ".png"
+			}
+			img := processImage(tc.b, "abc", ext, tc.width)
+			var files []file
+			for _, f := range img.files {
+				files = append(files, file{f.name, f.typ, f.density})
+			}
+			if diff := cmp.Diff(tc.files, files); diff != "" {
+				t.Errorf("processImage() files diff (-want +got):\n%s", diff)
+			}
+			if size := image.Pt(img.width, img.height); size != tc.size {
+				t.Errorf("processImage() size = %v, want %v", size, tc.size)
+			}
+		})
+	}
+}
+
+func TestProcessImageTooLarge(t *testing.T) {
+	defer func(max int64) { maxImagePixels = max }(maxImagePixels)
+	maxImagePixels = 400*300 - 1
+	b := encodePNG(t, screenshotImage(400, 300))
+	img := processImage(b, "abc", ".png", 150)
+	if len(img.files) != 1 || img.files[0].name != "abc.png" || !bytes.Equal(img.files[0].data, b) {
+		t.Errorf("processImage() of a too large image = %+v, want the image as is", img.files)
+	}
+	if img.width != 0 || img.height != 0 {
+		t.Errorf("processImage() size = %dx%d, want unknown", img.width, img.height)
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fetch
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/googlecodelabs/tools/claat/nodes"
+	"github.com/googlecodelabs/tools/claat/util"
+)
+
+// Image modes of a Fetcher, see Fetcher.Images.
+const (
+	ImagesSlurp   = "slurp"   // images are stored in the img dir and linked relatively
+	ImagesKeep    = "keep"    // image URLs are kept as is
+	ImagesRewrite = "rewrite" // images are stored in the img dir and linked with Fetcher.ImageURL
+)
+
+// imageURLData is the data of a Fetcher.ImageURL template.
+type imageURLData struct {
+	ID   string // codelab ID
+	Name string // image file name, e.g. "1a2b3c.png"
+	Hash string // file name without extension, the content hash of the image
+	Ext  string // file extension, e.g. ".png"
+}
+
+// checkImageMode returns an error if f.Images is not a valid image mode,
+// or a template is required but invalid. It returns the parsed template.
+func (f *Fetcher) checkImageMode() (*template.Template, error) {
+	switch f.Images {
+	case "", ImagesSlurp, ImagesKeep:
+		return nil, nil
+	case ImagesRewrite:
+		if f.ImageURL == "" {
+			return nil, fmt.Errorf("image mode %q needs an image URL template", f.Images)
+		}
+		t, err := template.New("image_url").Parse(f.ImageURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid image URL template: %v", err)
+		}
+		return t, nil
+	}
+	return nil, fmt.Errorf("unknown image mode %q; want %s, %s or %s", f.Images, ImagesSlurp, ImagesKeep, ImagesRewrite)
+}
+
+// keepImage reports whether the URL of image n is kept as is,
+// instead of slurping the image. Images without a URL, such as those
+// embedded in a Google Doc, are always slurped.
+func (f *Fetcher) keepImage(n *nodes.ImageNode) bool {
+	return (f.Images == ImagesKeep || f.keepLinked) && len(n.Bytes) == 0
+}
+
+// rewriteImages replaces relative URLs of images and pre-rendered diagrams
+// of codelab id in n, which were slurped into images, with URLs made with t.
+func rewriteImages(t *template.Template, id string, n []nodes.Node, images map[string]string) error {
+	rewrite := func(src string) (string, error) {
+		dir, file := filepath.Split(src)
+		if _, ok := images[file]; !ok || filepath.Clean(dir) != util.ImgDirname {
+			return src, nil
+		}
+		ext := filepath.Ext(file)
+		data := &imageURLData{ID: id, Name: file, Hash: strings.TrimSuffix(file, ext), Ext: ext}
+		var b strings.Builder
+		if err := t.Execute(&b, data); err != nil {
+			return "", fmt.Errorf("image URL template: %v", err)
+		}
+		return b.String(), nil
+	}
+	var err error
+	for _, in := range nodes.ImageNodes(n) {
+		if in.Src, err = rewrite(in.Src); err != nil {
+			return err
+		}
+		for _, v := range in.Variants {
+			if v.Src, err = rewrite(v.Src); err != nil {
+				return err
+			}
+		}
+	}
+	for _, dn := range nodes.DiagramNodes(n) {
+		if dn.Img, err = rewrite(dn.Img); err != nil {
+			return err
+		}
+	}
+	return nil
+}
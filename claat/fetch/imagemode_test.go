@@ -0,0 +1,92 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fetch
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/googlecodelabs/tools/claat/nodes"
+)
+
+func TestCheckImageMode(t *testing.T) {
+	tests := []struct {
+		mode, url string
+		ok        bool
+	}{
+		{"", "", true},
+		{ImagesSlurp, "", true},
+		{ImagesKeep, "", true},
+		{ImagesRewrite, "https://cdn/{{.ID}}/{{.Name}}", true},
+		{ImagesRewrite, "", false},
+		{ImagesRewrite, "https://cdn/{{.ID", false},
+		{"copy", "", false},
+	}
+	for _, tc := range tests {
+		f := &Fetcher{Images: tc.mode, ImageURL: tc.url}
+		_, err := f.checkImageMode()
+		if (err == nil) != tc.ok {
+			t.Errorf("checkImageMode(%q, %q) error = %v, want ok: %t", tc.mode, tc.url, err, tc.ok)
+		}
+	}
+}
+
+func TestKeepImage(t *testing.T) {
+	remote := nodes.NewImageNode(nodes.NewImageNodeOptions{Src: "https://example.com/a.png"})
+	embedded := nodes.NewImageNode(nodes.NewImageNodeOptions{Bytes: []byte("\x89PNG")})
+	f := &Fetcher{Images: ImagesKeep}
+	if !f.keepImage(remote) {
+		t.Errorf("keepImage(%q) = false, want true", remote.Src)
+	}
+	if f.keepImage(embedded) {
+		t.Error("keepImage(embedded) = true, want false")
+	}
+	f.Images = ImagesSlurp
+	if f.keepImage(remote) {
+		t.Errorf("slurp mode keepImage(%q) = true, want false", remote.Src)
+	}
+}
+
+func TestRewriteImages(t *testing.T) {
+	img := nodes.NewImageNode(nodes.NewImageNodeOptions{Src: filepath.Join("img", "abc-200w.png")})
+	img.Variants = []*nodes.ImageVariant{{Src: filepath.Join("img", "abc.png"), Density: 2}}
+	kept := nodes.NewImageNode(nodes.NewImageNodeOptions{Src: "https://example.com/img/abc.png"})
+	other := nodes.NewImageNode(nodes.NewImageNodeOptions{Src: filepath.Join("img", "unknown.png")})
+	dn := nodes.NewDiagramNode(nodes.DiagramDot, "a -> b")
+	dn.Img = filepath.Join("img", "def.svg")
+	images := map[string]string{"abc-200w.png": "a.png", "abc.png": "a.png", "def.svg": ""}
+
+	f := &Fetcher{Images: ImagesRewrite, ImageURL: "https://cdn/{{.ID}}/{{.Hash}}{{.Ext}}"}
+	tmpl, err := f.checkImageMode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := []nodes.Node{img, nodes.NewListNode(kept, other), dn}
+	if err := rewriteImages(tmpl, "codelab", in, images); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct{ got, want string }{
+		{img.Src, "https://cdn/codelab/abc-200w.png"},
+		{img.Variants[0].Src, "https://cdn/codelab/abc.png"},
+		{kept.Src, "https://example.com/img/abc.png"},
+		{other.Src, filepath.Join("img", "unknown.png")},
+		{dn.Img, "https://cdn/codelab/def.svg"},
+	}
+	for i, tc := range tests {
+		if tc.got != tc.want {
+			t.Errorf("%d: URL = %q, want %q", i, tc.got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fetch
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/googlecodelabs/tools/claat/outfs"
+	"github.com/googlecodelabs/tools/claat/types"
+	"github.com/googlecodelabs/tools/claat/util"
+)
+
+// recorder is a file system which writes files to out and records them
+// as assets, so that the manifest can be built without reading them back.
+type recorder struct {
+	out outfs.FS
+
+	mu    sync.Mutex
+	files map[string]*types.Asset // written files by name
+}
+
+func newRecorder(out outfs.FS) *recorder {
+	return &recorder{out: out, files: make(map[string]*types.Asset)}
+}
+
+// WriteFile implements outfs.FS.
+func (r *recorder) WriteFile(name string, data []byte) error {
+	if err := r.out.WriteFile(name, data); err != nil {
+		return err
+	}
+	a := types.NewAsset(name, assetType(name, data), "", data)
+	r.mu.Lock()
+	r.files[name] = a
+	r.mu.Unlock()
+	return nil
+}
+
+// manifest returns the asset manifest of files slurped into codelab dir:
+// images and other assets, mapped to their sources.
+// All of the files must have been written to r.
+func (r *recorder) manifest(dir string, images, assets map[string]string) ([]*types.Asset, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	res := []*types.Asset{}
+	for _, d := range []struct {
+		name  string
+		files map[string]string
+	}{
+		{util.ImgDirname, images},
+		{util.AssetDirname, assets},
+	} {
+		for file, src := range d.files {
+			p := path.Join(d.name, file)
+			w, ok := r.files[path.Join(dir, p)]
+			if !ok {
+				return nil, fmt.Errorf("%s: file was not written", p)
+			}
+			// embedded images have no meaningful source
+			if strings.HasPrefix(src, "data:") {
+				src = ""
+			}
+			a := *w
+			a.Path = p
+			a.Source = src
+			res = append(res, &a)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Path < res[j].Path })
+	return res, nil
+}
+
+// assetType returns the MIME type of file name with content b.
+func assetType(name string, b []byte) string {
+	if typ, err := sniffImage(b); err == nil {
+		return typ
+	}
+	if typ := mime.TypeByExtension(filepath.Ext(name)); typ != "" {
+		return typ
+	}
+	return http.DetectContentType(b)
+}
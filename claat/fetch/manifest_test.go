@@ -0,0 +1,64 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fetch
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googlecodelabs/tools/claat/outfs"
+	"github.com/googlecodelabs/tools/claat/types"
+)
+
+func TestManifest(t *testing.T) {
+	out := outfs.NewMemFS()
+	rec := newRecorder(out)
+	files := map[string]string{
+		"img/b.png":         "\x89PNG\r\n\x1a\nb",
+		"img/a.svg":         "<svg></svg>",
+		"assets/1-doc.pdf":  "%PDF-1.4",
+		"assets/2-data.bin": "\x00\x01",
+	}
+	for name, data := range files {
+		if err := rec.WriteFile("lab/"+name, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := out.Names(); len(got) != len(files) {
+		t.Errorf("written files = %v, want %d files", got, len(files))
+	}
+	images := map[string]string{"b.png": "https://example.com/b.png", "a.svg": "data:image/svg+xml,<svg></svg>"}
+	assets := map[string]string{"1-doc.pdf": "doc.pdf", "2-data.bin": "https://example.com/data.bin"}
+
+	got, err := rec.manifest("lab", images, assets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*types.Asset{
+		types.NewAsset("assets/1-doc.pdf", "application/pdf", "doc.pdf", []byte(files["assets/1-doc.pdf"])),
+		types.NewAsset("assets/2-data.bin", "application/octet-stream", "https://example.com/data.bin", []byte(files["assets/2-data.bin"])),
+		types.NewAsset("img/a.svg", "image/svg+xml", "", []byte(files["img/a.svg"])),
+		types.NewAsset("img/b.png", "image/png", "https://example.com/b.png", []byte(files["img/b.png"])),
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("manifest() got diff (-want +got): %s", diff)
+	}
+
+	delete(images, "a.svg")
+	images["missing.png"] = ""
+	if _, err := rec.manifest("lab", images, nil); err == nil {
+		t.Error("manifest(missing file) returned nil error")
+	}
+}
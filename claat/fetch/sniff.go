@@ -0,0 +1,149 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fetch
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// DefaultMaxAssetSize is the default maximum size of a codelab asset
+// in bytes, such as an image or a code snippet.
+const DefaultMaxAssetSize = 32 << 20
+
+// Image MIME types allowed in codelabs.
+const (
+	mimePNG  = "image/png"
+	mimeJPEG = "image/jpeg"
+	mimeGIF  = "image/gif"
+	mimeWebP = "image/webp"
+	mimeAVIF = "image/avif"
+	mimeSVG  = "image/svg+xml"
+)
+
+// imageExts are file extensions of allowed image MIME types.
+var imageExts = map[string]string{
+	mimePNG:  ".png",
+	mimeJPEG: ".jpeg",
+	mimeGIF:  ".gif",
+	mimeWebP: ".webp",
+	mimeAVIF: ".avif",
+	mimeSVG:  ".svg",
+}
+
+// errEmptyImage is returned for empty image data.
+var errEmptyImage = errors.New("empty response instead of an image")
+
+// sniffImage returns the MIME type of image data b, detected from its
+// content. It returns an error if b is not an image of an allowed type,
+// describing what b is instead, e.g. an HTML error page.
+func sniffImage(b []byte) (string, error) {
+	switch {
+	case len(b) == 0:
+		return "", errEmptyImage
+	case bytes.HasPrefix(b, []byte("\x89PNG\r\n\x1a\n")):
+		return mimePNG, nil
+	case bytes.HasPrefix(b, []byte("\xff\xd8\xff")):
+		return mimeJPEG, nil
+	case bytes.HasPrefix(b, []byte("GIF87a")), bytes.HasPrefix(b, []byte("GIF89a")):
+		return mimeGIF, nil
+	case len(b) >= 12 && string(b[:4]) == "RIFF" && string(b[8:12]) == "WEBP":
+		return mimeWebP, nil
+	case isAVIF(b):
+		return mimeAVIF, nil
+	case isSVG(b):
+		return mimeSVG, nil
+	}
+	typ := http.DetectContentType(b)
+	if strings.HasPrefix(typ, "text/html") {
+		if title := htmlTitle(b); title != "" {
+			return "", fmt.Errorf("got an HTML page %q instead of an image", title)
+		}
+		return "", errors.New("got an HTML page instead of an image")
+	}
+	return "", fmt.Errorf("unsupported image type %s", typ)
+}
+
+// imgExtFromBytes returns the file extension of image data b,
+// detected from its content.
+func imgExtFromBytes(b []byte) (string, error) {
+	typ, err := sniffImage(b)
+	if err != nil {
+		return "", err
+	}
+	return imageExts[typ], nil
+}
+
+// isAVIF reports whether b starts with an ISO BMFF file type box
+// with an AVIF major or compatible brand.
+func isAVIF(b []byte) bool {
+	if len(b) < 16 || string(b[4:8]) != "ftyp" {
+		return false
+	}
+	size := int(b[0])<<24 | int(b[1])<<16 | int(b[2])<<8 | int(b[3])
+	if size < 16 || size > len(b) {
+		size = len(b)
+	}
+	// major brand, minor version and compatible brands
+	for i := 8; i+4 <= size; i += 4 {
+		if i == 12 {
+			continue
+		}
+		if brand := string(b[i : i+4]); brand == "avif" || brand == "avis" {
+			return true
+		}
+	}
+	return false
+}
+
+// isSVG reports whether the root element of XML data b is svg.
+func isSVG(b []byte) bool {
+	b = bytes.TrimPrefix(b, []byte("\xef\xbb\xbf"))
+	if !bytes.HasPrefix(bytes.TrimSpace(b), []byte("<")) {
+		return false
+	}
+	d := xml.NewDecoder(bytes.NewReader(b))
+	d.Strict = false
+	for {
+		tok, err := d.RawToken()
+		if err != nil {
+			return false
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			return tok.Name.Local == "svg"
+		case xml.CharData:
+			if len(bytes.TrimSpace(tok)) > 0 {
+				return false
+			}
+		}
+	}
+}
+
+var htmlTitleRegexp = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// htmlTitle returns the title of HTML page b, if any.
+func htmlTitle(b []byte) string {
+	m := htmlTitleRegexp.FindSubmatch(b)
+	if m == nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(string(m[1])), " ")
+}
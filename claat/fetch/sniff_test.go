@@ -0,0 +1,95 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fetch
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSniffImageHTML(t *testing.T) {
+	page := "<!DOCTYPE html>\n<html><head><title>\n  Google Drive:\n Sign-in</title></head></html>"
+	_, err := sniffImage([]byte(page))
+	if err == nil || !strings.Contains(err.Error(), `HTML page "Google Drive: Sign-in" instead of an image`) {
+		t.Errorf("sniffImage(HTML) error = %v", err)
+	}
+}
+
+func TestReadImage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login.png":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><title>Sign in</title></html>"))
+		case "/logo.svg":
+			w.Write([]byte(`<svg onload="x()"><script>x()</script></svg>`))
+		case "/big.png":
+			w.Write([]byte("\x89PNG\r\n\x1a\n" + strings.Repeat("x", 100)))
+		}
+	}))
+	defer srv.Close()
+
+	tmp, err := ioutil.TempDir("", "TestReadImage-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	src := filepath.Join(tmp, "codelab.md")
+	if err := ioutil.WriteFile(filepath.Join(tmp, "photo.jpg"), []byte("\xff\xd8\xff\xe1\x00\x10Exif"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := NewFetcher("", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.MaxAssetSize = 64
+
+	tests := []struct {
+		url  string
+		ext  string
+		data string
+		err  string
+	}{
+		{url: "photo.jpg", ext: ".jpeg"},
+		{url: srv.URL + "/logo.svg", ext: ".svg", data: "<svg></svg>"},
+		{url: srv.URL + "/login.png", err: `got an HTML page "Sign in" instead of an image`},
+		{url: srv.URL + "/big.png", err: "larger than the maximum size of 64 bytes"},
+	}
+	for _, tc := range tests {
+		b, ext, err := f.readImage(src, tc.url, nil)
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("readImage(%q) error = %v, want %q", tc.url, err, tc.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("readImage(%q): %v", tc.url, err)
+			continue
+		}
+		if ext != tc.ext {
+			t.Errorf("readImage(%q) ext = %q, want %q", tc.url, ext, tc.ext)
+		}
+		if tc.data != "" && string(b) != tc.data {
+			t.Errorf("readImage(%q) = %q, want %q", tc.url, b, tc.data)
+		}
+	}
+}
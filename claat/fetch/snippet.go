@@ -0,0 +1,216 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fetch
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/googlecodelabs/tools/claat/nodes"
+)
+
+// snippetLangs maps source file extensions to code language hints.
+var snippetLangs = map[string]string{
+	".c":     "c",
+	".cc":    "cpp",
+	".cpp":   "cpp",
+	".cs":    "csharp",
+	".css":   "css",
+	".dart":  "dart",
+	".go":    "go",
+	".h":     "c",
+	".html":  "html",
+	".java":  "java",
+	".js":    "javascript",
+	".json":  "json",
+	".kt":    "kotlin",
+	".php":   "php",
+	".py":    "python",
+	".rb":    "ruby",
+	".rs":    "rust",
+	".sh":    "bash",
+	".sql":   "sql",
+	".swift": "swift",
+	".ts":    "typescript",
+	".xml":   "xml",
+	".yaml":  "yaml",
+	".yml":   "yaml",
+}
+
+// snippetMarkerRegexp matches region marker lines, e.g. "// [START setup]".
+var snippetMarkerRegexp = regexp.MustCompile(`\[(START|END) ([^\]\s]+)\]`)
+
+// slurpSnippets reads content of all code snippets in nodes.
+// Relative snippet paths are resolved against codelabSrc,
+// or the URL of the imported fragment they are in.
+func (f *Fetcher) slurpSnippets(codelabSrc string, n []nodes.Node) error {
+	type source struct{ src, root string }
+	root := localDir(codelabSrc)
+	srcs := make(map[*nodes.CodeNode]source)
+	for _, imp := range nodes.ImportNodes(n) {
+		for _, cn := range nodes.CodeNodes(imp.Content.Nodes) {
+			srcs[cn] = source{imp.URL, fragmentRoot(imp.URL, root)}
+		}
+	}
+	var errStr string
+	for _, cn := range nodes.CodeNodes(n) {
+		if cn.Src == "" || cn.Value != "" {
+			continue
+		}
+		s, ok := srcs[cn]
+		if !ok {
+			s = source{codelabSrc, root}
+		}
+		if err := f.slurpSnippet(s.src, s.root, cn); err != nil {
+			errStr += fmt.Sprintf("%s: %v\n", cn.Src, err)
+		}
+	}
+	if len(errStr) > 0 {
+		return fmt.Errorf("%s", errStr)
+	}
+	return nil
+}
+
+// slurpSnippet reads source file region referenced by cn.Src into cn.Value.
+// The file is either local, in which case its path must not escape
+// the directory of codelabSrc, remote, or in a git repository,
+// e.g. "git+https://github.com/org/repo.git//app/main.go?ref=v1.0#setup".
+// Relative paths in codelabs read from a git repository refer to files
+// of the same repository. Local git repositories must be within directory
+// root, see gitRepo.
+func (f *Fetcher) slurpSnippet(codelabSrc, root string, cn *nodes.CodeNode) error {
+	ref, region := cn.Src, ""
+	if i := strings.LastIndexByte(ref, '#'); i >= 0 {
+		ref, region = ref[:i], ref[i+1:]
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return err
+	}
+	srcURL, err := url.Parse(codelabSrc)
+	switch {
+	case f.resolve != nil || isGitRef(ref):
+		// read as is
+	case isGitRef(codelabSrc):
+		if u.Scheme == "" && !path.IsAbs(u.Path) {
+			if ref, err = resolveGitRef(codelabSrc, u.Path); err != nil {
+				return err
+			}
+		}
+	case err == nil && srcURL.Host != "":
+		// If the codelab source is being downloaded from the network, then we should interpret
+		// the snippet URL in the same way.
+		u = srcURL.ResolveReference(u)
+	}
+
+	var b []byte
+	switch {
+	case f.resolve != nil:
+		if b, err = f.readResolved(ref); err != nil {
+			return err
+		}
+	case isGitRef(ref):
+		if b, err = f.readGit(ref, root); err != nil {
+			return err
+		}
+	case u.Host == "":
+		p, err := restrictPathToParent(ref, filepath.Dir(codelabSrc))
+		if err != nil {
+			return err
+		}
+		if b, err = f.readLocalBytes(p); err != nil {
+			return err
+		}
+	default:
+		if b, err = f.slurpRemoteBytes(u.String(), 3); err != nil {
+			return err
+		}
+	}
+
+	v, err := snippetRegion(string(b), region)
+	if err != nil {
+		return err
+	}
+	cn.Value = v
+	if cn.Lang == "" && !cn.Term {
+		cn.Lang = snippetLangs[strings.ToLower(path.Ext(u.Path))]
+	}
+	return nil
+}
+
+// snippetRegion returns lines of src between [START region] and [END region]
+// marker lines, with common indentation removed.
+// Lines of other region markers are always omitted.
+// An empty region selects the whole src.
+func snippetRegion(src, region string) (string, error) {
+	var lines []string
+	in := region == ""
+	found := false
+	for _, l := range strings.SplitAfter(src, "\n") {
+		m := snippetMarkerRegexp.FindStringSubmatch(l)
+		if m == nil {
+			if in {
+				lines = append(lines, l)
+			}
+			continue
+		}
+		if m[2] != region {
+			continue
+		}
+		switch m[1] {
+		case "START":
+			in, found = true, true
+		case "END":
+			in = false
+		}
+	}
+	if region != "" && !found {
+		return "", fmt.Errorf("region %q not found", region)
+	}
+	return dedent(lines), nil
+}
+
+// dedent removes the longest common whitespace prefix of non-blank lines.
+func dedent(lines []string) string {
+	var prefix string
+	first := true
+	for _, l := range lines {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		indent := l[:len(l)-len(strings.TrimLeftFunc(l, unicode.IsSpace))]
+		if first {
+			prefix, first = indent, false
+			continue
+		}
+		for !strings.HasPrefix(indent, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	var buf strings.Builder
+	for _, l := range lines {
+		if strings.TrimSpace(l) == "" {
+			buf.WriteString(strings.TrimLeft(l, " \t"))
+			continue
+		}
+		buf.WriteString(strings.TrimPrefix(l, prefix))
+	}
+	return buf.String()
+}
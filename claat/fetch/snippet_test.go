@@ -0,0 +1,231 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package fetch
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/googlecodelabs/tools/claat/nodes"
+	_ "github.com/googlecodelabs/tools/claat/parser/md" // Explicitly register md parser
+)
+
+const snippetSrc = `package main
+
+// [START imports]
+import "fmt"
+// [END imports]
+
+func main() {
+	// [START setup]
+	x := 1
+	if x > 0 {
+		// [START print]
+		fmt.Println(x)
+		// [END print]
+	}
+	// [END setup]
+}
+`
+
+func TestSnippetRegion(t *testing.T) {
+	tests := []struct {
+		region  string
+		want    string
+		wantErr bool
+	}{
+		{"imports", "import \"fmt\"\n", false},
+		{"setup", "x := 1\nif x > 0 {\n\tfmt.Println(x)\n}\n", false},
+		{"print", "fmt.Println(x)\n", false},
+		{"", "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tx := 1\n\tif x > 0 {\n\t\tfmt.Println(x)\n\t}\n}\n", false},
+		{"missing", "", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.region, func(t *testing.T) {
+			v, err := snippetRegion(snippetSrc, tc.region)
+			if err != nil != tc.wantErr {
+				t.Errorf("snippetRegion(%q) error = %v, wantErr %v", tc.region, err, tc.wantErr)
+				return
+			}
+			if v != tc.want {
+				t.Errorf("snippetRegion(%q) = %q; want %q", tc.region, v, tc.want)
+			}
+		})
+	}
+}
+
+func TestSlurpSnippet(t *testing.T) {
+	f, err := NewFetcher("", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		src      string
+		wantLang string
+		wantErr  bool
+	}{
+		{"snippet.go#print", "go", false},
+		{"./snippet.go#print", "go", false},
+		{"../testdata/snippet.go#print", "go", false},
+		{"../snippet.go#print", "", true},
+		{"/etc/passwd", "", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.src, func(t *testing.T) {
+			cn := nodes.NewCodeSnippetNode(tc.src)
+			err := f.slurpSnippet("testdata/codelab.md", "", cn)
+			if err != nil != tc.wantErr {
+				t.Errorf("slurpSnippet(%q) error = %v, wantErr %v", tc.src, err, tc.wantErr)
+				return
+			}
+			if tc.wantErr {
+				return
+			}
+			if cn.Value != "fmt.Println(x)\n" {
+				t.Errorf("slurpSnippet(%q) value = %q", tc.src, cn.Value)
+			}
+			if cn.Lang != tc.wantLang {
+				t.Errorf("slurpSnippet(%q) lang = %q; want %q", tc.src, cn.Lang, tc.wantLang)
+			}
+		})
+	}
+}
+
+func TestSlurpSnippetGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git command is not available")
+	}
+	dir, err := ioutil.TempDir("", "TestSlurpSnippetGit-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	codelab := filepath.Join(dir, "codelab", "codelab.md")
+	repo := filepath.Join(dir, "codelab", "repo")
+	other := filepath.Join(dir, "other")
+	if err := os.MkdirAll(filepath.Join(repo, "app"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(codelab, []byte("# Codelab\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(repo, "app", "main.go"), []byte(snippetSrc), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "init"},
+		{"tag", "v1"},
+	} {
+		if _, err := runGit(repo, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := runGit("", "clone", "--quiet", repo, other); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := NewFetcher("", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := "git+file://" + filepath.ToSlash(repo)
+	for _, src := range []string{
+		base + "//app/main.go#print",
+		base + "//app/main.go?ref=v1#print",
+		"git+repo//app/main.go#print",
+	} {
+		cn := nodes.NewCodeSnippetNode(src)
+		if err := f.slurpSnippet(codelab, localDir(codelab), cn); err != nil {
+			t.Errorf("slurpSnippet(%q): %v", src, err)
+			continue
+		}
+		if cn.Value != "fmt.Println(x)\n" || cn.Lang != "go" {
+			t.Errorf("slurpSnippet(%q) = %q, %q; want the print region in go", src, cn.Value, cn.Lang)
+		}
+	}
+	// relative paths in fragments of a git repository refer to the repository
+	cn := nodes.NewCodeSnippetNode("main.go#print")
+	if err := f.slurpSnippet("git+repo//app/part.md?ref=v1", filepath.Dir(codelab), cn); err != nil {
+		t.Errorf("slurpSnippet(%q) in a git fragment: %v", cn.Src, err)
+	} else if cn.Value != "fmt.Println(x)\n" {
+		t.Errorf("slurpSnippet(%q) in a git fragment = %q; want the print region", cn.Src, cn.Value)
+	}
+	for _, tc := range []struct{ codelab, src string }{
+		{codelab, base + "/app/main.go"},
+		{codelab, base + "//../main.go"},
+		{codelab, base + "//app/missing.go"},
+		// local repositories outside of the codelab dir
+		{codelab, "git+file://" + filepath.ToSlash(other) + "//app/main.go"},
+		{codelab, "git+../other//app/main.go"},
+		{codelab, "git+" + filepath.ToSlash(other) + "//app/main.go"},
+		// local repositories of a codelab which is not a local file
+		{"https://example.com/codelab.md", base + "//app/main.go"},
+		{"1aBcDeF", base + "//app/main.go"},
+		// unsupported schemes
+		{codelab, "git+http://example.com/repo.git//app/main.go"},
+		{codelab, "git+git://example.com/repo.git//app/main.go"},
+	} {
+		if err := f.slurpSnippet(tc.codelab, localDir(tc.codelab), nodes.NewCodeSnippetNode(tc.src)); err == nil {
+			t.Errorf("slurpSnippet(%q, %q) returned nil error", tc.codelab, tc.src)
+		}
+	}
+}
+
+func TestSlurpSnippetsFragment(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestSlurpSnippetsFragment-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// imports are not relative to the codelab
+	frag := filepath.ToSlash(filepath.Join(dir, "frag", "part.md"))
+	files := map[string]string{
+		"codelab.md":   "id: frag\n\n# Fragment\n\n## Step 1\n\n<<snippet main.go#print>>\n\n<<" + frag + ">>\n",
+		"main.go":      "package main\n\n// [START print]\nfmt.Println(\"codelab\")\n// [END print]\n",
+		"frag/part.md": "Fragment.\n\n<<snippet main.go#print>>\n",
+		"frag/main.go": snippetSrc,
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := NewFetcher("", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clab, err := f.SlurpCodelabFS(filepath.Join(dir, "codelab.md"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, cn := range nodes.CodeNodes(clab.Steps[0].Content.Nodes) {
+		got = append(got, cn.Value)
+	}
+	// snippets of the fragment are relative to the fragment
+	want := []string{"fmt.Println(\"codelab\")\n", "fmt.Println(x)\n"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("snippets = %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,332 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fetch
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// XML namespaces allowed in sanitized SVG images.
+const (
+	nsSVG   = "http://www.w3.org/2000/svg"
+	nsXLink = "http://www.w3.org/1999/xlink"
+	nsXML   = "http://www.w3.org/XML/1998/namespace"
+)
+
+// svgElements are elements of the SVG namespace kept by sanitizeSVG.
+// Scripts, foreign objects, and animations which can set arbitrary
+// attributes, such as href, are not allowed.
+var svgElements = setOf(
+	"a", "circle", "clipPath", "defs", "desc", "ellipse", "g", "image",
+	"line", "linearGradient", "marker", "mask", "metadata", "path",
+	"pattern", "polygon", "polyline", "radialGradient", "rect", "stop",
+	"style", "svg", "switch", "symbol", "text", "textPath", "title",
+	"tspan", "use", "view",
+	"animateMotion", "animateTransform", "mpath",
+	"filter", "feBlend", "feColorMatrix", "feComponentTransfer",
+	"feComposite", "feConvolveMatrix", "feDiffuseLighting",
+	"feDisplacementMap", "feDistantLight", "feDropShadow", "feFlood",
+	"feFuncA", "feFuncB", "feFuncG", "feFuncR", "feGaussianBlur",
+	"feImage", "feMerge", "feMergeNode", "feMorphology", "feOffset",
+	"fePointLight", "feSpecularLighting", "feSpotLight", "feTile",
+	"feTurbulence",
+)
+
+// svgAttrs are attributes without a namespace kept by sanitizeSVG.
+// URL attributes are in svgURLAttrs.
+var svgAttrs = setOf(
+	// core and styling
+	"class", "id", "lang", "style", "tabindex", "transform", "version",
+	"baseProfile", "viewBox", "preserveAspectRatio", "systemLanguage",
+	"requiredExtensions", "target",
+	// geometry
+	"cx", "cy", "d", "dx", "dy", "fr", "fx", "fy", "height", "pathLength",
+	"points", "r", "rx", "ry", "width", "x", "x1", "x2", "y", "y1", "y2",
+	// presentation
+	"alignment-baseline", "baseline-shift", "clip", "clip-path", "clip-rule",
+	"color", "color-interpolation", "color-interpolation-filters",
+	"color-rendering", "direction", "display", "dominant-baseline", "fill",
+	"fill-opacity", "fill-rule", "filter", "flood-color", "flood-opacity",
+	"font-family", "font-size", "font-size-adjust", "font-stretch",
+	"font-style", "font-variant", "font-weight", "image-rendering",
+	"isolation", "letter-spacing", "lighting-color", "marker-end",
+	"marker-mid", "marker-start", "mask", "mix-blend-mode", "opacity",
+	"overflow", "paint-order", "pointer-events", "shape-rendering",
+	"stop-color", "stop-opacity", "stroke", "stroke-dasharray",
+	"stroke-dashoffset", "stroke-linecap", "stroke-linejoin",
+	"stroke-miterlimit", "stroke-opacity", "stroke-width", "text-anchor",
+	"text-decoration", "text-rendering", "unicode-bidi", "vector-effect",
+	"visibility", "word-spacing", "writing-mode",
+	// gradients, patterns, markers, masks and clipping
+	"gradientTransform", "gradientUnits", "spreadMethod", "offset",
+	"patternContentUnits", "patternTransform", "patternUnits",
+	"markerHeight", "markerUnits", "markerWidth", "orient", "refX", "refY",
+	"maskContentUnits", "maskUnits", "clipPathUnits",
+	// text
+	"lengthAdjust", "method", "rotate", "spacing", "startOffset", "textLength",
+	// filters
+	"amplitude", "azimuth", "baseFrequency", "bias", "diffuseConstant",
+	"divisor", "edgeMode", "elevation", "exponent", "filterUnits", "in",
+	"in2", "intercept", "k", "k1", "k2", "k3", "k4", "kernelMatrix",
+	"kernelUnitLength", "limitingConeAngle", "mode", "numOctaves",
+	"operator", "order", "pointsAtX", "pointsAtY", "pointsAtZ",
+	"preserveAlpha", "primitiveUnits", "radius", "result", "scale", "seed",
+	"slope", "specularConstant", "specularExponent", "stdDeviation",
+	"stitchTiles", "surfaceScale", "tableValues", "targetX", "targetY",
+	"type", "values", "xChannelSelector", "yChannelSelector", "z",
+	// transform and motion animations
+	"accumulate", "additive", "attributeName", "attributeType", "begin",
+	"by", "calcMode", "dur", "end", "from", "keyPoints", "keySplines",
+	"keyTimes", "max", "min", "path", "repeatCount", "repeatDur", "restart",
+	"to",
+)
+
+// svgURLAttrs are URL attributes, without a namespace or in the XLink
+// namespace, kept by sanitizeSVG if their value is a safe URL.
+var svgURLAttrs = setOf("href")
+
+// svgXLinkAttrs are attributes of the XLink namespace kept by sanitizeSVG,
+// besides svgURLAttrs.
+var svgXLinkAttrs = setOf("title")
+
+// svgXMLAttrs are attributes of the XML namespace kept by sanitizeSVG.
+var svgXMLAttrs = setOf("lang", "space")
+
+// svgDataImages are MIME types of data URLs allowed in SVG images.
+var svgDataImages = []string{mimePNG, mimeJPEG, mimeGIF, mimeWebP}
+
+func setOf(names ...string) map[string]bool {
+	m := make(map[string]bool, len(names))
+	for _, n := range names {
+		m[n] = true
+	}
+	return m
+}
+
+// svgElement is an open element in sanitizeSVG.
+type svgElement struct {
+	name xml.Name          // name as written in the source
+	ns   map[string]string // namespaces in scope, by prefix
+	skip bool              // whether the element is removed
+}
+
+// sanitizeSVG makes SVG data b safe to serve as a codelab asset,
+// so that it runs no script even if opened directly from the codelab
+// origin. Only allowed SVG elements and attributes are kept, see svgElements
+// and svgAttrs, along with their text. Elements outside the SVG namespace
+// are removed with their content, and so are links other than fragments,
+// relative or https URLs, and data URLs of raster images.
+// Doctype declarations and processing instructions, such as stylesheets,
+// are removed too.
+// The rest of the document is kept byte for byte.
+func sanitizeSVG(b []byte) ([]byte, error) {
+	var out bytes.Buffer
+	d := xml.NewDecoder(bytes.NewReader(b))
+	var (
+		last  int64 // input offset of the last copied token
+		stack []*svgElement
+		root  bool // whether the root element was seen
+	)
+	for {
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid SVG: %v", err)
+		}
+		raw := b[last:d.InputOffset()]
+		last = d.InputOffset()
+		var parent *svgElement
+		if len(stack) > 0 {
+			parent = stack[len(stack)-1]
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			if parent == nil && root {
+				return nil, errors.New("invalid SVG: multiple root elements")
+			}
+			el := &svgElement{name: tok.Name, ns: svgScope(parent, tok.Attr)}
+			stack = append(stack, el)
+			if parent == nil {
+				root = true
+			}
+			if el.skip = parent != nil && parent.skip || !el.allowed(); el.skip {
+				if parent == nil {
+					return nil, fmt.Errorf("invalid SVG: root element <%s> is not svg", xmlName(tok.Name))
+				}
+				continue
+			}
+			attrs := el.safeAttrs(tok.Attr)
+			if len(attrs) == len(tok.Attr) {
+				out.Write(raw)
+				continue
+			}
+			out.WriteString("<" + xmlName(tok.Name))
+			for _, a := range attrs {
+				out.WriteString(" " + xmlName(a.Name) + `="`)
+				xml.EscapeText(&out, []byte(a.Value))
+				out.WriteString(`"`)
+			}
+			if bytes.HasSuffix(raw, []byte("/>")) {
+				out.WriteString("/>")
+			} else {
+				out.WriteString(">")
+			}
+		case xml.EndElement:
+			if parent == nil || parent.name != tok.Name {
+				return nil, fmt.Errorf("invalid SVG: unexpected end element </%s>", xmlName(tok.Name))
+			}
+			stack = stack[:len(stack)-1]
+			if !parent.skip {
+				out.Write(raw)
+			}
+		case xml.ProcInst:
+			// only the XML declaration is kept
+			if tok.Target == "xml" && parent == nil && !root {
+				out.Write(raw)
+			}
+		case xml.Directive:
+			// doctype declarations can define entities expanding to markup
+		default:
+			if parent == nil || !parent.skip {
+				out.Write(raw)
+			}
+		}
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("invalid SVG: unclosed element <%s>", xmlName(stack[len(stack)-1].name))
+	}
+	if !root {
+		return nil, errors.New("invalid SVG: no root element")
+	}
+	return out.Bytes(), nil
+}
+
+// svgScope returns namespaces in scope of an element with attrs,
+// a child of parent.
+func svgScope(parent *svgElement, attrs []xml.Attr) map[string]string {
+	ns := map[string]string{"xml": nsXML}
+	if parent != nil {
+		ns = parent.ns
+	}
+	copied := false
+	for _, a := range attrs {
+		prefix, ok := nsDecl(a.Name)
+		if !ok {
+			continue
+		}
+		if !copied {
+			m := make(map[string]string, len(ns)+1)
+			for k, v := range ns {
+				m[k] = v
+			}
+			ns, copied = m, true
+		}
+		ns[prefix] = a.Value
+	}
+	return ns
+}
+
+// nsDecl returns the prefix declared by attribute n, and whether n
+// is a namespace declaration. The default namespace has an empty prefix.
+func nsDecl(n xml.Name) (string, bool) {
+	switch {
+	case n.Space == "" && n.Local == "xmlns":
+		return "", true
+	case n.Space == "xmlns":
+		return n.Local, true
+	}
+	return "", false
+}
+
+// allowed reports whether el is an allowed SVG element.
+// Elements without a namespace are considered SVG elements.
+func (el *svgElement) allowed() bool {
+	ns, ok := el.ns[el.name.Space]
+	if !ok && el.name.Space != "" {
+		// undeclared prefix
+		return false
+	}
+	return (ns == "" || ns == nsSVG) && svgElements[el.name.Local]
+}
+
+// safeAttrs returns allowed attributes of el among attrs.
+func (el *svgElement) safeAttrs(attrs []xml.Attr) []xml.Attr {
+	safe := make([]xml.Attr, 0, len(attrs))
+	for _, a := range attrs {
+		if el.safeAttr(a) {
+			safe = append(safe, a)
+		}
+	}
+	return safe
+}
+
+func (el *svgElement) safeAttr(a xml.Attr) bool {
+	if prefix, ok := nsDecl(a.Name); ok {
+		return a.Value == nsSVG || prefix != "" && a.Value == nsXLink
+	}
+	if a.Name.Space == "" {
+		return svgAttrs[a.Name.Local] || svgURLAttrs[a.Name.Local] && safeSVGURL(a.Value)
+	}
+	switch el.ns[a.Name.Space] {
+	case nsXLink:
+		return svgXLinkAttrs[a.Name.Local] || svgURLAttrs[a.Name.Local] && safeSVGURL(a.Value)
+	case nsXML:
+		return svgXMLAttrs[a.Name.Local]
+	}
+	return false
+}
+
+// safeSVGURL reports whether v is a link allowed in SVG images:
+// a fragment, a relative or https URL, or a data URL of a raster image.
+func safeSVGURL(v string) bool {
+	v = strings.TrimSpace(v)
+	if strings.HasPrefix(v, "#") {
+		return true
+	}
+	u, err := url.Parse(v)
+	if err != nil {
+		return false
+	}
+	switch u.Scheme {
+	case "":
+		return u.Host == "" && u.Opaque == ""
+	case "https":
+		return true
+	case "data":
+		typ := strings.ToLower(u.Opaque)
+		for _, t := range svgDataImages {
+			if strings.HasPrefix(typ, t+";") || strings.HasPrefix(typ, t+",") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// xmlName returns n as written in the source, with its prefix if any.
+func xmlName(n xml.Name) string {
+	if n.Space == "" {
+		return n.Local
+	}
+	return n.Space + ":" + n.Local
+}
@@ -0,0 +1,90 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fetch
+
+import "testing"
+
+func TestSanitizeSVG(t *testing.T) {
+	tests := []struct {
+		name, in, out string
+	}{
+		{
+			name: "Unchanged",
+			in:   `<?xml version="1.0"?>` + "\n" + `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"><a xlink:href="#x"><circle r='1'/></a></svg>`,
+			out:  `<?xml version="1.0"?>` + "\n" + `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"><a xlink:href="#x"><circle r='1'/></a></svg>`,
+		},
+		{
+			name: "Script",
+			in:   `<svg><script type="text/javascript"><![CDATA[alert(1)]]></script><g><SCRIPT>alert(2)</SCRIPT></g><rect/></svg>`,
+			out:  `<svg><g></g><rect/></svg>`,
+		},
+		{
+			name: "ForeignObject",
+			in:   `<svg><foreignObject><body xmlns="http://www.w3.org/1999/xhtml"><iframe src="x"/></body></foreignObject></svg>`,
+			out:  `<svg></svg>`,
+		},
+		{
+			name: "Attributes",
+			in:   `<svg onload="alert(1)"><a xlink:href=" java script:alert(1)" fill="red"><rect onclick='x()' width="1"/></a><stop offset="0"/></svg>`,
+			out:  `<svg><a fill="red"><rect width="1"/></a><stop offset="0"/></svg>`,
+		},
+		{
+			name: "ForeignNamespace",
+			in:   `<svg><x:iframe xmlns:x="http://www.w3.org/1999/xhtml" srcdoc="&lt;script&gt;alert(document.domain)&lt;/script&gt;"/><x:embed xmlns:x="http://www.w3.org/1999/xhtml" src="data:text/html;base64,PHNjcmlwdD4="/><y:rect/><g xmlns="http://www.w3.org/1999/xhtml"><rect/></g></svg>`,
+			out:  `<svg></svg>`,
+		},
+		{
+			name: "UnknownElements",
+			in:   `<svg><set attributeName="href" to="javascript:alert(1)"/><animate attributeName="href"/><handler>alert(1)</handler><circle/></svg>`,
+			out:  `<svg><circle/></svg>`,
+		},
+		{
+			name: "Links",
+			in:   `<svg xmlns:xlink="http://www.w3.org/1999/xlink"><a href="javascript:alert(1)"/><a href="img/a.png"/><a href="https://example.com/"/><a href="//example.com/"/><use xlink:href="data:image/svg+xml,&lt;svg/&gt;"/><image href="data:image/png;base64,iVBORw0="/><a xlink:href="http://example.com/"/></svg>`,
+			out:  `<svg xmlns:xlink="http://www.w3.org/1999/xlink"><a/><a href="img/a.png"/><a href="https://example.com/"/><a/><use/><image href="data:image/png;base64,iVBORw0="/><a/></svg>`,
+		},
+		{
+			name: "NamespaceDeclarations",
+			in:   `<svg xmlns="http://www.w3.org/2000/svg" xmlns:h="http://www.w3.org/1999/xhtml" xml:space="preserve"><g/></svg>`,
+			out:  `<svg xmlns="http://www.w3.org/2000/svg" xml:space="preserve"><g/></svg>`,
+		},
+		{
+			name: "Stylesheet",
+			in:   `<?xml version="1.0"?><?xml-stylesheet type="text/xsl" href="x.xsl"?><!DOCTYPE svg><svg/>`,
+			out:  `<?xml version="1.0"?><svg/>`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := sanitizeSVG([]byte(tc.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(out) != tc.out {
+				t.Errorf("sanitizeSVG(%q) =\n%s\nwant:\n%s", tc.in, out, tc.out)
+			}
+		})
+	}
+	for _, in := range []string{
+		`<svg><g></svg>`,
+		`<html><svg/></html>`,
+		`<svg xmlns="http://www.w3.org/1999/xhtml"/>`,
+		`<!DOCTYPE svg [<!ENTITY x "&#60;script&#62;alert(1)&#60;/script&#62;">]><svg>&x;</svg>`,
+	} {
+		if _, err := sanitizeSVG([]byte(in)); err == nil {
+			t.Errorf("sanitizeSVG(%q) returned nil error", in)
+		}
+	}
+}
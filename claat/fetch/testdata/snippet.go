@@ -0,0 +1,16 @@
+package main
+
+// [START imports]
+import "fmt"
+// [END imports]
+
+func main() {
+	// [START setup]
+	x := 1
+	if x > 0 {
+		// [START print]
+		fmt.Println(x)
+		// [END print]
+	}
+	// [END setup]
+}
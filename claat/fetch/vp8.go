@@ -0,0 +1,1236 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fetch
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"io"
+	"math"
+)
+
+// This file implements a lossy WebP (VP8) encoder, as specified in
+// RFC 6386, for photos.
+//
+// Like the lossless encoder, it is deliberately simple: each macroblock
+// is predicted as a whole with one of the 16x16 luma and 8x8 chroma modes,
+// without the 4x4 luma modes, segments or rate-distortion optimization,
+// and all coefficients are coded in a single partition.
+// Token probabilities are adapted to the image, which makes up for
+// some of that.
+
+const (
+	vp8MaxPartition = 1<<19 - 1 // maximum size of the first partition
+
+	// token probability planes
+	vp8PlaneYAC = 0 // luma blocks whose DC is coded in the Y2 block
+	vp8PlaneY2  = 1
+	vp8PlaneUV  = 2
+	vp8Planes   = 4
+
+	vp8Bands    = 8
+	vp8Contexts = 3
+	vp8Nodes    = 11 // probabilities of the coefficient token tree
+)
+
+// prediction modes of 16x16 luma and 8x8 chroma blocks
+const (
+	vp8PredDC = iota
+	vp8PredVE
+	vp8PredHE
+	vp8PredTM
+	vp8PredModes
+)
+
+// vp8TokenProbs are coefficient token probabilities
+// for each plane, band, context and node of the token tree.
+type vp8TokenProbs [vp8Planes][vp8Bands][vp8Contexts][vp8Nodes]uint8
+
+// vp8Block is a block of quantized coefficients to be coded.
+type vp8Block struct {
+	plane  uint8
+	ctx    uint8 // number of blocks above and to the left with coefficients
+	first  uint8 // index of the first coded coefficient
+	levels [16]int16
+}
+
+// vp8Encoder holds the state of a VP8 key frame being encoded.
+type vp8Encoder struct {
+	mbw, mbh int
+	src, rec [3][]uint8 // source and reconstructed Y, U and V planes
+
+	qi         int      // quantizer index
+	y1, y2, uv [2]int32 // DC and AC quantizer step sizes of each block type
+
+	modes  [][2]uint8 // luma and chroma mode of each macroblock
+	skip   []bool     // whether each macroblock has no coefficients
+	blocks []vp8Block // coded blocks in bitstream order
+	topNZ  [][9]uint8 // whether bottom blocks of macroblocks above have coefficients
+	leftNZ [9]uint8   // whether right blocks of the macroblock to the left have coefficients
+}
+
+// encodeLossyWebP writes m to w as a lossy WebP image
+// with the given quality from 1 to 100.
+// m is assumed to be opaque; its alpha channel is dropped.
+func encodeLossyWebP(w io.Writer, m image.Image, quality int) error {
+	b := m.Bounds()
+	width, height := b.Dx(), b.Dy()
+	if width < 1 || height < 1 || width > webpMaxSize || height > webpMaxSize {
+		return errors.New("webp: invalid image size")
+	}
+	e := &vp8Encoder{
+		mbw: (width + 15) / 16,
+		mbh: (height + 15) / 16,
+	}
+	e.src = vp8YUV(m, e.mbw, e.mbh)
+	for i, p := range e.src {
+		e.rec[i] = make([]uint8, len(p))
+	}
+	e.setQuality(quality)
+	e.modes = make([][2]uint8, e.mbw*e.mbh)
+	e.skip = make([]bool, e.mbw*e.mbh)
+	e.topNZ = make([][9]uint8, e.mbw)
+	for mby := 0; mby < e.mbh; mby++ {
+		e.leftNZ = [9]uint8{}
+		for mbx := 0; mbx < e.mbw; mbx++ {
+			e.encodeMacroblock(mbx, mby)
+		}
+	}
+
+	// adapt token probabilities to the coded blocks
+	var stats vp8TokenStats
+	t := &vp8TokenWriter{stats: &stats}
+	for i := range e.blocks {
+		t.writeBlock(&e.blocks[i])
+	}
+	probs, update := stats.probs()
+	t = &vp8TokenWriter{enc: &boolEncoder{rng: 255, bitCount: 24}, probs: &probs}
+	for i := range e.blocks {
+		t.writeBlock(&e.blocks[i])
+	}
+	tokens := t.enc.flush()
+	first := e.firstPartition(&probs, &update)
+	if len(first) > vp8MaxPartition {
+		return errors.New("webp: image too large")
+	}
+
+	size := 10 + len(first) + len(tokens)
+	pad := size & 1
+	hdr := make([]byte, 30)
+	copy(hdr[0:], "RIFF")
+	binary.LittleEndian.PutUint32(hdr[4:], uint32(12+size+pad))
+	copy(hdr[8:], "WEBPVP8 ")
+	binary.LittleEndian.PutUint32(hdr[16:], uint32(size))
+	// frame tag of a shown key frame, followed by the start code
+	tag := uint32(len(first))<<5 | 1<<4
+	hdr[20], hdr[21], hdr[22] = byte(tag), byte(tag>>8), byte(tag>>16)
+	copy(hdr[23:], "\x9d\x01\x2a")
+	binary.LittleEndian.PutUint16(hdr[26:], uint16(width))
+	binary.LittleEndian.PutUint16(hdr[28:], uint16(height))
+	for _, d := range [][]byte{hdr, first, tokens, make([]byte, pad)} {
+		if _, err := w.Write(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// vp8YUV converts m to Y, U and V planes of mbw by mbh macroblocks,
+// with BT.601 limited range colors, as WebP decoders expect.
+// Chroma is subsampled by 2 in both directions, and the image is padded
+// to whole macroblocks by repeating its edges.
+func vp8YUV(m image.Image, mbw, mbh int) [3][]uint8 {
+	b := m.Bounds()
+	rgba, ok := m.(*image.RGBA)
+	if !ok {
+		rgba = image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+		draw.Draw(rgba, rgba.Rect, m, b.Min, draw.Src)
+	}
+	r := rgba.Rect
+	pixel := func(x, y int) (int32, int32, int32) {
+		if x >= r.Dx() {
+			x = r.Dx() - 1
+		}
+		if y >= r.Dy() {
+			y = r.Dy() - 1
+		}
+		p := rgba.Pix[rgba.PixOffset(r.Min.X+x, r.Min.Y+y):]
+		return int32(p[0]), int32(p[1]), int32(p[2])
+	}
+	w, cw := 16*mbw, 8*mbw
+	planes := [3][]uint8{
+		make([]uint8, w*16*mbh),
+		make([]uint8, cw*8*mbh),
+		make([]uint8, cw*8*mbh),
+	}
+	for y := 0; y < 16*mbh; y++ {
+		for x := 0; x < w; x++ {
+			r, g, b := pixel(x, y)
+			planes[0][y*w+x] = uint8((16839*r + 33059*g + 6420*b + 16<<16 + 1<<15) >> 16)
+		}
+	}
+	for y := 0; y < 8*mbh; y++ {
+		for x := 0; x < cw; x++ {
+			var r, g, b int32
+			for _, d := range [4][2]int{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+				pr, pg, pb := pixel(2*x+d[0], 2*y+d[1])
+				r, g, b = r+pr, g+pg, b+pb
+			}
+			planes[1][y*cw+x] = clip8((-9719*r - 19081*g + 28800*b + 128<<18 + 1<<17) >> 18)
+			planes[2][y*cw+x] = clip8((28800*r - 24116*g - 4684*b + 128<<18 + 1<<17) >> 18)
+		}
+	}
+	return planes
+}
+
+// setQuality sets the quantizer index and step sizes for quality q.
+func (e *vp8Encoder) setQuality(q int) {
+	switch {
+	case q < 1:
+		q = 1
+	case q > 100:
+		q = 100
+	}
+	e.qi = (100 - q) * 127 / 99
+	dc, ac := int32(vp8DCSteps[e.qi]), int32(vp8ACSteps[e.qi])
+	e.y1 = [2]int32{dc, ac}
+	e.y2 = [2]int32{2 * dc, ac * 155 / 100}
+	if e.y2[1] < 8 {
+		e.y2[1] = 8
+	}
+	uvdc := e.qi
+	if uvdc > 117 {
+		uvdc = 117
+	}
+	e.uv = [2]int32{int32(vp8DCSteps[uvdc]), ac}
+}
+
+// filterLevel returns the loop filter level for the quantizer,
+// which grows with the AC step size.
+func (e *vp8Encoder) filterLevel() int {
+	l := int(vp8ACSteps[e.qi]) / 4
+	if l > 63 {
+		l = 63
+	}
+	return l
+}
+
+// encodeMacroblock predicts, transforms and quantizes the macroblock
+// at mbx, mby, and reconstructs it as decoders will.
+func (e *vp8Encoder) encodeMacroblock(mbx, mby int) {
+	var blocks [25]vp8Block
+	var nz [25]uint8
+	top := &e.topNZ[mbx]
+
+	// luma, with the DC coefficients of all blocks coded in the Y2 block
+	ymode := e.bestMode(0, 16, mbx, mby)
+	var pred [256]uint8
+	e.predict(pred[:], 0, 16, mbx, mby, ymode)
+	var coeffs [16][16]int32
+	var dc [16]int32
+	for i := range coeffs {
+		e.residual(&coeffs[i], pred[:], 0, 16, mbx, mby, i)
+		vp8FDCT(&coeffs[i])
+		dc[i] = coeffs[i][0]
+	}
+	vp8FWHT(&dc)
+	blocks[0] = vp8Block{plane: vp8PlaneY2, ctx: top[8] + e.leftNZ[8]}
+	nz[0] = vp8Quantize(&dc, e.y2, &blocks[0])
+	top[8], e.leftNZ[8] = nz[0], nz[0]
+	vp8IWHT(&dc)
+	for i := range coeffs {
+		bx, by := i%4, i/4
+		blk := &blocks[1+i]
+		*blk = vp8Block{plane: vp8PlaneYAC, ctx: top[bx] + e.leftNZ[by], first: 1}
+		nz[1+i] = vp8Quantize(&coeffs[i], e.y1, blk)
+		top[bx], e.leftNZ[by] = nz[1+i], nz[1+i]
+		coeffs[i][0] = dc[i]
+		e.reconstruct(&coeffs[i], pred[:], 0, 16, mbx, mby, i)
+	}
+
+	// chroma
+	cmode := e.bestMode(1, 8, mbx, mby)
+	for p := 1; p <= 2; p++ {
+		e.predict(pred[:64], p, 8, mbx, mby, cmode)
+		for i := 0; i < 4; i++ {
+			var c [16]int32
+			e.residual(&c, pred[:64], p, 8, mbx, mby, i)
+			vp8FDCT(&c)
+			bx, by := 2+2*p+i%2, 2+2*p+i/2
+			n := 17 + 4*(p-1) + i
+			blocks[n] = vp8Block{plane: vp8PlaneUV, ctx: top[bx] + e.leftNZ[by]}
+			nz[n] = vp8Quantize(&c, e.uv, &blocks[n])
+			top[bx], e.leftNZ[by] = nz[n], nz[n]
+			e.reconstruct(&c, pred[:64], p, 8, mbx, mby, i)
+		}
+	}
+
+	mb := mby*e.mbw + mbx
+	e.modes[mb] = [2]uint8{ymode, cmode}
+	e.skip[mb] = nz == [25]uint8{}
+	if !e.skip[mb] {
+		e.blocks = append(e.blocks, blocks[:]...)
+	}
+}
+
+// stride returns the row length of plane p.
+func (e *vp8Encoder) stride(p int) int {
+	if p == 0 {
+		return 16 * e.mbw
+	}
+	return 8 * e.mbw
+}
+
+// bestMode returns the prediction mode of the size by size block
+// of macroblock mbx, mby in plane p, and in the V plane for chroma,
+// with the smallest squared error.
+func (e *vp8Encoder) bestMode(p, size, mbx, mby int) uint8 {
+	planes := []int{p}
+	if p == 1 {
+		planes = append(planes, 2)
+	}
+	var best uint8
+	bestErr := int64(math.MaxInt64)
+	pred := make([]uint8, size*size)
+	for mode := uint8(0); mode < vp8PredModes; mode++ {
+		var sse int64
+		for _, p := range planes {
+			e.predict(pred, p, size, mbx, mby, mode)
+			src, stride := e.src[p], e.stride(p)
+			for y := 0; y < size; y++ {
+				row := src[(mby*size+y)*stride+mbx*size:]
+				for x := 0; x < size; x++ {
+					d := int64(row[x]) - int64(pred[y*size+x])
+					sse += d * d
+				}
+			}
+		}
+		if sse < bestErr {
+			best, bestErr = mode, sse
+		}
+	}
+	return best
+}
+
+// predict writes the prediction of the size by size block of macroblock
+// mbx, mby in plane p to dst. Decoders assume rows above the image are 127,
+// and columns to its left are 129.
+func (e *vp8Encoder) predict(dst []uint8, p, size, mbx, mby int, mode uint8) {
+	rec, stride := e.rec[p], e.stride(p)
+	x0, y0 := mbx*size, mby*size
+	var above, left [16]int32
+	corner := int32(127)
+	for i := 0; i < size; i++ {
+		above[i], left[i] = 127, 129
+		if mby > 0 {
+			above[i] = int32(rec[(y0-1)*stride+x0+i])
+		}
+		if mbx > 0 {
+			left[i] = int32(rec[(y0+i)*stride+x0-1])
+		}
+	}
+	switch {
+	case mby > 0 && mbx > 0:
+		corner = int32(rec[(y0-1)*stride+x0-1])
+	case mby > 0:
+		corner = 129
+	}
+
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			var v int32
+			switch mode {
+			case vp8PredVE:
+				v = above[x]
+			case vp8PredHE:
+				v = left[y]
+			case vp8PredTM:
+				v = left[y] + above[x] - corner
+			}
+			dst[y*size+x] = clip8(v)
+		}
+	}
+	if mode != vp8PredDC {
+		return
+	}
+	// DC prediction averages the edges within the image
+	sum, n := int32(0), 0
+	if mby > 0 {
+		for i := 0; i < size; i++ {
+			sum += above[i]
+		}
+		n += size
+	}
+	if mbx > 0 {
+		for i := 0; i < size; i++ {
+			sum += left[i]
+		}
+		n += size
+	}
+	avg := uint8(128)
+	if n > 0 {
+		avg = uint8((sum + int32(n/2)) / int32(n))
+	}
+	for i := range dst[:size*size] {
+		dst[i] = avg
+	}
+}
+
+// residual writes the differences of 4x4 block i of macroblock mbx, mby
+// in plane p from its prediction pred, of size by size pixels, to c.
+func (e *vp8Encoder) residual(c *[16]int32, pred []uint8, p, size, mbx, mby, i int) {
+	src, stride := e.src[p], e.stride(p)
+	bx, by := i%(size/4)*4, i/(size/4)*4
+	for y := 0; y < 4; y++ {
+		row := src[(mby*size+by+y)*stride+mbx*size+bx:]
+		for x := 0; x < 4; x++ {
+			c[4*y+x] = int32(row[x]) - int32(pred[(by+y)*size+bx+x])
+		}
+	}
+}
+
+// reconstruct adds the inverse transform of dequantized coefficients c
+// to the prediction of 4x4 block i, and stores the result in the
+// reconstructed plane p, exactly as decoders do.
+func (e *vp8Encoder) reconstruct(c *[16]int32, pred []uint8, p, size, mbx, mby, i int) {
+	const (
+		c1 = 85627 // 65536 * cos(pi/8) * sqrt(2)
+		c2 = 35468 // 65536 * sin(pi/8) * sqrt(2)
+	)
+	var m [4][4]int32
+	for i := 0; i < 4; i++ {
+		a := c[i] + c[8+i]
+		b := c[i] - c[8+i]
+		cc := (c[4+i]*c2)>>16 - (c[12+i]*c1)>>16
+		d := (c[4+i]*c1)>>16 + (c[12+i]*c2)>>16
+		m[i] = [4]int32{a + d, b + cc, b - cc, a - d}
+	}
+	rec, stride := e.rec[p], e.stride(p)
+	bx, by := i%(size/4)*4, i/(size/4)*4
+	for y := 0; y < 4; y++ {
+		dc := m[0][y] + 4
+		a := dc + m[2][y]
+		b := dc - m[2][y]
+		cc := (m[1][y]*c2)>>16 - (m[3][y]*c1)>>16
+		d := (m[1][y]*c1)>>16 + (m[3][y]*c2)>>16
+		row := rec[(mby*size+by+y)*stride+mbx*size+bx:]
+		pr := pred[(by+y)*size+bx:]
+		for x, v := range [4]int32{a + d, b + cc, b - cc, a - d} {
+			row[x] = clip8(int32(pr[x]) + v>>3)
+		}
+	}
+}
+
+// vp8FDCT transforms the residuals of a 4x4 block in place,
+// as the reference encoder does.
+func vp8FDCT(c *[16]int32) {
+	for i := 0; i < 16; i += 4 {
+		a := (c[i] + c[i+3]) * 8
+		b := (c[i+1] + c[i+2]) * 8
+		cc := (c[i+1] - c[i+2]) * 8
+		d := (c[i] - c[i+3]) * 8
+		c[i], c[i+2] = a+b, a-b
+		c[i+1] = (cc*2217 + d*5352 + 14500) >> 12
+		c[i+3] = (d*2217 - cc*5352 + 7500) >> 12
+	}
+	for i := 0; i < 4; i++ {
+		a := c[i] + c[12+i]
+		b := c[4+i] + c[8+i]
+		cc := c[4+i] - c[8+i]
+		d := c[i] - c[12+i]
+		c[i] = (a + b + 7) >> 4
+		c[8+i] = (a - b + 7) >> 4
+		c[4+i] = (cc*2217+d*5352+12000)>>16 + int32(btoi(d != 0))
+		c[12+i] = (d*2217 - cc*5352 + 51000) >> 16
+	}
+}
+
+// vp8FWHT transforms the DC coefficients of the 16 luma blocks
+// of a macroblock in place, as the reference encoder does.
+func vp8FWHT(c *[16]int32) {
+	for i := 0; i < 16; i += 4 {
+		a := (c[i] + c[i+2]) * 4
+		d := (c[i+1] + c[i+3]) * 4
+		cc := (c[i+1] - c[i+3]) * 4
+		b := (c[i] - c[i+2]) * 4
+		c[i] = a + d + int32(btoi(a != 0))
+		c[i+1] = b + cc
+		c[i+2] = b - cc
+		c[i+3] = a - d
+	}
+	for i := 0; i < 4; i++ {
+		a := c[i] + c[8+i]
+		d := c[4+i] + c[12+i]
+		cc := c[4+i] - c[12+i]
+		b := c[i] - c[8+i]
+		for k, v := range [4]int32{a + d, b + cc, b - cc, a - d} {
+			if v < 0 {
+				v++
+			}
+			c[4*k+i] = (v + 3) >> 3
+		}
+	}
+}
+
+// vp8IWHT inverts the transform of dequantized Y2 coefficients c in place,
+// exactly as decoders do.
+func vp8IWHT(c *[16]int32) {
+	var m [16]int32
+	for i := 0; i < 4; i++ {
+		a0 := c[i] + c[12+i]
+		a1 := c[4+i] + c[8+i]
+		a2 := c[4+i] - c[8+i]
+		a3 := c[i] - c[12+i]
+		m[i], m[8+i] = a0+a1, a0-a1
+		m[4+i], m[12+i] = a3+a2, a3-a2
+	}
+	for i := 0; i < 4; i++ {
+		dc := m[4*i] + 3
+		a0 := dc + m[4*i+3]
+		a1 := m[4*i+1] + m[4*i+2]
+		a2 := m[4*i+1] - m[4*i+2]
+		a3 := dc - m[4*i+3]
+		c[4*i] = int32(int16((a0 + a1) >> 3))
+		c[4*i+1] = int32(int16((a3 + a2) >> 3))
+		c[4*i+2] = int32(int16((a0 - a1) >> 3))
+		c[4*i+3] = int32(int16((a3 - a2) >> 3))
+	}
+}
+
+// vp8Quantize quantizes coefficients c from blk.first with the DC and AC
+// step sizes q into blk, and replaces them with their dequantized values.
+// It returns 1 if any coefficient is coded as non-zero, or 0 otherwise.
+func vp8Quantize(c *[16]int32, q [2]int32, blk *vp8Block) uint8 {
+	var nz uint8
+	for i := int(blk.first); i < 16; i++ {
+		z := vp8Zigzag[i]
+		step := q[btoi(z > 0)]
+		v, neg := c[z], c[z] < 0
+		if neg {
+			v = -v
+		}
+		// round AC coefficients towards zero, which saves more bits
+		// than it costs in quality
+		bias := step / 2
+		if z > 0 {
+			bias = step / 3
+		}
+		level := (v + bias) / step
+		if level > 2048 {
+			level = 2048
+		}
+		if neg {
+			level = -level
+		}
+		blk.levels[i] = int16(level)
+		c[z] = int32(int16(level * step))
+		if level != 0 {
+			nz = 1
+		}
+	}
+	return nz
+}
+
+// firstPartition returns the first partition of the frame, with the frame
+// header, token probabilities and prediction modes of macroblocks.
+func (e *vp8Encoder) firstPartition(probs *vp8TokenProbs, update *[vp8Planes][vp8Bands][vp8Contexts][vp8Nodes]bool) []byte {
+	enc := &boolEncoder{rng: 255, bitCount: 24}
+	enc.writeLiteral(0, 1) // color space
+	enc.writeLiteral(0, 1) // clamping type
+	enc.writeLiteral(0, 1) // no segments
+	enc.writeLiteral(0, 1) // normal loop filter
+	enc.writeLiteral(uint32(e.filterLevel()), 6)
+	enc.writeLiteral(0, 3) // sharpness
+	enc.writeLiteral(0, 1) // no loop filter adjustments
+	enc.writeLiteral(0, 2) // a single coefficient partition
+	enc.writeLiteral(uint32(e.qi), 7)
+	enc.writeLiteral(0, 5) // no quantizer deltas
+	enc.writeLiteral(0, 1) // refresh entropy probabilities
+	for i := range probs {
+		for j := range probs[i] {
+			for k := range probs[i][j] {
+				for l, p := range probs[i][j][k] {
+					u := update[i][j][k][l]
+					enc.writeBool(vp8TokenUpdateProbs[i][j][k][l], u)
+					if u {
+						enc.writeLiteral(uint32(p), 8)
+					}
+				}
+			}
+		}
+	}
+
+	skipped := 0
+	for _, s := range e.skip {
+		skipped += btoi(s)
+	}
+	skipProb := vp8Prob(len(e.skip)-skipped, len(e.skip))
+	enc.writeLiteral(1, 1)
+	enc.writeLiteral(uint32(skipProb), 8)
+	for i, modes := range e.modes {
+		enc.writeBool(skipProb, e.skip[i])
+		enc.writeBool(145, true) // 16x16 luma prediction
+		switch modes[0] {
+		case vp8PredDC:
+			enc.writeBool(156, false)
+			enc.writeBool(163, false)
+		case vp8PredVE:
+			enc.writeBool(156, false)
+			enc.writeBool(163, true)
+		case vp8PredHE:
+			enc.writeBool(156, true)
+			enc.writeBool(128, false)
+		case vp8PredTM:
+			enc.writeBool(156, true)
+			enc.writeBool(128, true)
+		}
+		enc.writeBool(142, modes[1] != vp8PredDC)
+		if modes[1] != vp8PredDC {
+			enc.writeBool(114, modes[1] != vp8PredVE)
+			if modes[1] != vp8PredVE {
+				enc.writeBool(183, modes[1] == vp8PredTM)
+			}
+		}
+	}
+	return enc.flush()
+}
+
+// vp8TokenStats counts the branches taken at each node of the token tree.
+type vp8TokenStats [vp8Planes][vp8Bands][vp8Contexts][vp8Nodes][2]int
+
+// probs returns token probabilities fitted to the stats, and whether each
+// of them is worth updating from its default.
+func (s *vp8TokenStats) probs() (probs vp8TokenProbs, update [vp8Planes][vp8Bands][vp8Contexts][vp8Nodes]bool) {
+	probs = vp8DefaultTokenProbs
+	for i := range s {
+		for j := range s[i] {
+			for k := range s[i][j] {
+				for l, n := range s[i][j][k] {
+					if n[0]+n[1] == 0 {
+						continue
+					}
+					old := probs[i][j][k][l]
+					p := vp8Prob(n[0], n[0]+n[1])
+					up := vp8TokenUpdateProbs[i][j][k][l]
+					saving := vp8Cost(old, n) - vp8Cost(p, n) -
+						8 - vp8Cost(up, [2]int{0, 1}) + vp8Cost(up, [2]int{1, 0})
+					if saving > 0 {
+						probs[i][j][k][l] = p
+						update[i][j][k][l] = true
+					}
+				}
+			}
+		}
+	}
+	return probs, update
+}
+
+// vp8Prob returns the probability of a zero bit, given n0 zeros of n bits.
+func vp8Prob(n0, n int) uint8 {
+	if n == 0 {
+		return 255
+	}
+	p := 256 * n0 / n
+	switch {
+	case p < 1:
+		return 1
+	case p > 255:
+		return 255
+	}
+	return uint8(p)
+}
+
+// vp8Cost returns the number of bits taken by n[0] zeros and n[1] ones
+// coded with probability p of a zero.
+func vp8Cost(p uint8, n [2]int) float64 {
+	p0 := float64(p) / 256
+	return -float64(n[0])*math.Log2(p0) - float64(n[1])*math.Log2(1-p0)
+}
+
+// vp8TokenWriter codes blocks of coefficients, or only counts the branches
+// taken in the token tree if enc is nil.
+type vp8TokenWriter struct {
+	enc   *boolEncoder
+	probs *vp8TokenProbs
+	stats *vp8TokenStats
+}
+
+// node codes bit b at node of the token tree.
+func (t *vp8TokenWriter) node(plane, band, ctx, node int, b bool) {
+	if t.stats != nil {
+		t.stats[plane][band][ctx][node][btoi(b)]++
+	}
+	if t.enc != nil {
+		t.enc.writeBool(t.probs[plane][band][ctx][node], b)
+	}
+}
+
+// extra codes bit b with fixed probability p.
+func (t *vp8TokenWriter) extra(p uint8, b bool) {
+	if t.enc != nil {
+		t.enc.writeBool(p, b)
+	}
+}
+
+// writeBlock codes the coefficient tokens of blk.
+func (t *vp8TokenWriter) writeBlock(blk *vp8Block) {
+	last := -1
+	for i := int(blk.first); i < 16; i++ {
+		if blk.levels[i] != 0 {
+			last = i
+		}
+	}
+	plane, band, ctx := int(blk.plane), int(vp8BandOf[blk.first]), int(blk.ctx)
+	t.node(plane, band, ctx, 0, last >= 0)
+	for i := int(blk.first); i <= last; i++ {
+		v := int(blk.levels[i])
+		neg := v < 0
+		if neg {
+			v = -v
+		}
+		band = int(vp8BandOf[i+1])
+		if v == 0 {
+			t.node(plane, int(vp8BandOf[i]), ctx, 1, false)
+			// no end of block can follow a zero
+			ctx = 0
+			continue
+		}
+		t.writeLevel(plane, int(vp8BandOf[i]), ctx, v)
+		t.extra(128, neg)
+		ctx = 2
+		if v == 1 {
+			ctx = 1
+		}
+		if i < 15 {
+			t.node(plane, band, ctx, 0, i < last)
+		}
+	}
+}
+
+// writeLevel codes the token and extra bits of non-zero level v.
+func (t *vp8TokenWriter) writeLevel(plane, band, ctx, v int) {
+	bit := func(node int, b bool) {
+		t.node(plane, band, ctx, node, b)
+	}
+	bit(1, true)
+	bit(2, v > 1)
+	switch {
+	case v == 1:
+	case v <= 4:
+		bit(3, false)
+		bit(4, v > 2)
+		if v > 2 {
+			bit(5, v == 4)
+		}
+	case v <= 10:
+		bit(3, true)
+		bit(6, false)
+		bit(7, v > 6)
+		if v <= 6 {
+			t.extra(159, v == 6)
+		} else {
+			t.extra(165, (v-7)&2 != 0)
+			t.extra(145, (v-7)&1 != 0)
+		}
+	default:
+		bit(3, true)
+		bit(6, true)
+		cat := 0
+		for cat < 3 && v >= 3+16<<cat {
+			cat++
+		}
+		bit(8, cat >= 2)
+		bit(9+cat/2, cat&1 != 0)
+		probs := vp8CatProbs[cat]
+		v -= 3 + 8<<cat
+		for i, p := range probs {
+			t.extra(p, v>>(len(probs)-1-i)&1 != 0)
+		}
+	}
+}
+
+// boolEncoder is the boolean entropy encoder of RFC 6386, section 7.3.
+type boolEncoder struct {
+	buf      []byte
+	rng      uint32
+	bottom   uint32
+	bitCount int
+}
+
+// writeBool writes bit b with probability p of a zero.
+func (e *boolEncoder) writeBool(p uint8, b bool) {
+	split := 1 + (e.rng-1)*uint32(p)>>8
+	if b {
+		e.bottom += split
+		e.rng -= split
+	} else {
+		e.rng = split
+	}
+	for e.rng < 128 {
+		e.rng <<= 1
+		if e.bottom&(1<<31) != 0 {
+			// propagate the carry
+			i := len(e.buf) - 1
+			for ; e.buf[i] == 0xff; i-- {
+				e.buf[i] = 0
+			}
+			e.buf[i]++
+		}
+		e.bottom <<= 1
+		e.bitCount--
+		if e.bitCount == 0 {
+			e.buf = append(e.buf, byte(e.bottom>>24))
+			e.bottom &= 1<<24 - 1
+			e.bitCount = 8
+		}
+	}
+}
+
+// writeLiteral writes the n low bits of v, most significant first,
+// with even probabilities.
+func (e *boolEncoder) writeLiteral(v uint32, n int) {
+	for i := n - 1; i >= 0; i-- {
+		e.writeBool(128, v>>uint(i)&1 != 0)
+	}
+}
+
+// flush pads the data with zeros so that all written bits can be
+// decoded, and returns it.
+func (e *boolEncoder) flush() []byte {
+	e.writeLiteral(0, 32)
+	return e.buf
+}
+
+// btoi returns 1 if b is true, or 0 otherwise.
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// clip8 clamps v to [0, 255].
+func clip8(v int32) uint8 {
+	switch {
+	case v < 0:
+		return 0
+	case v > 255:
+		return 255
+	}
+	return uint8(v)
+}
+
+var (
+	// vp8Zigzag is the order of coefficients in the bitstream.
+	vp8Zigzag = [16]int{0, 1, 4, 8, 5, 2, 3, 6, 9, 12, 13, 10, 7, 11, 14, 15}
+	// vp8BandOf is the band of each coefficient in zigzag order.
+	vp8BandOf = [17]uint8{0, 1, 2, 3, 6, 4, 5, 6, 6, 6, 6, 6, 6, 6, 6, 7, 0}
+	// vp8CatProbs are the probabilities of the extra bits
+	// of tokens of categories 3 to 6.
+	vp8CatProbs = [4][]uint8{
+		{173, 148, 140},
+		{176, 155, 140, 135},
+		{180, 157, 141, 134, 130},
+		{254, 254, 243, 230, 196, 177, 153, 140, 133, 130, 129},
+	}
+)
+
+// The quantizer step sizes are specified in section 14.1.
+var (
+	vp8DCSteps = [128]uint16{
+		4, 5, 6, 7, 8, 9, 10, 10,
+		11, 12, 13, 14, 15, 16, 17, 17,
+		18, 19, 20, 20, 21, 21, 22, 22,
+		23, 23, 24, 25, 25, 26, 27, 28,
+		29, 30, 31, 32, 33, 34, 35, 36,
+		37, 37, 38, 39, 40, 41, 42, 43,
+		44, 45, 46, 46, 47, 48, 49, 50,
+		51, 52, 53, 54, 55, 56, 57, 58,
+		59, 60, 61, 62, 63, 64, 65, 66,
+		67, 68, 69, 70, 71, 72, 73, 74,
+		75, 76, 76, 77, 78, 79, 80, 81,
+		82, 83, 84, 85, 86, 87, 88, 89,
+		91, 93, 95, 96, 98, 100, 101, 102,
+		104, 106, 108, 110, 112, 114, 116, 118,
+		122, 124, 126, 128, 130, 132, 134, 136,
+		138, 140, 143, 145, 148, 151, 154, 157,
+	}
+	vp8ACSteps = [128]uint16{
+		4, 5, 6, 7, 8, 9, 10, 11,
+		12, 13, 14, 15, 16, 17, 18, 19,
+		20, 21, 22, 23, 24, 25, 26, 27,
+		28, 29, 30, 31, 32, 33, 34, 35,
+		36, 37, 38, 39, 40, 41, 42, 43,
+		44, 45, 46, 47, 48, 49, 50, 51,
+		52, 53, 54, 55, 56, 57, 58, 60,
+		62, 64, 66, 68, 70, 72, 74, 76,
+		78, 80, 82, 84, 86, 88, 90, 92,
+		94, 96, 98, 100, 102, 104, 106, 108,
+		110, 112, 114, 116, 119, 122, 125, 128,
+		131, 134, 137, 140, 143, 146, 149, 152,
+		155, 158, 161, 164, 167, 170, 173, 177,
+		181, 185, 189, 193, 197, 201, 205, 209,
+		213, 217, 221, 225, 229, 234, 239, 245,
+		249, 254, 259, 264, 269, 274, 279, 284,
+	}
+)
+
+// vp8TokenUpdateProbs are the probabilities of not updating each token
+// probability, as specified in section 13.4.
+var vp8TokenUpdateProbs = vp8TokenProbs{
+	{
+		{
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{176, 246, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{223, 241, 252, 255, 255, 255, 255, 255, 255, 255, 255},
+			{249, 253, 253, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 244, 252, 255, 255, 255, 255, 255, 255, 255, 255},
+			{234, 254, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+			{253, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 246, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+			{239, 253, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+			{254, 255, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 248, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+			{251, 255, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 253, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+			{251, 254, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+			{254, 255, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 254, 253, 255, 254, 255, 255, 255, 255, 255, 255},
+			{250, 255, 254, 255, 254, 255, 255, 255, 255, 255, 255},
+			{254, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+	},
+	{
+		{
+			{217, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{225, 252, 241, 253, 255, 255, 254, 255, 255, 255, 255},
+			{234, 250, 241, 250, 253, 255, 253, 254, 255, 255, 255},
+		},
+		{
+			{255, 254, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{223, 254, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+			{238, 253, 254, 254, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 248, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+			{249, 254, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 253, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{247, 254, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 253, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+			{252, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 254, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+			{253, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 254, 253, 255, 255, 255, 255, 255, 255, 255, 255},
+			{250, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{254, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+	},
+	{
+		{
+			{186, 251, 250, 255, 255, 255, 255, 255, 255, 255, 255},
+			{234, 251, 244, 254, 255, 255, 255, 255, 255, 255, 255},
+			{251, 251, 243, 253, 254, 255, 254, 255, 255, 255, 255},
+		},
+		{
+			{255, 253, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+			{236, 253, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+			{251, 253, 253, 254, 254, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 254, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+			{254, 254, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 254, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{254, 254, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{254, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{254, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+	},
+	{
+		{
+			{248, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{250, 254, 252, 254, 255, 255, 255, 255, 255, 255, 255},
+			{248, 254, 249, 253, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 253, 253, 255, 255, 255, 255, 255, 255, 255, 255},
+			{246, 253, 253, 255, 255, 255, 255, 255, 255, 255, 255},
+			{252, 254, 251, 254, 254, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 254, 252, 255, 255, 255, 255, 255, 255, 255, 255},
+			{248, 254, 253, 255, 255, 255, 255, 255, 255, 255, 255},
+			{253, 255, 254, 254, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 251, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+			{245, 251, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+			{253, 253, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 251, 253, 255, 255, 255, 255, 255, 255, 255, 255},
+			{252, 253, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 254, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 252, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{249, 255, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 255, 253, 255, 255, 255, 255, 255, 255, 255, 255},
+			{250, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{254, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+	},
+}
+
+// vp8DefaultTokenProbs are the default token probabilities,
+// as specified in section 13.5.
+var vp8DefaultTokenProbs = vp8TokenProbs{
+	{
+		{
+			{128, 128, 128, 128, 128, 128, 128, 128, 128, 128, 128},
+			{128, 128, 128, 128, 128, 128, 128, 128, 128, 128, 128},
+			{128, 128, 128, 128, 128, 128, 128, 128, 128, 128, 128},
+		},
+		{
+			{253, 136, 254, 255, 228, 219, 128, 128, 128, 128, 128},
+			{189, 129, 242, 255, 227, 213, 255, 219, 128, 128, 128},
+			{106, 126, 227, 252, 214, 209, 255, 255, 128, 128, 128},
+		},
+		{
+			{1, 98, 248, 255, 236, 226, 255, 255, 128, 128, 128},
+			{181, 133, 238, 254, 221, 234, 255, 154, 128, 128, 128},
+			{78, 134, 202, 247, 198, 180, 255, 219, 128, 128, 128},
+		},
+		{
+			{1, 185, 249, 255, 243, 255, 128, 128, 128, 128, 128},
+			{184, 150, 247, 255, 236, 224, 128, 128, 128, 128, 128},
+			{77, 110, 216, 255, 236, 230, 128, 128, 128, 128, 128},
+		},
+		{
+			{1, 101, 251, 255, 241, 255, 128, 128, 128, 128, 128},
+			{170, 139, 241, 252, 236, 209, 255, 255, 128, 128, 128},
+			{37, 116, 196, 243, 228, 255, 255, 255, 128, 128, 128},
+		},
+		{
+			{1, 204, 254, 255, 245, 255, 128, 128, 128, 128, 128},
+			{207, 160, 250, 255, 238, 128, 128, 128, 128, 128, 128},
+			{102, 103, 231, 255, 211, 171, 128, 128, 128, 128, 128},
+		},
+		{
+			{1, 152, 252, 255, 240, 255, 128, 128, 128, 128, 128},
+			{177, 135, 243, 255, 234, 225, 128, 128, 128, 128, 128},
+			{80, 129, 211, 255, 194, 224, 128, 128, 128, 128, 128},
+		},
+		{
+			{1, 1, 255, 128, 128, 128, 128, 128, 128, 128, 128},
+			{246, 1, 255, 128, 128, 128, 128, 128, 128, 128, 128},
+			{255, 128, 128, 128, 128, 128, 128, 128, 128, 128, 128},
+		},
+	},
+	{
+		{
+			{198, 35, 237, 223, 193, 187, 162, 160, 145, 155, 62},
+			{131, 45, 198, 221, 172, 176, 220, 157, 252, 221, 1},
+			{68, 47, 146, 208, 149, 167, 221, 162, 255, 223, 128},
+		},
+		{
+			{1, 149, 241, 255, 221, 224, 255, 255, 128, 128, 128},
+			{184, 141, 234, 253, 222, 220, 255, 199, 128, 128, 128},
+			{81, 99, 181, 242, 176, 190, 249, 202, 255, 255, 128},
+		},
+		{
+			{1, 129, 232, 253, 214, 197, 242, 196, 255, 255, 128},
+			{99, 121, 210, 250, 201, 198, 255, 202, 128, 128, 128},
+			{23, 91, 163, 242, 170, 187, 247, 210, 255, 255, 128},
+		},
+		{
+			{1, 200, 246, 255, 234, 255, 128, 128, 128, 128, 128},
+			{109, 178, 241, 255, 231, 245, 255, 255, 128, 128, 128},
+			{44, 130, 201, 253, 205, 192, 255, 255, 128, 128, 128},
+		},
+		{
+			{1, 132, 239, 251, 219, 209, 255, 165, 128, 128, 128},
+			{94, 136, 225, 251, 218, 190, 255, 255, 128, 128, 128},
+			{22, 100, 174, 245, 186, 161, 255, 199, 128, 128, 128},
+		},
+		{
+			{1, 182, 249, 255, 232, 235, 128, 128, 128, 128, 128},
+			{124, 143, 241, 255, 227, 234, 128, 128, 128, 128, 128},
+			{35, 77, 181, 251, 193, 211, 255, 205, 128, 128, 128},
+		},
+		{
+			{1, 157, 247, 255, 236, 231, 255, 255, 128, 128, 128},
+			{121, 141, 235, 255, 225, 227, 255, 255, 128, 128, 128},
+			{45, 99, 188, 251, 195, 217, 255, 224, 128, 128, 128},
+		},
+		{
+			{1, 1, 251, 255, 213, 255, 128, 128, 128, 128, 128},
+			{203, 1, 248, 255, 255, 128, 128, 128, 128, 128, 128},
+			{137, 1, 177, 255, 224, 255, 128, 128, 128, 128, 128},
+		},
+	},
+	{
+		{
+			{253, 9, 248, 251, 207, 208, 255, 192, 128, 128, 128},
+			{175, 13, 224, 243, 193, 185, 249, 198, 255, 255, 128},
+			{73, 17, 171, 221, 161, 179, 236, 167, 255, 234, 128},
+		},
+		{
+			{1, 95, 247, 253, 212, 183, 255, 255, 128, 128, 128},
+			{239, 90, 244, 250, 211, 209, 255, 255, 128, 128, 128},
+			{155, 77, 195, 248, 188, 195, 255, 255, 128, 128, 128},
+		},
+		{
+			{1, 24, 239, 251, 218, 219, 255, 205, 128, 128, 128},
+			{201, 51, 219, 255, 196, 186, 128, 128, 128, 128, 128},
+			{69, 46, 190, 239, 201, 218, 255, 228, 128, 128, 128},
+		},
+		{
+			{1, 191, 251, 255, 255, 128, 128, 128, 128, 128, 128},
+			{223, 165, 249, 255, 213, 255, 128, 128, 128, 128, 128},
+			{141, 124, 248, 255, 255, 128, 128, 128, 128, 128, 128},
+		},
+		{
+			{1, 16, 248, 255, 255, 128, 128, 128, 128, 128, 128},
+			{190, 36, 230, 255, 236, 255, 128, 128, 128, 128, 128},
+			{149, 1, 255, 128, 128, 128, 128, 128, 128, 128, 128},
+		},
+		{
+			{1, 226, 255, 128, 128, 128, 128, 128, 128, 128, 128},
+			{247, 192, 255, 128, 128, 128, 128, 128, 128, 128, 128},
+			{240, 128, 255, 128, 128, 128, 128, 128, 128, 128, 128},
+		},
+		{
+			{1, 134, 252, 255, 255, 128, 128, 128, 128, 128, 128},
+			{213, 62, 250, 255, 255, 128, 128, 128, 128, 128, 128},
+			{55, 93, 255, 128, 128, 128, 128, 128, 128, 128, 128},
+		},
+		{
+			{128, 128, 128, 128, 128, 128, 128, 128, 128, 128, 128},
+			{128, 128, 128, 128, 128, 128, 128, 128, 128, 128, 128},
+			{128, 128, 128, 128, 128, 128, 128, 128, 128, 128, 128},
+		},
+	},
+	{
+		{
+			{202, 24, 213, 235, 186, 191, 220, 160, 240, 175, 255},
+			{126, 38, 182, 232, 169, 184, 228, 174, 255, 187, 128},
+			{61, 46, 138, 219, 151, 178, 240, 170, 255, 216, 128},
+		},
+		{
+			{1, 112, 230, 250, 199, 191, 247, 159, 255, 255, 128},
+			{166, 109, 228, 252, 211, 215, 255, 174, 128, 128, 128},
+			{39, 77, 162, 232, 172, 180, 245, 178, 255, 255, 128},
+		},
+		{
+			{1, 52, 220, 246, 198, 199, 249, 220, 255, 255, 128},
+			{124, 74, 191, 243, 183, 193, 250, 221, 255, 255, 128},
+			{24, 71, 130, 219, 154, 170, 243, 182, 255, 255, 128},
+		},
+		{
+			{1, 182, 225, 249, 219, 240, 255, 224, 128, 128, 128},
+			{149, 150, 226, 252, 216, 205, 255, 171, 128, 128, 128},
+			{28, 108, 170, 242, 183, 194, 254, 223, 255, 255, 128},
+		},
+		{
+			{1, 81, 230, 252, 204, 203, 255, 192, 128, 128, 128},
+			{123, 102, 209, 247, 188, 196, 255, 233, 128, 128, 128},
+			{20, 95, 153, 243, 164, 173, 255, 203, 128, 128, 128},
+		},
+		{
+			{1, 222, 248, 255, 216, 213, 128, 128, 128, 128, 128},
+			{168, 175, 246, 252, 235, 205, 255, 255, 128, 128, 128},
+			{47, 116, 215, 255, 211, 212, 255, 255, 128, 128, 128},
+		},
+		{
+			{1, 121, 236, 253, 212, 214, 255, 255, 128, 128, 128},
+			{141, 84, 213, 252, 201, 202, 255, 219, 128, 128, 128},
+			{42, 80, 160, 240, 162, 185, 255, 205, 128, 128, 128},
+		},
+		{
+			{1, 1, 255, 128, 128, 128, 128, 128, 128, 128, 128},
+			{244, 1, 255, 128, 128, 128, 128, 128, 128, 128, 128},
+			{238, 1, 255, 128, 128, 128, 128, 128, 128, 128, 128},
+		},
+	},
+}
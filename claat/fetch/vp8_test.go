@@ -0,0 +1,132 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fetch
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+// photoImage returns a w by h image with smooth shading and fine detail.
+func photoImage(w, h int) *image.RGBA {
+	m := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			fx, fy := float64(x), float64(y)
+			m.SetRGBA(x, y, color.RGBA{
+				R: uint8(128 + 100*math.Sin(fx/23+fy/41)),
+				G: uint8(128 + 60*math.Cos(fx/7)*math.Sin(fy/11)),
+				B: uint8(128 + 120*math.Sin((fx+fy)/2)),
+				A: 0xff,
+			})
+		}
+	}
+	return m
+}
+
+// psnr returns the peak signal-to-noise ratio of w by h pixels
+// of planes a and b, with rows of stride a and b bytes.
+func psnr(a []uint8, sa int, b []uint8, sb int, w, h int) float64 {
+	var sse float64
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			d := float64(a[y*sa+x]) - float64(b[y*sb+x])
+			sse += d * d
+		}
+	}
+	if sse == 0 {
+		return math.Inf(1)
+	}
+	return 10 * math.Log10(255*255*float64(w*h)/sse)
+}
+
+func TestEncodeLossyWebP(t *testing.T) {
+	tests := []struct {
+		name    string
+		m       image.Image
+		quality int
+		psnr    float64 // minimum PSNR of each plane
+	}{
+		{"1x1", image.NewRGBA(image.Rect(0, 0, 1, 1)), 90, 40},
+		{"flat", image.NewNRGBA(image.Rect(0, 0, 64, 64)), 90, 40},
+		{"mixed", testImage(97, 61, false), 90, 28},
+		{"gray", image.NewGray(image.Rect(3, 5, 20, 9)), 90, 40},
+		{"photo", photoImage(300, 200), 90, 35},
+		{"photo low", photoImage(300, 200), 10, 15},
+		{"photo best", photoImage(50, 40), 100, 45},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := encodeLossyWebP(&buf, tc.m, tc.quality); err != nil {
+				t.Fatalf("encodeLossyWebP: %v", err)
+			}
+			got, err := webp.Decode(&buf)
+			if err != nil {
+				t.Fatalf("webp.Decode: %v", err)
+			}
+			b := tc.m.Bounds()
+			if got.Bounds().Dx() != b.Dx() || got.Bounds().Dy() != b.Dy() {
+				t.Fatalf("size = %v, want %v", got.Bounds().Size(), b.Size())
+			}
+			ycc, ok := got.(*image.YCbCr)
+			if !ok {
+				t.Fatalf("decoded %T, want *image.YCbCr", got)
+			}
+			mbw, mbh := (b.Dx()+15)/16, (b.Dy()+15)/16
+			want := vp8YUV(tc.m, mbw, mbh)
+			cw, ch := (b.Dx()+1)/2, (b.Dy()+1)/2
+			planes := []struct {
+				name string
+				got  []uint8
+				w, h int
+			}{
+				{"Y", ycc.Y, b.Dx(), b.Dy()},
+				{"Cb", ycc.Cb, cw, ch},
+				{"Cr", ycc.Cr, cw, ch},
+			}
+			for i, p := range planes {
+				stride := ycc.YStride
+				if i > 0 {
+					stride = ycc.CStride
+				}
+				s := psnr(p.got, stride, want[i], len(want[i])/(16*mbh>>btoi(i > 0)), p.w, p.h)
+				if s < tc.psnr {
+					t.Errorf("%s PSNR = %.1f dB, want at least %.0f dB", p.name, s, tc.psnr)
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeLossyWebPQuality(t *testing.T) {
+	m := photoImage(200, 150)
+	var sizes []int
+	for _, q := range []int{10, 50, 90} {
+		var buf bytes.Buffer
+		if err := encodeLossyWebP(&buf, m, q); err != nil {
+			t.Fatalf("encodeLossyWebP(%d): %v", q, err)
+		}
+		sizes = append(sizes, buf.Len())
+	}
+	if sizes[0] >= sizes[1] || sizes[1] >= sizes[2] {
+		t.Errorf("sizes at quality 10, 50 and 90 = %v, want increasing", sizes)
+	}
+}
@@ -0,0 +1,665 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fetch
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"io"
+	"sort"
+)
+
+// This file implements a lossless WebP (VP8L) encoder, as specified in
+// https://developers.google.com/speed/webp/docs/webp_lossless_bitstream_specification.
+//
+// The encoder is deliberately simple: it applies the subtract green
+// and predictor transforms followed by LZ77 backward references,
+// and codes all pixels with a single group of prefix codes,
+// without a color cache.
+// This is good enough for screenshots and diagrams, which make up
+// most of codelab images.
+
+const (
+	webpMaxSize = 1 << 14 // maximum width and height
+
+	vp8lSignature = 0x2f
+
+	// transform types
+	vp8lPredictor     = 0
+	vp8lSubtractGreen = 2
+
+	vp8lPredictorBits  = 4 // predictor tiles are 16 by 16 pixels
+	vp8lPredictorModes = 14
+
+	vp8lNumLiterals   = 256
+	vp8lNumLengths    = 24
+	vp8lNumDistances  = 40
+	vp8lMaxCodeLength = 15
+	vp8lMaxCLCLength  = 7 // maximum length of the code length code
+
+	// LZ77 parameters
+	vp8lMinMatch   = 3
+	vp8lMaxMatch   = 4096
+	vp8lWindow     = 1<<20 - 120
+	vp8lHashBits   = 16
+	vp8lChainLimit = 32
+)
+
+// vp8lCodeLengthOrder is the order of code length code lengths in the bitstream.
+var vp8lCodeLengthOrder = [...]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// encodeWebP writes m to w as a lossless WebP image.
+func encodeWebP(w io.Writer, m image.Image) error {
+	b := m.Bounds()
+	width, height := b.Dx(), b.Dy()
+	if width < 1 || height < 1 || width > webpMaxSize || height > webpMaxSize {
+		return errors.New("webp: invalid image size")
+	}
+	nrgba, ok := m.(*image.NRGBA)
+	if !ok || nrgba.Stride != 4*width {
+		nrgba = image.NewNRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(nrgba, nrgba.Rect, m, b.Min, draw.Src)
+	}
+
+	// ARGB pixels with the subtract green transform applied
+	argb := make([]uint32, width*height)
+	alpha := false
+	for i := range argb {
+		p := nrgba.Pix[4*i : 4*i+4]
+		r, g, b, a := p[0]-p[1], p[1], p[2]-p[1], p[3]
+		argb[i] = uint32(a)<<24 | uint32(r)<<16 | uint32(g)<<8 | uint32(b)
+		alpha = alpha || a != 0xff
+	}
+
+	bw := &bitWriter{}
+	bw.writeBits(vp8lSignature, 8)
+	bw.writeBits(uint32(width-1), 14)
+	bw.writeBits(uint32(height-1), 14)
+	if alpha {
+		bw.writeBits(1, 1)
+	} else {
+		bw.writeBits(0, 1)
+	}
+	bw.writeBits(0, 3) // version
+	// transforms
+	bw.writeBits(1, 1)
+	bw.writeBits(vp8lSubtractGreen, 2)
+	bw.writeBits(1, 1)
+	bw.writeBits(vp8lPredictor, 2)
+	bw.writeBits(vp8lPredictorBits-2, 3)
+	modes, tiles := vp8lPredictModes(argb, width)
+	bw.writeBits(0, 1) // no color cache
+	writeVP8LPixels(bw, modes, tiles)
+	argb = vp8lResiduals(argb, width, modes, tiles)
+	bw.writeBits(0, 1)
+	// no color cache and a single group of prefix codes
+	bw.writeBits(0, 1)
+	bw.writeBits(0, 1)
+	writeVP8LPixels(bw, argb, width)
+	data := bw.bytes()
+
+	size := len(data)
+	pad := size & 1
+	hdr := make([]byte, 20)
+	copy(hdr[0:], "RIFF")
+	binary.LittleEndian.PutUint32(hdr[4:], uint32(12+size+pad))
+	copy(hdr[8:], "WEBPVP8L")
+	binary.LittleEndian.PutUint32(hdr[16:], uint32(size))
+	if _, err := w.Write(hdr); err != nil {
+		return err
+	}
+	if pad != 0 {
+		data = append(data, 0)
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// vp8lPredictModes chooses a predictor mode for each tile of argb pixels
+// with rows of width pixels, minimizing the magnitude of residuals.
+// It returns the modes as an image of tiles per row pixels,
+// with modes stored in the green channel.
+func vp8lPredictModes(argb []uint32, width int) ([]uint32, int) {
+	const size = 1 << vp8lPredictorBits
+	height := len(argb) / width
+	tiles := (width + size - 1) / size
+	modes := make([]uint32, tiles*((height+size-1)/size))
+	for ty := 0; ty*size < height; ty++ {
+		for tx := 0; tx < tiles; tx++ {
+			best, bestCost := 0, -1
+			for mode := 0; mode < vp8lPredictorModes; mode++ {
+				cost := 0
+				for y := ty * size; y < height && y < (ty+1)*size; y++ {
+					for x := tx * size; x < width && x < (tx+1)*size; x++ {
+						i := y*width + x
+						r := vp8lSub(argb[i], vp8lPredict(argb, i, width, mode))
+						for k := uint(0); k < 32; k += 8 {
+							c := int(int8(r >> k))
+							if c < 0 {
+								c = -c
+							}
+							cost += c
+						}
+					}
+				}
+				if bestCost < 0 || cost < bestCost {
+					best, bestCost = mode, cost
+				}
+			}
+			modes[ty*tiles+tx] = uint32(best) << 8
+		}
+	}
+	return modes, tiles
+}
+
+// vp8lResiduals returns the differences of argb pixels from their predictions
+// with modes of tiles per row.
+func vp8lResiduals(argb []uint32, width int, modes []uint32, tiles int) []uint32 {
+	res := make([]uint32, len(argb))
+	for i := range argb {
+		x, y := i%width, i/width
+		mode := int(modes[(y>>vp8lPredictorBits)*tiles+x>>vp8lPredictorBits] >> 8)
+		res[i] = vp8lSub(argb[i], vp8lPredict(argb, i, width, mode))
+	}
+	return res
+}
+
+// vp8lPredict returns the prediction of pixel i of argb,
+// with rows of width pixels, in predictor mode.
+// The top row and the leftmost column have fixed predictors.
+func vp8lPredict(argb []uint32, i, width, mode int) uint32 {
+	switch {
+	case i == 0:
+		return 0xff000000
+	case i < width:
+		return argb[i-1]
+	case i%width == 0:
+		return argb[i-width]
+	}
+	// the top right pixel of the rightmost column is
+	// the leftmost pixel of the current row
+	l, t, tl, tr := argb[i-1], argb[i-width], argb[i-width-1], argb[i-width+1]
+	switch mode {
+	case 1:
+		return l
+	case 2:
+		return t
+	case 3:
+		return tr
+	case 4:
+		return tl
+	case 5:
+		return vp8lAverage(vp8lAverage(l, tr), t)
+	case 6:
+		return vp8lAverage(l, tl)
+	case 7:
+		return vp8lAverage(l, t)
+	case 8:
+		return vp8lAverage(tl, t)
+	case 9:
+		return vp8lAverage(t, tr)
+	case 10:
+		return vp8lAverage(vp8lAverage(l, tl), vp8lAverage(t, tr))
+	case 11:
+		// select
+		if vp8lDistance(t, tl) < vp8lDistance(l, tl) {
+			return l
+		}
+		return t
+	case 12:
+		return vp8lChannels(func(k uint) int {
+			return int(l>>k&0xff) + int(t>>k&0xff) - int(tl>>k&0xff)
+		})
+	case 13:
+		a := vp8lAverage(l, t)
+		return vp8lChannels(func(k uint) int {
+			c := int(a >> k & 0xff)
+			return c + (c-int(tl>>k&0xff))/2
+		})
+	}
+	return 0xff000000
+}
+
+// vp8lChannels returns a pixel with channels computed by fn,
+// which is given the shift of each channel, clamped to [0, 255].
+func vp8lChannels(fn func(k uint) int) uint32 {
+	var p uint32
+	for k := uint(0); k < 32; k += 8 {
+		c := fn(k)
+		switch {
+		case c < 0:
+			c = 0
+		case c > 0xff:
+			c = 0xff
+		}
+		p |= uint32(c) << k
+	}
+	return p
+}
+
+// vp8lAverage returns the per channel average of pixels a and b, rounded down.
+func vp8lAverage(a, b uint32) uint32 {
+	return (a^b)&0xfefefefe>>1 + a&b
+}
+
+// vp8lDistance returns the sum of absolute channel differences of pixels a and b.
+func vp8lDistance(a, b uint32) int {
+	d := 0
+	for k := uint(0); k < 32; k += 8 {
+		c := int(a>>k&0xff) - int(b>>k&0xff)
+		if c < 0 {
+			c = -c
+		}
+		d += c
+	}
+	return d
+}
+
+// vp8lSub returns the per channel difference of pixels a and b, modulo 256.
+func vp8lSub(a, b uint32) uint32 {
+	var d uint32
+	for k := uint(0); k < 32; k += 8 {
+		d |= uint32(uint8(a>>k)-uint8(b>>k)) << k
+	}
+	return d
+}
+
+// vp8lSymbol is a literal pixel or a backward reference.
+type vp8lSymbol struct {
+	argb   uint32 // literal pixel, if length is zero
+	length int    // backward reference length
+	dist   int    // backward reference distance code
+}
+
+// writeVP8LPixels writes the prefix codes and entropy coded image data
+// of argb pixels, with rows of width pixels.
+func writeVP8LPixels(bw *bitWriter, argb []uint32, width int) {
+	syms := vp8lBackwardRefs(argb, width)
+
+	var (
+		green = make([]int, vp8lNumLiterals+vp8lNumLengths)
+		red   = make([]int, vp8lNumLiterals)
+		blue  = make([]int, vp8lNumLiterals)
+		alpha = make([]int, vp8lNumLiterals)
+		dist  = make([]int, vp8lNumDistances)
+	)
+	for _, s := range syms {
+		if s.length == 0 {
+			green[s.argb>>8&0xff]++
+			red[s.argb>>16&0xff]++
+			blue[s.argb&0xff]++
+			alpha[s.argb>>24]++
+			continue
+		}
+		c, _, _ := vp8lPrefix(s.length)
+		green[vp8lNumLiterals+c]++
+		c, _, _ = vp8lPrefix(s.dist)
+		dist[c]++
+	}
+	codes := make([]*prefixCode, 5)
+	for i, h := range [][]int{green, red, blue, alpha, dist} {
+		codes[i] = newPrefixCode(h, vp8lMaxCodeLength)
+		codes[i].write(bw)
+	}
+
+	for _, s := range syms {
+		if s.length == 0 {
+			codes[0].writeSymbol(bw, int(s.argb>>8&0xff))
+			codes[1].writeSymbol(bw, int(s.argb>>16&0xff))
+			codes[2].writeSymbol(bw, int(s.argb&0xff))
+			codes[3].writeSymbol(bw, int(s.argb>>24))
+			continue
+		}
+		c, n, extra := vp8lPrefix(s.length)
+		codes[0].writeSymbol(bw, vp8lNumLiterals+c)
+		bw.writeBits(extra, n)
+		c, n, extra = vp8lPrefix(s.dist)
+		codes[4].writeSymbol(bw, c)
+		bw.writeBits(extra, n)
+	}
+}
+
+// vp8lBackwardRefs finds LZ77 backward references in argb pixels,
+// using greedy matching with hash chains.
+// Pixels directly above and to the left are always tried first,
+// since they are cheapest to code.
+func vp8lBackwardRefs(argb []uint32, width int) []vp8lSymbol {
+	n := len(argb)
+	head := make([]int32, 1<<vp8lHashBits)
+	for i := range head {
+		head[i] = -1
+	}
+	chain := make([]int32, n)
+	hash := func(i int) uint32 {
+		h := argb[i]*0x1e35a7bd ^ argb[i+1]*0x9e3779b1 ^ argb[i+2]*0x85ebca6b
+		return h >> (32 - vp8lHashBits)
+	}
+	insert := func(i int) {
+		if i+vp8lMinMatch > n {
+			return
+		}
+		h := hash(i)
+		chain[i] = head[h]
+		head[h] = int32(i)
+	}
+	matchLen := func(i, j int) int {
+		m := n - i
+		if m > vp8lMaxMatch {
+			m = vp8lMaxMatch
+		}
+		l := 0
+		for l < m && argb[i+l] == argb[j+l] {
+			l++
+		}
+		return l
+	}
+
+	var syms []vp8lSymbol
+	for i := 0; i < n; {
+		best, bestDist := 0, 0
+		for _, d := range []int{width, 1} {
+			if d <= i {
+				if l := matchLen(i, i-d); l > best {
+					best, bestDist = l, d
+				}
+			}
+		}
+		if i+vp8lMinMatch <= n && best < vp8lMaxMatch {
+			for j, k := head[hash(i)], 0; j >= 0 && k < vp8lChainLimit && i-int(j) <= vp8lWindow; j, k = chain[j], k+1 {
+				if l := matchLen(i, int(j)); l > best {
+					best, bestDist = l, i-int(j)
+				}
+			}
+		}
+		if best < vp8lMinMatch {
+			syms = append(syms, vp8lSymbol{argb: argb[i]})
+			insert(i)
+			i++
+			continue
+		}
+		syms = append(syms, vp8lSymbol{length: best, dist: vp8lDistCode(bestDist, width)})
+		for k := 0; k < best; k++ {
+			insert(i + k)
+		}
+		i += best
+	}
+	return syms
+}
+
+// vp8lDistCode maps a backward reference distance in pixels to
+// a distance code. Only the two shortest codes of the 2D distance map
+// are used, for the pixel above and to the left.
+func vp8lDistCode(d, width int) int {
+	switch d {
+	case width:
+		return 1
+	case 1:
+		return 2
+	}
+	return d + 120
+}
+
+// vp8lPrefix returns the prefix code of v, which is a length or a distance
+// code, and the number and value of its extra bits.
+func vp8lPrefix(v int) (code int, nbits uint, extra uint32) {
+	v--
+	if v < 4 {
+		return v, 0, 0
+	}
+	h := uint(0)
+	for x := v; x > 1; x >>= 1 {
+		h++
+	}
+	second := v >> (h - 1) & 1
+	nbits = h - 1
+	return int(2*h) + second, nbits, uint32(v) & (1<<nbits - 1)
+}
+
+// bitWriter writes bits least significant first, as in the VP8L bitstream.
+type bitWriter struct {
+	buf   []byte
+	bits  uint64
+	nbits uint
+}
+
+func (w *bitWriter) writeBits(v uint32, n uint) {
+	w.bits |= uint64(v) << w.nbits
+	w.nbits += n
+	for w.nbits >= 8 {
+		w.buf = append(w.buf, byte(w.bits))
+		w.bits >>= 8
+		w.nbits -= 8
+	}
+}
+
+// bytes flushes pending bits and returns the written data.
+func (w *bitWriter) bytes() []byte {
+	if w.nbits > 0 {
+		w.buf = append(w.buf, byte(w.bits))
+		w.bits, w.nbits = 0, 0
+	}
+	return w.buf
+}
+
+// prefixCode is a canonical Huffman code of an alphabet.
+type prefixCode struct {
+	lengths []uint8
+	codes   []uint32 // bit reversed codes, ready to be written
+	symbols []int    // used symbols, in increasing order
+}
+
+// newPrefixCode creates a prefix code for symbols with histogram h,
+// with code lengths of at most limit.
+// Unused alphabets are coded as a single symbol 0.
+func newPrefixCode(h []int, limit int) *prefixCode {
+	c := &prefixCode{lengths: huffmanLengths(h, limit)}
+	for s, l := range c.lengths {
+		if l > 0 {
+			c.symbols = append(c.symbols, s)
+		}
+	}
+	if len(c.symbols) == 0 {
+		c.lengths[0] = 1
+		c.symbols = []int{0}
+	}
+	c.codes = canonicalCodes(c.lengths)
+	return c
+}
+
+// writeSymbol writes the code of symbol s.
+// A code with a single symbol takes no bits.
+func (c *prefixCode) writeSymbol(w *bitWriter, s int) {
+	if len(c.symbols) > 1 {
+		w.writeBits(c.codes[s], uint(c.lengths[s]))
+	}
+}
+
+// write writes the code lengths of c, using the simple form
+// for codes with up to two 8-bit symbols.
+func (c *prefixCode) write(w *bitWriter) {
+	if len(c.symbols) <= 2 && c.symbols[len(c.symbols)-1] < 256 {
+		w.writeBits(1, 1)
+		w.writeBits(uint32(len(c.symbols)-1), 1)
+		if c.symbols[0] < 2 {
+			w.writeBits(0, 1)
+			w.writeBits(uint32(c.symbols[0]), 1)
+		} else {
+			w.writeBits(1, 1)
+			w.writeBits(uint32(c.symbols[0]), 8)
+		}
+		if len(c.symbols) == 2 {
+			w.writeBits(uint32(c.symbols[1]), 8)
+		}
+		return
+	}
+
+	// code lengths, with runs of zeros coded by 17 and 18
+	type token struct {
+		sym   int
+		extra uint32
+		nbits uint
+	}
+	var toks []token
+	for i := 0; i < len(c.lengths); {
+		l := c.lengths[i]
+		run := 1
+		for i+run < len(c.lengths) && c.lengths[i+run] == l {
+			run++
+		}
+		i += run
+		if l != 0 {
+			for ; run > 0; run-- {
+				toks = append(toks, token{sym: int(l)})
+			}
+			continue
+		}
+		for run > 0 {
+			switch {
+			case run >= 11:
+				n := run
+				if n > 138 {
+					n = 138
+				}
+				toks = append(toks, token{18, uint32(n - 11), 7})
+				run -= n
+			case run >= 3:
+				toks = append(toks, token{17, uint32(run - 3), 3})
+				run = 0
+			default:
+				toks = append(toks, token{sym: 0})
+				run--
+			}
+		}
+	}
+	h := make([]int, len(vp8lCodeLengthOrder))
+	for _, t := range toks {
+		h[t.sym]++
+	}
+	clc := newPrefixCode(h, vp8lMaxCLCLength)
+	n := len(vp8lCodeLengthOrder)
+	for n > 4 && clc.lengths[vp8lCodeLengthOrder[n-1]] == 0 {
+		n--
+	}
+	w.writeBits(0, 1)
+	w.writeBits(uint32(n-4), 4)
+	for _, s := range vp8lCodeLengthOrder[:n] {
+		w.writeBits(uint32(clc.lengths[s]), 3)
+	}
+	w.writeBits(0, 1) // max_symbol is the alphabet size
+	for _, t := range toks {
+		clc.writeSymbol(w, t.sym)
+		w.writeBits(t.extra, t.nbits)
+	}
+}
+
+// huffmanLengths returns Huffman code lengths of symbols with histogram h,
+// limited to limit bits. Unused symbols have zero length, and a single
+// used symbol has length 1.
+// The limit is enforced by flattening the histogram until it is met.
+func huffmanLengths(h []int, limit int) []uint8 {
+	type node struct {
+		count       int
+		sym         int // leaf symbol, or -1
+		left, right int // child node indexes
+	}
+	counts := append([]int(nil), h...)
+	for {
+		var nodes []node
+		for s, c := range counts {
+			if c > 0 {
+				nodes = append(nodes, node{count: c, sym: s})
+			}
+		}
+		lengths := make([]uint8, len(h))
+		if len(nodes) == 0 {
+			return lengths
+		}
+		if len(nodes) == 1 {
+			lengths[nodes[0].sym] = 1
+			return lengths
+		}
+		sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].count < nodes[j].count })
+
+		// two queue construction: leaves are sorted,
+		// and internal nodes are created in increasing order of counts
+		nleaves := len(nodes)
+		li, ii := 0, nleaves
+		pick := func() int {
+			if li < nleaves && (ii >= len(nodes) || nodes[li].count <= nodes[ii].count) {
+				li++
+				return li - 1
+			}
+			ii++
+			return ii - 1
+		}
+		for k := 1; k < nleaves; k++ {
+			a, b := pick(), pick()
+			nodes = append(nodes, node{count: nodes[a].count + nodes[b].count, sym: -1, left: a, right: b})
+		}
+
+		depth := make([]int, len(nodes))
+		maxDepth := 0
+		for i := len(nodes) - 1; i >= nleaves; i-- {
+			depth[nodes[i].left] = depth[i] + 1
+			depth[nodes[i].right] = depth[i] + 1
+		}
+		for i := 0; i < nleaves; i++ {
+			lengths[nodes[i].sym] = uint8(depth[i])
+			if depth[i] > maxDepth {
+				maxDepth = depth[i]
+			}
+		}
+		if maxDepth <= limit {
+			return lengths
+		}
+		for s, c := range counts {
+			if c > 0 {
+				counts[s] = (c + 1) / 2
+			}
+		}
+	}
+}
+
+// canonicalCodes returns canonical codes of the given code lengths,
+// with bits reversed for writing least significant bit first.
+func canonicalCodes(lengths []uint8) []uint32 {
+	var count [vp8lMaxCodeLength + 1]uint32
+	for _, l := range lengths {
+		count[l]++
+	}
+	count[0] = 0
+	var next [vp8lMaxCodeLength + 1]uint32
+	code := uint32(0)
+	for l := 1; l <= vp8lMaxCodeLength; l++ {
+		code = (code + count[l-1]) << 1
+		next[l] = code
+	}
+	codes := make([]uint32, len(lengths))
+	for s, l := range lengths {
+		if l == 0 {
+			continue
+		}
+		c := next[l]
+		next[l]++
+		var r uint32
+		for k := uint8(0); k < l; k++ {
+			r = r<<1 | c>>k&1
+		}
+		codes[s] = r
+	}
+	return codes
+}
@@ -0,0 +1,130 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fetch
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+// testImage returns a w by h image with flat areas, gradients and noise.
+func testImage(w, h int, alpha bool) *image.NRGBA {
+	m := image.NewNRGBA(image.Rect(0, 0, w, h))
+	r := rand.New(rand.NewSource(1))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.NRGBA{R: 0xf0, G: 0xf0, B: 0xf0, A: 0xff}
+			switch {
+			case y < h/3:
+				c.R, c.G = uint8(x), uint8(y)
+			case x < w/3:
+				c = color.NRGBA{R: uint8(r.Intn(256)), G: uint8(r.Intn(256)), B: uint8(r.Intn(256)), A: 0xff}
+			}
+			if alpha {
+				c.A = uint8(x + y)
+			}
+			m.SetNRGBA(x, y, c)
+		}
+	}
+	return m
+}
+
+func TestEncodeWebP(t *testing.T) {
+	tests := []struct {
+		name string
+		m    image.Image
+	}{
+		{"1x1", image.NewNRGBA(image.Rect(0, 0, 1, 1))},
+		{"flat", image.NewNRGBA(image.Rect(0, 0, 64, 64))},
+		{"mixed", testImage(97, 61, false)},
+		{"alpha", testImage(40, 300, true)},
+		{"gray", image.NewGray(image.Rect(3, 5, 20, 9))},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			m := tc.m
+			var buf bytes.Buffer
+			if err := encodeWebP(&buf, m); err != nil {
+				t.Fatalf("encodeWebP: %v", err)
+			}
+			got, err := webp.Decode(&buf)
+			if err != nil {
+				t.Fatalf("webp.Decode: %v", err)
+			}
+			b := m.Bounds()
+			if got.Bounds().Dx() != b.Dx() || got.Bounds().Dy() != b.Dy() {
+				t.Fatalf("size = %v, want %v", got.Bounds().Size(), b.Size())
+			}
+			for y := 0; y < b.Dy(); y++ {
+				for x := 0; x < b.Dx(); x++ {
+					want := color.NRGBAModel.Convert(m.At(b.Min.X+x, b.Min.Y+y))
+					if c := color.NRGBAModel.Convert(got.At(x, y)); c != want {
+						t.Fatalf("pixel (%d, %d) = %v, want %v", x, y, c, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestVP8LPrefix(t *testing.T) {
+	tests := []struct {
+		v     int
+		code  int
+		nbits uint
+		extra uint32
+	}{
+		{1, 0, 0, 0},
+		{4, 3, 0, 0},
+		{5, 4, 1, 0},
+		{6, 4, 1, 1},
+		{7, 5, 1, 0},
+		{9, 6, 2, 0},
+		{4096, 23, 10, 1023},
+		{1 << 20, 39, 18, 1<<18 - 1},
+	}
+	for _, tc := range tests {
+		code, nbits, extra := vp8lPrefix(tc.v)
+		if code != tc.code || nbits != tc.nbits || extra != tc.extra {
+			t.Errorf("vp8lPrefix(%d) = %d, %d, %d; want %d, %d, %d", tc.v, code, nbits, extra, tc.code, tc.nbits, tc.extra)
+		}
+	}
+}
+
+func TestHuffmanLengths(t *testing.T) {
+	// Fibonacci counts make the deepest possible tree
+	h := make([]int, 30)
+	a, b := 1, 1
+	for i := range h {
+		h[i] = a
+		a, b = b, a+b
+	}
+	lengths := huffmanLengths(h, 7)
+	var kraft float64
+	for s, l := range lengths {
+		if l == 0 || l > 7 {
+			t.Fatalf("lengths[%d] = %d, want 1..7", s, l)
+		}
+		kraft += 1 / float64(uint(1)<<l)
+	}
+	if kraft != 1 {
+		t.Errorf("Kraft sum = %v, want 1", kraft)
+	}
+}
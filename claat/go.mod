@@ -7,6 +7,8 @@ require (
 	github.com/stoewer/go-strcase v1.2.0 // indirect
 	github.com/x1ddos/csslex v0.0.0-20160125172232-7894d8ab8bfe
 	github.com/yuin/goldmark v1.3.7
+	golang.org/x/image v0.0.0-20210628002857-a66eb6448b8d
 	golang.org/x/net v0.0.0-20210525063256-abc453219eb5
 	golang.org/x/oauth2 v0.0.0-20210514164344-f6687ab2804c
+	golang.org/x/text v0.3.6
 )
@@ -0,0 +1,214 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package i18n extracts translatable text of codelabs into translation
+// catalogs and merges translated catalogs back into codelabs.
+//
+// A catalog is a list of segments: the codelab title and summary, step titles,
+// runs of inline content such as paragraphs, list items and headers,
+// image alt text and titles, button labels and survey questions and options.
+// Code blocks, diagrams and display math are not translated.
+//
+// Inline formatting and links are represented in segment text with numbered
+// placeholders, which translations must keep:
+//
+//	Open <1>the console</1> and run <2/>.
+//
+// A paired placeholder, such as <1>...</1>, wraps bold or italic text
+// or a link, while a standalone one, such as <2/>, stands for inline code,
+// an inline image or math, which are kept as is.
+//
+// Catalogs are stored as XLIFF 2.0 or gettext PO files.
+package i18n
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/googlecodelabs/tools/claat/nodes"
+	"github.com/googlecodelabs/tools/claat/types"
+)
+
+// Segment is a unit of translation.
+type Segment struct {
+	ID      string // Stable ID, derived from Source
+	Source  string // Source text, with inline placeholders
+	Target  string // Translated text with the same placeholders, empty if not translated
+	Context string // Where the segment first appears, e.g. "Step 2: image alt"
+}
+
+// Catalog is a set of translations of a codelab.
+type Catalog struct {
+	ID         string // Codelab ID
+	SourceLang string // Language of the source codelab
+	TargetLang string // Language of translations, empty for source catalogs
+	Segments   []*Segment
+}
+
+// SegmentID returns a stable ID of a segment with source text src.
+// Segments with the same source text share the ID, and so the translation.
+func SegmentID(src string) string {
+	h := sha256.Sum256([]byte(src))
+	return hex.EncodeToString(h[:8])
+}
+
+// Extract returns a catalog of translatable segments of clab.
+// Source language of the catalog is the codelab language, or defaultLang
+// if the codelab has no language metadata. Target language is left empty.
+func Extract(clab *types.Codelab, defaultLang string) *Catalog {
+	cat := &Catalog{ID: clab.ID, SourceLang: clab.Lang}
+	if cat.SourceLang == "" {
+		cat.SourceLang = defaultLang
+	}
+	seen := make(map[string]bool)
+	w := &walker{fn: func(where, src string) (string, bool) {
+		id := SegmentID(src)
+		if !seen[id] {
+			seen[id] = true
+			cat.Segments = append(cat.Segments, &Segment{ID: id, Source: src, Context: where})
+		}
+		return "", false
+	}}
+	w.codelab(clab)
+	return cat
+}
+
+// translateFunc returns translation of a segment with source text src,
+// found at where, or false to keep the source.
+type translateFunc func(where, src string) (string, bool)
+
+// walker visits translatable text of a codelab, replacing it
+// with translations returned by fn.
+type walker struct {
+	fn   translateFunc
+	errs []error // translations which could not be applied
+}
+
+// text translates plain text, which has no placeholders.
+func (w *walker) text(where string, s *string) {
+	if !hasText(*s) {
+		return
+	}
+	t, ok := w.fn(where, *s)
+	if !ok {
+		return
+	}
+	if tt := tokenize(t); len(tt) != 1 || tt[0].id != 0 {
+		w.errs = append(w.errs, fmt.Errorf("%s: %q: unexpected placeholders", where, t))
+		return
+	}
+	*s = t
+}
+
+func (w *walker) codelab(clab *types.Codelab) {
+	w.text("Codelab title", &clab.Title)
+	w.text("Codelab summary", &clab.Summary)
+	for i, s := range clab.Steps {
+		where := fmt.Sprintf("Step %d", i+1)
+		w.text(where+": title", &s.Title)
+		w.list(where, s.Content)
+	}
+}
+
+// list visits content of l, translating runs of inline nodes
+// as single segments.
+func (w *walker) list(where string, l *nodes.ListNode) {
+	if l == nil {
+		return
+	}
+	var res []nodes.Node
+	for i := 0; i < len(l.Nodes); {
+		if !isRunNode(l.Nodes[i]) {
+			w.node(where, l.Nodes[i])
+			res = append(res, l.Nodes[i])
+			i++
+			continue
+		}
+		j := i + 1
+		for j < len(l.Nodes) && isRunNode(l.Nodes[j]) {
+			j++
+		}
+		res = append(res, w.run(where, l.Nodes[i:j])...)
+		i = j
+	}
+	l.Nodes = res
+}
+
+// run translates a run of inline nodes, returning the translated nodes.
+func (w *walker) run(where string, run []nodes.Node) []nodes.Node {
+	// inline images are kept as is, except for their alt text
+	for _, img := range nodes.ImageNodes(run) {
+		w.image(where, img)
+	}
+	var e encoder
+	e.encode(run)
+	src := e.b.String()
+	if !hasText(src) {
+		return run
+	}
+	t, ok := w.fn(where, src)
+	if !ok {
+		return run
+	}
+	res, err := decode(t, e.codes, run[0].Env())
+	if err != nil {
+		w.errs = append(w.errs, fmt.Errorf("%s: %q: %v", where, t, err))
+		return run
+	}
+	return res
+}
+
+func (w *walker) image(where string, n *nodes.ImageNode) {
+	w.text(where+": image alt", &n.Alt)
+	w.text(where+": image title", &n.Title)
+}
+
+// node visits translatable text of block node n and its children.
+func (w *walker) node(where string, n nodes.Node) {
+	switch n := n.(type) {
+	case *nodes.ListNode:
+		w.list(where, n)
+	case *nodes.HeaderNode:
+		w.list(where+": header", n.Content)
+	case *nodes.ItemsListNode:
+		for _, it := range n.Items {
+			w.list(where+": list item", it)
+		}
+	case *nodes.GridNode:
+		for _, r := range n.Rows {
+			for _, c := range r {
+				w.list(where+": table cell", c.Content)
+			}
+		}
+	case *nodes.ButtonNode:
+		w.list(where+": button", n.Content)
+	case *nodes.InfoboxNode:
+		w.list(where+": infobox", n.Content)
+	case *nodes.ImportNode:
+		w.list(where, n.Content)
+	case *nodes.CustomNode:
+		w.list(where, n.Content)
+	case *nodes.ImageNode:
+		w.image(where, n)
+	case *nodes.SurveyNode:
+		for _, g := range n.Groups {
+			w.text(where+": survey question", &g.Name)
+			for i := range g.Options {
+				w.text(where+": survey option", &g.Options[i])
+			}
+			w.text(where+": survey explanation", &g.Explanation)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package i18n
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googlecodelabs/tools/claat/nodes"
+	"github.com/googlecodelabs/tools/claat/types"
+)
+
+// testCodelab returns a codelab with all kinds of translatable content.
+func testCodelab() *types.Codelab {
+	clab := types.NewCodelab()
+	clab.ID = "test"
+	clab.Title = "Test Codelab"
+	s := clab.NewStep("Set up")
+	h := nodes.NewHeaderNode(2, text("Overview"))
+	h.MutateBlock(true)
+	p := nodes.NewListNode(text("Open "), nodes.NewURLNode("https://example.com", text("the console")), text(" and run "), code("claat"), text("."))
+	p.MutateBlock(true)
+	cb := nodes.NewCodeNode("fmt.Println(\"Hello\")\n", false, "go")
+	cb.MutateBlock(true)
+	img := nodes.NewImageNode(nodes.NewImageNodeOptions{Src: "img/a.png", Alt: "Architecture"})
+	items := nodes.NewItemsListNode("", 0)
+	items.NewItem(text("Open "), nodes.NewURLNode("https://example.com", text("the console")), text(" and run "), code("claat"), text("."))
+	btn := nodes.NewButtonNode(true, true, true, nodes.NewURLNode("https://example.com/a.zip", text("Download")))
+	btn.MutateBlock(true)
+	s.Content.Append(h, p, cb, nodes.NewListNode(img), items, btn,
+		nodes.NewSurveyNode("quiz", &nodes.SurveyGroup{
+			Name:    "Which one?",
+			Options: []string{"This", "That"},
+			Correct: []int{1},
+		}),
+	)
+	clab.Quiz = types.NewQuiz(clab.Steps)
+	return clab
+}
+
+func TestExtract(t *testing.T) {
+	cat := Extract(testCodelab(), "en")
+	var got []*Segment
+	for _, s := range cat.Segments {
+		got = append(got, &Segment{Source: s.Source, Context: s.Context})
+		if s.ID != SegmentID(s.Source) {
+			t.Errorf("segment %q ID = %q, want %q", s.Source, s.ID, SegmentID(s.Source))
+		}
+	}
+	want := []*Segment{
+		{Source: "Test Codelab", Context: "Codelab title"},
+		{Source: "Set up", Context: "Step 1: title"},
+		{Source: "Overview", Context: "Step 1: header"},
+		{Source: "Open <1>the console</1> and run <2/>.", Context: "Step 1"},
+		{Source: "Architecture", Context: "Step 1: image alt"},
+		{Source: "<1>Download</1>", Context: "Step 1: button"},
+		{Source: "Which one?", Context: "Step 1: survey question"},
+		{Source: "This", Context: "Step 1: survey option"},
+		{Source: "That", Context: "Step 1: survey option"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Extract() got diff (-want +got):\n%s", diff)
+	}
+	if cat.ID != "test" || cat.SourceLang != "en" || cat.TargetLang != "" {
+		t.Errorf("Extract() = %+v, want ID test and source language en", cat)
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package i18n
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/googlecodelabs/tools/claat/nodes"
+)
+
+// placeholderRegexp matches inline placeholders: <1>, </1> and <1/>.
+var placeholderRegexp = regexp.MustCompile(`<(/?)([0-9]+)(/?)>`)
+
+// token is a piece of segment text: either text or a placeholder.
+type token struct {
+	text  string
+	id    int  // placeholder id, 0 for text
+	close bool // closing tag of a paired placeholder, </1>
+	empty bool // standalone placeholder, <1/>
+}
+
+// tokenize splits segment text s into text and placeholder tokens.
+func tokenize(s string) []token {
+	var tt []token
+	var last int
+	for _, m := range placeholderRegexp.FindAllStringSubmatchIndex(s, -1) {
+		if m[0] > last {
+			tt = append(tt, token{text: s[last:m[0]]})
+		}
+		last = m[1]
+		close, empty := m[3] > m[2], m[7] > m[6]
+		if close && empty {
+			// not a placeholder, e.g. "</1/>"
+			tt = append(tt, token{text: s[m[0]:m[1]]})
+			continue
+		}
+		id, err := strconv.Atoi(s[m[4]:m[5]])
+		if err != nil || id == 0 {
+			tt = append(tt, token{text: s[m[0]:m[1]]})
+			continue
+		}
+		tt = append(tt, token{id: id, close: close, empty: empty})
+	}
+	if last < len(s) {
+		tt = append(tt, token{text: s[last:]})
+	}
+	return tt
+}
+
+// hasText reports whether segment text s has any letters
+// outside of placeholders, i.e. whether it needs translation.
+func hasText(s string) bool {
+	for _, t := range tokenize(s) {
+		if t.id == 0 && strings.IndexFunc(t.text, unicode.IsLetter) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// isRunNode reports whether n is part of a run of inline content,
+// which is translated as a single segment.
+func isRunNode(n nodes.Node) bool {
+	switch n := n.(type) {
+	case *nodes.TextNode, *nodes.URLNode:
+		return true
+	case *nodes.ImageNode:
+		return n.Block() == nil
+	case *nodes.MathNode:
+		return !n.Display
+	}
+	return false
+}
+
+// encoder converts a run of inline nodes into segment text.
+//
+// Formatted text and links are replaced with paired placeholders,
+// e.g. "Open <1>the console</1>", while inline code, images and math
+// are replaced with standalone placeholders, e.g. "Run <2/>".
+// Placeholders are numbered from 1, in the order of appearance.
+type encoder struct {
+	b     strings.Builder
+	codes []nodes.Node // original nodes of placeholders, by id-1
+}
+
+func (e *encoder) add(n nodes.Node) int {
+	e.codes = append(e.codes, n)
+	return len(e.codes)
+}
+
+func (e *encoder) encode(nn []nodes.Node) {
+	for _, n := range nn {
+		switch n := n.(type) {
+		case *nodes.TextNode:
+			switch {
+			case n.Code:
+				fmt.Fprintf(&e.b, "<%d/>", e.add(n))
+			case n.Bold || n.Italic:
+				id := e.add(n)
+				fmt.Fprintf(&e.b, "<%d>%s</%d>", id, n.Value, id)
+			default:
+				e.b.WriteString(n.Value)
+			}
+		case *nodes.URLNode:
+			id := e.add(n)
+			fmt.Fprintf(&e.b, "<%d>", id)
+			e.encode(n.Content.Nodes)
+			fmt.Fprintf(&e.b, "</%d>", id)
+		default:
+			fmt.Fprintf(&e.b, "<%d/>", e.add(n))
+		}
+	}
+}
+
+// paired reports whether n is replaced with a paired placeholder.
+func paired(n nodes.Node) bool {
+	switch n := n.(type) {
+	case *nodes.TextNode:
+		return !n.Code
+	case *nodes.URLNode:
+		return true
+	}
+	return false
+}
+
+// decode converts translated segment text s back into inline nodes,
+// using the original nodes of placeholders in codes.
+// Every placeholder of the source must be used exactly once, and paired
+// placeholders must be properly nested.
+// Text nodes which are not in a placeholder get environment env.
+func decode(s string, codes []nodes.Node, env []string) ([]nodes.Node, error) {
+	type frame struct {
+		id   int
+		code nodes.Node
+		nn   []nodes.Node
+	}
+	stack := []*frame{{}}
+	used := make([]bool, len(codes))
+	for _, t := range tokenize(s) {
+		top := stack[len(stack)-1]
+		if t.id == 0 {
+			if t.text == "" {
+				continue
+			}
+			tn := nodes.NewTextNode(nodes.NewTextNodeOptions{Value: t.text})
+			tn.MutateEnv(env)
+			// text gets formatting of all enclosing placeholders
+			for _, f := range stack {
+				if c, ok := f.code.(*nodes.TextNode); ok {
+					tn.Bold = tn.Bold || c.Bold
+					tn.Italic = tn.Italic || c.Italic
+					tn.MutateEnv(c.Env())
+				}
+			}
+			top.nn = append(top.nn, tn)
+			continue
+		}
+		if t.id > len(codes) {
+			return nil, fmt.Errorf("placeholder %d is not in the source", t.id)
+		}
+		code := codes[t.id-1]
+		if t.close {
+			if top.id != t.id {
+				return nil, fmt.Errorf("placeholder </%d> does not match an opening <%d>", t.id, t.id)
+			}
+			stack = stack[:len(stack)-1]
+			parent := stack[len(stack)-1]
+			if u, ok := code.(*nodes.URLNode); ok {
+				un := nodes.NewURLNode(u.URL, top.nn...)
+				un.Name = u.Name
+				un.Target = u.Target
+				un.MutateEnv(u.Env())
+				parent.nn = append(parent.nn, un)
+				continue
+			}
+			parent.nn = append(parent.nn, top.nn...)
+			continue
+		}
+		if used[t.id-1] {
+			return nil, fmt.Errorf("placeholder %d is used more than once", t.id)
+		}
+		used[t.id-1] = true
+		if t.empty != !paired(code) {
+			return nil, fmt.Errorf("placeholder %d has a wrong form", t.id)
+		}
+		if t.empty {
+			top.nn = append(top.nn, code)
+			continue
+		}
+		stack = append(stack, &frame{id: t.id, code: code})
+	}
+	if len(stack) > 1 {
+		return nil, fmt.Errorf("placeholder <%d> is not closed", stack[len(stack)-1].id)
+	}
+	for i, u := range used {
+		if !u {
+			return nil, fmt.Errorf("placeholder %d is missing", i+1)
+		}
+	}
+	return stack[0].nn, nil
+}
@@ -0,0 +1,127 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package i18n
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googlecodelabs/tools/claat/nodes"
+)
+
+func text(v string) *nodes.TextNode {
+	return nodes.NewTextNode(nodes.NewTextNodeOptions{Value: v})
+}
+
+func bold(v string) *nodes.TextNode {
+	return nodes.NewTextNode(nodes.NewTextNodeOptions{Value: v, Bold: true})
+}
+
+func code(v string) *nodes.TextNode {
+	return nodes.NewTextNode(nodes.NewTextNodeOptions{Value: v, Code: true})
+}
+
+func TestTokenize(t *testing.T) {
+	got := tokenize("a <1>b</1><2/> </3/> <0> c")
+	want := []token{
+		{text: "a "},
+		{id: 1},
+		{text: "b"},
+		{id: 1, close: true},
+		{id: 2, empty: true},
+		{text: " "},
+		{text: "</3/>"},
+		{text: " "},
+		{text: "<0>"},
+		{text: " c"},
+	}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(token{})); diff != "" {
+		t.Errorf("tokenize() got diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	run := []nodes.Node{
+		text("Open "),
+		nodes.NewURLNode("https://example.com", text("the "), bold("console")),
+		text(" and run "),
+		code("claat"),
+		text("."),
+	}
+	var e encoder
+	e.encode(run)
+	want := "Open <1>the <2>console</2></1> and run <3/>."
+	if got := e.b.String(); got != want {
+		t.Errorf("encode() = %q, want %q", got, want)
+	}
+	if len(e.codes) != 3 || e.codes[2] != run[3] {
+		t.Errorf("encode() codes = %v", e.codes)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	run := []nodes.Node{
+		text("Open "),
+		nodes.NewURLNode("https://example.com", text("the "), bold("console")),
+		text(" and run "),
+		code("claat"),
+	}
+	var e encoder
+	e.encode(run)
+
+	got, err := decode("<3/> を <1><2>コンソール</2>で</1>実行", e.codes, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []nodes.Node{
+		run[3],
+		text(" を "),
+		nodes.NewURLNode("https://example.com", bold("コンソール"), text("で")),
+		text("実行"),
+	}
+	if diff := nodes.Diff(want, got); diff != "" {
+		t.Errorf("decode() got diff (-want +got):\n%s", diff)
+	}
+
+	for _, s := range []string{
+		"<1>the <2>console</2></1>",          // missing placeholder
+		"<1><2>console</2></1> <3/> <3/>",    // duplicate placeholder
+		"<1><2>console</1></2> <3/>",         // wrong nesting
+		"<1><2>console</2></1> <3>claat</3>", // wrong form
+		"<1><2>console</2></1> <3/> <4/>",    // unknown placeholder
+		"<1><2>console</2> <3/>",             // not closed
+	} {
+		if _, err := decode(s, e.codes, nil); err == nil {
+			t.Errorf("decode(%q) returned nil error", s)
+		}
+	}
+}
+
+func TestHasText(t *testing.T) {
+	tests := map[string]bool{
+		"":             false,
+		"<1/>":         false,
+		"<1/>: 42.":    false,
+		"<1>x</1>":     true,
+		"Run <1/>":     true,
+		"日本語":          true,
+		"  <1/> <2/> ": false,
+	}
+	for s, want := range tests {
+		if got := hasText(s); got != want {
+			t.Errorf("hasText(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package i18n
+
+import (
+	"strings"
+
+	"github.com/googlecodelabs/tools/claat/types"
+)
+
+// FuzzyThreshold is the minimum similarity of source texts, from 0 to 1,
+// for a translation of a changed segment to be used. Similarity is computed
+// from the word edit distance of the two texts.
+const FuzzyThreshold = 0.75
+
+// MergeResult reports how segments of a codelab were translated by Merge.
+type MergeResult struct {
+	Exact        int     // Segments translated by ID
+	Fuzzy        int     // Changed segments translated with a similar segment
+	Untranslated int     // Segments left in the source language
+	Errors       []error // Translations which could not be applied, e.g. due to missing placeholders
+}
+
+// Merge translates clab in place with translations of cat,
+// setting the codelab language to cat.TargetLang.
+//
+// A segment is looked up by its ID first. When the source text has changed
+// since cat was extracted, the translation of the most similar segment
+// is used, if the similarity is at least FuzzyThreshold. Segments without
+// a translation, and translations with placeholders not matching the source,
+// are left in the source language.
+func Merge(clab *types.Codelab, cat *Catalog) *MergeResult {
+	res := &MergeResult{}
+	byID := make(map[string]*Segment)
+	var fuzzy []*Segment
+	for _, s := range cat.Segments {
+		if s.Target == "" {
+			continue
+		}
+		byID[s.ID] = s
+		fuzzy = append(fuzzy, s)
+	}
+
+	w := &walker{fn: func(where, src string) (string, bool) {
+		if s, ok := byID[SegmentID(src)]; ok {
+			res.Exact++
+			return s.Target, true
+		}
+		if s := closest(src, fuzzy); s != nil {
+			res.Fuzzy++
+			return s.Target, true
+		}
+		res.Untranslated++
+		return "", false
+	}}
+	w.codelab(clab)
+	res.Errors = w.errs
+	if cat.TargetLang != "" {
+		clab.Lang = cat.TargetLang
+	}
+	clab.Quiz = types.NewQuiz(clab.Steps)
+	return res
+}
+
+// closest returns the segment of ss with source text most similar to src,
+// or nil if none is similar enough.
+func closest(src string, ss []*Segment) *Segment {
+	words := strings.Fields(src)
+	var best *Segment
+	minSim := FuzzyThreshold
+	for _, s := range ss {
+		sw := strings.Fields(s.Source)
+		// skip segments which cannot be similar enough by length alone
+		n, m := len(words), len(sw)
+		if n > m {
+			n, m = m, n
+		}
+		if m == 0 || float64(n)/float64(m) < minSim {
+			continue
+		}
+		if sim := similarity(words, sw); sim >= minSim {
+			best, minSim = s, sim
+		}
+	}
+	return best
+}
+
+// similarity returns 1 minus the edit distance of words a and b,
+// relative to the length of the longer one.
+func similarity(a, b []string) float64 {
+	if len(a) < len(b) {
+		a, b = b, a
+	}
+	if len(a) == 0 {
+		return 1
+	}
+	// Levenshtein distance with a single row
+	row := make([]int, len(b)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		prev := row[0]
+		row[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur := min3(row[j]+1, row[j-1]+1, prev+cost)
+			prev, row[j] = row[j], cur
+		}
+	}
+	return 1 - float64(row[len(b)])/float64(len(a))
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
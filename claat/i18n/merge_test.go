@@ -0,0 +1,100 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package i18n
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/googlecodelabs/tools/claat/nodes"
+)
+
+func TestMerge(t *testing.T) {
+	seg := func(src, tgt string) *Segment {
+		return &Segment{ID: SegmentID(src), Source: src, Target: tgt}
+	}
+	cat := &Catalog{
+		ID:         "test",
+		SourceLang: "en",
+		TargetLang: "ja",
+		Segments: []*Segment{
+			seg("Test Codelab", "テスト Codelab"),
+			seg("Overview", "概要"),
+			// source has changed since extraction
+			{ID: "old", Source: "Open <1>the console</1> and then run <2/>.", Target: "<1>コンソール</1>を開いて <2/> を実行します。"},
+			seg("Architecture", "アーキテクチャ"),
+			seg("<1>Download</1>", "<1>ダウンロード</1>"),
+			// broken placeholders
+			seg("Which one?", "<1>どれ</1>?"),
+			seg("That", "あれ"),
+			// not translated
+			seg("This", ""),
+		},
+	}
+	clab := testCodelab()
+	res := Merge(clab, cat)
+	if res.Exact != 6 || res.Fuzzy != 2 || res.Untranslated != 2 || len(res.Errors) != 1 {
+		t.Errorf("Merge() = %+v, want 6 exact, 2 fuzzy, 2 untranslated and 1 error", res)
+	}
+	if clab.Lang != "ja" {
+		t.Errorf("clab.Lang = %q, want ja", clab.Lang)
+	}
+	if clab.Title != "テスト Codelab" || clab.Steps[0].Title != "Set up" {
+		t.Errorf("titles = %q, %q, want translated codelab title only", clab.Title, clab.Steps[0].Title)
+	}
+
+	content := clab.Steps[0].Content.Nodes
+	p := content[1].(*nodes.ListNode)
+	want := []nodes.Node{
+		nodes.NewURLNode("https://example.com", text("コンソール")),
+		text("を開いて "),
+		code("claat"),
+		text(" を実行します。"),
+	}
+	if diff := nodes.Diff(want, p.Nodes); diff != "" {
+		t.Errorf("paragraph got diff (-want +got):\n%s", diff)
+	}
+	if !p.Block().(bool) {
+		t.Error("paragraph is no longer a block")
+	}
+	if img := nodes.ImageNodes(content)[0]; img.Alt != "アーキテクチャ" || img.Src != "img/a.png" {
+		t.Errorf("image = %+v, want translated alt", img)
+	}
+	survey := content[len(content)-1].(*nodes.SurveyNode)
+	if g := survey.Groups[0]; g.Name != "Which one?" || strings.Join(g.Options, ",") != "This,あれ" {
+		t.Errorf("survey = %q %q", g.Name, g.Options)
+	}
+	if q := clab.Quiz.Questions[0]; q.Options[1] != "あれ" {
+		t.Errorf("quiz options = %q, want translated", q.Options)
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"", "", 1},
+		{"a b c d", "a b c d", 1},
+		{"a b c d", "a x c d", 0.75},
+		{"a b c d", "a b c", 0.75},
+		{"a b", "c d", 0},
+	}
+	for _, tc := range tests {
+		if got := similarity(strings.Fields(tc.a), strings.Fields(tc.b)); got != tc.want {
+			t.Errorf("similarity(%q, %q) = %v, want %v", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package i18n
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// PO header fields of catalog metadata.
+const (
+	poLanguage       = "Language"
+	poSourceLanguage = "X-Source-Language"
+	poCodelab        = "X-Codelab-ID"
+)
+
+// WritePO writes cat to w in gettext PO format.
+//
+// Segment IDs are stored as message contexts (msgctxt) and segment
+// contexts as extracted comments. Catalog metadata is stored
+// in the header entry.
+func WritePO(w io.Writer, cat *Catalog) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# Translations of codelab %s.\n", cat.ID)
+	bw.WriteString("msgid \"\"\n")
+	writePOString(bw, "msgstr", strings.Join([]string{
+		"Content-Type: text/plain; charset=UTF-8",
+		poLanguage + ": " + cat.TargetLang,
+		poSourceLanguage + ": " + cat.SourceLang,
+		poCodelab + ": " + cat.ID,
+	}, "\n")+"\n")
+	for _, s := range cat.Segments {
+		bw.WriteString("\n")
+		if s.Context != "" {
+			fmt.Fprintf(bw, "#. %s\n", s.Context)
+		}
+		writePOString(bw, "msgctxt", s.ID)
+		writePOString(bw, "msgid", s.Source)
+		writePOString(bw, "msgstr", s.Target)
+	}
+	return bw.Flush()
+}
+
+// writePOString writes keyword followed by quoted s,
+// split into lines after each newline.
+func writePOString(w *bufio.Writer, keyword, s string) {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) <= 1 {
+		fmt.Fprintf(w, "%s %s\n", keyword, quotePO(s))
+		return
+	}
+	fmt.Fprintf(w, "%s \"\"\n", keyword)
+	for _, l := range lines {
+		fmt.Fprintf(w, "%s\n", quotePO(l))
+	}
+}
+
+var poEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`, "\r", `\r`)
+
+func quotePO(s string) string {
+	return `"` + poEscaper.Replace(s) + `"`
+}
+
+// ReadPO reads a catalog in gettext PO format, as written by WritePO.
+//
+// Entries without a message context get an ID derived from msgid.
+// Entries marked as fuzzy are read as not translated, as in gettext.
+func ReadPO(r io.Reader) (*Catalog, error) {
+	cat := &Catalog{}
+	var (
+		entry  = make(map[string]string) // keyword -> value
+		key    string                    // keyword of the current value
+		fuzzy  bool
+		note   string
+		lineno int
+	)
+	flush := func() {
+		defer func() {
+			entry = make(map[string]string)
+			key, fuzzy, note = "", false, ""
+		}()
+		id, ok := entry["msgid"]
+		if !ok {
+			return
+		}
+		if id == "" {
+			readPOHeader(cat, entry["msgstr"])
+			return
+		}
+		s := &Segment{ID: entry["msgctxt"], Source: id, Target: entry["msgstr"], Context: note}
+		if s.ID == "" {
+			s.ID = SegmentID(id)
+		}
+		if fuzzy {
+			s.Target = ""
+		}
+		cat.Segments = append(cat.Segments, s)
+	}
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		lineno++
+		line := strings.TrimSpace(sc.Text())
+		if _, ok := entry["msgstr"]; ok && strings.HasPrefix(line, "#") {
+			// comments of a new entry without a separating blank line
+			flush()
+		}
+		switch {
+		case line == "":
+			flush()
+		case strings.HasPrefix(line, "#,"):
+			fuzzy = fuzzy || strings.Contains(line, "fuzzy")
+		case strings.HasPrefix(line, "#."):
+			note = strings.TrimSpace(line[2:])
+		case strings.HasPrefix(line, "#"):
+			// translator comments and references
+		case strings.HasPrefix(line, `"`):
+			if key == "" {
+				return nil, fmt.Errorf("line %d: string without a keyword", lineno)
+			}
+			v, err := strconv.Unquote(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineno, err)
+			}
+			entry[key] += v
+		default:
+			i := strings.IndexByte(line, ' ')
+			if i < 0 {
+				return nil, fmt.Errorf("line %d: missing string", lineno)
+			}
+			k := line[:i]
+			_, dup := entry[k]
+			_, hasID := entry["msgid"]
+			if dup || k == "msgctxt" && hasID {
+				// a new entry without a separating blank line
+				flush()
+			}
+			v, err := strconv.Unquote(strings.TrimSpace(line[i:]))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineno, err)
+			}
+			key = k
+			entry[key] = v
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+	return cat, nil
+}
+
+// readPOHeader sets catalog metadata from header entry h.
+func readPOHeader(cat *Catalog, h string) {
+	for _, l := range strings.Split(h, "\n") {
+		i := strings.IndexByte(l, ':')
+		if i < 0 {
+			continue
+		}
+		v := strings.TrimSpace(l[i+1:])
+		switch strings.TrimSpace(l[:i]) {
+		case poLanguage:
+			cat.TargetLang = v
+		case poSourceLanguage:
+			cat.SourceLang = v
+		case poCodelab:
+			cat.ID = v
+		}
+	}
+}
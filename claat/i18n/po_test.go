@@ -0,0 +1,94 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package i18n
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func testCatalog() *Catalog {
+	seg := func(src, tgt, ctx string) *Segment {
+		return &Segment{ID: SegmentID(src), Source: src, Target: tgt, Context: ctx}
+	}
+	return &Catalog{
+		ID:         "test",
+		SourceLang: "en",
+		TargetLang: "ja",
+		Segments: []*Segment{
+			seg("Test Codelab", "テスト Codelab", "Codelab title"),
+			seg("Open <1>the \"console\"</1> & run <2/>.", "<1>コンソール</1>を開いて <2/> を実行", "Step 1"),
+			seg("First line\nSecond line\n", "", "Step 2: list item"),
+		},
+	}
+}
+
+func TestPO(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WritePO(&buf, testCatalog()); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		`"Language: ja\n"`,
+		"#. Codelab title\nmsgctxt \"" + SegmentID("Test Codelab") + "\"\n",
+		`msgid "Open <1>the \"console\"</1> & run <2/>."`,
+		"msgid \"\"\n\"First line\\n\"\n\"Second line\\n\"\n",
+	} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("WritePO() output does not contain %q:\n%s", s, buf.String())
+		}
+	}
+	cat, err := ReadPO(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(testCatalog(), cat); diff != "" {
+		t.Errorf("ReadPO() got diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestReadPO(t *testing.T) {
+	po := `msgid ""
+msgstr "Language: fr\n"
+# translator comment
+#, fuzzy
+msgid "Hello"
+msgstr "Bonjour"
+#: reference
+msgid "World"
+msgstr "Monde"
+`
+	cat, err := ReadPO(strings.NewReader(po))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Catalog{
+		TargetLang: "fr",
+		Segments: []*Segment{
+			{ID: SegmentID("Hello"), Source: "Hello"},
+			{ID: SegmentID("World"), Source: "World", Target: "Monde"},
+		},
+	}
+	if diff := cmp.Diff(want, cat); diff != "" {
+		t.Errorf("ReadPO() got diff (-want +got):\n%s", diff)
+	}
+
+	if _, err := ReadPO(strings.NewReader("msgid \"unterminated\n")); err == nil {
+		t.Error("ReadPO() with a broken string returned nil error")
+	}
+}
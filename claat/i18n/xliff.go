@@ -0,0 +1,207 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package i18n
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type xliffDoc struct {
+	XMLName xml.Name    `xml:"urn:oasis:names:tc:xliff:document:2.0 xliff"`
+	Version string      `xml:"version,attr"`
+	SrcLang string      `xml:"srcLang,attr"`
+	TrgLang string      `xml:"trgLang,attr,omitempty"`
+	Files   []xliffFile `xml:"file"`
+}
+
+type xliffFile struct {
+	ID    string      `xml:"id,attr"`
+	Units []xliffUnit `xml:"unit"`
+}
+
+type xliffUnit struct {
+	ID       string         `xml:"id,attr"`
+	Notes    []xliffNote    `xml:"notes>note"`
+	Segments []xliffSegment `xml:"segment"`
+}
+
+type xliffNote struct {
+	Category string `xml:"category,attr,omitempty"`
+	Text     string `xml:",chardata"`
+}
+
+type xliffSegment struct {
+	Source xliffText  `xml:"source"`
+	Target *xliffText `xml:"target"`
+}
+
+// xliffText is segment content with inline markup.
+type xliffText struct {
+	Inner string `xml:",innerxml"`
+}
+
+// WriteXLIFF writes cat to w as an XLIFF 2.0 document.
+//
+// Each segment is a unit with the segment ID, and its context
+// is a note of the "location" category. Paired placeholders are written
+// as <pc> elements and standalone ones as <ph> elements.
+func WriteXLIFF(w io.Writer, cat *Catalog) error {
+	doc := &xliffDoc{
+		Version: "2.0",
+		SrcLang: cat.SourceLang,
+		TrgLang: cat.TargetLang,
+		Files:   []xliffFile{{ID: cat.ID}},
+	}
+	for _, s := range cat.Segments {
+		u := xliffUnit{ID: s.ID}
+		if s.Context != "" {
+			u.Notes = []xliffNote{{Category: "location", Text: s.Context}}
+		}
+		seg := xliffSegment{Source: xliffText{toXLIFF(s.Source)}}
+		if s.Target != "" || cat.TargetLang != "" {
+			seg.Target = &xliffText{toXLIFF(s.Target)}
+		}
+		u.Segments = []xliffSegment{seg}
+		doc.Files[0].Units = append(doc.Files[0].Units, u)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// toXLIFF converts segment text s to XLIFF inline content.
+func toXLIFF(s string) string {
+	var b bytes.Buffer
+	for _, t := range tokenize(s) {
+		switch {
+		case t.id == 0:
+			xml.EscapeText(&b, []byte(t.text))
+		case t.empty:
+			fmt.Fprintf(&b, `<ph id="%d"/>`, t.id)
+		case t.close:
+			b.WriteString("</pc>")
+		default:
+			fmt.Fprintf(&b, `<pc id="%d">`, t.id)
+		}
+	}
+	return b.String()
+}
+
+// ReadXLIFF reads a catalog from an XLIFF 2.0 document, as written
+// by WriteXLIFF. Units of all files of the document are read,
+// using the ID of the first file as the codelab ID.
+func ReadXLIFF(r io.Reader) (*Catalog, error) {
+	var doc xliffDoc
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+	if doc.Version != "" && !strings.HasPrefix(doc.Version, "2.") {
+		return nil, fmt.Errorf("unsupported XLIFF version %q", doc.Version)
+	}
+	cat := &Catalog{SourceLang: doc.SrcLang, TargetLang: doc.TrgLang}
+	for i, f := range doc.Files {
+		if i == 0 {
+			cat.ID = f.ID
+		}
+		for _, u := range f.Units {
+			s := &Segment{ID: u.ID}
+			for _, n := range u.Notes {
+				if n.Category == "location" {
+					s.Context = strings.TrimSpace(n.Text)
+				}
+			}
+			// a unit may be split into multiple segments
+			for _, seg := range u.Segments {
+				src, err := fromXLIFF(seg.Source.Inner)
+				if err != nil {
+					return nil, fmt.Errorf("unit %s: %v", u.ID, err)
+				}
+				s.Source += src
+				if seg.Target == nil {
+					continue
+				}
+				tgt, err := fromXLIFF(seg.Target.Inner)
+				if err != nil {
+					return nil, fmt.Errorf("unit %s: %v", u.ID, err)
+				}
+				s.Target += tgt
+			}
+			if s.ID == "" {
+				s.ID = SegmentID(s.Source)
+			}
+			cat.Segments = append(cat.Segments, s)
+		}
+	}
+	return cat, nil
+}
+
+// fromXLIFF converts XLIFF inline content to segment text.
+// Elements other than <pc> and <ph> are ignored, keeping their text.
+func fromXLIFF(inner string) (string, error) {
+	var b strings.Builder
+	var stack []string // ids of open elements, empty for ignored ones
+	d := xml.NewDecoder(strings.NewReader(inner))
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return b.String(), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		switch tok := tok.(type) {
+		case xml.CharData:
+			b.Write(tok)
+		case xml.StartElement:
+			var id string
+			for _, a := range tok.Attr {
+				if a.Name.Local == "id" {
+					id = a.Value
+				}
+			}
+			if _, err := strconv.Atoi(id); err != nil && (tok.Name.Local == "pc" || tok.Name.Local == "ph") {
+				return "", fmt.Errorf("<%s> with invalid id %q", tok.Name.Local, id)
+			}
+			switch tok.Name.Local {
+			case "pc":
+				fmt.Fprintf(&b, "<%s>", id)
+				stack = append(stack, id)
+			case "ph":
+				fmt.Fprintf(&b, "<%s/>", id)
+				stack = append(stack, "")
+			default:
+				stack = append(stack, "")
+			}
+		case xml.EndElement:
+			id := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if tok.Name.Local == "pc" {
+				fmt.Fprintf(&b, "</%s>", id)
+			}
+		}
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package i18n
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestXLIFF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteXLIFF(&buf, testCatalog()); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		`<xliff xmlns="urn:oasis:names:tc:xliff:document:2.0" version="2.0" srcLang="en" trgLang="ja">`,
+		`<file id="test">`,
+		`<unit id="` + SegmentID("Test Codelab") + `">`,
+		`<note category="location">Codelab title</note>`,
+		`<source>Open <pc id="1">the &#34;console&#34;</pc> &amp; run <ph id="2"/>.</source>`,
+		`<target><pc id="1">コンソール</pc>を開いて <ph id="2"/> を実行</target>`,
+	} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("WriteXLIFF() output does not contain %s:\n%s", s, buf.String())
+		}
+	}
+	cat, err := ReadXLIFF(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(testCatalog(), cat); diff != "" {
+		t.Errorf("ReadXLIFF() got diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestReadXLIFF(t *testing.T) {
+	doc := `<?xml version="1.0"?>
+<xliff xmlns="urn:oasis:names:tc:xliff:document:2.0" version="2.1" srcLang="en" trgLang="de">
+ <file id="codelab">
+  <unit id="u1">
+   <segment><source>Run <ph id="1" disp="claat"/>.</source><target>Führe <ph id="1"/> aus.</target></segment>
+   <segment><source> <pc id="2"><mrk id="m1">Now</mrk></pc></source><target> <pc id="2">Jetzt</pc></target></segment>
+  </unit>
+  <unit id="u2"><segment><source>Hello</source></segment></unit>
+ </file>
+</xliff>`
+	cat, err := ReadXLIFF(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Catalog{
+		ID:         "codelab",
+		SourceLang: "en",
+		TargetLang: "de",
+		Segments: []*Segment{
+			{ID: "u1", Source: "Run <1/>. <2>Now</2>", Target: "Führe <1/> aus. <2>Jetzt</2>"},
+			{ID: "u2", Source: "Hello"},
+		},
+	}
+	if diff := cmp.Diff(want, cat); diff != "" {
+		t.Errorf("ReadXLIFF() got diff (-want +got):\n%s", diff)
+	}
+
+	doc = `<xliff xmlns="urn:oasis:names:tc:xliff:document:2.0" version="1.2"></xliff>`
+	if _, err := ReadXLIFF(strings.NewReader(doc)); err == nil {
+		t.Error("ReadXLIFF() of XLIFF 1.2 returned nil error")
+	}
+}
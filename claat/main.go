@@ -41,14 +41,29 @@ var (
 
 	// Flags.
 	addr         = flag.String("addr", "localhost:9090", "hostname and port to bind web server to")
+	archives     = flag.String("archives", "", "build per-step code archives from tagged 'code' blocks or a directory of step-N snapshots")
+	assetHosts   = flag.String("asset_hosts", "", "Hosts of linked PDFs, archives and other downloads to copy into codelabs. Comma-delimited list.")
 	authToken    = flag.String("auth", "", "OAuth2 Bearer token; alternative credentials override.")
+	backup       = flag.Bool("backup", false, "keep the previous version of each exported or updated codelab in a hidden .<dir>.bak directory")
+	defaultLang  = flag.String("default_lang", "en", "language of codelabs which are not exported as translations")
+	diagrams     = flag.Bool("diagrams", false, "pre-render mermaid and dot diagrams into SVG images with the mmdc and dot commands")
 	expenv       = flag.String("e", "web", "codelab environment")
 	extra        = flag.String("extra", "", "Additional arguments to pass to format templates. JSON object of string,string key values.")
 	globalGA     = flag.String("ga", "UA-49880327-14", "global Google Analytics account")
-	output       = flag.String("o", ".", "output directory or '-' for stdout")
+	i18nFormat   = flag.String("i18n_format", "xliff", "translation catalog format of i18n extract: xliff or po")
+	images       = flag.String("images", "slurp", "image mode: slurp, keep or rewrite")
+	imageURL     = flag.String("image_url", "", "URL template of images in rewrite mode, e.g. https://cdn.example.com/{{.ID}}/{{.Hash}}{{.Ext}}")
+	lang         = flag.String("lang", "", "target language of catalogs written by i18n extract")
+	math         = flag.Bool("math", false, "parse $...$ and $$...$$ math expressions in codelab text")
+	maxAssetSize = flag.Int64("max_asset_size", 0, "maximum size of an image or another downloaded asset in bytes; 0 means 32MiB")
+	output       = flag.String("o", ".", "output directory, zip or tar.gz archive, or '-' for stdout")
 	passMetadata = flag.String("pass_metadata", "", "Metadata fields to pass through to the output. Comma-delimited list of field names.")
+	plugins      = flag.String("plugin", "", "Executables to pipe codelabs through before rendering. Comma-delimited list of paths.")
 	prefix       = flag.String("prefix", "https://storage.googleapis.com", "URL prefix for html format")
+	responsive   = flag.Bool("responsive_images", false, "strip image metadata, downscale images to their display width and add WebP variants")
+	snapshots    = flag.Bool("snapshots", false, "write project state after each step during extract")
 	tmplout      = flag.String("f", "html", "output format")
+	transforms   = flag.String("transform", "", "Transforms to apply to codelabs before rendering. Comma-delimited list of transform names.")
 )
 
 func main() {
@@ -63,7 +78,13 @@ func main() {
 	}
 
 	flag.Usage = usage
-	flag.CommandLine.Parse(os.Args[2:])
+	// i18n subcommands take flags after the subcommand name
+	args := os.Args[2:]
+	var subcmd []string
+	if os.Args[1] == "i18n" && len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		subcmd, args = args[:1], args[1:]
+	}
+	flag.CommandLine.Parse(args)
 
 	extraVars, err := ParseExtraVars(*extra)
 	if err != nil {
@@ -76,21 +97,71 @@ func main() {
 	switch os.Args[1] {
 	case "export":
 		exitCode = cmd.CmdExport(cmd.CmdExportOptions{
+			Archives:         *archives,
+			AssetHosts:       parseList(*assetHosts),
+			AuthToken:        *authToken,
+			Backup:           *backup,
+			DefaultLang:      *defaultLang,
+			Diagrams:         *diagrams,
+			Expenv:           *expenv,
+			ExtraVars:        extraVars,
+			GlobalGA:         *globalGA,
+			Images:           *images,
+			ImageURL:         *imageURL,
+			Math:             *math,
+			MaxAssetSize:     *maxAssetSize,
+			Output:           *output,
+			PassMetadata:     pm,
+			Prefix:           *prefix,
+			ResponsiveImages: *responsive,
+			Srcs:             flag.Args(),
+			Tmplout:          *tmplout,
+			Transforms:       parseList(*transforms),
+			Plugins:          parseList(*plugins),
+		})
+	case "extract":
+		exitCode = cmd.CmdExtract(cmd.CmdExtractOptions{
 			AuthToken:    *authToken,
 			Expenv:       *expenv,
-			ExtraVars:    extraVars,
-			GlobalGA:     *globalGA,
 			Output:       *output,
 			PassMetadata: pm,
-			Prefix:       *prefix,
+			Snapshots:    *snapshots,
 			Srcs:         flag.Args(),
-			Tmplout:      *tmplout,
+		})
+	case "i18n":
+		exitCode = cmd.CmdI18n(append(subcmd, flag.Args()...), cmd.CmdI18nOptions{
+			Export: cmd.CmdExportOptions{
+				AssetHosts:       parseList(*assetHosts),
+				AuthToken:        *authToken,
+				Backup:           *backup,
+				DefaultLang:      *defaultLang,
+				Diagrams:         *diagrams,
+				Expenv:           *expenv,
+				ExtraVars:        extraVars,
+				GlobalGA:         *globalGA,
+				Images:           *images,
+				ImageURL:         *imageURL,
+				Math:             *math,
+				MaxAssetSize:     *maxAssetSize,
+				Output:           *output,
+				PassMetadata:     pm,
+				Prefix:           *prefix,
+				ResponsiveImages: *responsive,
+				Tmplout:          *tmplout,
+				Transforms:       parseList(*transforms),
+				Plugins:          parseList(*plugins),
+			},
+			Format: *i18nFormat,
+			Lang:   *lang,
 		})
 	case "serve":
 		exitCode = cmd.CmdServe(*addr)
+	case "template":
+		exitCode = cmd.CmdTemplate(flag.Args())
 	case "update":
 		exitCode = cmd.CmdUpdate(cmd.CmdUpdateOptions{
 			AuthToken:    *authToken,
+			Backup:       *backup,
 			ExtraVars:    extraVars,
 			GlobalGA:     *globalGA,
 			PassMetadata: pm,
@@ -117,6 +188,18 @@ func parsePassMetadata(passMeta string) map[string]bool {
 	return fields
 }
 
+// parseList parses a comma separated list of names, keeping their order.
+// Empty names are skipped.
+func parseList(s string) []string {
+	var names []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			names = append(names, v)
+		}
+	}
+	return names
+}
+
 // ParseExtraVars parses extra template variables from command line.
 // extra is any additional arguments to pass to format templates. Should be formatted as JSON objects of string:string KV pairs.
 func ParseExtraVars(extra string) (map[string]string, error) {
@@ -141,7 +224,7 @@ func usage() {
 
 const usageText = `Usage: claat <cmd> [options] src [src ...]
 
-Available commands are: export, serve, update, version.
+Available commands are: export, extract, i18n, serve, template, update, version.
 
 ## Export command
 
@@ -153,6 +236,7 @@ The following formats are built-in:
 - html (Polymer-based app)
 - md (Markdown)
 - offline (plain HTML markup for offline consumption)
+- txt (plain text, e.g. for search indexing)
 
 Note that the built-in templates of the formats are not guaranteed to be stable.
 They can be found in https://github.com/googlecodelabs/tools/tree/master/claat/render.
@@ -161,6 +245,27 @@ Please avoid using default templates in production. Use your own copies.
 To use a custom format, specify a local file path to a Go template file.
 More info on Go templates: https://golang.org/pkg/text/template/.
 
+A custom format can also be a theme: a local directory of template files
+which build on one of the built-in formats. Its *.tmpl files are parsed after
+the built-in template, so they can redefine its blocks with {{define "name"}},
+such as "title", "head", "steps" and "scripts", and add named partials,
+invoked as {{template "file.tmpl" .}}. An index.tmpl file, if present,
+replaces the built-in template, which it can include as {{template "base" .}}.
+An optional theme.json file selects the built-in format, e.g.
+{"extends": "offline"}; the default is html. The static/ subdirectory
+of a theme is copied into each exported codelab directory.
+
+Besides the renderHTML, renderLite and renderMD functions used by the
+built-in templates, custom templates can use:
+
+- toc: step numbers, titles, unique anchors and durations of steps
+- totalDuration, remainingDuration: sum of step durations,
+  of all steps or from the given step number on
+- stepsByEnv: steps available in the given environment
+- wordCount, readingTime: text size of a step or a list of steps
+- images, links: image and link nodes of a step or a list of steps
+- renderText: plain text of content, as in the txt format
+
 Each 'src' can be either a remote HTTP resource or a local file.
 Source formats currently supported are:
 
@@ -170,12 +275,173 @@ Source formats currently supported are:
 When 'src' is a Google Doc, it must be specified as a doc ID,
 omitting https://docs.google.com/... part.
 
+A codelab with "lang" metadata other than -default_lang is a translation.
+Translations share the ID of the original codelab and are exported
+into its subdirectory named after their language, e.g. "codelab-id/ja".
+The html format links all translations with hreflang alternates
+and a language switcher, and sets the lang and dir attributes of pages,
+including right-to-left languages. Languages of all translations
+are recorded in codelab.json as "locales". Export translations together
+with the original codelab for all of them to link to each other.
+
 Instead of writing to an output directory, use "-o -" to specify
 stdout. In this case images and metadata are not exported.
-When writing to a directory, existing files will be overwritten.
+When writing to a directory, each codelab is first exported into a hidden
+staging directory next to it, which replaces the codelab directory only once
+the export succeeds. A failed export, e.g. because of a template error
+or an image which cannot be downloaded, leaves the previous version in place.
+Files of the previous version which the export does not own, such as files
+placed in img/ by hand, are carried over. With -backup, the previous version
+is kept in a hidden sibling directory, e.g. ".codelab-id.bak".
+
+To export into a single archive file instead, specify an output file name
+ending with .zip, .tar.gz or .tgz, e.g. "-o site.zip". The archive contains
+the codelab directories of all exported codelabs and replaces any existing
+file once written. Codelabs are staged in memory, so no temporary files
+are created besides the archive itself.
+
+With -archives, a zip archive of the project code is written to the zip/
+subdirectory of the codelab for every step which changes the code, and
+linked from the step with Download buttons for the starter code and
+the solution. Use "-archives code" to build the archives from code blocks
+tagged as described in the extract command, or specify a directory with
+step-N subdirectories, such as the one written by "claat extract -snapshots".
+A step-0 subdirectory, if present, is the starter code of the first step.
+The directory is recorded as an absolute path, so that "claat update" finds
+it from any working directory.
+
+With -math, LaTeX equations in codelab text, written as $...$ for inline
+math and $$...$$ for display math, are converted to MathML. Without it,
+dollar signs are plain text. Catalogs of codelabs with math should be
+extracted and merged with -math as well.
+
+With -diagrams, mermaid and dot diagrams are pre-rendered into SVG images
+with the mmdc (Mermaid CLI) and dot (Graphviz) commands, which must be
+installed. The html format shows the image until the diagram is drawn in
+the browser, and the offline format shows it above the diagram source.
+
+With -responsive_images, Exif and text metadata is stripped from PNG and JPEG
+images, and images larger than their width in the codelab are downscaled
+to that width at 1x and 2x pixel density. Each size is also encoded as
+WebP, lossless for PNG and lossy for JPEG images, and the WebP variants are
+kept when smaller than the original format. Images larger than 50 megapixels
+are kept as is.
+The html and lite formats then render images with srcset, a <picture>
+element for the WebP variants, and their intrinsic width and height.
+
+Images are recognized by their content: PNG, JPEG, GIF, WebP, AVIF and SVG
+are supported. Scripts and event handlers are removed from SVG images.
+An image URL which returns an HTML page, such as a sign-in or an error page,
+fails the export. Downloaded images and code snippets are limited
+to -max_asset_size bytes, 32MiB by default.
+
+The -images flag controls how images are exported. With "slurp", the default,
+they are stored in the img/ subdirectory of the codelab and linked relatively.
+With "keep", image URLs are left as is, e.g. for images already served from
+a CDN; only images embedded in the source document are stored. With "rewrite",
+images are stored like with "slurp", but linked with URLs made from
+the -image_url template, such as "https://cdn.example.com/{{.ID}}/{{.Hash}}{{.Ext}}".
+The template fields are ID (the codelab ID), Name (the image file name),
+Hash (the file name without extension) and Ext (the extension, e.g. ".png").
+Both settings are recorded in codelab.json and reused by the update command.
+
+Files linked from a codelab with a Download button or with a PDF or archive
+extension are copied into its assets/ subdirectory, and the links are
+rewritten to point there. Other links, e.g. to pages or other codelabs,
+are kept as is. Local files must be within the directory of the codelab,
+and remote files are copied only from hosts listed in -asset_hosts, such as
+"storage.example.com,*.example.org".
+
+Images and other assets written by an export are listed in the "manifest"
+of codelab.json, along with their size, SHA-256 hash, MIME type and source,
+so that publishers can upload only the changed files.
+
+With -transform, the named transforms modify each codelab after it is parsed
+and before it is rendered, in the given order. Programs using claat as a library
+can register their own transforms. The following transforms are built-in:
+
+- strip-drafts (removes steps and content tagged with the "draft" environment)
+
+With -plugin, each codelab is also piped through the given executables,
+after the transforms and in the given order. Plugins run before images and
+linked files are stored, so those added by plugins are stored too. A plugin
+reads a JSON object from stdin, with the following fields:
+
+- version: plugin protocol version, currently 1
+- context: export context, as stored in codelab.json, without the update
+  timestamp and the manifest
+- codelab: codelab metadata and steps, each with a tree of content nodes
+
+and writes a JSON object to stdout, with the following optional fields:
+
+- codelab: the modified codelab, in the same format; omit to keep it unchanged.
+  Its id and lang must not be changed.
+- files: additional output files, as a list of {"name", "content"} objects,
+  with names relative to the codelab directory and base64 encoded content
+- error: an error message, which fails the export
+
+A plugin exiting with non-zero code also fails the export.
 
 The program exits with non-zero code if at least one src could not be exported.
 
+## Extract command
+
+Extract takes one or more 'src' documents and writes their code
+snippets into a project tree, so that the code can be built and tested.
+
+Only code blocks tagged with a marker on their first line, or annotated
+with a file name, are extracted. The marker names a file path relative
+to the project root, and can be written as a comment in the snippet language:
+
+    // file: src/main.go
+    # patch: app.py
+
+A "file" marker replaces the file content with the rest of the block.
+A "patch" marker applies the rest of the block as a unified diff to the file
+written by one of the previous blocks. In markdown, the file name can
+instead follow the language in the info string of a fenced code block,
+e.g. "go src/main.go", or be written as "file=go.mod" without a language;
+such a block replaces the file content with the whole block.
+Code blocks and steps which are not in the environment specified
+with -e option are skipped.
+
+The project is written to a directory named after the codelab ID,
+in the output directory specified with -o option.
+With -snapshots, the project state after each step is written to
+a separate step-N subdirectory instead.
+
+## I18n command
+
+I18n with the "extract" subcommand takes one or more 'src' documents
+and writes their translatable text into translation catalogs,
+in the format specified with -i18n_format option: XLIFF 2.0 (xliff)
+or gettext PO (po). A catalog is named after the codelab ID and the target
+language specified with -lang option, e.g. codelab-id.ja.xlf, and written
+to the output directory specified with -o option, or to stdout with "-o -".
+Flags of i18n follow the subcommand:
+
+    claat i18n extract -i18n_format po -lang ja -o l10n codelab.md
+
+Catalogs contain titles, paragraphs, list items, headers, table cells,
+image alt text, button labels and survey questions and options.
+Code blocks are not translated. Inline formatting and links are kept
+as numbered placeholders, e.g. "Open <1>the console</1> and run <2/>",
+which translations must keep as well. Every segment has a stable ID
+derived from its source text.
+
+With the "merge" subcommand, i18n takes a 'src' document followed by one or
+more translated catalogs, and exports the source translated with each of them,
+as a translation into the catalog target language, using the same options
+as the export command:
+
+    claat i18n merge codelab.md codelab-id.ja.xlf codelab-id.fr.po
+
+Segments which changed since the catalog was extracted are translated
+with the most similar segment of the catalog, if similar enough. Segments
+without a translation are left in the source language.
+The catalog file is recorded in codelab.json as "catalog", and the update
+command merges it with the updated source again.
+
 ## Serve command
 
 Serve provides a simple web server for viewing exported codelabs.
@@ -187,6 +453,19 @@ it would appear in production.
 The serve command takes a -addr host:port option, to specify the
 desired hostname or IP address and port number to bind to.
 
+## Template command
+
+Template with the "check" subcommand takes one or more custom formats,
+as specified with the -f option of the export command, and renders
+a sample codelab with every kind of content in each of them:
+
+    claat template check path/to/template.html path/to/theme/
+
+Errors, such as references to missing fields or functions,
+are reported along with the template name and line number.
+Export renders each output file in memory, so that a template error
+never leaves a partially written file behind.
+
 ## Update command
 
 Update scans one or more 'src' local directories for codelab.json metadata
@@ -196,13 +475,18 @@ to be a codelab previously created with the export command.
 Current directory is assumed if no 'src' argument is given.
 
 Each found codelab is then re-exported using parameters from the metadata file.
-Unused codelab assets will be deleted, as well as the entire codelab directory,
-if codelab ID has changed since last update or export.
+Assets in the manifest of the previous export which are no longer used
+will be deleted; other files in the codelab directory are kept.
+The entire codelab directory is deleted if codelab ID has changed
+since last update or export.
 
 In the latter case, where codelab ID has changed, the new directory
 will be placed alongside the old one. In other words, it will have the same ancestor
 as the old one.
 
+As in the export command, codelabs are updated through a staging directory,
+and -backup keeps their previous versions. Hidden directories are not scanned.
+
 While -prefix and -ga can override existing codelab metadata, the other
 arguments have no effect during update.
 
@@ -13,6 +13,16 @@ func NewCodeNode(v string, term bool, lang string) *CodeNode {
 	}
 }
 
+// NewCodeSnippetNode creates a new Node of type NodeCode whose Value
+// is to be read from a source file region at src, in the form of "path#region".
+// The region part is optional.
+func NewCodeSnippetNode(src string) *CodeNode {
+	return &CodeNode{
+		node: node{typ: NodeCode},
+		Src:  src,
+	}
+}
+
 // CodeNode is either a source code snippet or a terminal output.
 // TODO is there any room to consolidate Term and Lang?
 type CodeNode struct {
@@ -20,9 +30,24 @@ type CodeNode struct {
 	Term  bool
 	Lang  string
 	Value string
+	Src   string // Source file reference of a snippet, resolved at export time
+	File  string // File name annotation of the block, e.g. "main.go"
 }
 
-// Empty returns true if cn.Value is zero, exluding space runes.
+// Empty returns true if cn.Value is zero, exluding space runes,
+// and cn is not a snippet yet to be resolved.
 func (cn *CodeNode) Empty() bool {
-	return strings.TrimSpace(cn.Value) == ""
+	return strings.TrimSpace(cn.Value) == "" && cn.Src == ""
+}
+
+// CodeNodes extracts all NodeCode nodes, recursively.
+func CodeNodes(nodes []Node) []*CodeNode {
+	var codes []*CodeNode
+	Inspect(nodes, func(n Node) bool {
+		if n, ok := n.(*CodeNode); ok {
+			codes = append(codes, n)
+		}
+		return true
+	})
+	return codes
 }
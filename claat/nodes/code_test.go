@@ -104,3 +104,26 @@ func TestCodeNodeEmpty(t *testing.T) {
 		})
 	}
 }
+
+func TestCodeNodes(t *testing.T) {
+	a := NewCodeNode("a", false, "go")
+	b := NewCodeNode("b", true, "")
+	c := NewCodeNode("c", false, "")
+	d := NewCodeNode("d", false, "")
+	imp := NewImportNode("foo")
+	imp.Content.Append(c)
+	il := NewItemsListNode("", 0)
+	il.NewItem(d)
+	in := []Node{
+		a,
+		NewListNode(NewInfoboxNode(InfoboxPositive, b)),
+		imp,
+		il,
+		NewTextNode(NewTextNodeOptions{Value: "foo"}),
+	}
+	out := CodeNodes(in)
+	want := []*CodeNode{a, b, c, d}
+	if diff := cmp.Diff(want, out, cmp.AllowUnexported(CodeNode{}, node{})); diff != "" {
+		t.Errorf("CodeNodes(%+v) got diff (-want +got): %s", in, diff)
+	}
+}
@@ -0,0 +1,29 @@
+package nodes
+
+// NewCustomNode creates a new node of a custom kind, with optional content.
+// Custom nodes are usually created by third-party parser directives
+// and rendered by funcs registered for their kind.
+func NewCustomNode(kind string, n ...Node) *CustomNode {
+	return &CustomNode{
+		node:    node{typ: NodeCustom},
+		Kind:    kind,
+		Attrs:   make(map[string]string),
+		Content: NewListNode(n...),
+	}
+}
+
+// CustomNode is a node of a kind which is not known to claat itself,
+// e.g. an organization-specific widget.
+type CustomNode struct {
+	node
+	Kind    string            // custom kind name
+	Attrs   map[string]string // arbitrary attributes
+	Value   string            // raw source, if any
+	Content *ListNode         // parsed content, if any
+}
+
+// Empty returns true if cn has no kind.
+// Otherwise, only renderers of the kind know whether cn has any content.
+func (cn *CustomNode) Empty() bool {
+	return cn.Kind == ""
+}
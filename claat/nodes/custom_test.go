@@ -0,0 +1,45 @@
+package nodes
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewCustomNode(t *testing.T) {
+	out := NewCustomNode("widget", NewTextNode(NewTextNodeOptions{Value: "hello"}))
+	want := &CustomNode{
+		node:    node{typ: NodeCustom},
+		Kind:    "widget",
+		Attrs:   map[string]string{},
+		Content: NewListNode(NewTextNode(NewTextNodeOptions{Value: "hello"})),
+	}
+	if diff := cmp.Diff(want, out, cmp.AllowUnexported(CustomNode{}, ListNode{}, TextNode{}, node{})); diff != "" {
+		t.Errorf("NewCustomNode() got diff (-want +got): %s", diff)
+	}
+}
+
+func TestCustomNodeEmpty(t *testing.T) {
+	tests := []struct {
+		name   string
+		inKind string
+		out    bool
+	}{
+		{
+			name: "NoKind",
+			out:  true,
+		},
+		{
+			name:   "NoContent",
+			inKind: "widget",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			n := NewCustomNode(tc.inKind)
+			if out := n.Empty(); out != tc.out {
+				t.Errorf("CustomNode.Empty() = %t, want %t", out, tc.out)
+			}
+		})
+	}
+}
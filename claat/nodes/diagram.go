@@ -0,0 +1,51 @@
+package nodes
+
+import "strings"
+
+// Diagram kinds, which are the languages diagrams are described in.
+const (
+	DiagramMermaid = "mermaid" // https://mermaid-js.github.io
+	DiagramDot     = "dot"     // Graphviz DOT language
+)
+
+// IsDiagramKind returns true if code in language lang describes a diagram.
+func IsDiagramKind(lang string) bool {
+	return lang == DiagramMermaid || lang == DiagramDot
+}
+
+// NewDiagramNode creates a new diagram node of the given kind,
+// e.g. DiagramMermaid, described by source v.
+func NewDiagramNode(kind, v string) *DiagramNode {
+	return &DiagramNode{
+		node:  node{typ: NodeDiagram},
+		Kind:  kind,
+		Value: v,
+	}
+}
+
+// DiagramNode is a diagram described in a text language.
+// The source is kept as is and rendered into an image
+// by the target format, where possible.
+type DiagramNode struct {
+	node
+	Kind  string
+	Value string
+	Img   string // Optional pre-rendered SVG image, set at export time
+}
+
+// Empty returns true if dn.Value is zero, exluding space runes.
+func (dn *DiagramNode) Empty() bool {
+	return strings.TrimSpace(dn.Value) == ""
+}
+
+// DiagramNodes extracts all NodeDiagram nodes, recursively.
+func DiagramNodes(nodes []Node) []*DiagramNode {
+	var res []*DiagramNode
+	Inspect(nodes, func(n Node) bool {
+		if n, ok := n.(*DiagramNode); ok {
+			res = append(res, n)
+		}
+		return true
+	})
+	return res
+}
@@ -0,0 +1,82 @@
+package nodes
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewDiagramNode(t *testing.T) {
+	out := NewDiagramNode(DiagramMermaid, "graph TD; A-->B")
+	want := &DiagramNode{
+		node:  node{typ: NodeDiagram},
+		Kind:  DiagramMermaid,
+		Value: "graph TD; A-->B",
+	}
+	if diff := cmp.Diff(want, out, cmp.AllowUnexported(DiagramNode{}, node{})); diff != "" {
+		t.Errorf("NewDiagramNode() got diff (-want +got): %s", diff)
+	}
+}
+
+func TestDiagramNodeEmpty(t *testing.T) {
+	tests := []struct {
+		name    string
+		inValue string
+		out     bool
+	}{
+		{
+			name: "Empty",
+			out:  true,
+		},
+		{
+			name:    "Spaces",
+			inValue: " \n\t",
+			out:     true,
+		},
+		{
+			name:    "NonEmpty",
+			inValue: "digraph { a -> b }",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			n := NewDiagramNode(DiagramDot, tc.inValue)
+			if out := n.Empty(); out != tc.out {
+				t.Errorf("DiagramNode.Empty() = %t, want %t", out, tc.out)
+			}
+		})
+	}
+}
+
+func TestIsDiagramKind(t *testing.T) {
+	tests := map[string]bool{
+		"mermaid": true,
+		"dot":     true,
+		"go":      false,
+		"":        false,
+	}
+	for lang, want := range tests {
+		if got := IsDiagramKind(lang); got != want {
+			t.Errorf("IsDiagramKind(%q) = %t, want %t", lang, got, want)
+		}
+	}
+}
+
+func TestDiagramNodes(t *testing.T) {
+	a := NewDiagramNode(DiagramMermaid, "a")
+	b := NewDiagramNode(DiagramDot, "b")
+	c := NewDiagramNode(DiagramMermaid, "c")
+	imp := NewImportNode("foo")
+	imp.Content.Append(c)
+	in := []Node{
+		a,
+		NewListNode(NewInfoboxNode(InfoboxPositive, b)),
+		imp,
+		NewCodeNode("d", false, "dot"),
+	}
+	out := DiagramNodes(in)
+	want := []*DiagramNode{a, b, c}
+	if diff := cmp.Diff(want, out, cmp.AllowUnexported(DiagramNode{}, node{})); diff != "" {
+		t.Errorf("DiagramNodes(%+v) got diff (-want +got): %s", in, diff)
+	}
+}
@@ -0,0 +1,118 @@
+package nodes
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Dump returns an indented, human-readable representation of node trees,
+// one node per line, meant for debugging and test failure messages.
+// Each line contains the node type and its non-zero fields, followed by
+// the children of the node.
+func Dump(nodes ...Node) string {
+	var b strings.Builder
+	for _, n := range nodes {
+		dump(&b, n, 0)
+	}
+	return b.String()
+}
+
+func dump(b *strings.Builder, n Node, depth int) {
+	b.WriteString(strings.Repeat("  ", depth))
+	if n == nil {
+		b.WriteString("<nil>\n")
+		return
+	}
+	v := reflect.Indirect(reflect.ValueOf(n))
+	b.WriteString(v.Type().Name())
+	// source references are pointers, which differ between equal trees
+	switch bl := n.Block().(type) {
+	case nil:
+	case bool:
+		fmt.Fprintf(b, " block=%v", bl)
+	default:
+		fmt.Fprintf(b, " block=%T", bl)
+	}
+	if env := n.Env(); len(env) > 0 {
+		fmt.Fprintf(b, " env=%s", strings.Join(env, ","))
+	}
+	if v.Kind() == reflect.Struct {
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			fv := v.Field(i)
+			if f.PkgPath != "" || isEmptyValue(fv) || isChildField(fv) {
+				continue
+			}
+			fmt.Fprintf(b, " %s=%s", f.Name, dumpValue(fv))
+		}
+	}
+	b.WriteString("\n")
+	if l, ok := n.(*ListNode); ok {
+		for _, c := range l.Nodes {
+			dump(b, c, depth+1)
+		}
+		return
+	}
+	for _, l := range childLists(n) {
+		dump(b, l, depth+1)
+	}
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+// isChildField reports whether v holds children of a node,
+// which are dumped on separate lines.
+func isChildField(v reflect.Value) bool {
+	switch v.Interface().(type) {
+	case *ListNode, []*ListNode, []Node, [][]*GridCell:
+		return true
+	}
+	return false
+}
+
+// dumpValue formats v, dereferencing pointers so that dumps of equal
+// trees are equal.
+func dumpValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.String:
+		return fmt.Sprintf("%q", v.String())
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return "nil"
+		}
+		return dumpValue(v.Elem())
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return fmt.Sprintf("[%d bytes]", v.Len())
+		}
+		var s []string
+		for i := 0; i < v.Len(); i++ {
+			s = append(s, dumpValue(v.Index(i)))
+		}
+		return "[" + strings.Join(s, " ") + "]"
+	case reflect.Map:
+		var s []string
+		for _, k := range v.MapKeys() {
+			s = append(s, dumpValue(k)+":"+dumpValue(v.MapIndex(k)))
+		}
+		sort.Strings(s)
+		return "{" + strings.Join(s, " ") + "}"
+	case reflect.Struct:
+		var s []string
+		for i := 0; i < v.NumField(); i++ {
+			if f := v.Type().Field(i); f.PkgPath == "" && !isEmptyValue(v.Field(i)) {
+				s = append(s, f.Name+":"+dumpValue(v.Field(i)))
+			}
+		}
+		return "{" + strings.Join(s, " ") + "}"
+	}
+	return fmt.Sprint(v.Interface())
+}
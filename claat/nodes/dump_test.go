@@ -0,0 +1,34 @@
+package nodes
+
+import "testing"
+
+func TestDump(t *testing.T) {
+	h := NewHeaderNode(2, NewTextNode(NewTextNodeOptions{Value: "Title", Bold: true}))
+	h.MutateEnv([]string{"web"})
+	cn := NewCustomNode("widget")
+	cn.Attrs["id"] = "1"
+	out := Dump(NewListNode(h, cn))
+	want := "ListNode\n" +
+		"  HeaderNode env=web Level=2\n" +
+		"    ListNode\n" +
+		"      TextNode Bold=true Value=\"Title\"\n" +
+		"  CustomNode Kind=\"widget\" Attrs={\"id\":\"1\"}\n" +
+		"    ListNode\n"
+	if out != want {
+		t.Errorf("Dump() = %q, want %q", out, want)
+	}
+}
+
+func TestDumpBlock(t *testing.T) {
+	type ref struct{ line int }
+	a := NewTextNode(NewTextNodeOptions{Value: "a"})
+	a.MutateBlock(true)
+	b := NewTextNode(NewTextNodeOptions{Value: "b"})
+	b.MutateBlock(&ref{1})
+	out := Dump(a, b)
+	want := "TextNode block=true Value=\"a\"\n" +
+		"TextNode block=*nodes.ref Value=\"b\"\n"
+	if out != want {
+		t.Errorf("Dump() = %q, want %q", out, want)
+	}
+}
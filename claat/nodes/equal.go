@@ -0,0 +1,119 @@
+package nodes
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Equal reports whether node trees a and b are deeply equal,
+// including unexported fields such as source references.
+// Nil and empty slices and maps, e.g. of environments, are considered equal.
+func Equal(a, b []Node) bool {
+	return equalValue(reflect.ValueOf(a), reflect.ValueOf(b), make(map[visit]bool))
+}
+
+// Diff returns a human-readable report of the differences between
+// node trees want and got, or an empty string if they are equal.
+// It is meant to be used in tests.
+func Diff(want, got []Node) string {
+	if Equal(want, got) {
+		return ""
+	}
+	var b strings.Builder
+	wl := strings.Split(strings.TrimSuffix(Dump(want...), "\n"), "\n")
+	gl := strings.Split(strings.TrimSuffix(Dump(got...), "\n"), "\n")
+	for i := 0; i < len(wl) || i < len(gl); i++ {
+		switch {
+		case i >= len(gl):
+			b.WriteString("-" + wl[i] + "\n")
+		case i >= len(wl):
+			b.WriteString("+" + gl[i] + "\n")
+		case wl[i] != gl[i]:
+			b.WriteString("-" + wl[i] + "\n+" + gl[i] + "\n")
+		default:
+			b.WriteString(" " + wl[i] + "\n")
+		}
+	}
+	return b.String()
+}
+
+// visit is a pair of compared pointers, recorded to stop at cycles,
+// e.g. of parent links in source references.
+type visit struct {
+	a, b uintptr
+	typ  reflect.Type
+}
+
+// equalValue is like reflect.DeepEqual, except that nil and empty
+// slices and maps are equal, and unexported fields are compared too.
+func equalValue(a, b reflect.Value, visited map[visit]bool) bool {
+	if !a.IsValid() || !b.IsValid() {
+		return a.IsValid() == b.IsValid()
+	}
+	if a.Type() != b.Type() {
+		return false
+	}
+	switch a.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if a.Kind() != reflect.Ptr && a.Len() != b.Len() {
+			return false
+		}
+		if a.IsNil() || b.IsNil() {
+			return a.Kind() != reflect.Ptr || a.IsNil() == b.IsNil()
+		}
+		v := visit{a.Pointer(), b.Pointer(), a.Type()}
+		if visited[v] {
+			return true
+		}
+		visited[v] = true
+	}
+	switch a.Kind() {
+	case reflect.Ptr:
+		return equalValue(a.Elem(), b.Elem(), visited)
+	case reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		return equalValue(a.Elem(), b.Elem(), visited)
+	case reflect.Slice, reflect.Array:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for i := 0; i < a.Len(); i++ {
+			if !equalValue(a.Index(i), b.Index(i), visited) {
+				return false
+			}
+		}
+		return true
+	case reflect.Map:
+		for _, k := range a.MapKeys() {
+			bv := b.MapIndex(k)
+			if !bv.IsValid() || !equalValue(a.MapIndex(k), bv, visited) {
+				return false
+			}
+		}
+		return true
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			if !equalValue(a.Field(i), b.Field(i), visited) {
+				return false
+			}
+		}
+		return true
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return a.Pointer() == b.Pointer()
+	case reflect.Bool:
+		return a.Bool() == b.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() == b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() == b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() == b.Float()
+	case reflect.Complex64, reflect.Complex128:
+		return a.Complex() == b.Complex()
+	case reflect.String:
+		return a.String() == b.String()
+	}
+	return false
+}
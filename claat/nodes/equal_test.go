@@ -0,0 +1,49 @@
+package nodes
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEqual(t *testing.T) {
+	a := NewTextNode(NewTextNodeOptions{Value: "foo"})
+	b := NewTextNode(NewTextNodeOptions{Value: "foo"})
+	b.MutateEnv(nil)
+	if !Equal([]Node{a}, []Node{b}) {
+		t.Errorf("Equal(%s, %s) = false, want true", Dump(a), Dump(b))
+	}
+	c := NewTextNode(NewTextNodeOptions{Value: "foo", Bold: true})
+	if Equal([]Node{a}, []Node{c}) {
+		t.Errorf("Equal(%s, %s) = true, want false", Dump(a), Dump(c))
+	}
+}
+
+func TestEqualBlock(t *testing.T) {
+	type ref struct {
+		line   int
+		parent *ref
+	}
+	// source references may link back to their parent
+	root := &ref{line: 1}
+	root.parent = root
+	other := &ref{line: 1}
+	other.parent = other
+	a := NewTextNode(NewTextNodeOptions{Value: "foo"})
+	a.MutateBlock(root)
+	b := NewTextNode(NewTextNodeOptions{Value: "foo"})
+	b.MutateBlock(other)
+	if !Equal([]Node{a}, []Node{b}) {
+		t.Errorf("Equal(%s, %s) = false, want true", Dump(a), Dump(b))
+	}
+	b.MutateBlock(&ref{line: 2})
+	if Equal([]Node{a}, []Node{b}) {
+		t.Errorf("Equal(%s, %s) = true, want false", Dump(a), Dump(b))
+	}
+	if Diff([]Node{a}, []Node{a}) != "" {
+		t.Errorf("Diff(%s, %s) is not empty", Dump(a), Dump(a))
+	}
+	c := NewTextNode(NewTextNodeOptions{Value: "bar"})
+	if want := "-TextNode Value=\"foo\"\n+TextNode Value=\"bar\"\n"; !strings.Contains(Diff([]Node{NewTextNode(NewTextNodeOptions{Value: "foo"})}, []Node{c}), want) {
+		t.Errorf("Diff(foo, bar) does not contain %q", want)
+	}
+}
@@ -1,6 +1,9 @@
 package nodes
 
-import "strings"
+import (
+	"math"
+	"strings"
+)
 
 type NewImageNodeOptions struct {
 	Src   string
@@ -31,6 +34,39 @@ type ImageNode struct {
 	Alt   string
 	Title string
 	Bytes []byte
+	// PixelWidth and PixelHeight are the intrinsic size of the image at Src,
+	// if known, e.g. after the image is processed by the fetcher.
+	PixelWidth  int
+	PixelHeight int
+	// Variants are alternative renditions of the image,
+	// e.g. for high density displays or in a more efficient format.
+	Variants []*ImageVariant
+}
+
+// ImageVariant is an alternative rendition of an image.
+type ImageVariant struct {
+	Src string
+	// Type is the MIME type of the variant, e.g. "image/webp",
+	// or empty if it is the same as that of the image.
+	Type string
+	// Density is the pixel density of the variant relative to
+	// the intrinsic size of the image, e.g. 2 for high density displays.
+	Density float32
+}
+
+// Size returns the display size of the image in CSS pixels,
+// based on its Width and intrinsic aspect ratio.
+// It returns zero values if the intrinsic size is not known.
+func (in *ImageNode) Size() (w, h int) {
+	if in.PixelWidth <= 0 || in.PixelHeight <= 0 {
+		return 0, 0
+	}
+	if in.Width <= 0 {
+		return in.PixelWidth, in.PixelHeight
+	}
+	w = int(math.Round(float64(in.Width)))
+	h = int(math.Round(float64(in.Width) * float64(in.PixelHeight) / float64(in.PixelWidth)))
+	return w, h
 }
 
 // Empty returns true if its Src is zero, excluding space runes.
@@ -38,35 +74,15 @@ func (in *ImageNode) Empty() bool {
 	return strings.TrimSpace(in.Src) == "" && len(in.Bytes) == 0
 }
 
-// ImageNodes extracts everything except NodeImage nodes, recursively.
+// ImageNodes extracts all NodeImage nodes, recursively.
 // TODO rename
 func ImageNodes(nodes []Node) []*ImageNode {
 	var imgs []*ImageNode
-	for _, n := range nodes {
-		switch n := n.(type) {
-		case *ImageNode:
+	Inspect(nodes, func(n Node) bool {
+		if n, ok := n.(*ImageNode); ok {
 			imgs = append(imgs, n)
-		case *ListNode:
-			imgs = append(imgs, ImageNodes(n.Nodes)...)
-		case *ItemsListNode:
-			for _, i := range n.Items {
-				imgs = append(imgs, ImageNodes(i.Nodes)...)
-			}
-		case *HeaderNode:
-			imgs = append(imgs, ImageNodes(n.Content.Nodes)...)
-		case *URLNode:
-			imgs = append(imgs, ImageNodes(n.Content.Nodes)...)
-		case *ButtonNode:
-			imgs = append(imgs, ImageNodes(n.Content.Nodes)...)
-		case *InfoboxNode:
-			imgs = append(imgs, ImageNodes(n.Content.Nodes)...)
-		case *GridNode:
-			for _, r := range n.Rows {
-				for _, c := range r {
-					imgs = append(imgs, ImageNodes(c.Content.Nodes)...)
-				}
-			}
 		}
-	}
+		return true
+	})
 	return imgs
 }
@@ -101,6 +101,28 @@ func TestImageNodeEmpty(t *testing.T) {
 	}
 }
 
+func TestImageNodeSize(t *testing.T) {
+	tests := []struct {
+		width      float32
+		pixelW     int
+		pixelH     int
+		outW, outH int
+	}{
+		{width: 100},
+		{pixelW: 640, pixelH: 480, outW: 640, outH: 480},
+		{width: 320, pixelW: 640, pixelH: 480, outW: 320, outH: 240},
+		{width: 300.4, pixelW: 301, pixelH: 101, outW: 300, outH: 101},
+		{width: 800, pixelW: 400, pixelH: 300, outW: 800, outH: 600},
+	}
+	for _, tc := range tests {
+		n := NewImageNode(NewImageNodeOptions{Src: "img/a.png", Width: tc.width})
+		n.PixelWidth, n.PixelHeight = tc.pixelW, tc.pixelH
+		if w, h := n.Size(); w != tc.outW || h != tc.outH {
+			t.Errorf("%+v Size() = %d, %d; want %d, %d", tc, w, h, tc.outW, tc.outH)
+		}
+	}
+}
+
 func TestImageNodes(t *testing.T) {
 	a1 := NewImageNode(NewImageNodeOptions{Src: "https://www.google.com/images/branding/googlelogo/1x/googlelogo_color_272x92dp.png"})
 	a2 := NewImageNode(NewImageNodeOptions{Src: "https://www.google.com/images/branding/googlelogo/1x/googlelogo_color_272x92dp.png"})
@@ -203,6 +225,11 @@ func TestImageNodes(t *testing.T) {
 			inNodes: []Node{c1},
 			out:     []*ImageNode{a1, a3, a2},
 		},
+		{
+			name:    "Import",
+			inNodes: []Node{importWithContent(a2, a3)},
+			out:     []*ImageNode{a2, a3},
+		},
 		{
 			name: "Text",
 			inNodes: []Node{
@@ -226,3 +253,9 @@ func TestImageNodes(t *testing.T) {
 		})
 	}
 }
+
+func importWithContent(n ...Node) *ImportNode {
+	in := NewImportNode("example.com/fragment.md")
+	in.Content.Append(n...)
+	return in
+}
@@ -28,24 +28,14 @@ func (in *ImportNode) MutateBlock(v interface{}) {
 	in.Content.MutateBlock(v)
 }
 
-// ImportNodes extracts everything except NodeImport nodes, recursively.
+// ImportNodes extracts all NodeImport nodes, recursively.
 func ImportNodes(nodes []Node) []*ImportNode {
 	var imps []*ImportNode
-	for _, n := range nodes {
-		switch n := n.(type) {
-		case *ImportNode:
+	Inspect(nodes, func(n Node) bool {
+		if n, ok := n.(*ImportNode); ok {
 			imps = append(imps, n)
-		case *ListNode:
-			imps = append(imps, ImportNodes(n.Nodes)...)
-		case *InfoboxNode:
-			imps = append(imps, ImportNodes(n.Content.Nodes)...)
-		case *GridNode:
-			for _, r := range n.Rows {
-				for _, c := range r {
-					imps = append(imps, ImportNodes(c.Content.Nodes)...)
-				}
-			}
 		}
-	}
+		return true
+	})
 	return imps
 }
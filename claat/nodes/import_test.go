@@ -140,6 +140,10 @@ func TestImportNodes(t *testing.T) {
 	c2 := NewListNode(a2, NewButtonNode(false, false, false, NewTextNode(NewTextNodeOptions{Value: "foobar"})))
 	c3 := NewListNode(c1, c2, a3)
 
+	d1 := NewItemsListNode("", 0)
+	d1.NewItem(a1)
+	d1.NewItem(NewTextNode(NewTextNodeOptions{Value: "foobar"}), a3)
+
 	tests := []struct {
 		name    string
 		inNodes []Node
@@ -173,6 +177,17 @@ func TestImportNodes(t *testing.T) {
 		{
 			name:    "Button",
 			inNodes: []Node{NewButtonNode(true, true, true, a3, a2, a1)},
+			out:     []*ImportNode{a3, a2, a1},
+		},
+		{
+			name:    "ItemsList",
+			inNodes: []Node{d1},
+			out:     []*ImportNode{a1, a3},
+		},
+		{
+			name:    "Header",
+			inNodes: []Node{NewHeaderNode(2, a1), NewURLNode("https://example.com", a2)},
+			out:     []*ImportNode{a1, a2},
 		},
 		{
 			name:    "Text",
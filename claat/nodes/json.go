@@ -0,0 +1,306 @@
+package nodes
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"unicode"
+)
+
+// Names of node types in the JSON representation.
+var jsonTypeNames = map[NodeType]string{
+	NodeList:        "list",
+	NodeGrid:        "grid",
+	NodeText:        "text",
+	NodeCode:        "code",
+	NodeInfobox:     "infobox",
+	NodeSurvey:      "survey",
+	NodeURL:         "url",
+	NodeImage:       "image",
+	NodeButton:      "button",
+	NodeItemsList:   "itemsList",
+	NodeItemsCheck:  "itemsCheck",
+	NodeItemsFAQ:    "itemsFAQ",
+	NodeHeader:      "header",
+	NodeHeaderCheck: "headerCheck",
+	NodeHeaderFAQ:   "headerFAQ",
+	NodeYouTube:     "youtube",
+	NodeIframe:      "iframe",
+	NodeImport:      "import",
+	NodeMath:        "math",
+	NodeDiagram:     "diagram",
+	NodeCustom:      "custom",
+}
+
+var (
+	nodeIfaceType = reflect.TypeOf((*Node)(nil)).Elem()
+	listNodeType  = reflect.TypeOf((*ListNode)(nil))
+)
+
+// EncodeJSON returns the JSON representation of a node tree rooted at n,
+// meant for exchanging codelab content with other programs.
+//
+// A node is encoded as an object with its type name in the "type" field,
+// optional "block" and "env" fields, and the exported fields of the node
+// with names in lower camel case, e.g.
+//
+//	{"type": "header", "level": 2, "content": {"type": "list", "nodes": [...]}}
+//
+// Fields with zero values are omitted.
+func EncodeJSON(n Node) ([]byte, error) {
+	v, err := encodeNode(n)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// DecodeJSON decodes a node tree from the representation produced
+// by EncodeJSON. Missing fields are left with their zero values,
+// except lists and maps, which are initialized as empty.
+func DecodeJSON(b []byte) (Node, error) {
+	return decodeNode(b)
+}
+
+func encodeNode(n Node) (map[string]interface{}, error) {
+	name, ok := jsonTypeNames[n.Type()]
+	if !ok {
+		return nil, fmt.Errorf("cannot encode node of type %d", n.Type())
+	}
+	m := map[string]interface{}{"type": name}
+	if n.Block() == true {
+		m["block"] = true
+	}
+	if env := n.Env(); len(env) > 0 {
+		m["env"] = env
+	}
+	v := reflect.ValueOf(n).Elem()
+	if err := encodeFields(m, v); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// encodeFields adds exported non-empty fields of struct v to m.
+func encodeFields(m map[string]interface{}, v reflect.Value) error {
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		if f.PkgPath != "" || isEmptyValue(v.Field(i)) {
+			continue
+		}
+		fv, err := encodeValue(v.Field(i))
+		if err != nil {
+			return err
+		}
+		m[jsonFieldName(f.Name)] = fv
+	}
+	return nil
+}
+
+func encodeValue(v reflect.Value) (interface{}, error) {
+	if v.Type().Implements(nodeIfaceType) || v.Type() == nodeIfaceType {
+		if v.IsNil() {
+			return nil, nil
+		}
+		return encodeNode(v.Interface().(Node))
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return encodeValue(v.Elem())
+	case reflect.Struct:
+		m := make(map[string]interface{})
+		return m, encodeFields(m, v)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			// base64 encoded by the json package
+			return v.Interface(), nil
+		}
+		a := make([]interface{}, v.Len())
+		for i := range a {
+			var err error
+			if a[i], err = encodeValue(v.Index(i)); err != nil {
+				return nil, err
+			}
+		}
+		return a, nil
+	}
+	return v.Interface(), nil
+}
+
+func decodeNode(b []byte) (Node, error) {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	var name string
+	if err := json.Unmarshal(m["type"], &name); err != nil {
+		return nil, fmt.Errorf("node type: %v", err)
+	}
+	n := newJSONNode(name)
+	if n == nil {
+		return nil, fmt.Errorf("unknown node type %q", name)
+	}
+	if err := decodeFields(m, reflect.ValueOf(n).Elem()); err != nil {
+		return nil, fmt.Errorf("%s node: %v", name, err)
+	}
+	if raw, ok := m["block"]; ok {
+		var block bool
+		if err := json.Unmarshal(raw, &block); err != nil {
+			return nil, fmt.Errorf("%s node block: %v", name, err)
+		}
+		if block {
+			n.MutateBlock(true)
+		}
+	}
+	if raw, ok := m["env"]; ok {
+		var env []string
+		if err := json.Unmarshal(raw, &env); err != nil {
+			return nil, fmt.Errorf("%s node env: %v", name, err)
+		}
+		n.MutateEnv(env)
+	}
+	return n, nil
+}
+
+// newJSONNode returns a new node of the named type with zero field values,
+// or nil if there's no such type.
+func newJSONNode(name string) Node {
+	var t NodeType
+	for k, v := range jsonTypeNames {
+		if v == name {
+			t = k
+			break
+		}
+	}
+	switch t {
+	case NodeList:
+		return &ListNode{node: node{typ: t}}
+	case NodeGrid:
+		return &GridNode{node: node{typ: t}}
+	case NodeText:
+		return &TextNode{node: node{typ: t}}
+	case NodeCode:
+		return &CodeNode{node: node{typ: t}}
+	case NodeInfobox:
+		return &InfoboxNode{node: node{typ: t}}
+	case NodeSurvey:
+		return &SurveyNode{node: node{typ: t}}
+	case NodeURL:
+		return &URLNode{node: node{typ: t}}
+	case NodeImage:
+		return &ImageNode{node: node{typ: t}}
+	case NodeButton:
+		return &ButtonNode{node: node{typ: t}}
+	case NodeItemsList, NodeItemsCheck, NodeItemsFAQ:
+		return &ItemsListNode{node: node{typ: t}}
+	case NodeHeader, NodeHeaderCheck, NodeHeaderFAQ:
+		return &HeaderNode{node: node{typ: t}}
+	case NodeYouTube:
+		return &YouTubeNode{node: node{typ: t}}
+	case NodeIframe:
+		return &IframeNode{node: node{typ: t}}
+	case NodeImport:
+		return &ImportNode{node: node{typ: t}}
+	case NodeMath:
+		return &MathNode{node: node{typ: t}}
+	case NodeDiagram:
+		return &DiagramNode{node: node{typ: t}}
+	case NodeCustom:
+		return &CustomNode{node: node{typ: t}}
+	}
+	return nil
+}
+
+// decodeFields sets exported fields of struct v from m.
+func decodeFields(m map[string]json.RawMessage, v reflect.Value) error {
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		fv := v.Field(i)
+		if raw, ok := m[jsonFieldName(f.Name)]; ok {
+			if err := decodeValue(raw, fv); err != nil {
+				return fmt.Errorf("%s: %v", jsonFieldName(f.Name), err)
+			}
+		}
+		// renderers expect lists and maps to be initialized
+		switch {
+		case fv.Type() == listNodeType && fv.IsNil():
+			fv.Set(reflect.ValueOf(NewListNode()))
+		case fv.Kind() == reflect.Map && fv.IsNil():
+			fv.Set(reflect.MakeMap(fv.Type()))
+		}
+	}
+	return nil
+}
+
+func decodeValue(raw json.RawMessage, v reflect.Value) error {
+	if string(raw) == "null" {
+		return nil
+	}
+	if v.Type() == nodeIfaceType || v.Type().Implements(nodeIfaceType) {
+		n, err := decodeNode(raw)
+		if err != nil {
+			return err
+		}
+		nv := reflect.ValueOf(n)
+		if !nv.Type().AssignableTo(v.Type()) {
+			return fmt.Errorf("got %s node, want %s", jsonTypeNames[n.Type()], v.Type())
+		}
+		v.Set(nv)
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		p := reflect.New(v.Type().Elem())
+		if err := decodeValue(raw, p.Elem()); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	case reflect.Struct:
+		var m map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return err
+		}
+		return decodeFields(m, v)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		var a []json.RawMessage
+		if err := json.Unmarshal(raw, &a); err != nil {
+			return err
+		}
+		s := reflect.MakeSlice(v.Type(), len(a), len(a))
+		for i, r := range a {
+			if err := decodeValue(r, s.Index(i)); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	}
+	return json.Unmarshal(raw, v.Addr().Interface())
+}
+
+// jsonFieldName converts a Go field name to lower camel case,
+// e.g. "URL" to "url" and "VideoID" to "videoID".
+func jsonFieldName(name string) string {
+	r := []rune(name)
+	for i := range r {
+		if !unicode.IsUpper(r[i]) {
+			break
+		}
+		// keep the last capital of a leading run followed by a lower case letter
+		if i > 0 && i+1 < len(r) && unicode.IsLower(r[i+1]) {
+			break
+		}
+		r[i] = unicode.ToLower(r[i])
+	}
+	return string(r)
+}
@@ -0,0 +1,124 @@
+package nodes
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	text := func(v string) *TextNode {
+		return NewTextNode(NewTextNodeOptions{Value: v, Bold: true})
+	}
+	h := NewHeaderNode(2, text("header"))
+	h.MutateType(NodeHeaderFAQ)
+	il := NewItemsListNode("1", 3)
+	il.MutateType(NodeItemsCheck)
+	il.NewItem(text("item"))
+	img := NewImageNode(NewImageNodeOptions{Src: "img/a.png", Width: 100, Bytes: []byte{1, 2}})
+	img.MutateEnv([]string{"web"})
+	img.PixelWidth, img.PixelHeight = 100, 50
+	img.Variants = []*ImageVariant{{Src: "img/a-200w.png", Density: 2}, {Src: "img/a.webp", Type: "image/webp", Density: 1}}
+	imp := NewImportNode("fragment.md")
+	imp.Content.Append(text("imported"))
+	imp.MutateBlock(true)
+	cn := NewCustomNode("widget", text("custom"))
+	cn.Attrs["id"] = "1"
+	root := NewListNode(
+		h,
+		il,
+		img,
+		imp,
+		cn,
+		NewURLNode("https://example.com", text("url")),
+		NewButtonNode(true, false, true, text("button")),
+		NewInfoboxNode(InfoboxNegative, text("infobox")),
+		NewGridNode([]*GridCell{{Colspan: 2, Rowspan: 1, Content: NewListNode(text("cell"))}}),
+		NewSurveyNode("s1", &SurveyGroup{Name: "q", Options: []string{"a", "b"}, Correct: []int{1}}),
+		NewCodeNode("x := 1", false, "go"),
+		NewMathNode("e^x", true),
+		NewDiagramNode(DiagramDot, "digraph {}"),
+		NewYouTubeNode("abc"),
+		NewIframeNode("https://example.com/embed"),
+	)
+	root.MutateBlock(true)
+
+	b, err := EncodeJSON(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := DecodeJSON(b)
+	if err != nil {
+		t.Fatalf("DecodeJSON(%s): %v", b, err)
+	}
+	if diff := Diff([]Node{root}, []Node{out}); diff != "" {
+		t.Errorf("round trip got diff (-want +got):\n%s\nJSON: %s", diff, b)
+	}
+}
+
+func TestDecodeJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		out  Node
+		err  string
+	}{
+		{
+			name: "Minimal",
+			in:   `{"type": "header", "level": 1}`,
+			out:  NewHeaderNode(1),
+		},
+		{
+			name: "Nested",
+			in:   `{"type": "list", "block": true, "nodes": [{"type": "text", "value": "foo", "env": ["web"]}]}`,
+			out: func() Node {
+				tn := NewTextNode(NewTextNodeOptions{Value: "foo"})
+				tn.MutateEnv([]string{"web"})
+				l := NewListNode(tn)
+				l.MutateBlock(true)
+				return l
+			}(),
+		},
+		{
+			name: "UnknownType",
+			in:   `{"type": "marquee"}`,
+			err:  `unknown node type "marquee"`,
+		},
+		{
+			name: "WrongContent",
+			in:   `{"type": "header", "content": {"type": "text"}}`,
+			err:  "got text node",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := DecodeJSON([]byte(tc.in))
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Errorf("DecodeJSON(%s) error = %v, want %q", tc.in, err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := Diff([]Node{tc.out}, []Node{out}); diff != "" {
+				t.Errorf("DecodeJSON(%s) got diff (-want +got):\n%s", tc.in, diff)
+			}
+		})
+	}
+}
+
+func TestJSONFieldName(t *testing.T) {
+	tests := map[string]string{
+		"Value":    "value",
+		"URL":      "url",
+		"VideoID":  "videoID",
+		"ListType": "listType",
+		"IDName":   "idName",
+	}
+	for in, want := range tests {
+		if out := jsonFieldName(in); out != want {
+			t.Errorf("jsonFieldName(%q) = %q, want %q", in, out, want)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package nodes
+
+import "strings"
+
+// NewMathNode creates a new math node with LaTeX source value.
+// The display argument selects display (block) math, as opposed to
+// math inlined in text.
+func NewMathNode(value string, display bool) *MathNode {
+	return &MathNode{
+		node:    node{typ: NodeMath},
+		Value:   value,
+		Display: display,
+	}
+}
+
+// MathNode is a math expression written in LaTeX notation,
+// e.g. "e^{i\pi} + 1 = 0".
+type MathNode struct {
+	node
+	Value   string
+	Display bool
+}
+
+// Empty returns true if mn's Value is zero, excluding space runes.
+func (mn *MathNode) Empty() bool {
+	return strings.TrimSpace(mn.Value) == ""
+}
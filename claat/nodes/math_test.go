@@ -0,0 +1,80 @@
+package nodes
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewMathNode(t *testing.T) {
+	tests := []struct {
+		name      string
+		inValue   string
+		inDisplay bool
+		out       *MathNode
+	}{
+		{
+			name: "Empty",
+			out: &MathNode{
+				node: node{typ: NodeMath},
+			},
+		},
+		{
+			name:    "Inline",
+			inValue: `\alpha^2`,
+			out: &MathNode{
+				node:  node{typ: NodeMath},
+				Value: `\alpha^2`,
+			},
+		},
+		{
+			name:      "Display",
+			inValue:   `\frac{a}{b}`,
+			inDisplay: true,
+			out: &MathNode{
+				node:    node{typ: NodeMath},
+				Value:   `\frac{a}{b}`,
+				Display: true,
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			out := NewMathNode(tc.inValue, tc.inDisplay)
+			if diff := cmp.Diff(tc.out, out, cmp.AllowUnexported(MathNode{}, node{})); diff != "" {
+				t.Errorf("NewMathNode(%q, %t) got diff (-want +got): %s", tc.inValue, tc.inDisplay, diff)
+				return
+			}
+		})
+	}
+}
+
+func TestMathNodeEmpty(t *testing.T) {
+	tests := []struct {
+		name    string
+		inValue string
+		out     bool
+	}{
+		{
+			name: "Empty",
+			out:  true,
+		},
+		{
+			name:    "Spaces",
+			inValue: " \n ",
+			out:     true,
+		},
+		{
+			name:    "NonEmpty",
+			inValue: "x",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			n := NewMathNode(tc.inValue, false)
+			if out := n.Empty(); out != tc.out {
+				t.Errorf("MathNode.Empty() = %t, want %t", out, tc.out)
+			}
+		})
+	}
+}
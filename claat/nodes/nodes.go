@@ -42,6 +42,9 @@ const (
 	NodeYouTube              // YouTube video
 	NodeIframe               // Embedded iframe
 	NodeImport               // A node which holds content imported from another resource
+	NodeMath                 // Math expression in LaTeX notation
+	NodeDiagram              // Diagram described in a text language, e.g. Mermaid
+	NodeCustom               // Third-party node kind
 )
 
 // Node is an interface common to all node types.
@@ -67,7 +70,7 @@ type Node interface {
 
 // IsInline returns true if t is an inline node type.
 func IsInline(t NodeType) bool {
-	return t&(NodeText|NodeURL|NodeImage|NodeButton) != 0
+	return t&(NodeText|NodeURL|NodeImage|NodeButton|NodeMath) != 0
 }
 
 // EmptyNodes returns true if all of nodes are empty.
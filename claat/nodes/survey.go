@@ -10,9 +10,15 @@ type SurveyNode struct {
 }
 
 // SurveyGroup contains group name/question and possible answers.
+//
+// A group with one or more Correct answers is a graded quiz question
+// rather than a poll.
 type SurveyGroup struct {
-	Name    string
-	Options []string
+	Name        string
+	Options     []string
+	Correct     []int  // Indices of the correct Options
+	Multi       bool   // More than one option can be selected
+	Explanation string // Shown to the learner once the question is answered
 }
 
 // NewSurveyNode creates a new survey node with optional questions.
@@ -35,3 +41,40 @@ func (sn *SurveyNode) Empty() bool {
 	}
 	return true
 }
+
+// Quiz returns true if at least one of the groups is graded.
+func (sn *SurveyNode) Quiz() bool {
+	for _, g := range sn.Groups {
+		if g.Graded() {
+			return true
+		}
+	}
+	return false
+}
+
+// Graded returns true if sg has at least one correct answer.
+func (sg *SurveyGroup) Graded() bool {
+	return len(sg.Correct) > 0
+}
+
+// IsCorrect reports whether the option at index i is a correct answer.
+func (sg *SurveyGroup) IsCorrect(i int) bool {
+	for _, c := range sg.Correct {
+		if c == i {
+			return true
+		}
+	}
+	return false
+}
+
+// SurveyNodes extracts all NodeSurvey nodes, recursively.
+func SurveyNodes(nodes []Node) []*SurveyNode {
+	var surveys []*SurveyNode
+	Inspect(nodes, func(n Node) bool {
+		if n, ok := n.(*SurveyNode); ok {
+			surveys = append(surveys, n)
+		}
+		return true
+	})
+	return surveys
+}
@@ -217,3 +217,81 @@ func TestSurveyNodeEmpty(t *testing.T) {
 		})
 	}
 }
+
+func TestSurveyNodeQuiz(t *testing.T) {
+	tests := []struct {
+		name     string
+		inGroups []*SurveyGroup
+		out      bool
+	}{
+		{
+			name: "NoGroups",
+		},
+		{
+			name: "Poll",
+			inGroups: []*SurveyGroup{
+				&SurveyGroup{
+					Name:    "pick a color",
+					Options: []string{"red", "blue"},
+				},
+			},
+		},
+		{
+			name: "SomeGraded",
+			inGroups: []*SurveyGroup{
+				&SurveyGroup{
+					Name:    "pick a color",
+					Options: []string{"red", "blue"},
+				},
+				&SurveyGroup{
+					Name:    "pick a prime",
+					Options: []string{"2", "4"},
+					Correct: []int{0},
+				},
+			},
+			out: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			n := NewSurveyNode("id", tc.inGroups...)
+			if out := n.Quiz(); out != tc.out {
+				t.Errorf("SurveyNode.Quiz() = %t, want %t", out, tc.out)
+			}
+		})
+	}
+}
+
+func TestSurveyGroupIsCorrect(t *testing.T) {
+	g := &SurveyGroup{
+		Name:    "pick primes",
+		Options: []string{"2", "3", "4"},
+		Correct: []int{0, 1},
+	}
+	for i, want := range []bool{true, true, false, false} {
+		if out := g.IsCorrect(i); out != want {
+			t.Errorf("SurveyGroup.IsCorrect(%d) = %t, want %t", i, out, want)
+		}
+	}
+}
+
+func TestSurveyNodes(t *testing.T) {
+	a := NewSurveyNode("a")
+	b := NewSurveyNode("b")
+	c := NewSurveyNode("c")
+	d := NewSurveyNode("d")
+	imp := NewImportNode("foo")
+	imp.Content.Append(c)
+	in := []Node{
+		a,
+		NewListNode(NewInfoboxNode(InfoboxPositive, b)),
+		imp,
+		NewGridNode([]*GridCell{{Content: NewListNode(d)}}),
+		NewTextNode(NewTextNodeOptions{Value: "foo"}),
+	}
+	out := SurveyNodes(in)
+	want := []*SurveyNode{a, b, c, d}
+	if diff := cmp.Diff(want, out, cmp.AllowUnexported(SurveyNode{}, node{})); diff != "" {
+		t.Errorf("SurveyNodes(%+v) got diff (-want +got): %s", in, diff)
+	}
+}
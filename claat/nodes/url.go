@@ -23,3 +23,28 @@ type URLNode struct {
 func (un *URLNode) Empty() bool {
 	return un.Content.Empty()
 }
+
+// IsDownload reports whether un is a download link, i.e. its content
+// contains a button marked Download.
+func (un *URLNode) IsDownload() bool {
+	var download bool
+	Inspect(un.Content.Nodes, func(n Node) bool {
+		if bn, ok := n.(*ButtonNode); ok && bn.Download {
+			download = true
+		}
+		return !download
+	})
+	return download
+}
+
+// URLNodes extracts all NodeURL nodes, recursively.
+func URLNodes(nodes []Node) []*URLNode {
+	var res []*URLNode
+	Inspect(nodes, func(n Node) bool {
+		if n, ok := n.(*URLNode); ok {
+			res = append(res, n)
+		}
+		return true
+	})
+	return res
+}
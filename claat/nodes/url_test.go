@@ -81,3 +81,43 @@ func TestURLNodeEmpty(t *testing.T) {
 		})
 	}
 }
+
+func TestURLNodeIsDownload(t *testing.T) {
+	text := NewTextNode(NewTextNodeOptions{Value: "Download"})
+	tests := []struct {
+		name string
+		in   *URLNode
+		out  bool
+	}{
+		{"Text", NewURLNode("a.zip", text), false},
+		{"Button", NewURLNode("a.zip", NewButtonNode(true, true, false, text)), false},
+		{"DownloadButton", NewURLNode("a.zip", NewButtonNode(true, true, true, text)), true},
+		{"Nested", NewURLNode("a.zip", NewListNode(NewButtonNode(false, false, true, text))), true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if out := tc.in.IsDownload(); out != tc.out {
+				t.Errorf("URLNode.IsDownload() = %t, want %t", out, tc.out)
+			}
+		})
+	}
+}
+
+func TestURLNodes(t *testing.T) {
+	a := NewURLNode("a")
+	b := NewURLNode("b")
+	c := NewURLNode("c")
+	imp := NewImportNode("foo")
+	imp.Content.Append(c)
+	in := []Node{
+		a,
+		NewListNode(NewInfoboxNode(InfoboxPositive, b)),
+		imp,
+		NewTextNode(NewTextNodeOptions{Value: "d"}),
+	}
+	out := URLNodes(in)
+	want := []*URLNode{a, b, c}
+	if diff := cmp.Diff(want, out, cmpOptURL); diff != "" {
+		t.Errorf("URLNodes(%+v) got diff (-want +got): %s", in, diff)
+	}
+}
@@ -0,0 +1,102 @@
+package nodes
+
+// A Visitor's Visit method is invoked for each node encountered by Walk.
+// If the result visitor w is not nil, Walk visits each of the children
+// of node n with the visitor w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(n Node) (w Visitor)
+}
+
+// Walk traverses a node tree in depth-first order. It starts by calling
+// v.Visit(n); n must not be nil. If the visitor w returned by v.Visit(n)
+// is not nil, Walk is invoked recursively with visitor w for each of
+// the children of n, followed by a call of w.Visit(nil).
+//
+// Children of container nodes held in a list field, such as
+// HeaderNode.Content or the items of ItemsListNode, are visited
+// directly; the list itself is not.
+func Walk(v Visitor, n Node) {
+	if v = v.Visit(n); v == nil {
+		return
+	}
+	for _, l := range childLists(n) {
+		for _, c := range l.Nodes {
+			Walk(v, c)
+		}
+	}
+	v.Visit(nil)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(n Node) Visitor {
+	if f(n) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses each of nodes in depth-first order, calling fn for
+// every node. If fn returns true, Inspect invokes fn recursively for
+// each of the children of the node, followed by a call of fn(nil).
+func Inspect(nodes []Node, fn func(Node) bool) {
+	for _, n := range nodes {
+		Walk(inspector(fn), n)
+	}
+}
+
+// Rewrite traverses each of nodes in depth-first order and replaces every
+// node with the result of fn. Children of a node are rewritten before
+// the node itself is passed to fn.
+//
+// To keep a node, fn returns it as the only element; to remove it,
+// fn returns nil. Lists held in a field of a container node are updated
+// in place, but cannot be replaced or removed.
+func Rewrite(nodes []Node, fn func(Node) []Node) []Node {
+	var res []Node
+	for _, n := range nodes {
+		for _, l := range childLists(n) {
+			l.Nodes = Rewrite(l.Nodes, fn)
+		}
+		res = append(res, fn(n)...)
+	}
+	return res
+}
+
+// childLists returns lists of child nodes held by container node n.
+// A ListNode holds its own children.
+func childLists(n Node) []*ListNode {
+	var ll []*ListNode
+	switch n := n.(type) {
+	case *ListNode:
+		ll = []*ListNode{n}
+	case *ImportNode:
+		ll = []*ListNode{n.Content}
+	case *ItemsListNode:
+		ll = n.Items
+	case *HeaderNode:
+		ll = []*ListNode{n.Content}
+	case *URLNode:
+		ll = []*ListNode{n.Content}
+	case *ButtonNode:
+		ll = []*ListNode{n.Content}
+	case *InfoboxNode:
+		ll = []*ListNode{n.Content}
+	case *CustomNode:
+		ll = []*ListNode{n.Content}
+	case *GridNode:
+		for _, r := range n.Rows {
+			for _, c := range r {
+				ll = append(ll, c.Content)
+			}
+		}
+	}
+	// Content of nodes created without a constructor may be missing.
+	res := ll[:0:0]
+	for _, l := range ll {
+		if l != nil {
+			res = append(res, l)
+		}
+	}
+	return res
+}
@@ -0,0 +1,106 @@
+package nodes
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	text := func(v string) *TextNode {
+		return NewTextNode(NewTextNodeOptions{Value: v})
+	}
+	il := NewItemsListNode("", 0)
+	il.NewItem(text("item"))
+	grid := NewGridNode([]*GridCell{{Content: NewListNode(text("cell"))}})
+	in := []Node{
+		NewListNode(text("list")),
+		importWithContent(text("import")),
+		il,
+		NewHeaderNode(2, text("header")),
+		NewURLNode("https://example.com", text("url")),
+		NewButtonNode(false, false, false, text("button")),
+		NewInfoboxNode(InfoboxPositive, text("infobox")),
+		NewCustomNode("widget", text("custom")),
+		grid,
+	}
+	var got []string
+	Inspect(in, func(n Node) bool {
+		if tn, ok := n.(*TextNode); ok {
+			got = append(got, tn.Value)
+		}
+		return true
+	})
+	want := "list import item header url button infobox custom cell"
+	if s := strings.Join(got, " "); s != want {
+		t.Errorf("Inspect visited %q, want %q", s, want)
+	}
+}
+
+func TestInspectSkip(t *testing.T) {
+	in := []Node{
+		NewInfoboxNode(InfoboxPositive, NewTextNode(NewTextNodeOptions{Value: "skipped"})),
+		NewListNode(NewTextNode(NewTextNodeOptions{Value: "visited"})),
+	}
+	var got []string
+	Inspect(in, func(n Node) bool {
+		if tn, ok := n.(*TextNode); ok {
+			got = append(got, tn.Value)
+		}
+		_, ok := n.(*InfoboxNode)
+		return !ok
+	})
+	if s := strings.Join(got, " "); s != "visited" {
+		t.Errorf("Inspect visited %q, want %q", s, "visited")
+	}
+}
+
+type countVisitor struct {
+	enter, exit int
+}
+
+func (v *countVisitor) Visit(n Node) Visitor {
+	if n == nil {
+		v.exit++
+	} else {
+		v.enter++
+	}
+	return v
+}
+
+func TestWalk(t *testing.T) {
+	v := &countVisitor{}
+	Walk(v, NewListNode(NewHeaderNode(1, NewTextNode(NewTextNodeOptions{Value: "foo"}))))
+	if v.enter != 3 || v.exit != 3 {
+		t.Errorf("Walk visited %d nodes and exited %d, want 3 and 3", v.enter, v.exit)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	text := func(v string) *TextNode {
+		return NewTextNode(NewTextNodeOptions{Value: v})
+	}
+	in := []Node{
+		text("keep"),
+		text("remove"),
+		NewInfoboxNode(InfoboxPositive, text("remove"), text("double")),
+	}
+	out := Rewrite(in, func(n Node) []Node {
+		tn, ok := n.(*TextNode)
+		switch {
+		case !ok:
+			return []Node{n}
+		case tn.Value == "remove":
+			return nil
+		case tn.Value == "double":
+			return []Node{n, text("copy")}
+		}
+		return []Node{n}
+	})
+	want := []Node{
+		text("keep"),
+		NewInfoboxNode(InfoboxPositive, text("double"), text("copy")),
+	}
+	if diff := Diff(want, out); diff != "" {
+		t.Errorf("Rewrite got diff (-want +got):\n%s", diff)
+	}
+}
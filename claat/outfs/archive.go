@@ -0,0 +1,155 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package outfs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IsArchive reports whether file name has the extension
+// of a supported archive format: .zip, .tar.gz or .tgz.
+func IsArchive(name string) bool {
+	name = strings.ToLower(name)
+	return strings.HasSuffix(name, ".zip") || isTarGz(name)
+}
+
+func isTarGz(name string) bool {
+	return strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz")
+}
+
+// Archive is a file system which streams files into an archive.
+// Files are written in the order of WriteFile calls.
+// Since archive entries cannot be replaced, writing a file again
+// with the same content does nothing, and with a different content
+// returns an error.
+type Archive struct {
+	mu      sync.Mutex
+	mod     time.Time                    // modification time of all files
+	written map[string][sha256.Size]byte // content hashes of written files
+	add     func(name string, data []byte) error
+	close   func() error
+	err     error // first write error, which makes the archive unusable
+}
+
+// NewArchive creates an archive written to w, in the format
+// indicated by the extension of file name, as in IsArchive.
+// It is a zip archive unless name ends with .tar.gz or .tgz.
+// The caller must call Close to complete the archive.
+func NewArchive(w io.Writer, name string) *Archive {
+	if isTarGz(strings.ToLower(name)) {
+		return NewTarGz(w)
+	}
+	return NewZip(w)
+}
+
+// NewZip creates a zip archive written to w.
+// The caller must call Close to complete the archive.
+func NewZip(w io.Writer) *Archive {
+	a := newArchive()
+	zw := zip.NewWriter(w)
+	a.add = func(name string, data []byte) error {
+		fw, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     name,
+			Method:   zip.Deflate,
+			Modified: a.mod,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = fw.Write(data)
+		return err
+	}
+	a.close = zw.Close
+	return a
+}
+
+// NewTarGz creates a gzip-compressed tar archive written to w.
+// The caller must call Close to complete the archive.
+func NewTarGz(w io.Writer) *Archive {
+	a := newArchive()
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	a.add = func(name string, data []byte) error {
+		err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     name,
+			Size:     int64(len(data)),
+			Mode:     0644,
+			ModTime:  a.mod,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = tw.Write(data)
+		return err
+	}
+	a.close = func() error {
+		if err := tw.Close(); err != nil {
+			return err
+		}
+		return gw.Close()
+	}
+	return a
+}
+
+func newArchive() *Archive {
+	return &Archive{
+		mod:     time.Now().Truncate(time.Second),
+		written: make(map[string][sha256.Size]byte),
+	}
+}
+
+// WriteFile implements FS.
+func (a *Archive) WriteFile(name string, data []byte) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.err != nil {
+		return a.err
+	}
+	if prev, ok := a.written[name]; ok {
+		if prev != sum {
+			return fmt.Errorf("%s: file is already in the archive with a different content", name)
+		}
+		return nil
+	}
+	if err := a.add(name, data); err != nil {
+		a.err = err
+		return err
+	}
+	a.written[name] = sum
+	return nil
+}
+
+// Close completes the archive. It does not close the underlying writer.
+func (a *Archive) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.err != nil {
+		return a.err
+	}
+	return a.close()
+}
@@ -0,0 +1,134 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package outfs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestIsArchive(t *testing.T) {
+	tests := map[string]bool{
+		"site.zip":         true,
+		"out/SITE.ZIP":     true,
+		"site.tar.gz":      true,
+		"site.tgz":         true,
+		"site.tar":         false,
+		"out":              false,
+		"codelabs.zip.dir": false,
+	}
+	for name, want := range tests {
+		if got := IsArchive(name); got != want {
+			t.Errorf("IsArchive(%q) = %t, want %t", name, got, want)
+		}
+	}
+}
+
+func readZip(t *testing.T, b []byte) map[string]string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = string(data)
+	}
+	return files
+}
+
+func readTarGz(t *testing.T, b []byte) map[string]string {
+	t.Helper()
+	gr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gr)
+	files := make(map[string]string)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[h.Name] = string(data)
+	}
+	return files
+}
+
+func TestArchive(t *testing.T) {
+	tests := []struct {
+		name string
+		read func(*testing.T, []byte) map[string]string
+	}{
+		{"site.zip", readZip},
+		{"site.tar.gz", readTarGz},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			a := NewArchive(&buf, tc.name)
+			for name, data := range map[string]string{
+				"codelab/index.html": "<html>",
+				"codelab/img/a.png":  "png",
+			} {
+				if err := a.WriteFile(name, []byte(data)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// same content is written once
+			if err := a.WriteFile("codelab/img/a.png", []byte("png")); err != nil {
+				t.Errorf("WriteFile(same content): %v", err)
+			}
+			if err := a.WriteFile("codelab/img/a.png", []byte("other")); err == nil {
+				t.Error("WriteFile(different content) returned nil error")
+			}
+			if err := a.WriteFile("/abs", nil); err == nil {
+				t.Error("WriteFile(/abs) returned nil error")
+			}
+			if err := a.Close(); err != nil {
+				t.Fatal(err)
+			}
+			want := map[string]string{
+				"codelab/index.html": "<html>",
+				"codelab/img/a.png":  "png",
+			}
+			if got := tc.read(t, buf.Bytes()); !reflect.DeepEqual(got, want) {
+				t.Errorf("archive files = %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package outfs provides writable file systems which codelabs are
+// exported into: a directory, memory, or a zip or tar.gz archive.
+package outfs
+
+import (
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// FS is a writable file system.
+// File names are slash-separated paths relative to its root, as in io/fs,
+// and parent directories are created as needed.
+// Implementations must be safe for concurrent use.
+type FS interface {
+	// WriteFile writes data to the named file, replacing its content
+	// if it already exists.
+	WriteFile(name string, data []byte) error
+}
+
+// checkName returns an error if name is not a valid file name,
+// as defined by fs.ValidPath.
+func checkName(name string) error {
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrInvalid}
+	}
+	return nil
+}
+
+// Dir returns a file system of the directory tree rooted at dir.
+func Dir(dir string) FS {
+	return dirFS(dir)
+}
+
+type dirFS string
+
+func (d dirFS) WriteFile(name string, data []byte) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	p := filepath.Join(string(d), filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(p, data, 0644)
+}
+
+// MemFS is a file system which keeps files in memory.
+// The zero value is an empty file system ready to use.
+type MemFS struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+// NewMemFS creates an empty in-memory file system.
+func NewMemFS() *MemFS {
+	return &MemFS{}
+}
+
+// WriteFile implements FS. It keeps a copy of data.
+func (m *MemFS) WriteFile(name string, data []byte) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.files == nil {
+		m.files = make(map[string][]byte)
+	}
+	m.files[name] = append([]byte(nil), data...)
+	return nil
+}
+
+// ReadFile returns the content of the named file.
+// The caller must not modify the returned slice.
+func (m *MemFS) ReadFile(name string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+	return b, nil
+}
+
+// Names returns sorted names of all files in m.
+func (m *MemFS) Names() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.files))
+	for name := range m.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Files returns all files in m, keyed by name.
+// The caller must not modify the content of the returned files.
+func (m *MemFS) Files() map[string][]byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	res := make(map[string][]byte, len(m.files))
+	for name, b := range m.files {
+		res[name] = b
+	}
+	return res
+}
+
+// CopyTo writes all files of m into dst, in the order of their names.
+func (m *MemFS) CopyTo(dst FS) error {
+	for _, name := range m.Names() {
+		b, err := m.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		if err := dst.WriteFile(name, b); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package outfs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDir(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "TestDir-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	d := Dir(tmp)
+	if err := d.WriteFile("a/b/c.txt", []byte("c")); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(tmp, "a", "b", "c.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "c" {
+		t.Errorf("a/b/c.txt = %q, want %q", b, "c")
+	}
+	for _, name := range []string{"", ".", "../x", "/abs", "a/../b", "a//b"} {
+		if err := d.WriteFile(name, nil); err == nil {
+			t.Errorf("WriteFile(%q) returned nil error", name)
+		}
+	}
+}
+
+func TestMemFS(t *testing.T) {
+	var m MemFS
+	data := []byte("one")
+	if err := m.WriteFile("b/one.txt", data); err != nil {
+		t.Fatal(err)
+	}
+	data[0] = 'x' // m keeps a copy
+	if err := m.WriteFile("a.txt", []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.WriteFile("a.txt", []byte("A")); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.WriteFile("../a.txt", nil); err == nil {
+		t.Error("WriteFile(../a.txt) returned nil error")
+	}
+
+	want := map[string][]byte{"a.txt": []byte("A"), "b/one.txt": []byte("one")}
+	if got := m.Files(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Files() = %q, want %q", got, want)
+	}
+	if got := m.Names(); !reflect.DeepEqual(got, []string{"a.txt", "b/one.txt"}) {
+		t.Errorf("Names() = %q", got)
+	}
+	if _, err := m.ReadFile("missing"); !os.IsNotExist(err) {
+		t.Errorf("ReadFile(missing) error = %v, want not exist", err)
+	}
+
+	dst := NewMemFS()
+	if err := m.CopyTo(dst); err != nil {
+		t.Fatal(err)
+	}
+	if got := dst.Files(); !reflect.DeepEqual(got, want) {
+		t.Errorf("CopyTo() files = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/googlecodelabs/tools/claat/nodes"
+)
+
+// Directive is a named instruction in a codelab source, with optional
+// arguments and body. Parsers convert directives into nodes using
+// a func registered with RegisterDirective.
+//
+// In markdown, a directive with a body is written as
+//
+//	:::name key="value" flag
+//	body
+//	:::
+//
+// and one without a body as a single line "::name key=value".
+// In Google Docs, each of these lines is a paragraph of its own,
+// and the paragraphs between the opening and closing lines are the body.
+type Directive struct {
+	Name    string            // directive name
+	Args    map[string]string // named arguments; flags have empty values
+	Body    string            // raw source of the body; empty in Google Docs
+	Content []nodes.Node      // parsed body
+}
+
+// Node returns a custom node of kind d.Name, holding d's arguments,
+// raw body and parsed content.
+func (d *Directive) Node() *nodes.CustomNode {
+	n := nodes.NewCustomNode(d.Name, d.Content...)
+	for k, v := range d.Args {
+		n.Attrs[k] = v
+	}
+	n.Value = d.Body
+	return n
+}
+
+// DirectiveFunc converts directive d into a node.
+// It may return nil to omit the directive from the output.
+type DirectiveFunc func(d *Directive) nodes.Node
+
+var directives = map[string]DirectiveFunc{}
+
+// RegisterDirective registers fn to convert directives named name.
+// Directives which are not registered are left in the text as is.
+// It panics if another func is already registered under the same name.
+func RegisterDirective(name string, fn DirectiveFunc) {
+	if _, exists := directives[name]; exists {
+		panic(fmt.Sprintf("directive %q already registered", name))
+	}
+	directives[name] = fn
+}
+
+// LookupDirective returns a func registered for directive name, if any.
+func LookupDirective(name string) (DirectiveFunc, bool) {
+	fn, ok := directives[name]
+	return fn, ok
+}
+
+// Kinds of directive lines, as returned by ParseDirectiveLine.
+const (
+	DirectiveNone  = iota // not a directive line
+	DirectiveOpen         // ":::name args", opening a directive with a body
+	DirectiveClose        // ":::", closing the body of a directive
+	DirectiveLeaf         // "::name args", a directive without a body
+)
+
+var (
+	directiveOpenRegexp  = regexp.MustCompile(`^:::([\w-]+)(?:\s+(.*?))?\s*$`)
+	directiveCloseRegexp = regexp.MustCompile(`^:::\s*$`)
+	directiveLeafRegexp  = regexp.MustCompile(`^::([\w-]+)(?:\s+(.*?))?\s*$`)
+)
+
+// ParseDirectiveLine parses a line of directive syntax. It returns
+// the kind of the line, one of the Directive constants, and the name
+// and unparsed arguments of opening and leaf directives.
+// Names are returned whether or not a directive is registered.
+func ParseDirectiveLine(line string) (kind int, name, args string) {
+	if directiveCloseRegexp.MatchString(line) {
+		return DirectiveClose, "", ""
+	}
+	if m := directiveOpenRegexp.FindStringSubmatch(line); m != nil {
+		return DirectiveOpen, m[1], m[2]
+	}
+	if m := directiveLeafRegexp.FindStringSubmatch(line); m != nil {
+		return DirectiveLeaf, m[1], m[2]
+	}
+	return DirectiveNone, "", ""
+}
+
+// directiveArgRegexp matches a directive argument: key, key=value or key="value".
+var directiveArgRegexp = regexp.MustCompile(`([\w-]+)(?:=("(?:[^"\\]|\\.)*"|\S*))?`)
+
+// ParseDirectiveArgs parses directive arguments, e.g. `color="dark blue" wide`.
+func ParseDirectiveArgs(s string) map[string]string {
+	args := make(map[string]string)
+	for _, m := range directiveArgRegexp.FindAllStringSubmatch(s, -1) {
+		v := m[2]
+		if uv, err := strconv.Unquote(v); err == nil {
+			v = uv
+		}
+		args[m[1]] = v
+	}
+	return args
+}
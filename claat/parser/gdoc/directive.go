@@ -0,0 +1,104 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gdoc
+
+import (
+	"strings"
+
+	"github.com/googlecodelabs/tools/claat/nodes"
+	"github.com/googlecodelabs/tools/claat/parser"
+)
+
+// smartQuotes replaces typographic quotes, which Google Docs
+// inserts as the author types, in directive arguments.
+var smartQuotes = strings.NewReplacer("“", `"`, "”", `"`)
+
+// directives replaces paragraphs of directives registered with
+// parser.RegisterDirective in top-level nodes nn with the nodes made
+// by their funcs. A directive with a body spans the paragraphs from
+// ":::name args" to ":::", and one without a body is a "::name args"
+// paragraph. Unclosed directives are left as they were written.
+func directives(nn []nodes.Node) []nodes.Node {
+	type frame struct {
+		at   int // index of the opening paragraph in out
+		name string
+		args string
+	}
+	var (
+		out   []nodes.Node
+		stack []frame
+	)
+	for _, n := range nn {
+		kind, name, args := parser.ParseDirectiveLine(paragraphText(n))
+		fn, registered := parser.LookupDirective(name)
+		switch {
+		case kind == parser.DirectiveClose && len(stack) > 0:
+			f := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			open := out[f.at]
+			d := &parser.Directive{
+				Name:    f.name,
+				Args:    parser.ParseDirectiveArgs(f.args),
+				Content: append([]nodes.Node(nil), out[f.at+1:]...),
+			}
+			out = out[:f.at]
+			fn, _ := parser.LookupDirective(f.name)
+			if r := directiveNode(fn, d, open); r != nil {
+				out = append(out, r)
+			}
+			continue
+		case kind == parser.DirectiveOpen && registered:
+			stack = append(stack, frame{at: len(out), name: name, args: smartQuotes.Replace(args)})
+		case kind == parser.DirectiveLeaf && registered:
+			d := &parser.Directive{Name: name, Args: parser.ParseDirectiveArgs(smartQuotes.Replace(args))}
+			if r := directiveNode(fn, d, n); r != nil {
+				out = append(out, r)
+			}
+			continue
+		}
+		out = append(out, n)
+	}
+	return out
+}
+
+// directiveNode converts d with fn into a node, which takes the place
+// of paragraph p in the output.
+func directiveNode(fn parser.DirectiveFunc, d *parser.Directive, p nodes.Node) nodes.Node {
+	n := fn(d)
+	if n == nil {
+		return nil
+	}
+	n.MutateBlock(p.Block())
+	n.MutateEnv(p.Env())
+	return n
+}
+
+// paragraphText returns the text of paragraph n without surrounding space,
+// or an empty string if n is not a paragraph of text only.
+func paragraphText(n nodes.Node) string {
+	l, ok := n.(*nodes.ListNode)
+	if !ok {
+		return ""
+	}
+	var b strings.Builder
+	for _, c := range l.Nodes {
+		t, ok := c.(*nodes.TextNode)
+		if !ok {
+			return ""
+		}
+		b.WriteString(t.Value)
+	}
+	return strings.TrimSpace(b.String())
+}
@@ -54,6 +54,22 @@ func isBold(css cssStyle, hn *html.Node) bool {
 		hasClassStyle(css, hn, "font-weight", "700")
 }
 
+// hasBoldText returns true if hn contains non-empty text in bold.
+func hasBoldText(css cssStyle, hn *html.Node) bool {
+	for c := hn.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode {
+			if strings.TrimSpace(c.Data) != "" && isBold(css, c) {
+				return true
+			}
+			continue
+		}
+		if hasBoldText(css, c) {
+			return true
+		}
+	}
+	return false
+}
+
 func isItalic(css cssStyle, hn *html.Node) bool {
 	if hn.Type == html.TextNode {
 		hn = hn.Parent
@@ -61,7 +61,7 @@ func (p *Parser) ParseFragment(r io.Reader, opts parser.Options) ([]nodes.Node,
 	if err != nil {
 		return nil, err
 	}
-	return parseFragment(doc)
+	return parseFragment(doc, opts)
 }
 
 const (
@@ -77,6 +77,11 @@ const (
 	headerCover = "what we've covered"
 	headerFAQ   = "frequently asked questions"
 
+	// survey question prefix of graded quiz questions, in lower case.
+	surveyQuiz = "quiz:"
+	// survey answer explanation prefix, in lower case.
+	surveyExplanation = "explanation:"
+
 	// google docs comments are links with commentPrefix.
 	commentPrefix = "#cmnt"
 
@@ -127,6 +132,7 @@ type docState struct {
 	flags        stateFlag       // current flags
 	stack        []*stackItem    // cur and flags stack
 	passMetadata map[string]bool // set of metadata fields to pass along.
+	math         bool            // whether to parse math expressions
 }
 
 type stackItem struct {
@@ -175,7 +181,7 @@ func (ds *docState) appendNodes(nn ...nodes.Node) {
 	ds.lastNode = nn[len(nn)-1]
 }
 
-func parseFragment(doc *html.Node) ([]nodes.Node, error) {
+func parseFragment(doc *html.Node, opts parser.Options) ([]nodes.Node, error) {
 	body := findAtom(doc, atom.Body)
 	if body == nil {
 		return nil, fmt.Errorf("document without a body")
@@ -187,6 +193,7 @@ func parseFragment(doc *html.Node) ([]nodes.Node, error) {
 
 	ds := newDocState()
 	ds.css = style
+	ds.math = opts.Math
 	ds.step = ds.clab.NewStep("fragment")
 	for ds.cur = body.FirstChild; ds.cur != nil; ds.cur = ds.cur.NextSibling {
 		if isComment(ds.css, ds.cur) {
@@ -195,7 +202,7 @@ func parseFragment(doc *html.Node) ([]nodes.Node, error) {
 		}
 		parseTop(ds)
 	}
-	finalizeStep(ds.step)
+	finalizeStep(ds.step, ds.math)
 	return ds.step.Content.Nodes, nil
 }
 
@@ -214,6 +221,7 @@ func parseDoc(doc *html.Node, opts parser.Options) (*types.Codelab, error) {
 	ds := newDocState()
 	ds.css = style
 	ds.passMetadata = opts.PassMetadata
+	ds.math = opts.Math
 
 	for ds.cur = body.FirstChild; ds.cur != nil; ds.cur = ds.cur.NextSibling {
 		if isComment(ds.css, ds.cur) {
@@ -242,14 +250,19 @@ func parseDoc(doc *html.Node, opts parser.Options) (*types.Codelab, error) {
 		}
 	}
 
-	finalizeStep(ds.step) // TODO: last ds.step is never finalized in newStep
+	finalizeStep(ds.step, ds.math) // TODO: last ds.step is never finalized in newStep
+	if err := types.CheckLang(ds.clab.Lang); err != nil {
+		return nil, err
+	}
 	ds.clab.Tags = util.Unique(ds.clab.Tags)
 	sort.Strings(ds.clab.Tags)
 	ds.clab.Duration = int(ds.totdur.Minutes())
 	return ds.clab, nil
 }
 
-func finalizeStep(s *types.Step) {
+// finalizeStep cleans up content of step s, parsing math expressions
+// in its text if math is true, and applies directives.
+func finalizeStep(s *types.Step, math bool) {
 	if s == nil {
 		return
 	}
@@ -257,6 +270,10 @@ func finalizeStep(s *types.Step) {
 	sort.Strings(s.Tags)
 	s.Content.Nodes = parser.BlockNodes(s.Content.Nodes)
 	s.Content.Nodes = parser.CompactNodes(s.Content.Nodes)
+	if math {
+		s.Content.Nodes = parser.SplitMath(s.Content.Nodes)
+	}
+	s.Content.Nodes = directives(s.Content.Nodes)
 	// TODO: find a better place for the code below
 	// find [[directive]] instructions and act accordingly
 	for i, n := range s.Content.Nodes {
@@ -380,7 +397,7 @@ func newStep(ds *docState) {
 	if t == "" {
 		return
 	}
-	finalizeStep(ds.step)
+	finalizeStep(ds.step, ds.math)
 	ds.step = ds.clab.NewStep(t)
 	ds.env = nil
 }
@@ -413,6 +430,8 @@ func metaTable(ds *docState) {
 			ds.clab.Feedback = s
 		case "analytics", "analytics_account", "google_analytics":
 			ds.clab.GA = s
+		case "lang", "language":
+			ds.clab.Lang = s
 		default:
 			// If not explicitly parsed, it might be a pass_metadata value.
 			if _, ok := ds.passMetadata[fieldName]; ok {
@@ -557,6 +576,12 @@ func tableRow(ds *docState) []*nodes.GridCell {
 }
 
 // survey expects a header followed by 1 or more lists.
+//
+// A header starting with "Quiz:" makes the question a graded quiz question,
+// whose options in bold are the correct answers. Without the prefix,
+// bold options are ordinary options of an ungraded poll.
+// A paragraph following the options and starting with "Explanation:"
+// is shown to the learner once the question is answered.
 func survey(ds *docState) nodes.Node {
 	// find direct parent of the survey elements
 	hn := findAtom(ds.cur, atom.Ul)
@@ -571,12 +596,15 @@ func survey(ds *docState) nodes.Node {
 			c = c.NextSibling
 			continue
 		}
-		opt, next := surveyOpt(c.NextSibling)
-		if len(opt) > 0 {
-			gg = append(gg, &nodes.SurveyGroup{
-				Name:    stringifyNode(c, true, false),
-				Options: opt,
-			})
+		name := stringifyNode(c, true, false)
+		quiz := strings.HasPrefix(strings.ToLower(name), surveyQuiz)
+		if quiz {
+			name = strings.TrimSpace(name[len(surveyQuiz):])
+		}
+		g, next := surveyGroup(ds.css, c.NextSibling, quiz)
+		if len(g.Options) > 0 {
+			g.Name = name
+			gg = append(gg, g)
 		}
 		c = next
 	}
@@ -588,11 +616,21 @@ func survey(ds *docState) nodes.Node {
 	return nodes.NewSurveyNode(id, gg...)
 }
 
-func surveyOpt(hn *html.Node) ([]string, *html.Node) {
-	var opt []string
+// surveyGroup parses options of a single survey question starting at hn,
+// with bold options as correct answers if quiz is true.
+// It returns the group without a name, and the next question header, if any.
+func surveyGroup(css cssStyle, hn *html.Node, quiz bool) (*nodes.SurveyGroup, *html.Node) {
+	g := &nodes.SurveyGroup{}
 	for ; hn != nil; hn = hn.NextSibling {
 		if isHeader(hn) {
-			return opt, hn
+			break
+		}
+		if hn.DataAtom == atom.P {
+			v := stringifyNode(hn, true, true)
+			if strings.HasPrefix(strings.ToLower(v), surveyExplanation) {
+				g.Explanation = strings.TrimSpace(v[len(surveyExplanation):])
+			}
+			continue
 		}
 		if hn.DataAtom != atom.Ul {
 			continue
@@ -601,10 +639,14 @@ func surveyOpt(hn *html.Node) ([]string, *html.Node) {
 			if li.DataAtom != atom.Li {
 				continue
 			}
-			opt = append(opt, stringifyNode(li, true, true))
+			if quiz && hasBoldText(css, li) {
+				g.Correct = append(g.Correct, len(g.Options))
+			}
+			g.Options = append(g.Options, stringifyNode(li, true, true))
 		}
 	}
-	return opt, nil
+	g.Multi = len(g.Correct) > 1
+	return g, hn
 }
 
 // code parses hn as inline or block codes.
@@ -615,3 +615,161 @@ func TestParseFragment(t *testing.T) {
 		t.Errorf("nodes:\n\n%s\nwant:\n\n%s", html1, html2)
 	}
 }
+
+func TestParseSurveyQuiz(t *testing.T) {
+	const markup = `
+	<html><head><style>
+		.bold { font-weight: bold }
+		.survey { background-color: #cfe2f3 }
+	</style></head>
+	<body>
+		<p class="title"><span>Test Codelab</span></p>
+		<h1>Quiz</h1>
+		<table cellpadding="0" cellspacing="0"><tbody><tr>
+		<td class="survey" colspan="1" rowspan="1">
+		<h4><span>Pick a color</span></h4>
+		<ul><li><span>red</span></li><li><span class="bold">blue</span></li></ul>
+		<h4><span>Quiz: Pick all primes</span></h4>
+		<ul>
+			<li><span class="bold">2</span></li>
+			<li><span class="bold">3</span></li>
+			<li><span>4</span></li>
+		</ul>
+		<p><span>Explanation: 4 is even.</span></p>
+		</td>
+		</tr></tbody></table>
+	</body>
+	</html>
+	`
+	p := &Parser{}
+	c, err := p.Parse(markupReader(markup), *parser.NewOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []*nodes.SurveyGroup
+	for _, sn := range nodes.SurveyNodes(c.Steps[0].Content.Nodes) {
+		got = append(got, sn.Groups...)
+	}
+	want := []*nodes.SurveyGroup{
+		{
+			Name:    "Pick a color",
+			Options: []string{"red", "blue"},
+		},
+		{
+			Name:        "Pick all primes",
+			Options:     []string{"2", "3", "4"},
+			Correct:     []int{0, 1},
+			Multi:       true,
+			Explanation: "4 is even.",
+		},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("survey groups:\n%+v\nwant:\n%+v", got, want)
+	}
+}
+
+func TestParseMath(t *testing.T) {
+	const markup = `
+	<html><head><style>
+		.code { font-family: "Courier New" }
+	</style></head>
+	<body>
+		<p class="title"><span>Test Codelab</span></p>
+		<h1>Math</h1>
+		<p><span>Mass-energy: $E = mc^2$, priced $5 or $10.</span></p>
+		<p><span>$$\int_0^1 x\,dx$$</span></p>
+		<p><span class="code">$not math$</span></p>
+	</body>
+	</html>
+	`
+	p := &Parser{}
+	opts := *parser.NewOptions()
+	opts.Math = true
+	c, err := p.Parse(markupReader(markup), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []*nodes.MathNode
+	for _, n := range c.Steps[0].Content.Nodes {
+		l, ok := n.(*nodes.ListNode)
+		if !ok {
+			continue
+		}
+		for _, n := range l.Nodes {
+			if m, ok := n.(*nodes.MathNode); ok {
+				got = append(got, m)
+			}
+		}
+	}
+	want := []*nodes.MathNode{
+		nodes.NewMathNode("E = mc^2", false),
+		nodes.NewMathNode(`\int_0^1 x\,dx`, true),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d math nodes, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		if got[i].Value != w.Value || got[i].Display != w.Display {
+			t.Errorf("math node %d = %q, %t; want %q, %t", i, got[i].Value, got[i].Display, w.Value, w.Display)
+		}
+	}
+}
+
+func init() {
+	parser.RegisterDirective("test-gdoc-widget", func(d *parser.Directive) nodes.Node {
+		return d.Node()
+	})
+}
+
+func TestParseDirective(t *testing.T) {
+	const markup = `
+	<html><head></head>
+	<body>
+		<p class="title"><span>Test Codelab</span></p>
+		<h1>Directives</h1>
+		<p><span>:::test-gdoc-widget color=“dark blue” wide</span></p>
+		<p><span>Some </span><span style="font-weight: bold">body</span><span> text.</span></p>
+		<p><span>:::</span></p>
+		<p><span>::test-gdoc-widget label=new</span></p>
+		<p><span>::unknown-widget</span></p>
+	</body>
+	</html>
+	`
+	p := &Parser{}
+	c, err := p.Parse(markupReader(markup), *parser.NewOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []*nodes.CustomNode
+	var rest []string
+	for _, n := range c.Steps[0].Content.Nodes {
+		if cn, ok := n.(*nodes.CustomNode); ok {
+			got = append(got, cn)
+			continue
+		}
+		s, err := render.Text(render.Context{}, n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rest = append(rest, strings.TrimSpace(s))
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d custom nodes, want 2; other content: %q", len(got), rest)
+	}
+	if want := map[string]string{"color": "dark blue", "wide": ""}; !reflect.DeepEqual(got[0].Attrs, want) {
+		t.Errorf("block directive attrs = %v, want %v", got[0].Attrs, want)
+	}
+	body, err := render.Text(render.Context{}, got[0].Content.Nodes...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(body) != "Some body text." {
+		t.Errorf("block directive content = %q, want %q", body, "Some body text.")
+	}
+	if want := map[string]string{"label": "new"}; !reflect.DeepEqual(got[1].Attrs, want) || !got[1].Content.Empty() {
+		t.Errorf("leaf directive = %v, %s; want %v without content", got[1].Attrs, nodes.Dump(got[1].Content), want)
+	}
+	if want := []string{"::unknown-widget"}; !reflect.DeepEqual(rest, want) {
+		t.Errorf("other content = %q, want %q", rest, want)
+	}
+}
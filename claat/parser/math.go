@@ -0,0 +1,146 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"strings"
+
+	"github.com/googlecodelabs/tools/claat/nodes"
+)
+
+// FindMath returns location of the first math expression in s,
+// delimited with either $...$ for inline math or $$...$$ for display math.
+// The expression, including delimiters, is s[start:end].
+// Start is negative if s contains no math.
+//
+// Inline math must not start or end with a space, contain unescaped $
+// or be followed by a digit, so that text like "$5 or $10" is not math.
+// Delimiters escaped with a backslash are ignored.
+func FindMath(s string) (start, end int, display bool) {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+			continue
+		case '$':
+			// math delimiter
+		default:
+			continue
+		}
+		if strings.HasPrefix(s[i:], "$$") {
+			if j := closeDisplayMath(s[i+2:]); j >= 0 {
+				return i, i + 2 + j + 2, true
+			}
+			i++
+			continue
+		}
+		if j := closeInlineMath(s[i+1:]); j >= 0 {
+			return i, i + 1 + j + 1, false
+		}
+	}
+	return -1, -1, false
+}
+
+// closeDisplayMath returns index of the closing $$ in s, or -1.
+func closeDisplayMath(s string) int {
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			i++
+		case strings.HasPrefix(s[i:], "$$"):
+			return i
+		}
+	}
+	return -1
+}
+
+// closeInlineMath returns index of the closing $ in s, or -1.
+func closeInlineMath(s string) int {
+	if s == "" || isSpace(s[0]) {
+		return -1
+	}
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '$':
+			if i == 0 || isSpace(s[i-1]) || i+1 < len(s) && '0' <= s[i+1] && s[i+1] <= '9' {
+				return -1
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// SplitMath replaces math expressions found in non-code text nodes
+// with math nodes, recursively.
+// Although the input slice is not modified, its elements are.
+func SplitMath(nodesToSplit []nodes.Node) []nodes.Node {
+	return nodes.Rewrite(nodesToSplit, func(n nodes.Node) []nodes.Node {
+		if t, ok := n.(*nodes.TextNode); ok && !t.Code {
+			return splitTextMath(t)
+		}
+		return []nodes.Node{n}
+	})
+}
+
+// splitTextMath splits t into text and math nodes.
+// It returns t as is if it contains no math.
+func splitTextMath(t *nodes.TextNode) []nodes.Node {
+	var res []nodes.Node
+	v := t.Value
+	for {
+		start, end, display := FindMath(v)
+		if start < 0 {
+			break
+		}
+		if start > 0 {
+			res = append(res, splitText(t, v[:start]))
+		}
+		delim := 1
+		if display {
+			delim = 2
+		}
+		m := nodes.NewMathNode(v[start+delim:end-delim], display)
+		m.MutateBlock(t.Block())
+		m.MutateEnv(t.Env())
+		res = append(res, m)
+		v = v[end:]
+	}
+	if res == nil {
+		return []nodes.Node{t}
+	}
+	if v != "" {
+		res = append(res, splitText(t, v))
+	}
+	return res
+}
+
+// splitText creates a copy of t with value v.
+func splitText(t *nodes.TextNode, v string) *nodes.TextNode {
+	n := nodes.NewTextNode(nodes.NewTextNodeOptions{
+		Value:  v,
+		Bold:   t.Bold,
+		Italic: t.Italic,
+	})
+	n.MutateBlock(t.Block())
+	n.MutateEnv(t.Env())
+	return n
+}
@@ -0,0 +1,71 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/googlecodelabs/tools/claat/nodes"
+)
+
+func TestFindMath(t *testing.T) {
+	tests := []struct {
+		in      string
+		math    string
+		display bool
+	}{
+		{in: "no math"},
+		{in: "$x$", math: "$x$"},
+		{in: "area is $\\pi r^2$.", math: "$\\pi r^2$"},
+		{in: "$$\\frac{a}{b}$$", math: "$$\\frac{a}{b}$$", display: true},
+		{in: "a $$\nx + y\n$$ b", math: "$$\nx + y\n$$", display: true},
+		{in: "costs $5 or $10"},
+		{in: "costs $5, or $x$", math: "$x$"},
+		{in: "$ x$"},
+		{in: "$x $"},
+		{in: "\\$x$"},
+		{in: "$x\\$y$", math: "$x\\$y$"},
+		{in: "$$x"},
+	}
+	for _, tc := range tests {
+		start, end, display := FindMath(tc.in)
+		var math string
+		if start >= 0 {
+			math = tc.in[start:end]
+		}
+		if math != tc.math || display != tc.display {
+			t.Errorf("FindMath(%q) = %q, %t; want %q, %t", tc.in, math, display, tc.math, tc.display)
+		}
+	}
+}
+
+func TestSplitMath(t *testing.T) {
+	env := []string{"web"}
+	text := func(v string) *nodes.TextNode {
+		n := nodes.NewTextNode(nodes.NewTextNodeOptions{Value: v, Bold: true})
+		n.MutateEnv(env)
+		return n
+	}
+	math := func(v string, display bool) *nodes.MathNode {
+		n := nodes.NewMathNode(v, display)
+		n.MutateEnv(env)
+		return n
+	}
+	code := nodes.NewTextNode(nodes.NewTextNodeOptions{Value: "$x$", Code: true})
+	in := []nodes.Node{
+		nodes.NewListNode(text("Euler: $e^{i\\pi}+1=0$, and $$x$$")),
+		code,
+	}
+	want := []nodes.Node{
+		nodes.NewListNode(
+			text("Euler: "),
+			math("e^{i\\pi}+1=0", false),
+			text(", and "),
+			math("x", true),
+		),
+		code,
+	}
+	got := SplitMath(in)
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("SplitMath() = %+v\nwant %+v", got[0], want[0])
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package md
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/googlecodelabs/tools/claat/nodes"
+	"github.com/googlecodelabs/tools/claat/parser"
+	"golang.org/x/net/html"
+)
+
+// Attributes of a div element a directive is converted into
+// before the markdown is rendered to HTML.
+const (
+	directiveNameAttr = "data-claat-directive"
+	directiveArgsAttr = "data-claat-args"
+	directiveBodyAttr = "data-claat-body"
+)
+
+var fenceRegexp = regexp.MustCompile("^ {0,3}(```|~~~)")
+
+// convertDirectives replaces directives registered with parser.RegisterDirective
+// with div elements, which are later converted to nodes by the directive func.
+// The body of a block directive is kept as markdown, so that it is
+// rendered along with the rest of the content.
+// Directives in fenced code blocks are left as is.
+func convertDirectives(content []byte) []byte {
+	lines := strings.Split(string(content), "\n")
+	type frame struct {
+		out  int // index of the opening line in the output
+		line int // index of the opening line in the input
+		name string
+		args string
+	}
+	var (
+		out   []string
+		stack []frame
+		fence string
+	)
+	for i, l := range lines {
+		if m := fenceRegexp.FindStringSubmatch(l); m != nil {
+			if fence == "" {
+				fence = m[1]
+			} else if m[1] == fence {
+				fence = ""
+			}
+			out = append(out, l)
+			continue
+		}
+		if fence != "" {
+			out = append(out, l)
+			continue
+		}
+		kind, name, args := parser.ParseDirectiveLine(l)
+		_, registered := parser.LookupDirective(name)
+		switch {
+		case kind == parser.DirectiveClose && len(stack) > 0:
+			f := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			body := strings.Join(lines[f.line+1:i], "\n")
+			out[f.out] = directiveDiv(f.name, f.args, body) + "\n"
+			out = append(out, "", "</div>", "")
+			continue
+		case kind == parser.DirectiveOpen && registered:
+			stack = append(stack, frame{out: len(out), line: i, name: name, args: args})
+			out = append(out, l)
+			continue
+		case kind == parser.DirectiveLeaf && registered:
+			out = append(out, directiveDiv(name, args, "")+"</div>", "")
+			continue
+		}
+		out = append(out, l)
+	}
+	// Unclosed directives remain in the output as they were written.
+	return []byte(strings.Join(out, "\n"))
+}
+
+// directiveDiv returns an opening div element of a directive.
+// Newlines are encoded so that the element stays a single HTML block.
+func directiveDiv(name, args, body string) string {
+	var b bytes.Buffer
+	b.WriteString(`<div ` + directiveNameAttr + `="` + html.EscapeString(name) + `"`)
+	if args != "" {
+		b.WriteString(` ` + directiveArgsAttr + `="` + escapeDirectiveAttr(args) + `"`)
+	}
+	if body != "" {
+		b.WriteString(` ` + directiveBodyAttr + `="` + escapeDirectiveAttr(body) + `"`)
+	}
+	b.WriteString(">")
+	return b.String()
+}
+
+func escapeDirectiveAttr(s string) string {
+	s = html.EscapeString(s)
+	s = strings.Replace(s, "\r", "", -1)
+	return strings.Replace(s, "\n", "&#10;", -1)
+}
+
+// directive converts ds.cur into a node using a func registered
+// for the directive name.
+func directive(ds *docState) nodes.Node {
+	name := nodeAttr(ds.cur, directiveNameAttr)
+	fn, ok := parser.LookupDirective(name)
+	if !ok {
+		return nil
+	}
+	d := &parser.Directive{
+		Name: name,
+		Args: parser.ParseDirectiveArgs(nodeAttr(ds.cur, directiveArgsAttr)),
+		Body: nodeAttr(ds.cur, directiveBodyAttr),
+	}
+	ds.push(nil)
+	nn := parseSubtree(ds)
+	nn = parser.BlockNodes(nn)
+	d.Content = parser.CompactNodes(nn)
+	ds.pop()
+	n := fn(d)
+	if n == nil {
+		return nil
+	}
+	n.MutateBlock(true)
+	return n
+}
@@ -0,0 +1,98 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package md
+
+import (
+	"bytes"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// fileAttr is the attribute of the <code> element of a fenced code block
+// holding the file name annotation of the block.
+const fileAttr = "data-file"
+
+// fenceInfo splits info string of a fenced code block into the language
+// and the file name annotation of the block, which is the word following
+// the language, optionally prefixed with "file=", e.g. "main.go"
+// in "go main.go" or "go file=main.go". A block without a language
+// can be annotated with "file=main.go" alone.
+func fenceInfo(info []byte) (lang, file []byte) {
+	f := bytes.Fields(info)
+	if len(f) > 0 && bytes.HasPrefix(f[0], fileParam) {
+		return nil, f[0][len(fileParam):]
+	}
+	if len(f) > 0 {
+		lang = f[0]
+	}
+	if len(f) > 1 {
+		file = bytes.TrimPrefix(f[1], fileParam)
+	}
+	return lang, file
+}
+
+// fileParam is the optional prefix of file name annotations.
+var fileParam = []byte("file=")
+
+// fenceRenderer renders fenced code blocks like the goldmark HTML renderer,
+// adding the file name annotation of the block as fileAttr.
+type fenceRenderer struct{}
+
+// RegisterFuncs implements renderer.NodeRenderer.
+func (r *fenceRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindFencedCodeBlock, r.render)
+}
+
+func (r *fenceRenderer) render(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		w.WriteString("</code></pre>\n")
+		return ast.WalkContinue, nil
+	}
+	fcb := n.(*ast.FencedCodeBlock)
+	w.WriteString("<pre><code")
+	var lang, file []byte
+	if fcb.Info != nil {
+		lang, file = fenceInfo(fcb.Info.Segment.Value(source))
+	}
+	if len(lang) > 0 {
+		w.WriteString(` class="language-`)
+		w.Write(util.EscapeHTML(lang))
+		w.WriteString(`"`)
+	}
+	if len(file) > 0 {
+		w.WriteString(" " + fileAttr + `="`)
+		w.Write(util.EscapeHTML(file))
+		w.WriteString(`"`)
+	}
+	w.WriteByte('>')
+	lines := fcb.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		w.Write(util.EscapeHTML(line.Value(source)))
+	}
+	return ast.WalkContinue, nil
+}
+
+// fenceExtension is a goldmark extension which keeps file name annotations
+// of fenced code blocks, e.g. "```go main.go".
+type fenceExtension struct{}
+
+// Extend implements goldmark.Extender.
+func (e *fenceExtension) Extend(m goldmark.Markdown) {
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(&fenceRenderer{}, 500)))
+}
@@ -114,6 +114,14 @@ func isCode(hn *html.Node) bool {
 	return hn.DataAtom == atom.Code && !isConsole(hn)
 }
 
+func isMath(hn *html.Node) bool {
+	return hn.DataAtom == atom.Span && hasClass(hn, mathClass)
+}
+
+func isDirective(hn *html.Node) bool {
+	return hn.Type == html.ElementNode && hasAttr(hn, directiveNameAttr)
+}
+
 func isButton(hn *html.Node) bool {
 	return hn.DataAtom == atom.Button
 }
@@ -161,6 +169,12 @@ func isSurvey(hn *html.Node) bool {
 	return true
 }
 
+// isSurveyExplanation returns true if hn is an answer explanation
+// of a survey question.
+func isSurveyExplanation(hn *html.Node) bool {
+	return hn.Type == html.ElementNode && hn.Data == surveyExplanationTag
+}
+
 // TODO Write an explanation for why the countTwo checks are necessary.
 func isTable(hn *html.Node) bool {
 	if hn.DataAtom != atom.Table {
@@ -182,6 +196,10 @@ func isFragmentImport(hn *html.Node) bool {
 	return hn.DataAtom == 0 && strings.HasPrefix(hn.Data, convertedImportsDataPrefix)
 }
 
+func isCodeSnippet(hn *html.Node) bool {
+	return hn.DataAtom == 0 && strings.HasPrefix(hn.Data, convertedSnippetsDataPrefix)
+}
+
 // countTwo starts counting the number of a Atom children in hn.
 // It returns as soon as the count exceeds 1, so the returned value is inexact.
 //
@@ -293,6 +311,29 @@ func nodeAttr(n *html.Node, key string) string {
 	return ""
 }
 
+// hasAttr reports whether the given node has an HTML attribute with the given key,
+// regardless of its value.
+// Keys are case insensitive.
+func hasAttr(n *html.Node, key string) bool {
+	key = strings.ToLower(key)
+	for _, attr := range n.Attr {
+		if strings.ToLower(attr.Key) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// hasClass reports whether the given node has the given class name.
+func hasClass(n *html.Node, name string) bool {
+	for _, c := range strings.Fields(nodeAttr(n, "class")) {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
 // TODO divide into smaller functions
 // TODO redo comment, more than just text nodes are handled and atom.A
 // TODO should we really have trim?
@@ -0,0 +1,132 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package md
+
+import (
+	"github.com/googlecodelabs/tools/claat/parser"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	gmparser "github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	gmtext "github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Classes of the <span> element goldmark renders math into.
+const (
+	mathClass        = "math"
+	mathDisplayClass = "display"
+)
+
+// kindMath is the goldmark AST node kind of a math expression.
+var kindMath = ast.NewNodeKind("Math")
+
+// mathNode is a goldmark AST node of a math expression in LaTeX notation,
+// written as $...$ or $$...$$ in markdown.
+type mathNode struct {
+	ast.BaseInline
+	value   []byte
+	display bool
+}
+
+// Kind implements ast.Node.
+func (n *mathNode) Kind() ast.NodeKind {
+	return kindMath
+}
+
+// Dump implements ast.Node.
+func (n *mathNode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Value": string(n.value)}, nil)
+}
+
+// mathParser is a goldmark inline parser of math expressions.
+// Similar to code spans, the content of math expressions is not
+// interpreted as markdown.
+type mathParser struct{}
+
+// Trigger implements gmparser.InlineParser.
+func (p *mathParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+// Parse implements gmparser.InlineParser.
+// Math expressions can span multiple lines of a paragraph.
+func (p *mathParser) Parse(parent ast.Node, block gmtext.Reader, pc gmparser.Context) ast.Node {
+	l, pos := block.Position()
+	var rest []byte
+	for {
+		line, _ := block.PeekLine()
+		if line == nil {
+			break
+		}
+		rest = append(rest, line...)
+		block.AdvanceLine()
+	}
+	block.SetPosition(l, pos)
+
+	start, end, display := parser.FindMath(string(rest))
+	if start != 0 {
+		return nil
+	}
+	delim := 1
+	if display {
+		delim = 2
+	}
+	n := &mathNode{value: rest[delim : end-delim], display: display}
+	for end > 0 {
+		line, _ := block.PeekLine()
+		if end < len(line) {
+			block.Advance(end)
+			break
+		}
+		end -= len(line)
+		block.AdvanceLine()
+	}
+	return n
+}
+
+// mathRenderer renders math nodes as <span class="math"> elements
+// with LaTeX source in text content.
+type mathRenderer struct{}
+
+// RegisterFuncs implements renderer.NodeRenderer.
+func (r *mathRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(kindMath, r.render)
+}
+
+func (r *mathRenderer) render(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	m := n.(*mathNode)
+	w.WriteString(`<span class="` + mathClass)
+	if m.display {
+		w.WriteString(" " + mathDisplayClass)
+	}
+	w.WriteString(`">`)
+	w.Write(util.EscapeHTML(m.value))
+	w.WriteString("</span>")
+	return ast.WalkSkipChildren, nil
+}
+
+// mathExtension is a goldmark extension which adds support
+// for $...$ and $$...$$ math expressions.
+type mathExtension struct{}
+
+// Extend implements goldmark.Extender.
+func (e *mathExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(gmparser.WithInlineParsers(util.Prioritized(&mathParser{}, 150)))
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(&mathRenderer{}, 500)))
+}
@@ -57,6 +57,7 @@ const (
 	MetaTags                = "tags"
 	MetaSource              = "source"
 	MetaDuration            = "duration"
+	MetaLang                = "lang"
 )
 
 const (
@@ -65,6 +66,10 @@ const (
 	metaEnvironment = "environment" // step environment instruction
 	metaTagImport   = "import"      // import remote resource instruction
 
+	// surveyExplanationTag is an element with an answer explanation
+	// of a graded survey question.
+	surveyExplanationTag = "explanation"
+
 	// possible content of special header nodes in lower case.
 	headerLearn = "what you'll learn"
 	headerCover = "what we've covered"
@@ -72,10 +77,14 @@ const (
 )
 
 var (
-	importsTagRegexp           = regexp.MustCompile("^<<([^<>()]+.md)>>\\s*$")
+	importsTagRegexp           = regexp.MustCompile("^<<([^<>()]+.md(?:\\?[^<>()\\s]*)?)>>\\s*$")
 	convertedImportsDataPrefix = "__unsupported_import_zmcgv2epyv="
 	convertedImportsPrefix     = []byte("<!--" + convertedImportsDataPrefix)
 	convertedImportsSuffix     = []byte("-->")
+
+	snippetsTagRegexp           = regexp.MustCompile("^<<snippet\\s+([^<>()\\s]+)>>\\s*$")
+	convertedSnippetsDataPrefix = "__unsupported_snippet_zmcgv2epyv="
+	convertedSnippetsPrefix     = []byte("<!--" + convertedSnippetsDataPrefix)
 )
 
 var metadataRegexp = regexp.MustCompile(`(.+?):(.+)`)
@@ -118,7 +127,7 @@ func (p *Parser) Parse(r io.Reader, opts parser.Options) (*types.Codelab, error)
 	if err != nil {
 		return nil, err
 	}
-	b, err = renderToHTML(b)
+	b, err = renderToHTML(b, opts.Math)
 	if err != nil {
 		return nil, err
 	}
@@ -137,7 +146,7 @@ func (p *Parser) ParseFragment(r io.Reader, opts parser.Options) ([]nodes.Node,
 	if err != nil {
 		return nil, err
 	}
-	b, err = renderToHTML(b)
+	b, err = renderToHTML(b, opts.Math)
 	if err != nil {
 		return nil, err
 	}
@@ -231,9 +240,15 @@ func (ds *docState) appendNodes(nn ...nodes.Node) {
 
 // renderToHTML preprocesses Markdown bytes and then calls a Markdown parser on the Markdown.
 // It takes a raw markdown bytes and outputs parsed xhtml in bytes.
-func renderToHTML(b []byte) ([]byte, error) {
+// Math expressions are parsed only if math is true.
+func renderToHTML(b []byte, math bool) ([]byte, error) {
+	b = convertDirectives(b)
 	b = convertImports(b)
-	gmParser := goldmark.New(goldmark.WithRendererOptions(gmhtml.WithUnsafe()), goldmark.WithExtensions(extension.Typographer, extension.Table))
+	exts := []goldmark.Extender{extension.Typographer, extension.Table, &fenceExtension{}}
+	if math {
+		exts = append(exts, &mathExtension{})
+	}
+	gmParser := goldmark.New(goldmark.WithRendererOptions(gmhtml.WithUnsafe()), goldmark.WithExtensions(exts...))
 	var out bytes.Buffer
 	if err := gmParser.Convert(b, &out); err != nil {
 		panic(err)
@@ -340,6 +355,10 @@ func parseNode(ds *docState) (nodes.Node, bool) {
 	case isMeta(ds.cur):
 		metaStep(ds)
 		return nil, true
+	case isDirective(ds.cur):
+		return directive(ds), true
+	case isMath(ds.cur):
+		return math(ds), true
 	case ds.cur.Type == html.TextNode || ds.cur.DataAtom == atom.Br:
 		return text(ds), true
 	case ds.cur.DataAtom == atom.A:
@@ -370,6 +389,8 @@ func parseNode(ds *docState) (nodes.Node, bool) {
 		return youtube(ds), true
 	case isFragmentImport(ds.cur):
 		return fragmentImport(ds), true
+	case isCodeSnippet(ds.cur):
+		return codeSnippet(ds), true
 	}
 	return nil, false
 }
@@ -449,6 +470,14 @@ func addMetadataToCodelab(m map[string]string, c *types.Codelab, opts parser.Opt
 		case MetaSource:
 			// Directly assign the source doc ID to the source field.
 			c.Source = v
+		case MetaLang:
+			// Assign the content language to the codelab field,
+			// rejecting anything that is not a BCP 47 tag.
+			lang := strings.TrimSpace(v)
+			if err := types.CheckLang(lang); err != nil {
+				return err
+			}
+			c.Lang = lang
 		case MetaDuration:
 			// Convert the duration to an integer and assign to the duration field.
 			duration, err := strconv.Atoi(v)
@@ -656,23 +685,34 @@ func tableRow(ds *docState) []*nodes.GridCell {
 
 // survey expects 1 or more name Nodes followed by 1 or more input Nodes.
 // Each input node is expected to have a value attribute.
+//
+// Inputs marked with a "correct" attribute turn the group into a graded
+// quiz question. A "multiple" attribute on the name node allows selecting
+// more than one option, and an optional explanation node following the inputs
+// is shown once the question is answered.
 func survey(ds *docState) nodes.Node {
 	var gg []*nodes.SurveyGroup
 	ns := findChildAtoms(ds.cur, atom.Name)
 	for _, n := range ns {
 		var inputs []*html.Node
+		var explanation string
 		for hn := n.NextSibling; hn != nil; hn = hn.NextSibling {
 			if hn.DataAtom == atom.Input {
 				inputs = append(inputs, hn)
+			} else if isSurveyExplanation(hn) {
+				explanation = stringifyNode(hn, true)
 			} else if hn.DataAtom == atom.Name {
 				break
 			}
 		}
-		opt := surveyOpt(inputs)
+		opt, correct := surveyOpt(inputs)
 		if len(opt) > 0 {
 			gg = append(gg, &nodes.SurveyGroup{
-				Name:    strings.TrimSpace(n.FirstChild.Data),
-				Options: opt,
+				Name:        strings.TrimSpace(n.FirstChild.Data),
+				Options:     opt,
+				Correct:     correct,
+				Multi:       hasAttr(n, "multiple") || len(correct) > 1,
+				Explanation: explanation,
 			})
 		}
 	}
@@ -684,16 +724,22 @@ func survey(ds *docState) nodes.Node {
 	return nodes.NewSurveyNode(id, gg...)
 }
 
-func surveyOpt(inputs []*html.Node) []string {
+// surveyOpt returns values of the inputs, along with indices of those
+// marked as correct answers.
+func surveyOpt(inputs []*html.Node) ([]string, []int) {
 	var opt []string
+	var correct []int
 	for _, input := range inputs {
 		for _, attr := range input.Attr {
 			if attr.Key == "value" {
+				if hasAttr(input, "correct") {
+					correct = append(correct, len(opt))
+				}
 				opt = append(opt, attr.Val)
 			}
 		}
 	}
-	return opt
+	return opt, correct
 }
 
 // code parses hn as inline or block codes.
@@ -723,7 +769,13 @@ func code(ds *docState, term bool) nodes.Node {
 			}
 		}
 	}
+	if kind := strings.TrimPrefix(lan, "language-"); nodes.IsDiagramKind(kind) {
+		n := nodes.NewDiagramNode(kind, strings.TrimLeft(v, "\n"))
+		n.MutateBlock(elem)
+		return n
+	}
 	n := nodes.NewCodeNode(v, term, lan)
+	n.File = nodeAttr(ds.cur, fileAttr)
 	n.MutateBlock(elem)
 	return n
 }
@@ -836,6 +888,19 @@ func fragmentImport(ds *docState) nodes.Node {
 	return nil
 }
 
+// codeSnippet creates a CodeNode whose content is read from a source file
+// at export time.
+func codeSnippet(ds *docState) nodes.Node {
+	src := strings.TrimPrefix(ds.cur.Data, convertedSnippetsDataPrefix)
+	if src == "" {
+		return nil
+	}
+	n := nodes.NewCodeSnippetNode(html.UnescapeString(src))
+	// each snippet is a block on its own
+	n.MutateBlock(ds.cur)
+	return n
+}
+
 func iframe(ds *docState) nodes.Node {
 	u, err := url.Parse(nodeAttr(ds.cur, "alt"))
 	if err != nil {
@@ -879,6 +944,13 @@ func button(ds *docState) nodes.Node {
 	return ln
 }
 
+// math creates a MathNode out of a math expression rendered by mathExtension.
+func math(ds *docState) nodes.Node {
+	n := nodes.NewMathNode(stringifyNode(ds.cur, false), hasClass(ds.cur, mathDisplayClass))
+	n.MutateBlock(findNearestBlockAncestor(ds.cur))
+	return n
+}
+
 // Link creates a URLNode out of hn, parsing href and name attributes.
 // It returns nil if hn contents is empty.
 // The resuling link's content is always a single text node.
@@ -1002,7 +1074,13 @@ func convertImports(content []byte) []byte {
 	slices := bytes.Split(content, []byte("\n"))
 	escaped := [][]byte{}
 	for _, slice := range slices {
-		if matches := importsTagRegexp.FindSubmatch(slice); len(matches) > 0 {
+		if matches := snippetsTagRegexp.FindSubmatch(slice); len(matches) > 1 {
+			slice = bytes.Join([][]byte{
+				convertedSnippetsPrefix,
+				[]byte(html.EscapeString(string(matches[1]))),
+				convertedImportsSuffix,
+			}, []byte(""))
+		} else if matches := importsTagRegexp.FindSubmatch(slice); len(matches) > 0 {
 			if len(matches) > 1 {
 				url := string(matches[1])
 				slice = bytes.Join([][]byte{
@@ -239,6 +239,21 @@ extra_field_two: bbbbb
 	}
 }
 
+func TestParseMetadataLang(t *testing.T) {
+	content := "---\nid: codelab\nlang: pt-BR\n\n---\n# Title\n"
+	c := mustParseCodelab(content, *parser.NewOptions())
+	if c.Lang != "pt-BR" {
+		t.Errorf("c.Lang = %q, want %q", c.Lang, "pt-BR")
+	}
+
+	for _, lang := range []string{"../../escaped", "fr/../..", "en us"} {
+		content := "---\nid: codelab\nlang: " + lang + "\n\n---\n# Title\n"
+		if _, err := parseCodelab(content, *parser.NewOptions()); err == nil {
+			t.Errorf("parseCodelab(lang: %s): want error", lang)
+		}
+	}
+}
+
 func TestParseFragment(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -336,8 +351,9 @@ after
 with space
 <<space/is allowed.md>>
 ## Step 2
-<<import_another_file.md>>`,
-			want: []string{"import1.md", "example/import2.md", "space/is allowed.md", "import_another_file.md"},
+<<import_another_file.md>>
+<<git+https://example.com/repo.git//intro.md?ref=v1.0>>`,
+			want: []string{"import1.md", "example/import2.md", "space/is allowed.md", "import_another_file.md", "git+https://example.com/repo.git//intro.md?ref=v1.0"},
 		},
 		{
 			name: "import not in steps",
@@ -416,3 +432,201 @@ I'm going to inject some HTML
 		})
 	}
 }
+
+func TestParseSurveyQuiz(t *testing.T) {
+	input := stdHeader + `
+## Step 1
+
+<form>
+<name>Pick a color</name>
+<input value="red">
+<input value="blue">
+<name multiple>Pick all primes</name>
+<input value="2" correct>
+<input value="3" correct>
+<input value="4">
+<explanation>4 is even.</explanation>
+</form>
+`
+	lab := mustParseCodelab(input, *parser.NewOptions())
+	var got []*nodes.SurveyGroup
+	for _, sn := range nodes.SurveyNodes(lab.Steps[0].Content.Nodes) {
+		got = append(got, sn.Groups...)
+	}
+	want := []*nodes.SurveyGroup{
+		{
+			Name:    "Pick a color",
+			Options: []string{"red", "blue"},
+		},
+		{
+			Name:        "Pick all primes",
+			Options:     []string{"2", "3", "4"},
+			Correct:     []int{0, 1},
+			Multi:       true,
+			Explanation: "4 is even.",
+		},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("Parsing\n%s\nGot groups:\n%+v\nWant groups:\n%+v\n", input, got, want)
+	}
+}
+
+func TestParseWithSnippet(t *testing.T) {
+	input := stdHeader + `
+## Step 1
+before
+
+<<snippet ../samples/app/main.go#setup>>
+<<snippet ../samples/app/main.go#teardown>>
+
+<<snippet not allowed>>
+<<snippet notes.md>>
+after`
+	lab := mustParseCodelab(input, *parser.NewOptions())
+	var got []string
+	for _, n := range nodes.CodeNodes(lab.Steps[0].Content.Nodes) {
+		got = append(got, n.Src)
+	}
+	want := []string{"../samples/app/main.go#setup", "../samples/app/main.go#teardown", "notes.md"}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("Parsing\n%s\nGot snippets:\n%s\nWant snippets:\n%s\n", input, got, want)
+	}
+	if imps := nodes.ImportNodes(lab.Steps[0].Content.Nodes); len(imps) != 0 {
+		t.Errorf("Parsing\n%s\nGot imports: %+v, want none", input, imps)
+	}
+}
+
+func TestParseMath(t *testing.T) {
+	input := stdHeader + "\n## Step 1\n" +
+		"Euler's identity is $e^{i\\pi} + 1 = 0$, it costs $5 or $10.\n\n" +
+		"$$\n\\sum_{i=1}^n i = \\frac{n(n+1)}{2}\n$$\n\n" +
+		"Escaped \\$x$ and `$code$`.\n"
+	opts := *parser.NewOptions()
+	opts.Math = true
+	lab := mustParseCodelab(input, opts)
+	var got []*nodes.MathNode
+	for _, n := range lab.Steps[0].Content.Nodes {
+		l, ok := n.(*nodes.ListNode)
+		if !ok {
+			continue
+		}
+		for _, n := range l.Nodes {
+			if m, ok := n.(*nodes.MathNode); ok {
+				got = append(got, m)
+			}
+		}
+	}
+	want := []struct {
+		value   string
+		display bool
+	}{
+		{`e^{i\pi} + 1 = 0`, false},
+		{"\n\\sum_{i=1}^n i = \\frac{n(n+1)}{2}\n", true},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Parsing\n%s\nGot %d math nodes, want %d", input, len(got), len(want))
+	}
+	for i, w := range want {
+		if got[i].Value != w.value || got[i].Display != w.display {
+			t.Errorf("math node %d = %q, %t; want %q, %t", i, got[i].Value, got[i].Display, w.value, w.display)
+		}
+	}
+}
+
+func TestParseMathDisabled(t *testing.T) {
+	input := stdHeader + "\n## Step 1\nEuler's identity is $e^{i\\pi} + 1 = 0$.\n"
+	lab := mustParseCodelab(input, *parser.NewOptions())
+	var hasMath bool
+	nodes.Inspect(lab.Steps[0].Content.Nodes, func(n nodes.Node) bool {
+		if _, ok := n.(*nodes.MathNode); ok {
+			hasMath = true
+		}
+		return true
+	})
+	if hasMath {
+		t.Errorf("Parsing\n%s\nGot math nodes with math disabled", input)
+	}
+}
+
+func TestParseDiagram(t *testing.T) {
+	input := stdHeader + "\n## Step 1\n" +
+		"```mermaid\ngraph TD\n  A-->B\n```\n\n" +
+		"```dot\ndigraph { a -> b }\n```\n\n" +
+		"```go\nfunc main() {}\n```\n"
+	lab := mustParseCodelab(input, *parser.NewOptions())
+	var got []string
+	for _, n := range nodes.DiagramNodes(lab.Steps[0].Content.Nodes) {
+		got = append(got, n.Kind+": "+n.Value)
+	}
+	want := []string{"mermaid: graph TD\n  A-->B\n", "dot: digraph { a -> b }\n"}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("Parsing\n%s\nGot diagrams:\n%q\nWant diagrams:\n%q\n", input, got, want)
+	}
+	if codes := nodes.CodeNodes(lab.Steps[0].Content.Nodes); len(codes) != 1 {
+		t.Errorf("Parsing\n%s\nGot %d code nodes, want 1", input, len(codes))
+	}
+}
+
+func TestParseCodeFile(t *testing.T) {
+	input := stdHeader + "\n## Step 1\n" +
+		"```go main.go\nfunc main() {}\n```\n\n" +
+		"```go file=cmd/tool.go\npackage main\n```\n\n" +
+		"``` file=go.mod\nmodule example.com/x\n```\n\n" +
+		"```go\nfunc f() {}\n```\n"
+	lab := mustParseCodelab(input, *parser.NewOptions())
+	var got []string
+	for _, n := range nodes.CodeNodes(lab.Steps[0].Content.Nodes) {
+		got = append(got, n.Lang+": "+n.File)
+	}
+	want := []string{"language-go: main.go", "language-go: cmd/tool.go", ": go.mod", "language-go: "}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("Parsing\n%s\nGot code blocks:\n%q\nWant code blocks:\n%q\n", input, got, want)
+	}
+}
+
+func init() {
+	parser.RegisterDirective("test-widget", func(d *parser.Directive) nodes.Node {
+		return d.Node()
+	})
+}
+
+func TestParseDirective(t *testing.T) {
+	input := stdHeader + "\n## Step 1\n" +
+		":::test-widget color=\"dark blue\" wide\nSome *body* text.\n:::\n\n" +
+		"::test-widget id=42\n\n" +
+		"```\n::test-widget id=code\n```\n\n" +
+		":::unknown\nnot a directive\n:::\n"
+	lab := mustParseCodelab(input, *parser.NewOptions())
+	var got []*nodes.CustomNode
+	var text string
+	for _, n := range lab.Steps[0].Content.Nodes {
+		switch n := n.(type) {
+		case *nodes.CustomNode:
+			got = append(got, n)
+		case *nodes.ListNode:
+			for _, n := range n.Nodes {
+				if tn, ok := n.(*nodes.TextNode); ok {
+					text += tn.Value
+				}
+			}
+		}
+	}
+	if len(got) != 2 {
+		t.Fatalf("Parsing\n%s\nGot %d custom nodes, want 2", input, len(got))
+	}
+	if got[0].Kind != "test-widget" || got[0].Value != "Some *body* text." || got[0].Block() != true {
+		t.Errorf("got[0] = %q, %q, block %v; want test-widget, %q, block true", got[0].Kind, got[0].Value, got[0].Block(), "Some *body* text.")
+	}
+	if want := map[string]string{"color": "dark blue", "wide": ""}; !reflect.DeepEqual(got[0].Attrs, want) {
+		t.Errorf("got[0].Attrs = %v; want %v", got[0].Attrs, want)
+	}
+	if len(got[0].Content.Nodes) != 1 {
+		t.Errorf("got[0] has %d content nodes, want 1", len(got[0].Content.Nodes))
+	}
+	if want := map[string]string{"id": "42"}; !reflect.DeepEqual(got[1].Attrs, want) || got[1].Value != "" {
+		t.Errorf("got[1] = %v, %q; want %v, empty", got[1].Attrs, got[1].Value, want)
+	}
+	if !strings.Contains(text, ":::unknown") {
+		t.Errorf("unregistered directive text %q does not contain \":::unknown\"", text)
+	}
+}
@@ -35,6 +35,8 @@ type Parser interface {
 // Container for parsing options.
 type Options struct {
 	PassMetadata map[string]bool
+	// Math enables parsing of $...$ and $$...$$ math expressions in text.
+	Math bool
 }
 
 func NewOptions() *Options {
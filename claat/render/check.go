@@ -0,0 +1,189 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/googlecodelabs/tools/claat/nodes"
+	"github.com/googlecodelabs/tools/claat/types"
+)
+
+// Check renders a sample codelab, see SampleCodelab, in the named format,
+// discarding the output. It reports the first parsing or execution error.
+// Errors of template formats include the template name and line number,
+// e.g. "template: custom.html:12:5: executing ...".
+//
+// The name argument is the same as the one of LookupFormat.
+func Check(name string, opt ...Option) error {
+	f, err := LookupFormat(name)
+	if err != nil {
+		return err
+	}
+	clab := SampleCodelab()
+	p := &Page{Context: Context{
+		Env:       "web",
+		Prefix:    "https://example.com",
+		GlobalGA:  "UA-0000000-0",
+		GlobalGA4: "G-0000000000",
+		Format:    name,
+		Meta:      &clab.Meta,
+		Steps:     clab.Steps,
+		Updated:   time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC).Format(time.RFC3339),
+		Extra:     map[string]string{"extra": "value"},
+	}}
+	if !f.PerStep() {
+		return f.Render(ioutil.Discard, p, opt...)
+	}
+	for i, step := range clab.Steps {
+		p.Current = step
+		p.StepNum = i + 1
+		p.Prev = i > 0
+		p.Next = i < len(clab.Steps)-1
+		if err := f.Render(ioutil.Discard, p, opt...); err != nil {
+			return fmt.Errorf("step %d: %v", i+1, err)
+		}
+	}
+	return nil
+}
+
+// SampleCodelab returns a synthetic codelab with all metadata fields set
+// and content of every node type, for testing templates and renderers.
+func SampleCodelab() *types.Codelab {
+	clab := types.NewCodelab()
+	clab.ID = "sample-codelab"
+	clab.Duration = 30
+	clab.Title = "Sample Codelab"
+	clab.Authors = "Codelab Author"
+	clab.Summary = "A codelab with every kind of content."
+	clab.Source = "sample-codelab.md"
+	clab.Theme = "web"
+	status := types.LegacyStatus{"draft"}
+	clab.Status = &status
+	clab.Categories = []string{"web", "sample"}
+	clab.Tags = []string{"kiosk", "web"}
+	clab.Feedback = "https://example.com/issues"
+	clab.GA = "UA-0000000-1"
+	clab.GA4 = "G-0000000001"
+	clab.Extra["extra"] = "value"
+
+	text := func(s string) *nodes.TextNode {
+		return nodes.NewTextNode(nodes.NewTextNodeOptions{Value: s})
+	}
+	block := func(n nodes.Node) nodes.Node {
+		n.MutateBlock(true)
+		return n
+	}
+
+	// Step 1: text and links
+	s := clab.NewStep("Text")
+	s.Duration = 5 * time.Minute
+	s.Content.Append(
+		nodes.NewHeaderNode(2, text("Header")),
+		block(nodes.NewListNode(
+			text("Plain, "),
+			nodes.NewTextNode(nodes.NewTextNodeOptions{Value: "bold", Bold: true}),
+			text(", "),
+			nodes.NewTextNode(nodes.NewTextNodeOptions{Value: "italic", Italic: true}),
+			text(" and "),
+			nodes.NewTextNode(nodes.NewTextNodeOptions{Value: "code", Code: true}),
+			text(" text with a "),
+			nodes.NewURLNode("https://example.com", text("link")),
+			text("."),
+		)),
+		block(nodes.NewButtonNode(true, true, true,
+			nodes.NewURLNode("https://example.com/code.zip", text("Download")))),
+		block(nodes.NewInfoboxNode(nodes.InfoboxPositive, text("Positive infobox"))),
+		block(nodes.NewInfoboxNode(nodes.InfoboxNegative, text("Negative infobox"))),
+		block(nodes.NewMathNode("e^{i\\pi} + 1 = 0", true)),
+		nodes.NewListNode(text("Inline math "), nodes.NewMathNode("x^2", false)),
+	)
+	ul := nodes.NewItemsListNode("", 0)
+	ul.NewItem(text("Unordered item"))
+	ol := nodes.NewItemsListNode("1", 1)
+	ol.NewItem(text("Ordered item"))
+	s.Content.Append(ul, ol)
+
+	// Step 2: code and media, for the web environment only
+	s = clab.NewStep("Code and media")
+	s.Tags = []string{"web"}
+	s.Duration = 10 * time.Minute
+	code := nodes.NewCodeNode("package main\n", false, "go")
+	code.MutateBlock(true)
+	term := nodes.NewCodeNode("go run main.go\n", true, "")
+	term.MutateBlock(true)
+	grid := nodes.NewGridNode(
+		[]*nodes.GridCell{
+			{Colspan: 1, Rowspan: 1, Content: nodes.NewListNode(text("Cell 1"))},
+			{Colspan: 1, Rowspan: 1, Content: nodes.NewListNode(text("Cell 2"))},
+		},
+	)
+	grid.MutateBlock(true)
+	imp := nodes.NewImportNode("https://example.com/fragment.md")
+	imp.Content.Append(text("Imported content"))
+	imp.MutateBlock(true)
+	s.Content.Append(
+		code,
+		term,
+		block(nodes.NewDiagramNode(nodes.DiagramMermaid, "graph TD; A-->B")),
+		grid,
+		block(nodes.NewImageNode(nodes.NewImageNodeOptions{
+			Src:   "img/sample.png",
+			Width: 320,
+			Alt:   "Sample image",
+			Title: "Sample",
+		})),
+		block(nodes.NewYouTubeNode("dQw4w9WgXcQ")),
+		block(nodes.NewIframeNode("https://example.com/embed")),
+		imp,
+		block(nodes.NewCustomNode("sample", text("Custom content"))),
+	)
+
+	// Step 3: checklists, FAQ and a quiz, for the kiosk and web environments;
+	// Check renders the web environment, which must include every node type
+	s = clab.NewStep("Questions")
+	s.Tags = []string{"kiosk", "web"}
+	s.Duration = 15 * time.Minute
+	hc := nodes.NewHeaderNode(3, text("Checklist"))
+	hc.MutateType(nodes.NodeHeaderCheck)
+	cl := nodes.NewItemsListNode("", 0)
+	cl.MutateType(nodes.NodeItemsCheck)
+	cl.NewItem(text("Checklist item"))
+	hf := nodes.NewHeaderNode(3, text("FAQ"))
+	hf.MutateType(nodes.NodeHeaderFAQ)
+	faq := nodes.NewItemsListNode("", 0)
+	faq.MutateType(nodes.NodeItemsFAQ)
+	faq.NewItem(nodes.NewURLNode("https://example.com/faq", text("Question")))
+	s.Content.Append(
+		hc, cl, hf, faq,
+		block(nodes.NewSurveyNode("sample-survey",
+			&nodes.SurveyGroup{
+				Name:    "Poll question",
+				Options: []string{"Option A", "Option B"},
+			},
+			&nodes.SurveyGroup{
+				Name:        "Quiz question",
+				Options:     []string{"Right", "Wrong"},
+				Correct:     []int{0},
+				Explanation: "Explanation",
+			},
+		)),
+	)
+
+	clab.Quiz = types.NewQuiz(clab.Steps)
+	return clab
+}
@@ -0,0 +1,92 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/googlecodelabs/tools/claat/nodes"
+)
+
+func TestSampleCodelab(t *testing.T) {
+	all := []nodes.NodeType{
+		nodes.NodeList, nodes.NodeGrid, nodes.NodeText, nodes.NodeCode,
+		nodes.NodeInfobox, nodes.NodeSurvey, nodes.NodeURL, nodes.NodeImage,
+		nodes.NodeButton, nodes.NodeItemsList, nodes.NodeItemsCheck, nodes.NodeItemsFAQ,
+		nodes.NodeHeader, nodes.NodeHeaderCheck, nodes.NodeHeaderFAQ, nodes.NodeYouTube,
+		nodes.NodeIframe, nodes.NodeImport, nodes.NodeMath, nodes.NodeDiagram,
+		nodes.NodeCustom,
+	}
+	// only steps of the environment rendered by Check count
+	seen := make(map[nodes.NodeType]bool)
+	clab := SampleCodelab()
+	for _, s := range StepsByEnv(clab.Steps, "web") {
+		nodes.Inspect(s.Content.Nodes, func(n nodes.Node) bool {
+			if n != nil {
+				seen[n.Type()] = true
+			}
+			return true
+		})
+	}
+	for _, typ := range all {
+		if !seen[typ] {
+			t.Errorf("SampleCodelab has no node of type %d", typ)
+		}
+	}
+	if clab.Quiz == nil {
+		t.Error("SampleCodelab has no quiz")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "TestCheck-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	write := func(name, content string) string {
+		p := filepath.Join(tmp, name)
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	tests := []struct {
+		name string
+		err  string // error substring, empty for no error
+	}{
+		{"html", ""},
+		{"md", ""},
+		{"offline", ""},
+		{write("ok.html", "{{range .Steps}}{{.Content | renderHTML $.Context}}{{end}}"), ""},
+		{write("field.html", "<p>\n{{.Meta.Missing}}</p>"), "field.html:2:7: executing"},
+		{write("func.md", "\n\n{{missing .}}"), "func.md:3: function \"missing\" not defined"},
+		{filepath.Join(tmp, "missing.html"), "no such file"},
+	}
+	for _, tc := range tests {
+		err := Check(tc.name)
+		switch {
+		case tc.err == "" && err != nil:
+			t.Errorf("Check(%q): %v", tc.name, err)
+		case tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)):
+			t.Errorf("Check(%q) = %v, want error containing %q", tc.name, err, tc.err)
+		}
+	}
+}
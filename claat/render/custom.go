@@ -0,0 +1,66 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/googlecodelabs/tools/claat/nodes"
+	"golang.org/x/net/html"
+)
+
+// CustomRenderer renders custom nodes of a single kind.
+// Any of the funcs may be nil, in which case a default rendering is used:
+// the node content in HTML formats and plain text, and a directive in markdown.
+type CustomRenderer struct {
+	// HTML writes markup of n for the html format.
+	HTML func(w io.Writer, env string, n *nodes.CustomNode) error
+	// Lite returns markup of n for the offline format.
+	Lite func(env string, n *nodes.CustomNode) (*html.Node, error)
+	// MD writes markdown of n.
+	MD func(w io.Writer, env string, n *nodes.CustomNode) error
+	// Text writes plain text of n.
+	Text func(w io.Writer, env string, n *nodes.CustomNode) error
+}
+
+var customRenderers = map[string]CustomRenderer{}
+
+// RegisterCustom registers r to render custom nodes of the kind.
+// It panics if another renderer is already registered for the same kind.
+func RegisterCustom(kind string, r CustomRenderer) {
+	if _, exists := customRenderers[kind]; exists {
+		panic(fmt.Sprintf("custom renderer %q already registered", kind))
+	}
+	customRenderers[kind] = r
+}
+
+// customArgs formats attributes of n as directive arguments, in key order.
+func customArgs(n *nodes.CustomNode) string {
+	keys := make([]string, 0, len(n.Attrs))
+	for k := range n.Attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var s string
+	for _, k := range keys {
+		s += " " + k
+		if v := n.Attrs[k]; v != "" {
+			s += fmt.Sprintf("=%q", v)
+		}
+	}
+	return s
+}
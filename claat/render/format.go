@@ -0,0 +1,240 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	htmlTemplate "html/template"
+	textTemplate "text/template"
+
+	"github.com/googlecodelabs/tools/claat/types"
+)
+
+// Format is an output format of codelabs.
+type Format interface {
+	// PerStep reports whether each step is rendered into a separate file.
+	PerStep() bool
+	// Filename returns name of the output file, relative to the codelab dir.
+	// Step is the 1-based step number for per-step formats, 0 otherwise.
+	Filename(step int) string
+	// Render writes content of a single output file to w.
+	Render(w io.Writer, p *Page, opt ...Option) error
+}
+
+// StaticFormat is a format with static assets, such as stylesheets,
+// which are copied into the codelab dir along with the rendered files.
+type StaticFormat interface {
+	Format
+	// StaticDir returns a local directory of the assets, or an empty
+	// string if there are none. The directory is copied as a whole,
+	// under its own base name.
+	StaticDir() string
+}
+
+// Page is the data rendered into a single output file.
+// It is also the execution context of templates.
+type Page struct {
+	Context
+	Current *types.Step // Current step of per-step formats
+	StepNum int         // 1-based number of the current step
+	Prev    bool        // Whether the current step has a previous one
+	Next    bool        // Whether the current step has a next one
+}
+
+// TemplateFormat is a format rendered with a Go template.
+type TemplateFormat struct {
+	Name   string // Template name, for error messages
+	Source []byte // Template text
+	HTML   bool   // Parse with html/template rather than text/template
+	Ext    string // Output files extension, including the dot
+	Steps  bool   // Render each step into a separate file
+
+	cache templateCache
+}
+
+// PerStep implements Format.
+func (tf *TemplateFormat) PerStep() bool {
+	return tf.Steps
+}
+
+// Filename implements Format.
+// A single file, or the first step, is named index; other steps step-N.
+func (tf *TemplateFormat) Filename(step int) string {
+	return stepFilename(step, tf.Ext)
+}
+
+// Render implements Format by executing the template with p.
+func (tf *TemplateFormat) Render(w io.Writer, p *Page, opt ...Option) error {
+	t, err := tf.template(funcMapOption(opt))
+	if err != nil {
+		return err
+	}
+	return t.Execute(w, p)
+}
+
+// template returns the parsed template. Templates without
+// user-supplied functions are parsed once and reused.
+func (tf *TemplateFormat) template(fmap map[string]interface{}) (executer, error) {
+	if len(fmap) > 0 {
+		return tf.parse(fmap)
+	}
+	return tf.cache.get(func() (executer, error) {
+		return tf.parse(nil)
+	})
+}
+
+func (tf *TemplateFormat) parse(fmap map[string]interface{}) (executer, error) {
+	funcs := mergeFuncs(fmap)
+	if tf.HTML {
+		return htmlTemplate.New(tf.Name).
+			Funcs(funcs).
+			Parse(string(tf.Source))
+	}
+	return textTemplate.New(tf.Name).
+		Funcs(funcs).
+		Parse(string(tf.Source))
+}
+
+// templateFormat is a format executing a template,
+// which accepts arbitrary data.
+type templateFormat interface {
+	template(fmap map[string]interface{}) (executer, error)
+}
+
+// templateCache holds a template parsed on first use.
+// It is safe for concurrent use.
+type templateCache struct {
+	once sync.Once
+	t    executer
+	err  error
+}
+
+// get returns the cached template, parsing it with parse on the first call.
+func (c *templateCache) get(parse func() (executer, error)) (executer, error) {
+	c.once.Do(func() {
+		c.t, c.err = parse()
+	})
+	return c.t, c.err
+}
+
+// mergeFuncs returns the built-in template functions overridden with fmap.
+func mergeFuncs(fmap map[string]interface{}) map[string]interface{} {
+	funcs := make(map[string]interface{}, len(funcMap)+len(fmap))
+	for k, v := range funcMap {
+		funcs[k] = v
+	}
+	for k, v := range fmap {
+		funcs[k] = v
+	}
+	return funcs
+}
+
+// stepFilename returns name of the output file of step n
+// with extension ext.
+func stepFilename(n int, ext string) string {
+	if n <= 1 {
+		return "index" + ext
+	}
+	return fmt.Sprintf("step-%d%s", n, ext)
+}
+
+var formats = map[string]Format{
+	"html": &TemplateFormat{
+		Name:   "html",
+		Source: newHTMLTemplate,
+		HTML:   true,
+		Ext:    ".html",
+	},
+	"md": &TemplateFormat{
+		Name:   "md",
+		Source: newMDTemplate,
+		Ext:    ".md",
+	},
+	"offline": &TemplateFormat{
+		Name:   "offline",
+		Source: newOfflineTemplate,
+		HTML:   true,
+		Ext:    ".html",
+		Steps:  true,
+	},
+	"txt": textFormat{},
+}
+
+// RegisterFormat registers a new output format f under name.
+// It panics if another format is already registered under the same name.
+func RegisterFormat(name string, f Format) {
+	if _, exists := formats[name]; exists {
+		panic(fmt.Sprintf("format %q already registered", name))
+	}
+	formats[name] = f
+}
+
+// Local formats loaded by LookupFormat, keyed by path.
+var (
+	localMu      sync.Mutex
+	localFormats = make(map[string]Format)
+)
+
+// LookupFormat returns a format registered under name.
+// Otherwise, name is a path to a local theme directory, see Theme,
+// or a local template file, which is parsed as HTML if file extension
+// is ".html", text otherwise.
+// Output of a local template is written to an index.html file.
+//
+// Local formats are loaded once, so that their templates are parsed
+// only once when rendering many codelabs.
+func LookupFormat(name string) (Format, error) {
+	if f, ok := formats[name]; ok {
+		return f, nil
+	}
+	localMu.Lock()
+	defer localMu.Unlock()
+	if f, ok := localFormats[name]; ok {
+		return f, nil
+	}
+	f, err := loadFormat(name)
+	if err != nil {
+		return nil, err
+	}
+	localFormats[name] = f
+	return f, nil
+}
+
+// loadFormat loads a local theme or template file.
+func loadFormat(name string) (Format, error) {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		return LoadTheme(name)
+	}
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return &TemplateFormat{
+		Name:   name,
+		Source: b,
+		HTML:   filepath.Ext(name) == ".html",
+		Ext:    ".html",
+	}, nil
+}
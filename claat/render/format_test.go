@@ -0,0 +1,103 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/googlecodelabs/tools/claat/types"
+)
+
+type titleFormat struct{}
+
+func (titleFormat) PerStep() bool            { return true }
+func (titleFormat) Filename(step int) string { return stepFilename(step, ".txt") }
+func (titleFormat) Render(w io.Writer, p *Page, opt ...Option) error {
+	_, err := io.WriteString(w, p.Current.Title)
+	return err
+}
+
+func init() {
+	RegisterFormat("test-title", titleFormat{})
+}
+
+func TestLookupFormat(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "TestLookupFormat-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	tmpl := filepath.Join(tmp, "custom.html")
+	if err := ioutil.WriteFile(tmpl, []byte("{{.Meta.Title}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		perStep   bool
+		filenames []string
+	}{
+		{"html", false, []string{"index.html"}},
+		{"md", false, []string{"index.md"}},
+		{"offline", true, []string{"index.html", "step-2.html"}},
+		{"txt", false, []string{"index.txt"}},
+		{"test-title", true, []string{"index.txt", "step-2.txt"}},
+		{tmpl, false, []string{"index.html"}},
+	}
+	for _, tc := range tests {
+		f, err := LookupFormat(tc.name)
+		if err != nil {
+			t.Errorf("LookupFormat(%q): %v", tc.name, err)
+			continue
+		}
+		if f.PerStep() != tc.perStep {
+			t.Errorf("LookupFormat(%q).PerStep() = %t, want %t", tc.name, f.PerStep(), tc.perStep)
+		}
+		for i, want := range tc.filenames {
+			n := i
+			if tc.perStep {
+				n = i + 1
+			}
+			if out := f.Filename(n); out != want {
+				t.Errorf("LookupFormat(%q).Filename(%d) = %q, want %q", tc.name, n, out, want)
+			}
+		}
+	}
+	if _, err := LookupFormat(filepath.Join(tmp, "missing.html")); err == nil {
+		t.Error("LookupFormat of a missing template returned nil error")
+	}
+}
+
+func TestExecuteFormat(t *testing.T) {
+	p := &Page{
+		Context: Context{Meta: &types.Meta{Title: "Codelab"}},
+		Current: &types.Step{Title: "Step"},
+	}
+	var buf bytes.Buffer
+	if err := Execute(&buf, "test-title", p); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "Step" {
+		t.Errorf("Execute(test-title) = %q, want %q", buf.String(), "Step")
+	}
+	if err := Execute(&buf, "test-title", &p.Context); err == nil {
+		t.Error("Execute(test-title) with *Context data returned nil error")
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/googlecodelabs/tools/claat/nodes"
+	"github.com/googlecodelabs/tools/claat/types"
+)
+
+// WordsPerMinute is the reading speed used to estimate reading time.
+const WordsPerMinute = 200
+
+// TOCEntry is a step entry of a codelab table of contents.
+type TOCEntry struct {
+	Num       int           // 1-based step number
+	Title     string        // Step title
+	Anchor    string        // Unique step anchor, derived from the title
+	Duration  time.Duration // Step duration
+	Remaining time.Duration // Duration of this and the following steps
+}
+
+// TOC returns a table of contents of steps.
+//
+// Anchors are lower case titles with runs of other characters than letters
+// and digits replaced with a dash, e.g. "set-up-the-project". They stay the
+// same as long as the titles do. Duplicate anchors get a numeric suffix,
+// e.g. "summary-2", and steps with empty anchors are named "step-N".
+func TOC(steps []*types.Step) []*TOCEntry {
+	toc := make([]*TOCEntry, len(steps))
+	seen := make(map[string]bool)
+	for i, s := range steps {
+		a := anchor(s.Title)
+		if a == "" {
+			a = "step-" + strconv.Itoa(i+1)
+		}
+		for base, n := a, 2; seen[a]; n++ {
+			a = fmt.Sprintf("%s-%d", base, n)
+		}
+		seen[a] = true
+		toc[i] = &TOCEntry{
+			Num:       i + 1,
+			Title:     s.Title,
+			Anchor:    a,
+			Duration:  s.Duration,
+			Remaining: RemainingDuration(steps, i+1),
+		}
+	}
+	return toc
+}
+
+// anchor converts s into an URL fragment.
+func anchor(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	return b.String()
+}
+
+// TotalDuration returns the sum of steps durations.
+func TotalDuration(steps []*types.Step) time.Duration {
+	return RemainingDuration(steps, 1)
+}
+
+// RemainingDuration returns the sum of durations of step n,
+// which is 1-based, and the steps following it.
+func RemainingDuration(steps []*types.Step, n int) time.Duration {
+	var d time.Duration
+	for i := n - 1; i < len(steps); i++ {
+		if i >= 0 {
+			d += steps[i].Duration
+		}
+	}
+	return d
+}
+
+// StepsByEnv returns steps available in the environment env,
+// i.e. steps without tags and steps tagged with env.
+// All steps are returned if env is empty.
+func StepsByEnv(steps []*types.Step, env string) []*types.Step {
+	var res []*types.Step
+	for _, s := range steps {
+		if MatchEnv(s.Tags, env) {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+// MatchEnv reports whether sorted tags match the environment env,
+// i.e. tags are empty or contain env. Any tags match an empty env.
+func MatchEnv(tags []string, env string) bool {
+	if len(tags) == 0 || env == "" {
+		return true
+	}
+	i := sort.SearchStrings(tags, env)
+	return i < len(tags) && tags[i] == env
+}
+
+// WordCount returns the number of words in the text of v, as rendered
+// by Text, which is a step, a slice of steps or content nodes.
+// Words are runs of non-space characters with a letter or digit,
+// so that list bullets and other markup are not counted.
+func WordCount(v interface{}) (int, error) {
+	nn, err := contentNodes(v)
+	if err != nil {
+		return 0, err
+	}
+	s, err := Text(Context{}, nn...)
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	for _, w := range strings.Fields(s) {
+		if strings.IndexFunc(w, isWordRune) >= 0 {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// ReadingTime returns estimated time to read the text of v,
+// at WordsPerMinute, rounded up to whole minutes.
+// See WordCount for types of v.
+func ReadingTime(v interface{}) (time.Duration, error) {
+	n, err := WordCount(v)
+	if err != nil {
+		return 0, err
+	}
+	m := (n + WordsPerMinute - 1) / WordsPerMinute
+	return time.Duration(m) * time.Minute, nil
+}
+
+// Images returns all images of v, in the order of appearance.
+// See WordCount for types of v.
+func Images(v interface{}) ([]*nodes.ImageNode, error) {
+	nn, err := contentNodes(v)
+	if err != nil {
+		return nil, err
+	}
+	return nodes.ImageNodes(nn), nil
+}
+
+// Links returns all links of v with non-empty URLs, in the order of appearance.
+// See WordCount for types of v.
+func Links(v interface{}) ([]*nodes.URLNode, error) {
+	nn, err := contentNodes(v)
+	if err != nil {
+		return nil, err
+	}
+	var links []*nodes.URLNode
+	nodes.Inspect(nn, func(n nodes.Node) bool {
+		if u, ok := n.(*nodes.URLNode); ok && u.URL != "" {
+			links = append(links, u)
+		}
+		return true
+	})
+	return links, nil
+}
+
+// contentNodes returns content nodes of v, which is a step,
+// a slice of steps, a node or a slice of nodes.
+func contentNodes(v interface{}) ([]nodes.Node, error) {
+	switch v := v.(type) {
+	case *types.Step:
+		return []nodes.Node{v.Content}, nil
+	case []*types.Step:
+		nn := make([]nodes.Node, len(v))
+		for i, s := range v {
+			nn[i] = s.Content
+		}
+		return nn, nil
+	case nodes.Node:
+		return []nodes.Node{v}, nil
+	case []nodes.Node:
+		return v, nil
+	}
+	return nil, fmt.Errorf("cannot get content nodes of %T", v)
+}
@@ -0,0 +1,165 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googlecodelabs/tools/claat/nodes"
+	"github.com/googlecodelabs/tools/claat/types"
+)
+
+func TestTOC(t *testing.T) {
+	steps := []*types.Step{
+		{Title: "Set up the project!", Duration: 5 * time.Minute},
+		{Title: "Summary", Duration: 2 * time.Minute},
+		{Title: "Summary", Duration: time.Minute},
+		{Title: "¿Qué sigue?"},
+		{Title: "???"},
+	}
+	want := []*TOCEntry{
+		{1, "Set up the project!", "set-up-the-project", 5 * time.Minute, 8 * time.Minute},
+		{2, "Summary", "summary", 2 * time.Minute, 3 * time.Minute},
+		{3, "Summary", "summary-2", time.Minute, time.Minute},
+		{4, "¿Qué sigue?", "qué-sigue", 0, 0},
+		{5, "???", "step-5", 0, 0},
+	}
+	if diff := cmp.Diff(want, TOC(steps)); diff != "" {
+		t.Errorf("TOC mismatch (-want +got):\n%s", diff)
+	}
+	if d := TotalDuration(steps); d != 8*time.Minute {
+		t.Errorf("TotalDuration = %v, want 8m", d)
+	}
+	if d := RemainingDuration(steps, 3); d != time.Minute {
+		t.Errorf("RemainingDuration(3) = %v, want 1m", d)
+	}
+}
+
+func TestStepsByEnv(t *testing.T) {
+	steps := []*types.Step{
+		{Title: "All"},
+		{Title: "Web", Tags: []string{"web"}},
+		{Title: "Kiosk", Tags: []string{"kiosk"}},
+	}
+	tests := []struct {
+		env  string
+		want []string
+	}{
+		{"", []string{"All", "Web", "Kiosk"}},
+		{"web", []string{"All", "Web"}},
+		{"kiosk", []string{"All", "Kiosk"}},
+	}
+	for _, tc := range tests {
+		var got []string
+		for _, s := range StepsByEnv(steps, tc.env) {
+			got = append(got, s.Title)
+		}
+		if !cmp.Equal(got, tc.want) {
+			t.Errorf("StepsByEnv(%q) = %v, want %v", tc.env, got, tc.want)
+		}
+	}
+}
+
+func TestSummaries(t *testing.T) {
+	text := func(s string) nodes.Node {
+		return nodes.NewTextNode(nodes.NewTextNodeOptions{Value: s})
+	}
+	para := func(n ...nodes.Node) nodes.Node {
+		l := nodes.NewListNode(n...)
+		l.MutateBlock(true)
+		return l
+	}
+	kiosk := text(" Kiosk only.")
+	kiosk.MutateEnv([]string{"kiosk"})
+	img := nodes.NewImageNode(nodes.NewImageNodeOptions{Src: "a.png"})
+	items := nodes.NewItemsListNode("", 0)
+	items.NewItem(text("one"))
+	items.NewItem(nodes.NewURLNode("https://example.com/b", text("two")))
+	step := &types.Step{Content: nodes.NewListNode(
+		nodes.NewHeaderNode(2, text("Title")),
+		para(text("Hello, "), nodes.NewURLNode("https://example.com/a", text("world")), text("."), kiosk),
+		para(img),
+		items,
+	)}
+
+	// bullets are not words, link URLs are
+	n, err := WordCount(step)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 9 {
+		t.Errorf("WordCount = %d, want 9", n)
+	}
+	d, err := ReadingTime([]*types.Step{step})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d != time.Minute {
+		t.Errorf("ReadingTime = %v, want 1m", d)
+	}
+	imgs, err := Images(step)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imgs) != 1 || imgs[0] != img {
+		t.Errorf("Images = %v, want [%v]", imgs, img)
+	}
+	links, err := Links(step.Content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var urls []string
+	for _, l := range links {
+		urls = append(urls, l.URL)
+	}
+	if want := []string{"https://example.com/a", "https://example.com/b"}; !cmp.Equal(urls, want) {
+		t.Errorf("Links = %v, want %v", urls, want)
+	}
+	if _, err := WordCount("text"); err == nil {
+		t.Error("WordCount(string) returned nil error")
+	}
+}
+
+func TestSummaryFuncs(t *testing.T) {
+	clab := SampleCodelab()
+	p := &Page{Context: Context{Env: "web", Meta: &clab.Meta, Steps: clab.Steps}}
+	tmpl := `{{range toc .Steps}}{{.Num}}:{{.Anchor}}:{{durationStr .Remaining}} {{end}}` +
+		`|{{durationStr (totalDuration .Steps)}}` +
+		`|{{len (stepsByEnv .Steps .Env)}}` +
+		`|{{len (images .Steps)}}` +
+		`|{{(index .Steps 0).Content | renderText $.Context}}`
+	tf := &TemplateFormat{Name: "summary", Source: []byte(tmpl)}
+	var buf bytes.Buffer
+	if err := tf.Render(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(buf.String(), "|")
+	want := []string{
+		"1:text:30:00 2:code-and-media:25:00 3:questions:15:00 ",
+		"30:00",
+		"3",
+		"1",
+		"Header\n\nPlain, bold, italic and code text with a link [https://example.com].\n",
+	}
+	for i, w := range want {
+		if i >= len(parts) || !strings.HasPrefix(parts[i], w) {
+			t.Errorf("output:\n%s\nwant part %d with prefix %q", buf.String(), i, w)
+		}
+	}
+}
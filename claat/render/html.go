@@ -19,11 +19,11 @@ import (
 	"fmt"
 	htmlTemplate "html/template"
 	"io"
-	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/googlecodelabs/tools/claat/nodes"
+	"golang.org/x/net/html"
 )
 
 // TODO: render HTML using golang/x/net/html or template.
@@ -56,11 +56,7 @@ type htmlWriter struct {
 }
 
 func (hw *htmlWriter) matchEnv(v []string) bool {
-	if len(v) == 0 || hw.env == "" {
-		return true
-	}
-	i := sort.SearchStrings(v, hw.env)
-	return i < len(v) && v[i] == hw.env
+	return MatchEnv(v, hw.env)
 }
 
 func (hw *htmlWriter) write(nodesToWrite ...nodes.Node) error {
@@ -77,9 +73,14 @@ func (hw *htmlWriter) write(nodesToWrite ...nodes.Node) error {
 			hw.url(n)
 		case *nodes.ButtonNode:
 			hw.button(n)
+		case *nodes.MathNode:
+			hw.math(n)
 		case *nodes.CodeNode:
 			hw.code(n)
 			hw.writeString("\n")
+		case *nodes.DiagramNode:
+			hw.diagram(n)
+			hw.writeString("\n")
 		case *nodes.ListNode:
 			hw.list(n)
 			hw.writeString("\n")
@@ -110,6 +111,9 @@ func (hw *htmlWriter) write(nodesToWrite ...nodes.Node) error {
 		case *nodes.IframeNode:
 			hw.iframe(n)
 			hw.writeString("\n")
+		case *nodes.CustomNode:
+			hw.custom(n)
+			hw.writeString("\n")
 		}
 		if hw.err != nil {
 			return hw.err
@@ -175,6 +179,13 @@ func (hw *htmlWriter) text(n *nodes.TextNode) {
 }
 
 func (hw *htmlWriter) image(n *nodes.ImageNode) {
+	types := imageSourceTypes(n)
+	if len(types) > 0 {
+		hw.writeString("<picture>")
+		for _, t := range types {
+			hw.writeFmt("<source type=%q srcset=%q>", t, imageSrcset(n, t))
+		}
+	}
 	hw.writeString("<img")
 	if n.Alt != "" {
 		hw.writeFmt(" alt=%q", n.Alt)
@@ -182,10 +193,67 @@ func (hw *htmlWriter) image(n *nodes.ImageNode) {
 	if n.Title != "" {
 		hw.writeFmt(" title=%q", n.Title)
 	}
-	if n.Width > 0 {
-		hw.writeFmt(` style="width: %.2fpx"`, n.Width)
+	if style := imageStyle(n); style != "" {
+		hw.writeFmt(" style=%q", style)
+	}
+	if w, h := n.Size(); w > 0 {
+		hw.writeFmt(` width="%d" height="%d"`, w, h)
+	}
+	if srcset := imageSrcset(n, ""); srcset != "" {
+		hw.writeFmt(" srcset=%q", srcset)
 	}
 	hw.writeFmt(" src=%q>", n.Src)
+	if len(types) > 0 {
+		hw.writeString("</picture>")
+	}
+}
+
+// imageStyle returns the inline style of image n.
+// Images with a known intrinsic size keep their aspect ratio
+// when their width is constrained.
+func imageStyle(n *nodes.ImageNode) string {
+	var s []string
+	if n.Width > 0 {
+		s = append(s, fmt.Sprintf("width: %.2fpx", n.Width))
+	}
+	if w, _ := n.Size(); w > 0 {
+		s = append(s, "height: auto")
+	}
+	return strings.Join(s, "; ")
+}
+
+// imageSourceTypes returns the MIME types of variants of image n
+// in a format other than that of the image, in order of appearance.
+func imageSourceTypes(n *nodes.ImageNode) []string {
+	var types []string
+	seen := make(map[string]bool)
+	for _, v := range n.Variants {
+		if v.Type != "" && !seen[v.Type] {
+			seen[v.Type] = true
+			types = append(types, v.Type)
+		}
+	}
+	return types
+}
+
+// imageSrcset returns the srcset attribute value of variants of image n
+// of MIME type typ, or an empty string if there are none.
+// An empty typ selects variants in the format of the image,
+// preceded by the image itself.
+func imageSrcset(n *nodes.ImageNode, typ string) string {
+	var set []string
+	for _, v := range n.Variants {
+		if v.Type == typ {
+			set = append(set, v.Src+" "+strconv.FormatFloat(float64(v.Density), 'f', -1, 32)+"x")
+		}
+	}
+	if len(set) == 0 {
+		return ""
+	}
+	if typ == "" {
+		set = append([]string{n.Src + " 1x"}, set...)
+	}
+	return strings.Join(set, ", ")
 }
 
 func (hw *htmlWriter) url(n *nodes.URLNode) {
@@ -220,6 +288,15 @@ func (hw *htmlWriter) button(n *nodes.ButtonNode) {
 	hw.writeString("</paper-button>")
 }
 
+func (hw *htmlWriter) math(n *nodes.MathNode) {
+	var buf bytes.Buffer
+	if err := html.Render(&buf, mathML(n.Value, n.Display)); err != nil {
+		hw.err = err
+		return
+	}
+	hw.writeString(ReplaceDoubleCurlyBracketsWithEntity(buf.String()))
+}
+
 func (hw *htmlWriter) code(n *nodes.CodeNode) {
 	hw.writeString("<pre>")
 	if !n.Term {
@@ -236,6 +313,29 @@ func (hw *htmlWriter) code(n *nodes.CodeNode) {
 	hw.writeString("</pre>")
 }
 
+// diagram writes a custom element which renders diagram source in the browser.
+// The pre-rendered image, if any, and the source code block are written
+// as fallback content, shown until the element renders the diagram.
+func (hw *htmlWriter) diagram(n *nodes.DiagramNode) {
+	hw.writeFmt("<google-codelab-diagram kind=%q>", escape(n.Kind))
+	if n.Img != "" {
+		hw.writeFmt("<img alt=%q src=%q>", escape(n.Kind+" diagram"), n.Img)
+	}
+	hw.code(nodes.NewCodeNode(n.Value, false, n.Kind))
+	hw.writeString("</google-codelab-diagram>")
+}
+
+func (hw *htmlWriter) custom(n *nodes.CustomNode) {
+	if hw.err != nil {
+		return
+	}
+	if r := customRenderers[n.Kind]; r.HTML != nil {
+		hw.err = r.HTML(hw.w, hw.env, n)
+		return
+	}
+	hw.write(n.Content.Nodes...)
+}
+
 func (hw *htmlWriter) list(n *nodes.ListNode) {
 	wrap := n.Block() == true
 	if wrap {
@@ -322,13 +422,29 @@ func (hw *htmlWriter) infobox(n *nodes.InfoboxNode) {
 }
 
 func (hw *htmlWriter) survey(n *nodes.SurveyNode) {
-	hw.writeFmt("<google-codelab-survey survey-id=%q>\n", n.ID)
+	hw.writeFmt("<google-codelab-survey survey-id=%q", n.ID)
+	if n.Quiz() {
+		hw.writeString(" quiz")
+	}
+	hw.writeString(">\n")
 	for _, g := range n.Groups {
-		hw.writeFmt("<h4>%s</h4>\n<paper-radio-group>\n", g.Name)
-		for _, o := range g.Options {
-			hw.writeFmt("<paper-radio-button>%s</paper-radio-button>\n", escape(o))
+		hw.writeFmt("<h4>%s</h4>\n", g.Name)
+		group, option := "paper-radio-group", "paper-radio-button"
+		if g.Multi {
+			group, option = "paper-checkbox-group", "paper-checkbox"
+		}
+		hw.writeFmt("<%s>\n", group)
+		for i, o := range g.Options {
+			hw.writeFmt("<%s", option)
+			if g.IsCorrect(i) {
+				hw.writeString(" correct")
+			}
+			hw.writeFmt(">%s</%s>\n", escape(o), option)
+		}
+		hw.writeFmt("</%s>\n", group)
+		if g.Explanation != "" {
+			hw.writeFmt("<p class=\"survey-explanation\">%s</p>\n", escape(g.Explanation))
 		}
-		hw.writeString("</paper-radio-group>\n")
 	}
 	hw.writeString("</google-codelab-survey>")
 }
@@ -17,10 +17,13 @@ package render
 import (
 	"bytes"
 	"errors"
+	"fmt"
+	"io"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/googlecodelabs/tools/claat/nodes"
+	"golang.org/x/net/html"
 )
 
 func TestHTMLEnv(t *testing.T) {
@@ -526,6 +529,71 @@ func TestImage(t *testing.T) {
 	}
 }
 
+func TestImageResponsive(t *testing.T) {
+	n := nodes.NewImageNode(nodes.NewImageNodeOptions{Src: "img/a-300w.png", Width: 300, Alt: "foo"})
+	n.PixelWidth, n.PixelHeight = 300, 200
+	n.Variants = []*nodes.ImageVariant{
+		{Src: "img/a.png", Density: 1.5},
+		{Src: "img/a-300w.webp", Type: "image/webp", Density: 1},
+		{Src: "img/a-450w.webp", Type: "image/webp", Density: 1.5},
+	}
+	sized := nodes.NewImageNode(nodes.NewImageNodeOptions{Src: "img/b.png"})
+	sized.PixelWidth, sized.PixelHeight = 64, 48
+
+	tests := []struct {
+		name   string
+		render func(w *bytes.Buffer, n nodes.Node) error
+		n      *nodes.ImageNode
+		out    string
+	}{
+		{
+			name: "HTML",
+			render: func(w *bytes.Buffer, n nodes.Node) error {
+				return WriteHTML(w, "", "", n)
+			},
+			n: n,
+			out: `<picture><source type="image/webp" srcset="img/a-300w.webp 1x, img/a-450w.webp 1.5x">` +
+				`<img alt="foo" style="width: 300.00px; height: auto" width="300" height="200" srcset="img/a-300w.png 1x, img/a.png 1.5x" src="img/a-300w.png"></picture>`,
+		},
+		{
+			name: "HTMLSize",
+			render: func(w *bytes.Buffer, n nodes.Node) error {
+				return WriteHTML(w, "", "", n)
+			},
+			n:   sized,
+			out: `<img style="height: auto" width="64" height="48" src="img/b.png">`,
+		},
+		{
+			name: "Lite",
+			render: func(w *bytes.Buffer, n nodes.Node) error {
+				return WriteLite(w, "", n)
+			},
+			n: n,
+			out: `<picture><source type="image/webp" srcset="img/a-300w.webp 1x, img/a-450w.webp 1.5x"/>` +
+				`<img src="img/a-300w.png" style="width: 300.00px; height: auto" width="300" height="200" srcset="img/a-300w.png 1x, img/a.png 1.5x"/></picture>`,
+		},
+		{
+			name: "LiteSize",
+			render: func(w *bytes.Buffer, n nodes.Node) error {
+				return WriteLite(w, "", n)
+			},
+			n:   sized,
+			out: `<img src="img/b.png" style="height: auto" width="64" height="48"/>`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tc.render(&buf, tc.n); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.out, buf.String()); diff != "" {
+				t.Errorf("render got diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestURL(t *testing.T) {
 	a := nodes.NewURLNode("google.com")
 	a.Name = "foobar"
@@ -1055,6 +1123,36 @@ func TestSurvey(t *testing.T) {
 <paper-radio-button>cc</paper-radio-button>
 <paper-radio-button>ccc</paper-radio-button>
 </paper-radio-group>
+</google-codelab-survey>`,
+		},
+		{
+			name: "Quiz",
+			inNode: nodes.NewSurveyNode("quiz",
+				&nodes.SurveyGroup{
+					Name:        "pick a prime",
+					Options:     []string{"2", "4"},
+					Correct:     []int{0},
+					Explanation: "4 is even",
+				},
+				&nodes.SurveyGroup{
+					Name:    "pick all primes",
+					Options: []string{"2", "3", "4"},
+					Correct: []int{0, 1},
+					Multi:   true,
+				}),
+			out: `<google-codelab-survey survey-id="quiz" quiz>
+<h4>pick a prime</h4>
+<paper-radio-group>
+<paper-radio-button correct>2</paper-radio-button>
+<paper-radio-button>4</paper-radio-button>
+</paper-radio-group>
+<p class="survey-explanation">4 is even</p>
+<h4>pick all primes</h4>
+<paper-checkbox-group>
+<paper-checkbox correct>2</paper-checkbox>
+<paper-checkbox correct>3</paper-checkbox>
+<paper-checkbox>4</paper-checkbox>
+</paper-checkbox-group>
 </google-codelab-survey>`,
 		},
 	}
@@ -1181,3 +1279,189 @@ func TestIframe(t *testing.T) {
 		})
 	}
 }
+
+func TestDiagram(t *testing.T) {
+	plain := nodes.NewDiagramNode(nodes.DiagramMermaid, "graph TD\n  A-->B\n")
+	img := nodes.NewDiagramNode(nodes.DiagramDot, "digraph { a -> b }\n")
+	img.Img = "img/d1a9.svg"
+	tests := []struct {
+		name   string
+		render func(w *bytes.Buffer, n nodes.Node) error
+		n      *nodes.DiagramNode
+		out    string
+	}{
+		{
+			name: "HTML",
+			render: func(w *bytes.Buffer, n nodes.Node) error {
+				return WriteHTML(w, "", "", n)
+			},
+			n:   plain,
+			out: "<google-codelab-diagram kind=\"mermaid\"><pre><code language=\"mermaid\" class=\"mermaid\">graph TD\n  A--&gt;B\n</code></pre></google-codelab-diagram>\n",
+		},
+		{
+			name: "HTMLImage",
+			render: func(w *bytes.Buffer, n nodes.Node) error {
+				return WriteHTML(w, "", "", n)
+			},
+			n: img,
+			out: "<google-codelab-diagram kind=\"dot\"><img alt=\"dot diagram\" src=\"img/d1a9.svg\">" +
+				"<pre><code language=\"dot\" class=\"dot\">digraph { a -&gt; b }\n</code></pre></google-codelab-diagram>\n",
+		},
+		{
+			name: "Lite",
+			render: func(w *bytes.Buffer, n nodes.Node) error {
+				return WriteLite(w, "", n)
+			},
+			n:   plain,
+			out: "<figure class=\"diagram\"><pre><code language=\"mermaid\" class=\"mermaid\">graph TD\n  A--&gt;B\n</code></pre></figure>",
+		},
+		{
+			name: "LiteImage",
+			render: func(w *bytes.Buffer, n nodes.Node) error {
+				return WriteLite(w, "", n)
+			},
+			n: img,
+			out: "<figure class=\"diagram\"><img class=\"diagram__img\" src=\"img/d1a9.svg\" alt=\"dot diagram\"/>" +
+				"<details><summary>Diagram source</summary><pre><code language=\"dot\" class=\"dot\">digraph { a -&gt; b }\n</code></pre></details></figure>",
+		},
+		{
+			name: "MD",
+			render: func(w *bytes.Buffer, n nodes.Node) error {
+				return WriteMD(w, "", "", n)
+			},
+			n:   plain,
+			out: "\n\n```mermaid\ngraph TD\n  A-->B\n```\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tc.render(&buf, tc.n); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.out, buf.String()); diff != "" {
+				t.Errorf("render got diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func init() {
+	RegisterCustom("test-badge", CustomRenderer{
+		HTML: func(w io.Writer, env string, n *nodes.CustomNode) error {
+			_, err := fmt.Fprintf(w, "<test-badge>%s</test-badge>", n.Attrs["label"])
+			return err
+		},
+		Lite: func(env string, n *nodes.CustomNode) (*html.Node, error) {
+			hn := &html.Node{Type: html.ElementNode, Data: "span"}
+			hn.AppendChild(&html.Node{Type: html.TextNode, Data: n.Attrs["label"]})
+			return hn, nil
+		},
+	})
+}
+
+func TestCustom(t *testing.T) {
+	badge := nodes.NewCustomNode("test-badge")
+	badge.Attrs["label"] = "new"
+	other := nodes.NewCustomNode("other", nodes.NewTextNode(nodes.NewTextNodeOptions{Value: "content"}))
+	other.Attrs["id"] = "1"
+	other.Value = "raw *content*"
+	raw := nodes.NewCustomNode("other")
+	raw.Value = "raw *content*"
+	tests := []struct {
+		name   string
+		render func(w *bytes.Buffer, n nodes.Node) error
+		n      *nodes.CustomNode
+		out    string
+	}{
+		{
+			name: "HTML",
+			render: func(w *bytes.Buffer, n nodes.Node) error {
+				return WriteHTML(w, "", "", n)
+			},
+			n:   badge,
+			out: "<test-badge>new</test-badge>\n",
+		},
+		{
+			name: "HTMLDefault",
+			render: func(w *bytes.Buffer, n nodes.Node) error {
+				return WriteHTML(w, "", "", n)
+			},
+			n:   other,
+			out: "content\n",
+		},
+		{
+			name: "Lite",
+			render: func(w *bytes.Buffer, n nodes.Node) error {
+				return WriteLite(w, "", n)
+			},
+			n:   badge,
+			out: "<span>new</span>",
+		},
+		{
+			name: "LiteDefault",
+			render: func(w *bytes.Buffer, n nodes.Node) error {
+				return WriteLite(w, "", n)
+			},
+			n:   other,
+			out: "<div>content</div>",
+		},
+		{
+			name: "MDLeaf",
+			render: func(w *bytes.Buffer, n nodes.Node) error {
+				return WriteMD(w, "", "", n)
+			},
+			n:   badge,
+			out: "\n\n::test-badge label=\"new\"",
+		},
+		{
+			name: "MDBlock",
+			render: func(w *bytes.Buffer, n nodes.Node) error {
+				return WriteMD(w, "", "", n)
+			},
+			n:   other,
+			out: "\n\n:::other id=\"1\"\ncontent\n:::",
+		},
+		{
+			name: "MDValue",
+			render: func(w *bytes.Buffer, n nodes.Node) error {
+				return WriteMD(w, "", "", n)
+			},
+			n:   raw,
+			out: "\n\n:::other\nraw *content*\n:::",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tc.render(&buf, tc.n); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.out, buf.String()); diff != "" {
+				t.Errorf("render got diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMDCodeFile(t *testing.T) {
+	tests := []struct {
+		lang, file string
+		out        string
+	}{
+		{"go", "main.go", "\n\n```go main.go\nfunc main() {}\n```\n"},
+		{"", "go.mod", "\n\n```file=go.mod\nfunc main() {}\n```\n"},
+		{"go", "", "\n\n```go\nfunc main() {}\n```\n"},
+	}
+	for _, tc := range tests {
+		n := nodes.NewCodeNode("func main() {}\n", false, tc.lang)
+		n.File = tc.file
+		var buf bytes.Buffer
+		if err := WriteMD(&buf, "", "", n); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(tc.out, buf.String()); diff != "" {
+			t.Errorf("WriteMD(%q, %q) got diff (-want +got):\n%s", tc.lang, tc.file, diff)
+		}
+	}
+}
@@ -19,7 +19,6 @@ import (
 	"fmt"
 	htmlTemplate "html/template"
 	"io"
-	"sort"
 	"strconv"
 	"strings"
 
@@ -50,11 +49,7 @@ type liteWriter struct {
 }
 
 func (lw *liteWriter) matchEnv(v []string) bool {
-	if len(v) == 0 || lw.env == "" {
-		return true
-	}
-	i := sort.SearchStrings(v, lw.env)
-	return i < len(v) && v[i] == lw.env
+	return MatchEnv(v, lw.env)
 }
 
 func (lw *liteWriter) write(nodes ...nodes.Node) error {
@@ -64,6 +59,9 @@ func (lw *liteWriter) write(nodes ...nodes.Node) error {
 			doc.AppendChild(hn)
 		}
 	}
+	if lw.err != nil {
+		return lw.err
+	}
 	return html.Render(lw.w, doc)
 }
 
@@ -81,8 +79,12 @@ func (lw *liteWriter) htmlnode(n nodes.Node) *html.Node {
 		hn = lw.alink(n)
 	case *nodes.ButtonNode:
 		hn = lw.button(n)
+	case *nodes.MathNode:
+		hn = mathML(n.Value, n.Display)
 	case *nodes.CodeNode:
 		hn = lw.code(n)
+	case *nodes.DiagramNode:
+		hn = lw.diagram(n)
 	case *nodes.ListNode:
 		hn = lw.list(n)
 	case *nodes.ImportNode:
@@ -101,10 +103,23 @@ func (lw *liteWriter) htmlnode(n nodes.Node) *html.Node {
 		hn = lw.header(n)
 	case *nodes.YouTubeNode:
 		hn = lw.youtube(n)
+	case *nodes.CustomNode:
+		hn = lw.custom(n)
 	}
 	return hn
 }
 
+func (lw *liteWriter) custom(n *nodes.CustomNode) *html.Node {
+	if r := customRenderers[n.Kind]; r.Lite != nil {
+		hn, err := r.Lite(lw.env, n)
+		if err != nil && lw.err == nil {
+			lw.err = err
+		}
+		return hn
+	}
+	return lw.list(n.Content)
+}
+
 func (lw *liteWriter) text(n *nodes.TextNode) *html.Node {
 	top := &html.Node{Type: html.TextNode, Data: n.Value}
 	if n.Bold {
@@ -131,13 +146,32 @@ func (lw *liteWriter) image(n *nodes.ImageNode) *html.Node {
 		Data: atom.Img.String(),
 		Attr: []html.Attribute{{Key: "src", Val: n.Src}},
 	}
-	if n.Width > 0 {
-		hn.Attr = append(hn.Attr, html.Attribute{
-			Key: "style",
-			Val: fmt.Sprintf("width: %.2fpx", n.Width),
+	if style := imageStyle(n); style != "" {
+		hn.Attr = append(hn.Attr, html.Attribute{Key: "style", Val: style})
+	}
+	if w, h := n.Size(); w > 0 {
+		hn.Attr = append(hn.Attr,
+			html.Attribute{Key: "width", Val: strconv.Itoa(w)},
+			html.Attribute{Key: "height", Val: strconv.Itoa(h)},
+		)
+	}
+	if srcset := imageSrcset(n, ""); srcset != "" {
+		hn.Attr = append(hn.Attr, html.Attribute{Key: "srcset", Val: srcset})
+	}
+	types := imageSourceTypes(n)
+	if len(types) == 0 {
+		return hn
+	}
+	pic := &html.Node{Type: html.ElementNode, Data: atom.Picture.String()}
+	for _, t := range types {
+		pic.AppendChild(&html.Node{
+			Type: html.ElementNode,
+			Data: atom.Source.String(),
+			Attr: []html.Attribute{{Key: "type", Val: t}, {Key: "srcset", Val: imageSrcset(n, t)}},
 		})
 	}
-	return hn
+	pic.AppendChild(hn)
+	return pic
 }
 
 func (lw *liteWriter) alink(n *nodes.URLNode) *html.Node {
@@ -209,6 +243,37 @@ func (lw *liteWriter) code(n *nodes.CodeNode) *html.Node {
 	return top
 }
 
+// diagram renders n as its pre-rendered image, if any, with the diagram
+// source in a code block. The code block is collapsed when the image is present.
+func (lw *liteWriter) diagram(n *nodes.DiagramNode) *html.Node {
+	top := &html.Node{
+		Type: html.ElementNode,
+		Data: atom.Figure.String(),
+		Attr: []html.Attribute{{Key: "class", Val: "diagram"}},
+	}
+	code := lw.code(nodes.NewCodeNode(n.Value, false, n.Kind))
+	if n.Img == "" {
+		top.AppendChild(code)
+		return top
+	}
+	top.AppendChild(&html.Node{
+		Type: html.ElementNode,
+		Data: atom.Img.String(),
+		Attr: []html.Attribute{
+			{Key: "class", Val: "diagram__img"},
+			{Key: "src", Val: n.Img},
+			{Key: "alt", Val: n.Kind + " diagram"},
+		},
+	})
+	details := &html.Node{Type: html.ElementNode, Data: atom.Details.String()}
+	summary := &html.Node{Type: html.ElementNode, Data: atom.Summary.String()}
+	summary.AppendChild(&html.Node{Type: html.TextNode, Data: "Diagram source"})
+	details.AppendChild(summary)
+	details.AppendChild(code)
+	top.AppendChild(details)
+	return top
+}
+
 func (lw *liteWriter) list(n *nodes.ListNode) *html.Node {
 	a := atom.P
 	if n.Block() != true {
@@ -323,6 +388,9 @@ func (lw *liteWriter) survey(n *nodes.SurveyNode) *html.Node {
 			{Key: "data-survey-id", Val: n.ID},
 		},
 	}
+	if n.Quiz() {
+		top.Attr = append(top.Attr, html.Attribute{Key: "data-quiz", Val: "1"})
+	}
 	for i, g := range n.Groups {
 		h4 := &html.Node{
 			Type: html.ElementNode,
@@ -332,16 +400,23 @@ func (lw *liteWriter) survey(n *nodes.SurveyNode) *html.Node {
 		h4.AppendChild(&html.Node{Type: html.TextNode, Data: g.Name})
 		top.AppendChild(h4)
 		id := fmt.Sprintf("%s-%d", n.ID, i)
-		for _, o := range g.Options {
+		typ := "radio"
+		if g.Multi {
+			typ = "checkbox"
+		}
+		for j, o := range g.Options {
 			oh := &html.Node{
 				Type: html.ElementNode,
 				Data: atom.Input.String(),
 				Attr: []html.Attribute{
-					{Key: "type", Val: "radio"},
+					{Key: "type", Val: typ},
 					{Key: "name", Val: id},
 					{Key: "value", Val: o},
 				},
 			}
+			if g.IsCorrect(j) {
+				oh.Attr = append(oh.Attr, html.Attribute{Key: "data-correct", Val: "1"})
+			}
 			lab := &html.Node{
 				Type: html.ElementNode,
 				Data: atom.Label.String(),
@@ -351,6 +426,15 @@ func (lw *liteWriter) survey(n *nodes.SurveyNode) *html.Node {
 			lab.AppendChild(&html.Node{Type: html.TextNode, Data: o})
 			top.AppendChild(lab)
 		}
+		if g.Explanation != "" {
+			p := &html.Node{
+				Type: html.ElementNode,
+				Data: atom.P.String(),
+				Attr: []html.Attribute{{Key: "class", Val: "survey__explanation"}},
+			}
+			p.AppendChild(&html.Node{Type: html.TextNode, Data: g.Explanation})
+			top.AppendChild(p)
+		}
 	}
 	return top
 }
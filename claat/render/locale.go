@@ -0,0 +1,126 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import "strings"
+
+// Locale is a translation of the codelab being rendered,
+// including the codelab itself.
+type Locale struct {
+	Lang    string // BCP 47 language tag, e.g. "pt-BR"
+	Name    string // Native language name, e.g. "Português"
+	URL     string // URL of the translation, relative to the current page
+	Current bool   // The codelab being rendered
+}
+
+// NewLocales returns locales of langs, as seen from the translation
+// into current language. Translations into defaultLang are located
+// in the codelab directory while others are in its subdirectories
+// named after their language, e.g. "codelab-id/ja".
+func NewLocales(langs []string, current, defaultLang string) []*Locale {
+	isDefault := func(lang string) bool {
+		return lang == "" || strings.EqualFold(lang, defaultLang)
+	}
+	root := "./"
+	if !isDefault(current) {
+		root = "../"
+	}
+	res := make([]*Locale, len(langs))
+	for i, lang := range langs {
+		u := root
+		if !isDefault(lang) {
+			u += lang + "/"
+		}
+		res[i] = &Locale{
+			Lang:    lang,
+			Name:    LangName(lang),
+			URL:     u,
+			Current: strings.EqualFold(lang, current) || isDefault(lang) && isDefault(current),
+		}
+	}
+	return res
+}
+
+// rtlLangs are languages written from right to left.
+var rtlLangs = map[string]bool{
+	"ar":  true, // Arabic
+	"ckb": true, // Central Kurdish
+	"dv":  true, // Divehi
+	"fa":  true, // Persian
+	"he":  true, // Hebrew
+	"ps":  true, // Pashto
+	"sd":  true, // Sindhi
+	"ug":  true, // Uyghur
+	"ur":  true, // Urdu
+	"yi":  true, // Yiddish
+}
+
+// LangDir returns text direction of language lang, "rtl" or "ltr",
+// suitable for the HTML dir attribute.
+func LangDir(lang string) string {
+	if rtlLangs[baseLang(lang)] {
+		return "rtl"
+	}
+	return "ltr"
+}
+
+// langNames are native names of common languages.
+var langNames = map[string]string{
+	"ar":    "العربية",
+	"de":    "Deutsch",
+	"en":    "English",
+	"es":    "Español",
+	"fa":    "فارسی",
+	"fr":    "Français",
+	"he":    "עברית",
+	"hi":    "हिन्दी",
+	"id":    "Bahasa Indonesia",
+	"it":    "Italiano",
+	"ja":    "日本語",
+	"ko":    "한국어",
+	"nl":    "Nederlands",
+	"pl":    "Polski",
+	"pt":    "Português",
+	"ru":    "Русский",
+	"th":    "ไทย",
+	"tr":    "Türkçe",
+	"uk":    "Українська",
+	"ur":    "اردو",
+	"vi":    "Tiếng Việt",
+	"zh":    "中文",
+	"zh-cn": "简体中文",
+	"zh-tw": "繁體中文",
+}
+
+// LangName returns the native name of language lang,
+// or lang itself if the language is unknown.
+func LangName(lang string) string {
+	if n, ok := langNames[strings.ToLower(lang)]; ok {
+		return n
+	}
+	if n, ok := langNames[baseLang(lang)]; ok {
+		return n
+	}
+	return lang
+}
+
+// baseLang returns the lower case primary language subtag of lang,
+// e.g. "pt" for "pt-BR".
+func baseLang(lang string) string {
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	return strings.ToLower(lang)
+}
@@ -0,0 +1,113 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googlecodelabs/tools/claat/types"
+)
+
+func TestNewLocales(t *testing.T) {
+	langs := []string{"en", "ja", "pt-BR"}
+	tests := []struct {
+		current string
+		want    []*Locale
+	}{
+		{"", []*Locale{
+			{Lang: "en", Name: "English", URL: "./", Current: true},
+			{Lang: "ja", Name: "日本語", URL: "./ja/"},
+			{Lang: "pt-BR", Name: "Português", URL: "./pt-BR/"},
+		}},
+		{"ja", []*Locale{
+			{Lang: "en", Name: "English", URL: "../"},
+			{Lang: "ja", Name: "日本語", URL: "../ja/", Current: true},
+			{Lang: "pt-BR", Name: "Português", URL: "../pt-BR/"},
+		}},
+	}
+	for _, tc := range tests {
+		got := NewLocales(langs, tc.current, "en")
+		if diff := cmp.Diff(tc.want, got); diff != "" {
+			t.Errorf("NewLocales(%q) got diff (-want +got):\n%s", tc.current, diff)
+		}
+	}
+}
+
+func TestLangDir(t *testing.T) {
+	tests := map[string]string{
+		"":      "ltr",
+		"en":    "ltr",
+		"ar":    "rtl",
+		"he-IL": "rtl",
+		"FA":    "rtl",
+		"fr_CA": "ltr",
+	}
+	for lang, want := range tests {
+		if got := LangDir(lang); got != want {
+			t.Errorf("LangDir(%q) = %q, want %q", lang, got, want)
+		}
+	}
+}
+
+func TestLangName(t *testing.T) {
+	tests := map[string]string{
+		"ja":    "日本語",
+		"zh-TW": "繁體中文",
+		"zh-HK": "中文",
+		"xx":    "xx",
+	}
+	for lang, want := range tests {
+		if got := LangName(lang); got != want {
+			t.Errorf("LangName(%q) = %q, want %q", lang, got, want)
+		}
+	}
+}
+
+func TestHTMLLocales(t *testing.T) {
+	meta := &types.Meta{ID: "codelab", Title: "Codelab", Lang: "ar"}
+	ctx := Context{
+		Env:     "web",
+		Format:  "html",
+		Meta:    meta,
+		Locales: NewLocales([]string{"ar", "en"}, "ar", "en"),
+	}
+	var buf bytes.Buffer
+	if err := Execute(&buf, "html", ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		`<html lang="ar" dir="rtl">`,
+		`<link rel="alternate" hreflang="en" href="../">`,
+		`<a href="../ar/" hreflang="ar" lang="ar" aria-current="page">العربية</a>`,
+	} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("html output does not contain %s:\n%s", s, buf.String())
+		}
+	}
+
+	// no locales, no language switcher
+	meta.Lang = ""
+	ctx.Locales = nil
+	buf.Reset()
+	if err := Execute(&buf, "html", ctx); err != nil {
+		t.Fatal(err)
+	}
+	if s := buf.String(); !strings.Contains(s, "<html>") || strings.Contains(s, "hreflang") {
+		t.Errorf("html output without locales has lang attributes or links:\n%s", s)
+	}
+}
@@ -0,0 +1,756 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// mathML converts LaTeX math source tex into a MathML <math> element.
+// The original source is kept in an annotation of the element.
+//
+// Only a commonly used subset of LaTeX math is supported.
+// Source which cannot be converted is rendered in an <merror> element
+// instead of failing the export.
+func mathML(tex string, display bool) *html.Node {
+	m := mathElem("math")
+	if display {
+		setAttr(m, "display", "block")
+	}
+	body, err := (&texParser{s: tex}).parse()
+	if err != nil {
+		body = mathElem("merror", mathElem("mtext", textNode(tex)))
+		setAttr(body, "title", err.Error())
+	}
+	ann := mathElem("annotation", textNode(tex))
+	setAttr(ann, "encoding", "application/x-tex")
+	m.AppendChild(mathElem("semantics", body, ann))
+	return m
+}
+
+// texIdentifiers maps LaTeX commands to identifier characters.
+var texIdentifiers = map[string]string{
+	"alpha": "α", "beta": "β", "gamma": "γ", "delta": "δ", "epsilon": "ϵ",
+	"varepsilon": "ε", "zeta": "ζ", "eta": "η", "theta": "θ", "vartheta": "ϑ",
+	"iota": "ι", "kappa": "κ", "lambda": "λ", "mu": "μ", "nu": "ν", "xi": "ξ",
+	"pi": "π", "varpi": "ϖ", "rho": "ρ", "varrho": "ϱ", "sigma": "σ",
+	"varsigma": "ς", "tau": "τ", "upsilon": "υ", "phi": "ϕ", "varphi": "φ",
+	"chi": "χ", "psi": "ψ", "omega": "ω",
+	"ell": "ℓ", "hbar": "ℏ", "imath": "ı", "jmath": "ȷ", "Re": "ℜ", "Im": "ℑ",
+	"aleph": "ℵ", "wp": "℘", "infty": "∞", "emptyset": "∅", "varnothing": "∅",
+}
+
+// texUprightIdentifiers maps LaTeX commands to identifiers which are
+// rendered upright, i.e. uppercase Greek letters.
+var texUprightIdentifiers = map[string]string{
+	"Gamma": "Γ", "Delta": "Δ", "Theta": "Θ", "Lambda": "Λ", "Xi": "Ξ",
+	"Pi": "Π", "Sigma": "Σ", "Upsilon": "Υ", "Phi": "Φ", "Psi": "Ψ",
+	"Omega": "Ω",
+}
+
+// texOperators maps LaTeX commands to operator characters.
+var texOperators = map[string]string{
+	"pm": "±", "mp": "∓", "times": "×", "div": "÷", "cdot": "⋅", "ast": "∗",
+	"star": "⋆", "circ": "∘", "bullet": "∙", "oplus": "⊕", "ominus": "⊖",
+	"otimes": "⊗", "odot": "⊙", "setminus": "∖", "cup": "∪", "cap": "∩",
+	"wedge": "∧", "land": "∧", "vee": "∨", "lor": "∨", "neg": "¬", "lnot": "¬",
+	"leq": "≤", "le": "≤", "geq": "≥", "ge": "≥", "neq": "≠", "ne": "≠",
+	"ll": "≪", "gg": "≫", "approx": "≈", "equiv": "≡", "sim": "∼",
+	"simeq": "≃", "cong": "≅", "propto": "∝", "prec": "≺", "succ": "≻",
+	"in": "∈", "notin": "∉", "ni": "∋", "subset": "⊂", "supset": "⊃",
+	"subseteq": "⊆", "supseteq": "⊇", "mid": "∣", "parallel": "∥",
+	"perp": "⊥", "forall": "∀", "exists": "∃", "nexists": "∄",
+	"partial": "∂", "nabla": "∇", "angle": "∠", "triangle": "△",
+	"to": "→", "rightarrow": "→", "leftarrow": "←", "gets": "←",
+	"leftrightarrow": "↔", "Rightarrow": "⇒", "Leftarrow": "⇐",
+	"Leftrightarrow": "⇔", "implies": "⟹", "iff": "⟺", "mapsto": "↦",
+	"uparrow": "↑", "downarrow": "↓", "longrightarrow": "⟶",
+	"longleftarrow": "⟵", "ldots": "…", "dots": "…", "cdots": "⋯",
+	"vdots": "⋮", "ddots": "⋱", "prime": "′", "colon": ":",
+	"langle": "⟨", "rangle": "⟩", "lfloor": "⌊", "rfloor": "⌋",
+	"lceil": "⌈", "rceil": "⌉", "lvert": "|", "rvert": "|", "vert": "|",
+	"lVert": "‖", "rVert": "‖", "Vert": "‖", "|": "‖",
+	"{": "{", "}": "}", "mod": "mod", "bmod": "mod",
+}
+
+// texLargeOperators maps LaTeX commands to operators with limits,
+// which are rendered under and over the operator in display math.
+var texLargeOperators = map[string]string{
+	"sum": "∑", "prod": "∏", "coprod": "∐", "bigcup": "⋃", "bigcap": "⋂",
+	"bigoplus": "⨁", "bigotimes": "⨂", "bigvee": "⋁", "bigwedge": "⋀",
+	"lim": "lim", "limsup": "lim sup", "liminf": "lim inf", "max": "max",
+	"min": "min", "sup": "sup", "inf": "inf", "argmax": "arg max",
+	"argmin": "arg min", "det": "det", "gcd": "gcd", "Pr": "Pr",
+}
+
+// texIntegrals maps LaTeX commands to operators with scripts,
+// e.g. integrals.
+var texIntegrals = map[string]string{
+	"int": "∫", "iint": "∬", "iiint": "∭", "oint": "∮",
+}
+
+// texFunctions are names of LaTeX commands rendered as upright function names.
+var texFunctions = map[string]bool{
+	"sin": true, "cos": true, "tan": true, "cot": true, "sec": true,
+	"csc": true, "arcsin": true, "arccos": true, "arctan": true,
+	"sinh": true, "cosh": true, "tanh": true, "coth": true, "log": true,
+	"ln": true, "lg": true, "exp": true, "dim": true, "ker": true,
+	"deg": true, "arg": true, "hom": true,
+}
+
+// texAccents maps LaTeX accent commands to accent characters.
+var texAccents = map[string]string{
+	"hat": "^", "widehat": "^", "bar": "¯", "overline": "¯", "vec": "→",
+	"overrightarrow": "→", "tilde": "~", "widetilde": "~", "dot": "˙",
+	"ddot": "¨", "check": "ˇ", "breve": "˘", "acute": "´", "grave": "`",
+}
+
+// texFonts maps LaTeX font commands to MathML math variants.
+var texFonts = map[string]string{
+	"mathbf": "bold", "boldsymbol": "bold-italic", "mathit": "italic",
+	"mathrm": "normal", "mathbb": "double-struck", "mathcal": "script",
+	"mathscr": "script", "mathfrak": "fraktur", "mathsf": "sans-serif",
+	"mathtt": "monospace",
+}
+
+// texTexts are LaTeX commands which take a text argument.
+var texTexts = map[string]bool{
+	"text": true, "textrm": true, "textit": true, "textbf": true,
+	"mbox": true, "operatorname": true,
+}
+
+// texSpaces maps LaTeX spacing commands to space widths.
+var texSpaces = map[string]string{
+	",": "0.1667em", ":": "0.2222em", ">": "0.2222em", ";": "0.2778em",
+	"!": "-0.1667em", " ": "0.25em", "quad": "1em", "qquad": "2em",
+}
+
+// texMatrixFences maps LaTeX matrix environments to their delimiters.
+var texMatrixFences = map[string][2]string{
+	"matrix":  {"", ""},
+	"array":   {"", ""},
+	"pmatrix": {"(", ")"},
+	"bmatrix": {"[", "]"},
+	"Bmatrix": {"{", "}"},
+	"vmatrix": {"|", "|"},
+	"Vmatrix": {"‖", "‖"},
+	"cases":   {"{", ""},
+}
+
+// texAlignEnvs are LaTeX environments of aligned equations.
+var texAlignEnvs = map[string]bool{
+	"align": true, "align*": true, "aligned": true, "gather": true,
+	"gather*": true, "gathered": true, "split": true, "eqnarray": true,
+}
+
+// texIgnored are LaTeX commands which have no effect on the output.
+var texIgnored = map[string]bool{
+	"displaystyle": true, "textstyle": true, "limits": true,
+	"nolimits": true, "nonumber": true,
+}
+
+// texParser is a recursive descent parser of LaTeX math.
+// Tokens are single characters, except for commands which are
+// returned with the leading backslash, e.g. `\alpha`.
+type texParser struct {
+	s   string // LaTeX source
+	pos int    // current position in s
+}
+
+// parse converts the whole source into a MathML <mrow> element.
+func (p *texParser) parse() (*html.Node, error) {
+	row, err := p.row()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.next(); t != "" {
+		return nil, fmt.Errorf("unexpected %s", t)
+	}
+	return mathElem("mrow", row...), nil
+}
+
+// skipSpace advances p past any white space.
+func (p *texParser) skipSpace() {
+	for p.pos < len(p.s) && strings.IndexByte(" \t\r\n", p.s[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+// next returns the next token, or an empty string at the end of source.
+func (p *texParser) next() string {
+	p.skipSpace()
+	if p.pos >= len(p.s) {
+		return ""
+	}
+	if p.s[p.pos] != '\\' {
+		_, n := utf8.DecodeRuneInString(p.s[p.pos:])
+		t := p.s[p.pos : p.pos+n]
+		p.pos += n
+		return t
+	}
+	start := p.pos
+	p.pos++
+	for p.pos < len(p.s) && isASCIILetter(p.s[p.pos]) {
+		p.pos++
+	}
+	if p.pos == start+1 && p.pos < len(p.s) {
+		// control symbol, e.g. `\{` or `\,`
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+// peek returns the next token without consuming it.
+func (p *texParser) peek() string {
+	pos := p.pos
+	t := p.next()
+	p.pos = pos
+	return t
+}
+
+// expect consumes the next token, which must be t.
+func (p *texParser) expect(t string) error {
+	if got := p.next(); got != t {
+		if got == "" {
+			return fmt.Errorf("missing %s", t)
+		}
+		return fmt.Errorf("unexpected %s, want %s", got, t)
+	}
+	return nil
+}
+
+// row parses a sequence of atoms with their scripts, until the end
+// of source or one of the terminators. The terminator is not consumed.
+func (p *texParser) row(terminators ...string) ([]*html.Node, error) {
+	var res []*html.Node
+	for {
+		t := p.peek()
+		if t == "" || t == "}" || hasString(terminators, t) {
+			return res, nil
+		}
+		switch t {
+		case `\right`, `\end`, "&", `\\`:
+			return nil, fmt.Errorf("unexpected %s", t)
+		}
+		n, err := p.scripted()
+		if err != nil {
+			return nil, err
+		}
+		if n != nil {
+			res = append(res, n)
+		}
+	}
+}
+
+// scripted parses an atom followed by optional sub- and superscripts.
+func (p *texParser) scripted() (*html.Node, error) {
+	t := p.next()
+	var base *html.Node
+	if t != "^" && t != "_" && t != "'" {
+		var err error
+		if base, err = p.atom(t); err != nil {
+			return nil, err
+		}
+		t = p.peek()
+		if t == "^" || t == "_" || t == "'" {
+			p.next()
+		}
+	}
+	var sub, sup *html.Node
+	var primes string
+	for {
+		var err error
+		switch t {
+		case "'":
+			primes += "′"
+		case "^":
+			if sup != nil {
+				return nil, fmt.Errorf("double superscript")
+			}
+			if sup, err = p.arg(); err != nil {
+				return nil, err
+			}
+		case "_":
+			if sub != nil {
+				return nil, fmt.Errorf("double subscript")
+			}
+			if sub, err = p.arg(); err != nil {
+				return nil, err
+			}
+		default:
+			return scripts(base, sub, sup, primes), nil
+		}
+		t = p.peek()
+		if t == "^" || t == "_" || t == "'" {
+			p.next()
+		}
+	}
+}
+
+// scripts attaches sub- and superscripts to base.
+// Any of the arguments can be nil or empty.
+func scripts(base, sub, sup *html.Node, primes string) *html.Node {
+	if primes != "" {
+		pn := mathElem("mo", textNode(primes))
+		if sup == nil {
+			sup = pn
+		} else {
+			sup = mathElem("mrow", pn, sup)
+		}
+	}
+	if sub == nil && sup == nil {
+		return base
+	}
+	if base == nil {
+		base = mathElem("mrow")
+	}
+	under, over := "msub", "msup"
+	both := "msubsup"
+	if base.Data == "mo" && attrVal(base, "movablelimits") == "true" {
+		under, over, both = "munder", "mover", "munderover"
+	}
+	switch {
+	case sub != nil && sup != nil:
+		return mathElem(both, base, sub, sup)
+	case sub != nil:
+		return mathElem(under, base, sub)
+	default:
+		return mathElem(over, base, sup)
+	}
+}
+
+// arg parses a command argument or a script,
+// which is either a group in braces or a single atom.
+func (p *texParser) arg() (*html.Node, error) {
+	t := p.next()
+	if t == "" {
+		return nil, fmt.Errorf("missing argument")
+	}
+	n, err := p.atom(t)
+	if err != nil {
+		return nil, err
+	}
+	if n == nil {
+		n = mathElem("mrow")
+	}
+	return n, nil
+}
+
+// rawArg returns source of a command argument in braces as is.
+func (p *texParser) rawArg() (string, error) {
+	if err := p.expect("{"); err != nil {
+		return "", err
+	}
+	depth := 1
+	start := p.pos
+	for ; p.pos < len(p.s); p.pos++ {
+		switch p.s[p.pos] {
+		case '\\':
+			p.pos++
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				v := p.s[start:p.pos]
+				p.pos++
+				return v, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("missing }")
+}
+
+// group parses content up to the closing brace into a single node.
+func (p *texParser) group() (*html.Node, error) {
+	row, err := p.row()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect("}"); err != nil {
+		return nil, err
+	}
+	if len(row) == 1 {
+		return row[0], nil
+	}
+	return mathElem("mrow", row...), nil
+}
+
+// atom converts token t, which has been already consumed,
+// and the arguments of t into a node.
+// It returns nil node for tokens with no visual representation.
+func (p *texParser) atom(t string) (*html.Node, error) {
+	switch {
+	case t == "{":
+		return p.group()
+	case t == "}":
+		return nil, fmt.Errorf("unexpected }")
+	case isDigit(t[0]):
+		start := p.pos - 1
+		for p.pos < len(p.s) && (isDigit(p.s[p.pos]) || p.s[p.pos] == '.' && p.pos+1 < len(p.s) && isDigit(p.s[p.pos+1])) {
+			p.pos++
+		}
+		return mathElem("mn", textNode(p.s[start:p.pos])), nil
+	case t[0] == '\\':
+		return p.command(t[1:])
+	case t == "~":
+		return mathElem("mtext", textNode(" ")), nil
+	case t == "-":
+		return mathElem("mo", textNode("−")), nil
+	case t == "*":
+		return mathElem("mo", textNode("∗")), nil
+	}
+	r, _ := utf8.DecodeRuneInString(t)
+	if unicode.IsLetter(r) {
+		return mathElem("mi", textNode(t)), nil
+	}
+	return mathElem("mo", textNode(t)), nil
+}
+
+// command converts LaTeX command name, without the leading backslash,
+// and its arguments into a node.
+func (p *texParser) command(name string) (*html.Node, error) {
+	if v, ok := texIdentifiers[name]; ok {
+		return mathElem("mi", textNode(v)), nil
+	}
+	if v, ok := texUprightIdentifiers[name]; ok {
+		n := mathElem("mi", textNode(v))
+		setAttr(n, "mathvariant", "normal")
+		return n, nil
+	}
+	if v, ok := texOperators[name]; ok {
+		return mathElem("mo", textNode(v)), nil
+	}
+	if v, ok := texLargeOperators[name]; ok {
+		n := mathElem("mo", textNode(v))
+		setAttr(n, "movablelimits", "true")
+		return n, nil
+	}
+	if v, ok := texIntegrals[name]; ok {
+		return mathElem("mo", textNode(v)), nil
+	}
+	if texFunctions[name] {
+		return mathElem("mi", textNode(name)), nil
+	}
+	if w, ok := texSpaces[name]; ok {
+		n := mathElem("mspace")
+		setAttr(n, "width", w)
+		return n, nil
+	}
+	if texIgnored[name] {
+		return nil, nil
+	}
+	if strings.Contains("#$%&_", name) && len(name) == 1 {
+		// escaped special characters
+		return mathElem("mo", textNode(name)), nil
+	}
+	if v, ok := texAccents[name]; ok {
+		base, err := p.arg()
+		if err != nil {
+			return nil, err
+		}
+		acc := mathElem("mo", textNode(v))
+		n := mathElem("mover", base, acc)
+		setAttr(n, "accent", "true")
+		return n, nil
+	}
+	if v, ok := texFonts[name]; ok {
+		n, err := p.arg()
+		if err != nil {
+			return nil, err
+		}
+		setMathVariant(n, v)
+		return n, nil
+	}
+	if texTexts[name] {
+		v, err := p.rawArg()
+		if err != nil {
+			return nil, err
+		}
+		if name == "operatorname" {
+			return mathElem("mi", textNode(v)), nil
+		}
+		return mathElem("mtext", textNode(v)), nil
+	}
+	switch name {
+	case "frac", "dfrac", "tfrac", "cfrac":
+		num, err := p.arg()
+		if err != nil {
+			return nil, err
+		}
+		den, err := p.arg()
+		if err != nil {
+			return nil, err
+		}
+		return mathElem("mfrac", num, den), nil
+	case "binom":
+		n, err := p.arg()
+		if err != nil {
+			return nil, err
+		}
+		k, err := p.arg()
+		if err != nil {
+			return nil, err
+		}
+		frac := mathElem("mfrac", n, k)
+		setAttr(frac, "linethickness", "0")
+		return mathElem("mrow", fence("("), frac, fence(")")), nil
+	case "sqrt":
+		var index *html.Node
+		if p.peek() == "[" {
+			p.next()
+			row, err := p.row("]")
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			index = mathElem("mrow", row...)
+		}
+		base, err := p.arg()
+		if err != nil {
+			return nil, err
+		}
+		if index != nil {
+			return mathElem("mroot", base, index), nil
+		}
+		return mathElem("msqrt", base), nil
+	case "overset", "stackrel", "underset":
+		script, err := p.arg()
+		if err != nil {
+			return nil, err
+		}
+		base, err := p.arg()
+		if err != nil {
+			return nil, err
+		}
+		if name == "underset" {
+			return mathElem("munder", base, script), nil
+		}
+		return mathElem("mover", base, script), nil
+	case "underline":
+		base, err := p.arg()
+		if err != nil {
+			return nil, err
+		}
+		n := mathElem("munder", base, mathElem("mo", textNode("_")))
+		setAttr(n, "accentunder", "true")
+		return n, nil
+	case "left":
+		return p.leftRight()
+	case "begin":
+		return p.env()
+	}
+	return nil, fmt.Errorf(`unsupported command \%s`, name)
+}
+
+// leftRight parses content between \left and \right delimiters.
+func (p *texParser) leftRight() (*html.Node, error) {
+	open, err := p.delim()
+	if err != nil {
+		return nil, err
+	}
+	row, err := p.row(`\right`)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(`\right`); err != nil {
+		return nil, err
+	}
+	closing, err := p.delim()
+	if err != nil {
+		return nil, err
+	}
+	res := mathElem("mrow")
+	if open != "" {
+		res.AppendChild(fence(open))
+	}
+	for _, n := range row {
+		res.AppendChild(n)
+	}
+	if closing != "" {
+		res.AppendChild(fence(closing))
+	}
+	return res, nil
+}
+
+// delim parses a \left or \right delimiter.
+// It returns an empty string for the null delimiter ".".
+func (p *texParser) delim() (string, error) {
+	t := p.next()
+	switch {
+	case t == ".":
+		return "", nil
+	case strings.Contains("()[]|/", t) && len(t) == 1:
+		return t, nil
+	case strings.HasPrefix(t, `\`):
+		if v, ok := texOperators[t[1:]]; ok {
+			return v, nil
+		}
+	}
+	return "", fmt.Errorf("invalid delimiter %q", t)
+}
+
+// env parses a \begin{name} ... \end{name} environment into a table.
+func (p *texParser) env() (*html.Node, error) {
+	name, err := p.rawArg()
+	if err != nil {
+		return nil, err
+	}
+	fences, isMatrix := texMatrixFences[name]
+	if !isMatrix && !texAlignEnvs[name] {
+		return nil, fmt.Errorf("unsupported environment %s", name)
+	}
+	if name == "array" {
+		// column spec is not supported
+		if _, err := p.rawArg(); err != nil {
+			return nil, err
+		}
+	}
+	table := mathElem("mtable")
+	switch {
+	case name == "cases":
+		setAttr(table, "columnalign", "left left")
+	case texAlignEnvs[name]:
+		setAttr(table, "columnalign", "right left")
+		setAttr(table, "displaystyle", "true")
+	}
+	tr := mathElem("mtr")
+	for {
+		row, err := p.row("&", `\\`, `\end`)
+		if err != nil {
+			return nil, err
+		}
+		tr.AppendChild(mathElem("mtd", mathElem("mrow", row...)))
+		switch p.next() {
+		case "&":
+			continue
+		case `\\`:
+			table.AppendChild(tr)
+			tr = mathElem("mtr")
+			continue
+		}
+		// \end
+		break
+	}
+	if !isEmptyRow(tr) {
+		table.AppendChild(tr)
+	}
+	end, err := p.rawArg()
+	if err != nil {
+		return nil, err
+	}
+	if end != name {
+		return nil, fmt.Errorf(`\begin{%s} ended by \end{%s}`, name, end)
+	}
+	if fences[0] == "" && fences[1] == "" {
+		return table, nil
+	}
+	res := mathElem("mrow")
+	if fences[0] != "" {
+		res.AppendChild(fence(fences[0]))
+	}
+	res.AppendChild(table)
+	if fences[1] != "" {
+		res.AppendChild(fence(fences[1]))
+	}
+	return res, nil
+}
+
+// isEmptyRow reports whether table row tr has a single empty cell,
+// as is the case after a trailing \\.
+func isEmptyRow(tr *html.Node) bool {
+	td := tr.FirstChild
+	return td == nil || td.NextSibling == nil && td.FirstChild.FirstChild == nil
+}
+
+// fence returns a stretchy delimiter operator.
+func fence(v string) *html.Node {
+	n := mathElem("mo", textNode(v))
+	setAttr(n, "fence", "true")
+	setAttr(n, "stretchy", "true")
+	return n
+}
+
+// setMathVariant sets mathvariant attribute of all token elements in n.
+func setMathVariant(n *html.Node, v string) {
+	if n.Type != html.ElementNode {
+		return
+	}
+	switch n.Data {
+	case "mi", "mn", "mo", "mtext":
+		setAttr(n, "mathvariant", v)
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		setMathVariant(c, v)
+	}
+}
+
+// mathElem creates a new MathML element with the children.
+func mathElem(name string, children ...*html.Node) *html.Node {
+	n := &html.Node{Type: html.ElementNode, Data: name}
+	for _, c := range children {
+		n.AppendChild(c)
+	}
+	return n
+}
+
+func textNode(v string) *html.Node {
+	return &html.Node{Type: html.TextNode, Data: v}
+}
+
+// setAttr sets attribute key of n to val, replacing any existing value.
+func setAttr(n *html.Node, key, val string) {
+	for i, a := range n.Attr {
+		if a.Key == key {
+			n.Attr[i].Val = val
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: val})
+}
+
+func attrVal(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func hasString(a []string, s string) bool {
+	for _, v := range a {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isASCIILetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
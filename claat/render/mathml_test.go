@@ -0,0 +1,150 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/googlecodelabs/tools/claat/nodes"
+	"golang.org/x/net/html"
+)
+
+func TestMathML(t *testing.T) {
+	tests := []struct {
+		name string
+		tex  string
+		out  string // content of <semantics> element, except for annotation
+	}{
+		{
+			name: "Scripts",
+			tex:  `e^{i\pi} + 1 = 0`,
+			out:  `<mrow><msup><mi>e</mi><mrow><mi>i</mi><mi>π</mi></mrow></msup><mo>+</mo><mn>1</mn><mo>=</mo><mn>0</mn></mrow>`,
+		},
+		{
+			name: "Limits",
+			tex:  `\sum_{i=1}^n x_i'`,
+			out:  `<mrow><munderover><mo movablelimits="true">∑</mo><mrow><mi>i</mi><mo>=</mo><mn>1</mn></mrow><mi>n</mi></munderover><msubsup><mi>x</mi><mi>i</mi><mo>′</mo></msubsup></mrow>`,
+		},
+		{
+			name: "FracSqrt",
+			tex:  `\frac{-b \pm \sqrt{b^2-4ac}}{2a}`,
+			out:  `<mrow><mfrac><mrow><mo>−</mo><mi>b</mi><mo>±</mo><msqrt><mrow><msup><mi>b</mi><mn>2</mn></msup><mo>−</mo><mn>4</mn><mi>a</mi><mi>c</mi></mrow></msqrt></mrow><mrow><mn>2</mn><mi>a</mi></mrow></mfrac></mrow>`,
+		},
+		{
+			name: "Fences",
+			tex:  `\left\langle x \right.`,
+			out:  `<mrow><mrow><mo fence="true" stretchy="true">⟨</mo><mi>x</mi></mrow></mrow>`,
+		},
+		{
+			name: "Matrix",
+			tex:  `\begin{bmatrix} 1 & 0 \\ 0 & 1 \\ \end{bmatrix}`,
+			out:  `<mrow><mrow><mo fence="true" stretchy="true">[</mo><mtable><mtr><mtd><mrow><mn>1</mn></mrow></mtd><mtd><mrow><mn>0</mn></mrow></mtd></mtr><mtr><mtd><mrow><mn>0</mn></mrow></mtd><mtd><mrow><mn>1</mn></mrow></mtd></mtr></mtable><mo fence="true" stretchy="true">]</mo></mrow></mrow>`,
+		},
+		{
+			name: "FontsAndText",
+			tex:  `x \in \mathbb{R} \text{ if } x>0`,
+			out:  `<mrow><mi>x</mi><mo>∈</mo><mi mathvariant="double-struck">R</mi><mtext> if </mtext><mi>x</mi><mo>&gt;</mo><mn>0</mn></mrow>`,
+		},
+		{
+			name: "Unsupported",
+			tex:  `\foo{x}`,
+			out:  `<merror title="unsupported command \foo"><mtext>\foo{x}</mtext></merror>`,
+		},
+		{
+			name: "Unbalanced",
+			tex:  `\frac{1}{2`,
+			out:  `<merror title="missing }"><mtext>\frac{1}{2</mtext></merror>`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := html.Render(&buf, mathML(tc.tex, false)); err != nil {
+				t.Fatal(err)
+			}
+			out := buf.String()
+			out = strings.TrimPrefix(out, "<math><semantics>")
+			out = out[:strings.Index(out, "<annotation")]
+			if out != tc.out {
+				t.Errorf("mathML(%q) =\n%s\nwant:\n%s", tc.tex, out, tc.out)
+			}
+		})
+	}
+}
+
+func TestMathRender(t *testing.T) {
+	inline := nodes.NewMathNode(`x^2`, false)
+	display := nodes.NewMathNode(`x^2`, true)
+	tests := []struct {
+		name   string
+		render func(nodes.Node) (string, error)
+		n      nodes.Node
+		out    string
+	}{
+		{
+			name: "HTMLInline",
+			render: func(n nodes.Node) (string, error) {
+				var buf bytes.Buffer
+				err := WriteHTML(&buf, "", "", n)
+				return buf.String(), err
+			},
+			n:   inline,
+			out: `<math><semantics><mrow><msup><mi>x</mi><mn>2</mn></msup></mrow><annotation encoding="application/x-tex">x^2</annotation></semantics></math>`,
+		},
+		{
+			name: "LiteDisplay",
+			render: func(n nodes.Node) (string, error) {
+				var buf bytes.Buffer
+				err := WriteLite(&buf, "", n)
+				return buf.String(), err
+			},
+			n:   display,
+			out: `<math display="block"><semantics><mrow><msup><mi>x</mi><mn>2</mn></msup></mrow><annotation encoding="application/x-tex">x^2</annotation></semantics></math>`,
+		},
+		{
+			name: "MDInline",
+			render: func(n nodes.Node) (string, error) {
+				var buf bytes.Buffer
+				err := WriteMD(&buf, "", "", n)
+				return buf.String(), err
+			},
+			n:   inline,
+			out: `$x^2$`,
+		},
+		{
+			name: "MDDisplay",
+			render: func(n nodes.Node) (string, error) {
+				var buf bytes.Buffer
+				err := WriteMD(&buf, "", "", n)
+				return buf.String(), err
+			},
+			n:   display,
+			out: `$$x^2$$`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := tc.render(tc.n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out != tc.out {
+				t.Errorf("got:\n%s\nwant:\n%s", out, tc.out)
+			}
+		})
+	}
+}
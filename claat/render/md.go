@@ -20,7 +20,6 @@ import (
 	"html"
 	"io"
 	"path"
-	"sort"
 	"strconv"
 	"strings"
 
@@ -87,11 +86,7 @@ func (mw *mdWriter) newBlock() {
 }
 
 func (mw *mdWriter) matchEnv(v []string) bool {
-	if len(v) == 0 || mw.env == "" {
-		return true
-	}
-	i := sort.SearchStrings(v, mw.env)
-	return i < len(v) && v[i] == mw.env
+	return MatchEnv(v, mw.env)
 }
 
 func (mw *mdWriter) write(nodesToWrite ...nodes.Node) error {
@@ -108,8 +103,12 @@ func (mw *mdWriter) write(nodesToWrite ...nodes.Node) error {
 			mw.url(n)
 		case *nodes.ButtonNode:
 			mw.write(n.Content.Nodes...)
+		case *nodes.MathNode:
+			mw.math(n)
 		case *nodes.CodeNode:
 			mw.code(n)
+		case *nodes.DiagramNode:
+			mw.code(nodes.NewCodeNode(n.Value, false, n.Kind))
 		case *nodes.ListNode:
 			mw.list(n)
 		case *nodes.ImportNode:
@@ -129,6 +128,8 @@ func (mw *mdWriter) write(nodesToWrite ...nodes.Node) error {
 			mw.header(n)
 		case *nodes.YouTubeNode:
 			mw.youtube(n)
+		case *nodes.CustomNode:
+			mw.custom(n)
 		}
 		if mw.err != nil {
 			return mw.err
@@ -173,6 +174,15 @@ func (mw *mdWriter) text(n *nodes.TextNode) {
 	mw.writeString(right)
 }
 
+// math writes LaTeX source of n as is, in $...$ or $$...$$ delimiters.
+func (mw *mdWriter) math(n *nodes.MathNode) {
+	delim := "$"
+	if n.Display {
+		delim = "$$"
+	}
+	mw.writeString(delim + n.Value + delim)
+}
+
 func (mw *mdWriter) image(n *nodes.ImageNode) {
 	mw.space()
 	mw.writeString("<img ")
@@ -232,6 +242,12 @@ func (mw *mdWriter) code(n *nodes.CodeNode) {
 	} else {
 		mw.writeString(n.Lang)
 	}
+	switch {
+	case n.File != "" && n.Lang == "" && !n.Term:
+		mw.writeString("file=" + n.File)
+	case n.File != "":
+		mw.writeString(" " + n.File)
+	}
 	mw.writeString("\n")
 	mw.writeString(n.Value)
 	if !mw.lineStart {
@@ -240,6 +256,43 @@ func (mw *mdWriter) code(n *nodes.CodeNode) {
 	mw.writeString("```")
 }
 
+// custom writes n with a renderer registered for its kind,
+// or as a directive the md parser can read back.
+func (mw *mdWriter) custom(n *nodes.CustomNode) {
+	mw.newBlock()
+	if r := customRenderers[n.Kind]; r.MD != nil {
+		var buf bytes.Buffer
+		if err := r.MD(&buf, mw.env, n); err != nil {
+			if mw.err == nil {
+				mw.err = err
+			}
+			return
+		}
+		// Write line by line, so that the prefix applies to every line.
+		for _, l := range strings.SplitAfter(buf.String(), "\n") {
+			mw.writeString(l)
+		}
+		return
+	}
+	if n.Value == "" && n.Content.Empty() {
+		mw.writeString("::" + n.Kind + customArgs(n))
+		return
+	}
+	mw.writeString(":::" + n.Kind + customArgs(n) + "\n")
+	if !n.Content.Empty() {
+		// content may differ from the source, e.g. when translated
+		mw.write(n.Content.Nodes...)
+	} else {
+		for _, l := range strings.SplitAfter(n.Value, "\n") {
+			mw.writeString(l)
+		}
+	}
+	if !mw.lineStart {
+		mw.writeString("\n")
+	}
+	mw.writeString(":::")
+}
+
 func (mw *mdWriter) list(n *nodes.ListNode) {
 	if n.Block() == true {
 		mw.newBlock()
@@ -295,14 +348,28 @@ func (mw *mdWriter) survey(n *nodes.SurveyNode) {
 	mw.writeString("<form>")
 	mw.writeString("\n")
 	for _, g := range n.Groups {
-		mw.writeString("<name>")
+		mw.writeString("<name")
+		if g.Multi {
+			mw.writeString(" multiple")
+		}
+		mw.writeString(">")
 		mw.writeEscape(g.Name)
 		mw.writeString("</name>")
 		mw.writeString("\n")
-		for _, o := range g.Options {
+		for i, o := range g.Options {
 			mw.writeString("<input value=\"")
 			mw.writeEscape(o)
-			mw.writeString("\">")
+			mw.writeString("\"")
+			if g.IsCorrect(i) {
+				mw.writeString(" correct")
+			}
+			mw.writeString(">")
+			mw.writeString("\n")
+		}
+		if g.Explanation != "" {
+			mw.writeString("<explanation>")
+			mw.writeEscape(g.Explanation)
+			mw.writeString("</explanation>")
 			mw.writeString("\n")
 		}
 	}
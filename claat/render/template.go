@@ -15,18 +15,14 @@
 package render
 
 import (
+	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
-	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 
-	htmlTemplate "html/template"
-	textTemplate "text/template"
-
 	mdParse "github.com/googlecodelabs/tools/claat/parser/md"
 	"github.com/googlecodelabs/tools/claat/types"
 
@@ -44,24 +40,31 @@ type Context struct {
 	Steps     []*types.Step
 	Updated   string
 	Extra     map[string]string // Extra variables passed from the command line.
+	Locales   []*Locale         // All translations of the codelab, if any.
 }
 
 // Execute renders a template of the fmt format into w.
 //
 // The fmt argument can also be a path to a local file.
+// Formats which are not template-based are rendered only if data is a *Page.
 //
 // Template execution context data is expected to be of type *Context
 // but can be an arbitrary struct, as long as it contains at least Context's fields
 // for the built-in templates to be successfully executed.
 func Execute(w io.Writer, fmt string, data interface{}, opt ...Option) error {
-	var funcs map[string]interface{}
-	for _, o := range opt {
-		switch o := o.(type) {
-		case optFuncMap:
-			funcs = o
+	f, err := LookupFormat(fmt)
+	if err != nil {
+		return err
+	}
+	tf, ok := f.(templateFormat)
+	if !ok {
+		p, ok := data.(*Page)
+		if !ok {
+			return errors.New("format " + fmt + " requires *Page data")
 		}
+		return f.Render(w, p, opt...)
 	}
-	t, err := parseTemplate(fmt, funcs)
+	t, err := tf.template(funcMapOption(opt))
 	if err != nil {
 		return err
 	}
@@ -108,6 +111,7 @@ var funcMap = map[string]interface{}{
 		res += kvLine(mdParse.MetaAnalyticsGa4Account, meta.GA4)
 		res += kvLine(mdParse.MetaSource, meta.Source)
 		res += kvLine(mdParse.MetaDuration, strconv.Itoa(meta.Duration))
+		res += kvLine(mdParse.MetaLang, meta.Lang)
 
 		for k, v := range meta.Extra {
 			res += kvLine(k, v)
@@ -115,13 +119,20 @@ var funcMap = map[string]interface{}{
 
 		return res
 	},
-	"matchEnv": func(tags []string, t string) bool {
-		if len(tags) == 0 || t == "" {
-			return true
-		}
-		i := sort.SearchStrings(tags, t)
-		return i < len(tags) && tags[i] == t
-	},
+	"matchEnv": MatchEnv,
+	// navigation and summaries
+	"toc":               TOC,
+	"totalDuration":     TotalDuration,
+	"remainingDuration": RemainingDuration,
+	"stepsByEnv":        StepsByEnv,
+	"wordCount":         WordCount,
+	"readingTime":       ReadingTime,
+	"images":            Images,
+	"links":             Links,
+	// translations
+	"langDir":    LangDir,
+	"langName":   LangName,
+	"renderText": Text,
 	// lite/offline versions; multiple step files
 	"inc": func(n int) int {
 		return n + 1
@@ -139,18 +150,10 @@ var funcMap = map[string]interface{}{
 		return a
 	},
 	"stepLink": func(n int) string {
-		if n <= 1 {
-			return "index.html"
-		}
-		return fmt.Sprintf("step-%d.html", n)
+		return stepFilename(n, ".html")
 	},
 }
 
-type template struct {
-	bytes []byte
-	html  bool
-}
-
 //go:embed template.html
 var newHTMLTemplate []byte
 
@@ -160,65 +163,6 @@ var newMDTemplate []byte
 //go:embed template-offline.html
 var newOfflineTemplate []byte
 
-// parseTemplate parses template name defined either in tmpldata
-// or a local file.
-//
-// A local file template is parsed as HTML if file extension is ".html",
-// text otherwise.
-func parseTemplate(name string, fmap map[string]interface{}) (executer, error) {
-	var tmpl *template
-	switch name {
-	case "html":
-		tmpl = &template{
-			bytes: newHTMLTemplate,
-			html:  true,
-		}
-	case "md":
-		tmpl = &template{
-			bytes: newMDTemplate,
-		}
-	case "offline":
-		tmpl = &template{
-			bytes: newOfflineTemplate,
-			html:  true,
-		}
-	default:
-		// TODO: add templates in-mem caching
-		var err error
-		if tmpl, err = readTemplate(name); err != nil {
-			return nil, err
-		}
-	}
-
-	funcs := make(map[string]interface{}, len(funcMap))
-	for k, v := range funcMap {
-		funcs[k] = v
-	}
-	for k, v := range fmap {
-		funcs[k] = v
-	}
-
-	if tmpl.html {
-		return htmlTemplate.New(name).
-			Funcs(funcs).
-			Parse(string(tmpl.bytes))
-	}
-	return textTemplate.New(name).
-		Funcs(funcs).
-		Parse(string(tmpl.bytes))
-}
-
-func readTemplate(name string) (*template, error) {
-	b, err := ioutil.ReadFile(name)
-	if err != nil {
-		return nil, err
-	}
-	return &template{
-		bytes: b,
-		html:  filepath.Ext(name) == ".html",
-	}, nil
-}
-
 // Option is the type of optional arguments for Execute.
 type Option interface {
 	option()
@@ -232,3 +176,15 @@ func WithFuncMap(fm map[string]interface{}) Option {
 type optFuncMap map[string]interface{}
 
 func (o optFuncMap) option() {}
+
+// funcMapOption returns template functions supplied with opt, if any.
+func funcMapOption(opt []Option) map[string]interface{} {
+	var funcs map[string]interface{}
+	for _, o := range opt {
+		switch o := o.(type) {
+		case optFuncMap:
+			funcs = o
+		}
+	}
+	return funcs
+}
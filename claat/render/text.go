@@ -0,0 +1,383 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/googlecodelabs/tools/claat/nodes"
+)
+
+// Text renders nodes as readable plain text for the target env.
+//
+// Blocks are separated with blank lines, list items are prefixed
+// with bullets or numbers, tables are aligned in columns, code is indented
+// and link URLs follow the link text in brackets.
+func Text(ctx Context, nodes ...nodes.Node) (string, error) {
+	var buf bytes.Buffer
+	if err := WriteText(&buf, ctx.Env, nodes...); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// WriteText does the same as Text but outputs rendered text to w.
+func WriteText(w io.Writer, env string, nodes ...nodes.Node) error {
+	tw := textWriter{w: w, env: env, lineStart: true}
+	if err := tw.write(nodes...); err != nil {
+		return err
+	}
+	if !tw.lineStart {
+		tw.writeString("\n")
+	}
+	return tw.err
+}
+
+// textFormat is the "txt" format, rendering a codelab into a single
+// plain text file with Text.
+type textFormat struct{}
+
+// PerStep implements Format.
+func (textFormat) PerStep() bool {
+	return false
+}
+
+// Filename implements Format.
+func (textFormat) Filename(step int) string {
+	return stepFilename(step, ".txt")
+}
+
+// Render implements Format. The codelab title and step titles
+// are underlined, followed by the content of steps in p.Env.
+func (textFormat) Render(w io.Writer, p *Page, opt ...Option) error {
+	tw := textWriter{w: w, env: p.Env, lineStart: true}
+	tw.underline(p.Meta.Title, "=")
+	if p.Meta.Summary != "" {
+		tw.newBlock()
+		tw.writeString(p.Meta.Summary)
+	}
+	for i, s := range StepsByEnv(p.Steps, p.Env) {
+		tw.newBlock()
+		tw.underline(strconv.Itoa(i+1)+". "+s.Title, "-")
+		tw.newBlock()
+		if err := tw.write(s.Content); err != nil {
+			return err
+		}
+	}
+	tw.endLine()
+	return tw.err
+}
+
+type textWriter struct {
+	w         io.Writer // output writer
+	env       string    // target environment
+	err       error     // error during any writeXxx methods
+	prefix    string    // prefix of every line, e.g. indentation of nested content
+	lineStart bool      // at the start of a line
+	written   bool      // anything has been written
+	pending   bool      // a blank line is due before the next content
+	item      bool      // only a list bullet has been written on the current line
+}
+
+func (tw *textWriter) writeString(s string) {
+	if s == "" {
+		return
+	}
+	tw.flush()
+	for s != "" && tw.err == nil {
+		line := s
+		if i := strings.IndexByte(s, '\n'); i >= 0 {
+			line = s[:i+1]
+		}
+		s = s[len(line):]
+		if tw.lineStart {
+			p := tw.prefix
+			if line == "\n" {
+				p = strings.TrimRight(p, " ")
+			}
+			line = p + line
+		}
+		_, tw.err = io.WriteString(tw.w, line)
+		tw.lineStart = strings.HasSuffix(line, "\n")
+		tw.written = true
+		tw.item = false
+	}
+}
+
+// newBlock terminates the current paragraph, if any, so that the next
+// content is separated from it with a blank line.
+func (tw *textWriter) newBlock() {
+	if !tw.written || tw.item {
+		return
+	}
+	tw.endLine()
+	tw.pending = true
+}
+
+// flush writes a pending blank line, so that it does not get
+// the prefix of the next content.
+func (tw *textWriter) flush() {
+	if tw.pending {
+		tw.pending = false
+		tw.writeString("\n")
+	}
+}
+
+// endLine terminates the current line, if any.
+func (tw *textWriter) endLine() {
+	if !tw.lineStart {
+		tw.writeString("\n")
+	}
+}
+
+// indent appends p to the line prefix, returning a func restoring it.
+func (tw *textWriter) indent(p string) func() {
+	prev := tw.prefix
+	tw.prefix += p
+	return func() { tw.prefix = prev }
+}
+
+func (tw *textWriter) matchEnv(v []string) bool {
+	return MatchEnv(v, tw.env)
+}
+
+func (tw *textWriter) write(nodesToWrite ...nodes.Node) error {
+	for _, n := range nodesToWrite {
+		if !tw.matchEnv(n.Env()) {
+			continue
+		}
+		block := n.Block() == true
+		if block {
+			tw.newBlock()
+		}
+		switch n := n.(type) {
+		case *nodes.TextNode:
+			tw.writeString(n.Value)
+		case *nodes.ImageNode:
+			tw.image(n)
+		case *nodes.URLNode:
+			tw.url(n)
+		case *nodes.ButtonNode:
+			tw.write(n.Content.Nodes...)
+		case *nodes.MathNode:
+			tw.math(n)
+		case *nodes.CodeNode:
+			tw.code(n.Value)
+		case *nodes.DiagramNode:
+			tw.code(n.Value)
+		case *nodes.ListNode:
+			tw.list(n)
+		case *nodes.ImportNode:
+			tw.write(n.Content.Nodes...)
+		case *nodes.ItemsListNode:
+			tw.itemsList(n)
+		case *nodes.GridNode:
+			tw.table(n)
+		case *nodes.InfoboxNode:
+			tw.infobox(n)
+		case *nodes.SurveyNode:
+			tw.survey(n)
+		case *nodes.HeaderNode:
+			tw.header(n)
+		case *nodes.YouTubeNode:
+			tw.link("https://www.youtube.com/watch?v=" + n.VideoID)
+		case *nodes.IframeNode:
+			tw.link(n.URL)
+		case *nodes.CustomNode:
+			tw.custom(n)
+		}
+		if block {
+			tw.newBlock()
+		}
+		if tw.err != nil {
+			return tw.err
+		}
+	}
+	return nil
+}
+
+// link writes url of an embedded resource in brackets, in a separate block.
+func (tw *textWriter) link(url string) {
+	tw.newBlock()
+	tw.writeString("[" + url + "]")
+	tw.newBlock()
+}
+
+func (tw *textWriter) image(n *nodes.ImageNode) {
+	alt := n.Alt
+	if alt == "" {
+		alt = n.Title
+	}
+	if alt != "" {
+		tw.writeString("[" + alt + "]")
+	}
+}
+
+// url writes link text followed by the URL in brackets,
+// unless the text is the URL itself.
+func (tw *textWriter) url(n *nodes.URLNode) {
+	tw.write(n.Content.Nodes...)
+	if n.URL == "" {
+		return
+	}
+	if s, err := Text(Context{Env: tw.env}, n.Content.Nodes...); err == nil && strings.TrimSpace(s) == n.URL {
+		return
+	}
+	tw.writeString(" [" + n.URL + "]")
+}
+
+func (tw *textWriter) math(n *nodes.MathNode) {
+	if n.Display {
+		tw.code(n.Value)
+		return
+	}
+	tw.writeString(n.Value)
+}
+
+// code writes v as an indented block.
+func (tw *textWriter) code(v string) {
+	if strings.TrimSpace(v) == "" {
+		return
+	}
+	tw.newBlock()
+	restore := tw.indent("    ")
+	tw.writeString(strings.TrimRight(v, "\n") + "\n")
+	restore()
+	tw.newBlock()
+}
+
+func (tw *textWriter) custom(n *nodes.CustomNode) {
+	if r := customRenderers[n.Kind]; r.Text != nil {
+		var buf bytes.Buffer
+		if err := r.Text(&buf, tw.env, n); err != nil {
+			tw.err = err
+			return
+		}
+		tw.newBlock()
+		tw.writeString(buf.String())
+		tw.newBlock()
+		return
+	}
+	tw.write(n.Content.Nodes...)
+}
+
+func (tw *textWriter) list(n *nodes.ListNode) {
+	tw.write(n.Nodes...)
+}
+
+func (tw *textWriter) itemsList(n *nodes.ItemsListNode) {
+	tw.newBlock()
+	for i, item := range n.Items {
+		if i > 0 {
+			tw.endLine()
+		}
+		bullet := "- "
+		if n.Type() == nodes.NodeItemsList && n.Start > 0 {
+			bullet = strconv.Itoa(i+n.Start) + ". "
+		}
+		tw.writeString(bullet)
+		tw.item = true
+		restore := tw.indent(strings.Repeat(" ", len(bullet)))
+		tw.write(item.Nodes...)
+		restore()
+	}
+	tw.newBlock()
+}
+
+func (tw *textWriter) infobox(n *nodes.InfoboxNode) {
+	tw.newBlock()
+	tw.flush()
+	restore := tw.indent("> ")
+	tw.item = true // no blank line before the first paragraph
+	tw.write(n.Content.Nodes...)
+	tw.endLine()
+	restore()
+	tw.newBlock()
+}
+
+func (tw *textWriter) survey(n *nodes.SurveyNode) {
+	for _, g := range n.Groups {
+		tw.newBlock()
+		tw.writeString(g.Name + "\n")
+		box := "( ) "
+		if g.Multi {
+			box = "[ ] "
+		}
+		for _, o := range g.Options {
+			tw.writeString(box + o + "\n")
+		}
+	}
+	tw.newBlock()
+}
+
+func (tw *textWriter) header(n *nodes.HeaderNode) {
+	tw.newBlock()
+	tw.write(n.Content.Nodes...)
+	tw.newBlock()
+}
+
+// underline writes title followed by a line of c characters.
+func (tw *textWriter) underline(title, c string) {
+	tw.writeString(title + "\n")
+	tw.writeString(strings.Repeat(c, utf8.RuneCountInString(title)) + "\n")
+}
+
+// table writes n with cells aligned in columns. Cell content is written
+// on a single line and the first row is underlined as a header.
+func (tw *textWriter) table(n *nodes.GridNode) {
+	if n.Empty() {
+		return
+	}
+	var widths []int
+	rows := make([][]string, len(n.Rows))
+	for i, r := range n.Rows {
+		for j, c := range r {
+			var buf bytes.Buffer
+			if err := WriteText(&buf, tw.env, c.Content.Nodes...); err != nil {
+				tw.err = err
+				return
+			}
+			s := strings.Join(strings.Fields(buf.String()), " ")
+			rows[i] = append(rows[i], s)
+			if j >= len(widths) {
+				widths = append(widths, 0)
+			}
+			if w := utf8.RuneCountInString(s); w > widths[j] {
+				widths[j] = w
+			}
+		}
+	}
+
+	tw.newBlock()
+	for i, r := range rows {
+		var line string
+		for j, s := range r {
+			line += s + strings.Repeat(" ", widths[j]-utf8.RuneCountInString(s)+2)
+		}
+		tw.writeString(strings.TrimRight(line, " ") + "\n")
+		if i == 0 && len(rows) > 1 {
+			var rule []string
+			for _, w := range widths {
+				rule = append(rule, strings.Repeat("-", w))
+			}
+			tw.writeString(strings.Join(rule, "  ") + "\n")
+		}
+	}
+	tw.newBlock()
+}
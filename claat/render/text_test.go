@@ -0,0 +1,148 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googlecodelabs/tools/claat/nodes"
+	"github.com/googlecodelabs/tools/claat/types"
+)
+
+func TestWriteText(t *testing.T) {
+	text := func(s string) nodes.Node {
+		return nodes.NewTextNode(nodes.NewTextNodeOptions{Value: s})
+	}
+	para := func(n ...nodes.Node) nodes.Node {
+		l := nodes.NewListNode(n...)
+		l.MutateBlock(true)
+		return l
+	}
+	items := func(start int, n ...nodes.Node) *nodes.ItemsListNode {
+		il := nodes.NewItemsListNode("", start)
+		for _, it := range n {
+			il.NewItem(it)
+		}
+		return il
+	}
+	kiosk := para(text("Kiosk only."))
+	kiosk.MutateEnv([]string{"kiosk"})
+	nested := items(0, text("one"))
+	nested.Items[0].Append(items(0, text("nested")))
+	code := nodes.NewCodeNode("func main() {\n\tfmt.Println()\n}\n", false, "go")
+	code.MutateBlock(true)
+	grid := nodes.NewGridNode(
+		[]*nodes.GridCell{
+			{Content: nodes.NewListNode(text("Name"))},
+			{Content: nodes.NewListNode(text("Value"))},
+		},
+		[]*nodes.GridCell{
+			{Content: nodes.NewListNode(text("long name"))},
+			{Content: nodes.NewListNode(text("1"))},
+		},
+	)
+	grid.MutateBlock(true)
+	info := nodes.NewInfoboxNode(nodes.InfoboxPositive, para(text("First.")), para(text("Second.")))
+	info.MutateBlock(true)
+
+	tests := []struct {
+		name string
+		in   []nodes.Node
+		out  string
+	}{
+		{
+			name: "Paragraphs",
+			in: []nodes.Node{
+				nodes.NewHeaderNode(2, text("Title")),
+				para(text("Hello, "), nodes.NewURLNode("https://example.com", text("world")), text(".")),
+				kiosk,
+				para(nodes.NewURLNode("https://example.com", text("https://example.com"))),
+			},
+			out: "Title\n\nHello, world [https://example.com].\n\nhttps://example.com\n",
+		},
+		{
+			name: "Lists",
+			in: []nodes.Node{
+				nested,
+				items(3, text("three"), text("four")),
+			},
+			out: "- one\n\n  - nested\n\n3. three\n4. four\n",
+		},
+		{
+			name: "Code",
+			in:   []nodes.Node{para(text("Run:")), code, para(text("Done."))},
+			out:  "Run:\n\n    func main() {\n    \tfmt.Println()\n    }\n\nDone.\n",
+		},
+		{
+			name: "Table",
+			in:   []nodes.Node{grid},
+			out:  "Name       Value\n---------  -----\nlong name  1\n",
+		},
+		{
+			name: "Infobox",
+			in:   []nodes.Node{para(text("Note:")), info},
+			out:  "Note:\n\n> First.\n>\n> Second.\n",
+		},
+		{
+			name: "Embeds",
+			in: []nodes.Node{
+				para(nodes.NewImageNode(nodes.NewImageNodeOptions{Src: "a.png", Alt: "Diagram"})),
+				nodes.NewYouTubeNode("abc"),
+			},
+			out: "[Diagram]\n\n[https://www.youtube.com/watch?v=abc]\n",
+		},
+		{
+			name: "Survey",
+			in: []nodes.Node{nodes.NewSurveyNode("s",
+				&nodes.SurveyGroup{Name: "Pick one", Options: []string{"A", "B"}},
+				&nodes.SurveyGroup{Name: "Pick many", Options: []string{"C"}, Multi: true},
+			)},
+			out: "Pick one\n( ) A\n( ) B\n\nPick many\n[ ] C\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := Text(Context{Env: "web"}, tc.in...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.out, out); diff != "" {
+				t.Errorf("Text mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTextFormat(t *testing.T) {
+	p := &Page{Context: Context{
+		Env:  "web",
+		Meta: &types.Meta{Title: "Codelab", Summary: "About it."},
+		Steps: []*types.Step{
+			{Title: "Intro", Content: nodes.NewListNode(nodes.NewTextNode(nodes.NewTextNodeOptions{Value: "Hello."}))},
+			{Title: "Kiosk", Tags: []string{"kiosk"}, Content: nodes.NewListNode()},
+			{Title: "End", Content: nodes.NewListNode()},
+		},
+	}}
+	var buf bytes.Buffer
+	if err := Execute(&buf, "txt", p); err != nil {
+		t.Fatal(err)
+	}
+	want := "Codelab\n=======\n\nAbout it.\n\n1. Intro\n--------\n\nHello.\n\n2. End\n------\n"
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("txt format mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	htmlTemplate "html/template"
+	textTemplate "text/template"
+)
+
+const (
+	// themeConfig is the optional theme configuration file.
+	themeConfig = "theme.json"
+	// themeIndex, if present in a theme, replaces the base template.
+	themeIndex = "index.tmpl"
+	// ThemeStaticDir is the theme subdirectory of static assets.
+	// It is copied as is into the codelab dir.
+	ThemeStaticDir = "static"
+)
+
+// Theme is a format defined by a directory of templates
+// on top of one of the built-in template formats.
+//
+// All *.tmpl files of the directory are parsed after the base template,
+// in lexical order, so that their {{define}} actions override
+// the base {{block}}s and add new named partials.
+// A template file can be invoked by its name, e.g. {{template "footer.tmpl" .}}.
+// If the directory has an index.tmpl file, it is executed instead of the base
+// template, which is still available as {{template "base" .}}.
+//
+// An optional theme.json file configures the theme:
+//
+//	{"extends": "offline"}
+//
+// The default base format is html.
+type Theme struct {
+	Dir     string `json:"-"`       // Theme directory
+	Extends string `json:"extends"` // Name of the base format
+
+	base  *TemplateFormat
+	files []string // Template files, in parsing order
+	cache templateCache
+}
+
+// LoadTheme loads the theme in directory dir.
+func LoadTheme(dir string) (*Theme, error) {
+	th := &Theme{Dir: dir, Extends: "html"}
+	b, err := ioutil.ReadFile(filepath.Join(dir, themeConfig))
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(b, th); err != nil {
+			return nil, fmt.Errorf("%s: %v", filepath.Join(dir, themeConfig), err)
+		}
+	case !os.IsNotExist(err):
+		return nil, err
+	}
+	base, ok := formats[th.Extends].(*TemplateFormat)
+	if !ok {
+		return nil, fmt.Errorf("theme %s: %q is not a built-in template format", dir, th.Extends)
+	}
+	th.base = base
+	if th.files, err = filepath.Glob(filepath.Join(dir, "*.tmpl")); err != nil {
+		return nil, err
+	}
+	sort.Strings(th.files)
+	return th, nil
+}
+
+// PerStep implements Format.
+func (th *Theme) PerStep() bool {
+	return th.base.PerStep()
+}
+
+// Filename implements Format.
+func (th *Theme) Filename(step int) string {
+	return th.base.Filename(step)
+}
+
+// Render implements Format by executing the theme templates with p.
+func (th *Theme) Render(w io.Writer, p *Page, opt ...Option) error {
+	t, err := th.template(funcMapOption(opt))
+	if err != nil {
+		return err
+	}
+	return t.Execute(w, p)
+}
+
+// StaticDir implements StaticFormat.
+func (th *Theme) StaticDir() string {
+	dir := filepath.Join(th.Dir, ThemeStaticDir)
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		return ""
+	}
+	return dir
+}
+
+func (th *Theme) template(fmap map[string]interface{}) (executer, error) {
+	if len(fmap) > 0 {
+		return th.parse(fmap)
+	}
+	return th.cache.get(func() (executer, error) {
+		return th.parse(nil)
+	})
+}
+
+func (th *Theme) parse(fmap map[string]interface{}) (executer, error) {
+	funcs := mergeFuncs(fmap)
+	if th.base.HTML {
+		t, err := htmlTemplate.New("base").Funcs(funcs).Parse(string(th.base.Source))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range th.files {
+			b, err := ioutil.ReadFile(f)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := t.New(filepath.Base(f)).Parse(string(b)); err != nil {
+				return nil, err
+			}
+		}
+		if it := t.Lookup(themeIndex); it != nil {
+			return it, nil
+		}
+		return t, nil
+	}
+
+	t, err := textTemplate.New("base").Funcs(funcs).Parse(string(th.base.Source))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range th.files {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := t.New(filepath.Base(f)).Parse(string(b)); err != nil {
+			return nil, err
+		}
+	}
+	if it := t.Lookup(themeIndex); it != nil {
+		return it, nil
+	}
+	return t, nil
+}
@@ -0,0 +1,197 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/googlecodelabs/tools/claat/nodes"
+	"github.com/googlecodelabs/tools/claat/types"
+)
+
+// writeTheme creates a theme directory with the files and returns its path.
+func writeTheme(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "TestTheme-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestTheme(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		perStep bool
+		want    []string // output substrings
+		notWant []string
+	}{
+		{
+			name: "blocks",
+			files: map[string]string{
+				"blocks.tmpl": `{{define "title"}}Themed {{.Meta.Title}}{{end}}` +
+					`{{define "scripts"}}{{template "footer.tmpl" .}}{{end}}`,
+				"footer.tmpl": `<footer>{{.Meta.ID}}</footer>`,
+			},
+			want:    []string{"<title>Themed Codelab</title>", "<footer>theme-id</footer>", "<google-codelab-step"},
+			notWant: []string{"native-shim.js"},
+		},
+		{
+			name: "index",
+			files: map[string]string{
+				"index.tmpl": `<main>{{template "base" .}}</main>`,
+			},
+			want: []string{"<main>\n<!doctype html>", "<title>Codelab</title>", "</html>\n</main>"},
+		},
+		{
+			name: "extends",
+			files: map[string]string{
+				"theme.json": `{"extends": "offline"}`,
+				"step.tmpl":  `{{define "step"}}<p>{{.Current.Title}}</p>{{end}}`,
+			},
+			perStep: true,
+			want:    []string{"<p>Step One</p>", "codelab__toc"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := writeTheme(t, tc.files)
+			defer os.RemoveAll(dir)
+			th, err := LoadTheme(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if th.PerStep() != tc.perStep {
+				t.Errorf("PerStep() = %t, want %t", th.PerStep(), tc.perStep)
+			}
+			p := &Page{
+				Context: Context{
+					Meta:  &types.Meta{ID: "theme-id", Title: "Codelab"},
+					Steps: []*types.Step{{Title: "Step One", Content: nodes.NewListNode()}},
+				},
+			}
+			p.Current = p.Steps[0]
+			p.StepNum = 1
+			var buf bytes.Buffer
+			if err := th.Render(&buf, p); err != nil {
+				t.Fatal(err)
+			}
+			out := buf.String()
+			for _, s := range tc.want {
+				if !strings.Contains(out, s) {
+					t.Errorf("output does not contain %q:\n%s", s, out)
+				}
+			}
+			for _, s := range tc.notWant {
+				if strings.Contains(out, s) {
+					t.Errorf("output contains %q:\n%s", s, out)
+				}
+			}
+		})
+	}
+}
+
+func TestThemeErrors(t *testing.T) {
+	tests := map[string]map[string]string{
+		"config":   {"theme.json": `{`},
+		"base":     {"theme.json": `{"extends": "missing"}`},
+		"template": {"bad.tmpl": `{{define "title"}}`},
+	}
+	for name, files := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := writeTheme(t, files)
+			defer os.RemoveAll(dir)
+			th, err := LoadTheme(dir)
+			if err == nil {
+				err = th.Render(ioutil.Discard, &Page{Context: Context{Meta: &types.Meta{}}})
+			}
+			if err == nil {
+				t.Error("got nil error")
+			}
+		})
+	}
+}
+
+func TestThemeStatic(t *testing.T) {
+	dir := writeTheme(t, map[string]string{"static/theme.css": "body {}"})
+	defer os.RemoveAll(dir)
+	f, err := LookupFormat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sf, ok := f.(StaticFormat)
+	if !ok {
+		t.Fatalf("LookupFormat(%q) = %T, want StaticFormat", dir, f)
+	}
+	if got, want := sf.StaticDir(), filepath.Join(dir, "static"); got != want {
+		t.Errorf("StaticDir() = %q, want %q", got, want)
+	}
+
+	empty := writeTheme(t, nil)
+	defer os.RemoveAll(empty)
+	th, err := LoadTheme(empty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := th.StaticDir(); got != "" {
+		t.Errorf("StaticDir() of a theme without assets = %q, want empty", got)
+	}
+}
+
+func TestLookupFormatCache(t *testing.T) {
+	dir := writeTheme(t, map[string]string{"title.tmpl": `{{define "title"}}One{{end}}`})
+	defer os.RemoveAll(dir)
+	f1, err := LookupFormat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &Page{Context: Context{Meta: &types.Meta{}}}
+	if err := f1.Render(ioutil.Discard, p); err != nil {
+		t.Fatal(err)
+	}
+	// templates are not parsed again
+	if err := ioutil.WriteFile(filepath.Join(dir, "title.tmpl"), []byte(`{{define "title"}}Two{{end}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f2, err := LookupFormat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f1 != f2 {
+		t.Errorf("LookupFormat(%q) returned a new format", dir)
+	}
+	var buf bytes.Buffer
+	if err := f2.Render(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "<title>One</title>") {
+		t.Errorf("cached theme output does not contain the first title:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transform
+
+import (
+	"github.com/googlecodelabs/tools/claat/nodes"
+	"github.com/googlecodelabs/tools/claat/types"
+)
+
+// draftEnv is the environment of content which is not meant to be published.
+const draftEnv = "draft"
+
+func init() {
+	Register("strip-drafts", stripDrafts)
+}
+
+// stripDrafts removes steps and nodes tagged with the draft environment,
+// regardless of the export environment.
+func stripDrafts(clab *types.Codelab) error {
+	var steps []*types.Step
+	for _, s := range clab.Steps {
+		if hasDraftEnv(s.Tags) {
+			continue
+		}
+		s.Content.Nodes = nodes.Rewrite(s.Content.Nodes, func(n nodes.Node) []nodes.Node {
+			if hasDraftEnv(n.Env()) {
+				return nil
+			}
+			return []nodes.Node{n}
+		})
+		steps = append(steps, s)
+	}
+	clab.Steps = steps
+	var tags []string
+	for _, t := range clab.Tags {
+		if t != draftEnv {
+			tags = append(tags, t)
+		}
+	}
+	clab.Tags = tags
+	return nil
+}
+
+func hasDraftEnv(env []string) bool {
+	for _, e := range env {
+		if e == draftEnv {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transform
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/googlecodelabs/tools/claat/nodes"
+	"github.com/googlecodelabs/tools/claat/types"
+)
+
+func TestStripDrafts(t *testing.T) {
+	text := func(v string, env ...string) nodes.Node {
+		n := nodes.NewTextNode(nodes.NewTextNodeOptions{Value: v})
+		n.MutateEnv(env)
+		return n
+	}
+	clab := types.NewCodelab()
+	clab.Tags = []string{"draft", "web"}
+	s1 := clab.NewStep("Published")
+	s1.Content.Append(
+		text("keep"),
+		nodes.NewInfoboxNode(nodes.InfoboxPositive, text("note", "draft"), text("web only", "web")),
+		text("todo", "draft", "web"),
+	)
+	s2 := clab.NewStep("Work in progress")
+	s2.Tags = []string{"draft"}
+
+	if err := Apply(clab, "strip-drafts"); err != nil {
+		t.Fatal(err)
+	}
+	if len(clab.Steps) != 1 || clab.Steps[0] != s1 {
+		t.Fatalf("clab.Steps = %v, want only the first step", clab.Steps)
+	}
+	want := []nodes.Node{
+		text("keep"),
+		nodes.NewInfoboxNode(nodes.InfoboxPositive, text("web only", "web")),
+	}
+	if diff := nodes.Diff(want, s1.Content.Nodes); diff != "" {
+		t.Errorf("step content got diff (-want +got):\n%s", diff)
+	}
+	if !reflect.DeepEqual(clab.Tags, []string{"web"}) {
+		t.Errorf("clab.Tags = %v, want [web]", clab.Tags)
+	}
+}
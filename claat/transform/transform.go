@@ -0,0 +1,61 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package transform runs named transforms, which modify a codelab
+// after it has been parsed and before it is rendered.
+package transform
+
+import (
+	"fmt"
+
+	"github.com/googlecodelabs/tools/claat/types"
+)
+
+// Func modifies clab in place.
+type Func func(clab *types.Codelab) error
+
+var transforms = map[string]Func{}
+
+// Register registers a new transform fn under name.
+// It panics if another transform is already registered under the same name.
+func Register(name string, fn Func) {
+	if _, exists := transforms[name]; exists {
+		panic(fmt.Sprintf("transform %q already registered", name))
+	}
+	transforms[name] = fn
+}
+
+// Apply runs transforms registered under names on clab, in the given order.
+// It stops at the first transform which fails.
+//
+// The answer key of graded questions is updated afterwards,
+// since transforms may add or remove questions.
+func Apply(clab *types.Codelab, names ...string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	// check all names upfront, so that clab is left intact on typos
+	for _, name := range names {
+		if _, ok := transforms[name]; !ok {
+			return fmt.Errorf("no transform registered as %q", name)
+		}
+	}
+	for _, name := range names {
+		if err := transforms[name](clab); err != nil {
+			return fmt.Errorf("transform %s: %v", name, err)
+		}
+	}
+	clab.Quiz = types.NewQuiz(clab.Steps)
+	return nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transform
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/googlecodelabs/tools/claat/types"
+)
+
+func init() {
+	Register("test-append-a", func(clab *types.Codelab) error {
+		clab.Title += "a"
+		return nil
+	})
+	Register("test-append-b", func(clab *types.Codelab) error {
+		clab.Title += "b"
+		return nil
+	})
+	Register("test-fail", func(clab *types.Codelab) error {
+		return errors.New("failed")
+	})
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		inNames []string
+		out     string
+		err     string
+	}{
+		{
+			name: "None",
+		},
+		{
+			name:    "Order",
+			inNames: []string{"test-append-b", "test-append-a", "test-append-b"},
+			out:     "bab",
+		},
+		{
+			name:    "Unknown",
+			inNames: []string{"test-append-a", "test-unknown"},
+			err:     `no transform registered as "test-unknown"`,
+		},
+		{
+			name:    "Fail",
+			inNames: []string{"test-append-a", "test-fail", "test-append-b"},
+			out:     "a",
+			err:     "transform test-fail: failed",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			clab := types.NewCodelab()
+			err := Apply(clab, tc.inNames...)
+			if (err != nil || tc.err != "") && (err == nil || !strings.Contains(err.Error(), tc.err)) {
+				t.Errorf("Apply(%v) error = %v, want %q", tc.inNames, err, tc.err)
+			}
+			if clab.Title != tc.out {
+				t.Errorf("Apply(%v) title = %q, want %q", tc.inNames, clab.Title, tc.out)
+			}
+		})
+	}
+}
+
+func TestRegisterDuplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Register of a duplicate name did not panic")
+		}
+	}()
+	Register("test-append-a", func(*types.Codelab) error { return nil })
+}
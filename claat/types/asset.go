@@ -0,0 +1,53 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path"
+	"strings"
+)
+
+// Asset is a file written by a codelab export, such as an image,
+// and an entry of the asset manifest stored in codelab metadata.
+type Asset struct {
+	Path   string `json:"path"`             // Slash-separated path relative to the codelab dir
+	Size   int64  `json:"size"`             // Size in bytes
+	SHA256 string `json:"sha256"`           // Hex-encoded SHA-256 hash of the content
+	Type   string `json:"type,omitempty"`   // MIME type
+	Source string `json:"source,omitempty"` // Original URL or path, if the file was fetched; "diagram:<kind>:<crc>" if pre-rendered
+}
+
+// NewAsset creates a manifest entry of file p with content b.
+func NewAsset(p, typ, source string, b []byte) *Asset {
+	sum := sha256.Sum256(b)
+	return &Asset{
+		Path:   p,
+		Size:   int64(len(b)),
+		SHA256: hex.EncodeToString(sum[:]),
+		Type:   typ,
+		Source: source,
+	}
+}
+
+// IsLocal reports whether a.Path is a relative path which does not
+// escape the codelab dir. Paths read from codelab metadata must be
+// checked before files are removed.
+func (a *Asset) IsLocal() bool {
+	p := a.Path
+	return p != "" && p != "." && p == path.Clean(p) && !path.IsAbs(p) &&
+		p != ".." && !strings.HasPrefix(p, "../") && !strings.Contains(p, `\`)
+}
@@ -0,0 +1,54 @@
+// Copyright 2016-2019 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewAsset(t *testing.T) {
+	got := NewAsset("img/a.png", "image/png", "a.png", []byte("abc"))
+	want := &Asset{
+		Path:   "img/a.png",
+		Size:   3,
+		SHA256: "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+		Type:   "image/png",
+		Source: "a.png",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("NewAsset() got diff (-want +got): %s", diff)
+	}
+}
+
+func TestAssetIsLocal(t *testing.T) {
+	tests := map[string]bool{
+		"img/a.png":      true,
+		"assets/a b.pdf": true,
+		"":               false,
+		".":              false,
+		"..":             false,
+		"../a.png":       false,
+		"img/../../a":    false,
+		"/etc/passwd":    false,
+		`img\..\..\a`:    false,
+	}
+	for p, want := range tests {
+		if got := (&Asset{Path: p}).IsLocal(); got != want {
+			t.Errorf("Asset{Path: %q}.IsLocal() = %t, want %t", p, got, want)
+		}
+	}
+}
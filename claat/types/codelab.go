@@ -16,9 +16,14 @@
 package types
 
 import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/googlecodelabs/tools/claat/nodes"
+	"golang.org/x/text/language"
 )
 
 // Meta contains a single codelab metadata.
@@ -37,14 +42,53 @@ type Meta struct {
 	GA         string            `json:"ga,omitempty"`       // Codelab-specific GA tracking ID
 	GA4        string            `json:"ga4,omitempty"`      // Codelab-specific GA4 tracking ID
 	Extra      map[string]string `json:"extra,omitempty"`    // Extra metadata specified in pass_metadata
+	Quiz       *Quiz             `json:"quiz,omitempty"`     // Answer key of graded survey questions
+	Lang       string            `json:"lang,omitempty"`     // Content language, a BCP 47 tag such as "ja" or "pt-BR"
+	Locales    []string          `json:"locales,omitempty"`  // Languages of all exported translations, sorted
 
 	URL string `json:"url"` // Legacy ID; TODO: remove
 }
 
+// DefaultLang is the default language of codelabs without lang metadata.
+const DefaultLang = "en"
+
+// IsTranslation reports whether the codelab is a translation of another
+// codelab with the same ID, i.e. its language is set and differs
+// from defaultLang.
+func (m *Meta) IsTranslation(defaultLang string) bool {
+	return m.Lang != "" && !strings.EqualFold(m.Lang, defaultLang)
+}
+
+// Dir returns the output directory of the codelab, relative to the output
+// directory of all codelabs: its ID, followed by its language for
+// translations, e.g. "codelab-id/ja".
+// It returns an error if the language is not a valid BCP 47 tag.
+func (m *Meta) Dir(defaultLang string) (string, error) {
+	if err := CheckLang(m.Lang); err != nil {
+		return "", err
+	}
+	if m.IsTranslation(defaultLang) {
+		return filepath.Join(m.ID, m.Lang), nil
+	}
+	return m.ID, nil
+}
+
+// CheckLang returns an error if lang is neither empty
+// nor a valid BCP 47 language tag, such as "ja" or "pt-BR".
+func CheckLang(lang string) error {
+	if lang == "" {
+		return nil
+	}
+	if _, err := language.Parse(lang); err != nil {
+		return fmt.Errorf("invalid lang %q: %v", lang, err)
+	}
+	return nil
+}
+
 // Codelab is a top-level structure containing metadata and codelab steps.
 type Codelab struct {
 	Meta
-	Steps []*Step
+	Steps []*Step `json:"steps"`
 }
 
 func NewCodelab() *Codelab {
@@ -68,3 +112,51 @@ type Step struct {
 	Duration time.Duration   // Duration
 	Content  *nodes.ListNode // Root node of the step nodes tree
 }
+
+// stepJSON is the JSON representation of a step.
+type stepJSON struct {
+	Title    string          `json:"title"`
+	Tags     []string        `json:"tags,omitempty"`
+	Duration int64           `json:"duration,omitempty"` // In seconds
+	Content  json.RawMessage `json:"content"`
+}
+
+// MarshalJSON implements Marshaler interface.
+// Step content is encoded with nodes.EncodeJSON.
+func (s *Step) MarshalJSON() ([]byte, error) {
+	content, err := nodes.EncodeJSON(s.Content)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(&stepJSON{
+		Title:    s.Title,
+		Tags:     s.Tags,
+		Duration: int64(s.Duration / time.Second),
+		Content:  content,
+	})
+}
+
+// UnmarshalJSON implements Unmarshaler interface.
+func (s *Step) UnmarshalJSON(b []byte) error {
+	var v stepJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	s.Title = v.Title
+	s.Tags = v.Tags
+	s.Duration = time.Duration(v.Duration) * time.Second
+	s.Content = nodes.NewListNode()
+	if len(v.Content) == 0 {
+		return nil
+	}
+	n, err := nodes.DecodeJSON(v.Content)
+	if err != nil {
+		return fmt.Errorf("step %q: %v", v.Title, err)
+	}
+	l, ok := n.(*nodes.ListNode)
+	if !ok {
+		return fmt.Errorf("step %q: content is not a list node", v.Title)
+	}
+	s.Content = l
+	return nil
+}
@@ -1,7 +1,13 @@
 package types
 
 import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
+
+	"github.com/googlecodelabs/tools/claat/nodes"
 )
 
 func TestNewCodelab(t *testing.T) {
@@ -26,3 +32,60 @@ func TestNewStep(t *testing.T) {
 		t.Errorf(`Codelab.NewStep("foobar") did not initialize s.Content`)
 	}
 }
+
+func TestMetaDir(t *testing.T) {
+	tests := []struct {
+		lang string
+		want string
+	}{
+		{"", "codelab"},
+		{"en", "codelab"},
+		{"ja", "codelab/ja"},
+		{"pt-BR", "codelab/pt-BR"},
+	}
+	for _, test := range tests {
+		m := &Meta{ID: "codelab", Lang: test.lang}
+		got, err := m.Dir(DefaultLang)
+		if err != nil {
+			t.Errorf("Meta{Lang: %q}.Dir(): %v", test.lang, err)
+			continue
+		}
+		if filepath.ToSlash(got) != test.want {
+			t.Errorf("Meta{Lang: %q}.Dir() = %q, want %q", test.lang, got, test.want)
+		}
+	}
+
+	for _, lang := range []string{"..", "../../escaped", "ja/..", "/tmp"} {
+		m := &Meta{ID: "codelab", Lang: lang}
+		if got, err := m.Dir(DefaultLang); err == nil {
+			t.Errorf("Meta{Lang: %q}.Dir() = %q, want error", lang, got)
+		}
+	}
+}
+
+func TestStepJSON(t *testing.T) {
+	s := &Step{
+		Title:    "Setup",
+		Tags:     []string{"web"},
+		Duration: 90 * time.Second,
+		Content:  nodes.NewListNode(nodes.NewTextNode(nodes.NewTextNodeOptions{Value: "foo"})),
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"title":"Setup","tags":["web"],"duration":90,"content":{"nodes":[{"type":"text","value":"foo"}],"type":"list"}}`
+	if string(b) != want {
+		t.Errorf("json.Marshal(step) = %s, want %s", b, want)
+	}
+	var out Step
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Title != s.Title || out.Duration != s.Duration || !reflect.DeepEqual(out.Tags, s.Tags) {
+		t.Errorf("json.Unmarshal(%s) = %+v, want %+v", b, out, s)
+	}
+	if !nodes.Equal(out.Content.Nodes, s.Content.Nodes) {
+		t.Errorf("json.Unmarshal(%s) content = %s, want %s", b, nodes.Dump(out.Content), nodes.Dump(s.Content))
+	}
+}
@@ -8,11 +8,32 @@ import (
 // Context is an export context.
 // It is defined in this package so that it can be used by both cli and a server.
 type Context struct {
-	Env     string       `json:"environment"`       // Current export environment
-	Format  string       `json:"format"`            // Output format, e.g. "html"
-	Prefix  string       `json:"prefix,omitempty"`  // Assets URL prefix for HTML-based formats
-	MainGA  string       `json:"mainga,omitempty"`  // Global Google Analytics ID
-	Updated *ContextTime `json:"updated,omitempty"` // Last update timestamp
+	Env              string       `json:"environment"`                 // Current export environment
+	Format           string       `json:"format"`                      // Output format, e.g. "html"
+	Prefix           string       `json:"prefix,omitempty"`            // Assets URL prefix for HTML-based formats
+	MainGA           string       `json:"mainga,omitempty"`            // Global Google Analytics ID
+	Updated          *ContextTime `json:"updated,omitempty"`           // Last update timestamp
+	Archives         string       `json:"archives,omitempty"`          // Step archives source, "code" or an absolute dir
+	Transforms       []string     `json:"transforms,omitempty"`        // Transforms applied before rendering, in order
+	Plugins          []string     `json:"plugins,omitempty"`           // Plugin executables run after transforms, in order
+	DefaultLang      string       `json:"default_lang,omitempty"`      // Language of codelabs not exported into a locale subdirectory
+	Math             bool         `json:"math,omitempty"`              // Math expressions are parsed in text
+	Diagrams         bool         `json:"diagrams,omitempty"`          // Diagrams are pre-rendered into SVG images
+	ResponsiveImages bool         `json:"responsive_images,omitempty"` // Images are downscaled and have WebP variants
+	MaxAssetSize     int64        `json:"max_asset_size,omitempty"`    // Maximum size of an asset in bytes, 0 for the default
+	AssetHosts       []string     `json:"asset_hosts,omitempty"`       // Hosts of remote downloads copied into the codelab
+	Manifest         []*Asset     `json:"manifest"`                    // Files written by the export and owned by the codelab, sorted by path; nil in exports predating manifests
+	Images           string       `json:"images,omitempty"`            // Image mode: "slurp", "keep" or "rewrite"
+	ImageURL         string       `json:"image_url,omitempty"`         // Template of image URLs in "rewrite" mode
+	Catalog          string       `json:"catalog,omitempty"`           // Translation catalog merged into the codelab source
+}
+
+// DefaultLanguage returns the default language of codelabs exported in ctx.
+func (ctx *Context) DefaultLanguage() string {
+	if ctx.DefaultLang == "" {
+		return DefaultLang
+	}
+	return ctx.DefaultLang
 }
 
 // ContextMeta is a composition of export context and meta data.
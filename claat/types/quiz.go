@@ -0,0 +1,98 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"reflect"
+
+	"github.com/googlecodelabs/tools/claat/nodes"
+)
+
+// Quiz is an answer key of all graded survey questions in a codelab.
+// It is exported along with the codelab metadata so that the answers
+// can be graded outside of the rendered content.
+type Quiz struct {
+	Score     int             `json:"score"`     // Max attainable score, one point per question
+	Questions []*QuizQuestion `json:"questions"` // Graded questions in the order of appearance
+}
+
+// QuizQuestion is a single graded question of a survey.
+type QuizQuestion struct {
+	Survey      string   `json:"survey"`                // ID of the survey containing the question
+	Step        int      `json:"step"`                  // 1-based step number
+	Name        string   `json:"name"`                  // Question text
+	Options     []string `json:"options"`               // All possible answers
+	Correct     []int    `json:"correct"`               // Indices of correct Options
+	Multi       bool     `json:"multi,omitempty"`       // Multiple options may be selected
+	Explanation string   `json:"explanation,omitempty"` // Answer explanation
+}
+
+// NewQuiz collects graded survey questions of all steps.
+// It returns nil if none of the steps contain a graded question.
+func NewQuiz(steps []*Step) *Quiz {
+	var q Quiz
+	for i, s := range steps {
+		for _, sn := range nodes.SurveyNodes(s.Content.Nodes) {
+			for _, g := range sn.Groups {
+				if !g.Graded() {
+					continue
+				}
+				q.Questions = append(q.Questions, &QuizQuestion{
+					Survey:      sn.ID,
+					Step:        i + 1,
+					Name:        g.Name,
+					Options:     g.Options,
+					Correct:     g.Correct,
+					Multi:       g.Multi,
+					Explanation: g.Explanation,
+				})
+			}
+		}
+	}
+	if len(q.Questions) == 0 {
+		return nil
+	}
+	q.Score = len(q.Questions)
+	return &q
+}
+
+// Grade computes a score of the given answers, keyed by question index
+// in q.Questions. Each answer is a set of selected option indices,
+// in any order; duplicate indices count once.
+// A question is scored only if the selection matches the correct options exactly.
+func (q *Quiz) Grade(answers map[int][]int) int {
+	var score int
+	for i, qq := range q.Questions {
+		a, ok := answers[i]
+		if ok && sameSet(a, qq.Correct) {
+			score++
+		}
+	}
+	return score
+}
+
+// sameSet reports whether a and b contain the same set of indices.
+func sameSet(a, b []int) bool {
+	return reflect.DeepEqual(indexSet(a), indexSet(b))
+}
+
+// indexSet returns the set of indices in a.
+func indexSet(a []int) map[int]bool {
+	set := make(map[int]bool, len(a))
+	for _, v := range a {
+		set[v] = true
+	}
+	return set
+}
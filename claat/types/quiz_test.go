@@ -0,0 +1,87 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googlecodelabs/tools/claat/nodes"
+)
+
+func TestNewQuiz(t *testing.T) {
+	c := NewCodelab()
+	c.NewStep("poll").Content.Append(nodes.NewSurveyNode("poll-1", &nodes.SurveyGroup{
+		Name:    "pick a color",
+		Options: []string{"red", "blue"},
+	}))
+	c.NewStep("quiz").Content.Append(nodes.NewSurveyNode("quiz-2",
+		&nodes.SurveyGroup{
+			Name:        "pick a prime",
+			Options:     []string{"2", "4"},
+			Correct:     []int{0},
+			Explanation: "4 is even",
+		},
+		&nodes.SurveyGroup{
+			Name:    "pick all primes",
+			Options: []string{"2", "3", "4"},
+			Correct: []int{0, 1},
+			Multi:   true,
+		},
+	))
+
+	want := &Quiz{
+		Score: 2,
+		Questions: []*QuizQuestion{
+			{
+				Survey:      "quiz-2",
+				Step:        2,
+				Name:        "pick a prime",
+				Options:     []string{"2", "4"},
+				Correct:     []int{0},
+				Explanation: "4 is even",
+			},
+			{
+				Survey:  "quiz-2",
+				Step:    2,
+				Name:    "pick all primes",
+				Options: []string{"2", "3", "4"},
+				Correct: []int{0, 1},
+				Multi:   true,
+			},
+		},
+	}
+	if diff := cmp.Diff(want, NewQuiz(c.Steps)); diff != "" {
+		t.Errorf("NewQuiz() got diff (-want +got):\n%s", diff)
+	}
+	if q := NewQuiz(c.Steps[:1]); q != nil {
+		t.Errorf("NewQuiz(poll) = %+v, want nil", q)
+	}
+}
+
+func TestQuizGrade(t *testing.T) {
+	q := &Quiz{
+		Score: 2,
+		Questions: []*QuizQuestion{
+			{Correct: []int{0}},
+			{Correct: []int{0, 1}, Multi: true},
+		},
+	}
+	tests := []struct {
+		name    string
+		answers map[int][]int
+		out     int
+	}{
+		{"NoAnswers", nil, 0},
+		{"AllCorrect", map[int][]int{0: {0}, 1: {1, 0}}, 2},
+		{"Partial", map[int][]int{0: {0}, 1: {1}}, 1},
+		{"Wrong", map[int][]int{0: {1}, 1: {1, 2}}, 0},
+		{"Duplicates", map[int][]int{0: {0, 0}, 1: {0, 0}}, 1},
+		{"TooMany", map[int][]int{0: {0, 1}, 1: {0, 1, 2}}, 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if out := q.Grade(tc.answers); out != tc.out {
+				t.Errorf("Quiz.Grade(%v) = %d, want %d", tc.answers, out, tc.out)
+			}
+		})
+	}
+}
@@ -22,6 +22,14 @@ import (
 // relative to the codelab dir.
 const ImgDirname = "img"
 
+// ZipDirname is where a codelab step archives are stored,
+// relative to the codelab dir.
+const ZipDirname = "zip"
+
+// AssetDirname is where other codelab assets, such as linked documents
+// and downloads, are stored, relative to the codelab dir.
+const AssetDirname = "assets"
+
 // Unique de-dupes a.
 // The argument a is not modified.
 func Unique(a []string) []string {
@@ -0,0 +1,153 @@
+/**
+ * @license
+ * Copyright 2018 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+goog.module('googlecodelabs.CodelabDiagram');
+
+/** @const {string} */
+const KIND_ATTR = 'kind';
+
+/** @const {string} */
+const RENDERED_ATTR = 'rendered';
+
+/** @const {string} */
+const DIAGRAM_CLASS = 'diagram-rendered';
+
+/**
+ * Links allowed in rendered diagrams: http and https URLs, fragments
+ * and relative URLs.
+ * @const {!RegExp}
+ */
+const SAFE_URL = /^(?:https?:|#|[^:]*$)/i;
+
+/**
+ * Diagram renderers, keyed by diagram kind. A renderer returns a promise
+ * of the SVG markup, or null if the rendering library is not loaded.
+ * @const {!Object<string, function(string, string): ?Promise<string>>}
+ */
+const RENDERERS = {
+  'mermaid': (id, source) => {
+    const mermaid = window['mermaid'];
+    if (!mermaid || typeof mermaid['render'] !== 'function') {
+      return null;
+    }
+    // Newer versions resolve to an object with the svg markup,
+    // older ones return the markup.
+    return Promise.resolve(mermaid['render'](id, source))
+        .then((res) => typeof res === 'string' ? res : res['svg']);
+  },
+  'dot': (id, source) => {
+    const Viz = window['Viz'];
+    if (typeof Viz !== 'function') {
+      return null;
+    }
+    return new Viz()['renderString'](source);
+  },
+};
+
+/** @type {number} */
+let diagramCount = 0;
+
+/**
+ * Renders diagram source in the browser if a rendering library for the
+ * diagram kind is loaded on the page. The pre-rendered image, if any, and
+ * the source code block are kept as fallback content.
+ * @extends {HTMLElement}
+ * @suppress {reportUnknownTypes}
+ */
+class CodelabDiagram extends HTMLElement {
+  /** @return {string} */
+  static getTagName() { return 'google-codelab-diagram'; }
+
+  constructor() {
+    super();
+
+    /** @private {boolean} */
+    this.hasSetup_ = false;
+  }
+
+  /**
+   * @export
+   * @override
+   */
+  connectedCallback() {
+    if (!this.hasSetup_) {
+      this.setupDom_();
+    }
+  }
+
+  /**
+   * @return {!Promise}
+   * @private
+   */
+  setupDom_() {
+    this.hasSetup_ = true;
+    const sourceEl = this.querySelector('pre');
+    const render = RENDERERS[this.getAttribute(KIND_ATTR) || ''];
+    if (!sourceEl || !render) {
+      return Promise.resolve();
+    }
+    const id = `google-codelab-diagram-${++diagramCount}`;
+    const res = render(id, sourceEl.textContent);
+    if (!res) {
+      return Promise.resolve();
+    }
+    return res.then((markup) => {
+      const svg = CodelabDiagram.parseSVG_(markup);
+      if (!svg) {
+        return;
+      }
+      const container = document.createElement('div');
+      container.className = DIAGRAM_CLASS;
+      container.appendChild(svg);
+      this.appendChild(container);
+      this.setAttribute(RENDERED_ATTR, '');
+    }, (err) => {
+      // Keep the fallback content.
+      console.warn('googlecodelabs.CodelabDiagram', err);
+    });
+  }
+
+  /**
+   * Parses SVG markup, dropping scripts, event handlers and links
+   * with an unsafe URL scheme.
+   * @param {string} markup
+   * @return {?Element}
+   * @private
+   */
+  static parseSVG_(markup) {
+    const doc = new DOMParser().parseFromString(markup, 'image/svg+xml');
+    const root = doc.documentElement;
+    if (!root || root.localName !== 'svg') {
+      return null;
+    }
+    for (const el of Array.from(root.querySelectorAll('script'))) {
+      el.remove();
+    }
+    for (const el of [root, ...Array.from(root.querySelectorAll('*'))]) {
+      for (const attr of Array.from(el.attributes)) {
+        const name = attr.localName.toLowerCase();
+        if (name.startsWith('on') ||
+            (name === 'href' && !SAFE_URL.test(attr.value.trim()))) {
+          el.removeAttributeNode(attr);
+        }
+      }
+    }
+    return /** @type {!Element} */ (document.importNode(root, true));
+  }
+}
+
+exports = CodelabDiagram;
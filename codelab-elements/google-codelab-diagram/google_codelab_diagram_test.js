@@ -0,0 +1,96 @@
+/**
+ * @license
+ * Copyright 2018 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+goog.module('googlecodelabs.CodelabDiagramTest');
+goog.setTestOnly();
+
+const CodelabDiagram = goog.require('googlecodelabs.CodelabDiagram');
+window.customElements.define(CodelabDiagram.getTagName(), CodelabDiagram);
+const testSuite = goog.require('goog.testing.testSuite');
+goog.require('goog.testing.asserts');
+goog.require('goog.testing.jsunit');
+
+let div;
+
+const diagramHtml = '<google-codelab-diagram kind="mermaid">' +
+  '<img alt="mermaid diagram" src="img/a.svg">' +
+  '<pre><code language="mermaid" class="mermaid">graph TD\n  A--&gt;B\n' +
+  '</code></pre></google-codelab-diagram>';
+
+/** @return {!Promise} */
+const tick = () => new Promise((resolve) => setTimeout(resolve, 0));
+
+testSuite({
+
+  setUp() {
+    div = document.createElement('div');
+    div.innerHTML = diagramHtml;
+  },
+
+  tearDown() {
+    delete window['mermaid'];
+    document.body.innerHTML = '';
+    div = null;
+  },
+
+  async testCodelabDiagramKeepsFallbackWithoutRenderer() {
+    document.body.appendChild(div);
+    await tick();
+    const diagramCE = div.querySelector('google-codelab-diagram');
+    assertFalse(diagramCE.hasAttribute('rendered'));
+    assertNotNull(diagramCE.querySelector('img'));
+    assertNull(diagramCE.querySelector('svg'));
+  },
+
+  async testCodelabDiagramRendersSource() {
+    let source = '';
+    window['mermaid'] = {
+      'render': (id, src) => {
+        source = src;
+        return Promise.resolve({
+          'svg': '<svg xmlns="http://www.w3.org/2000/svg" onload="x()">' +
+              '<a href="javascript:x()"><text>A</text></a>' +
+              '<a href="#b"><text>B</text></a>' +
+              '<script>x()</script></svg>',
+        });
+      },
+    };
+    document.body.appendChild(div);
+    await tick();
+    const diagramCE = div.querySelector('google-codelab-diagram');
+    assertEquals('graph TD\n  A-->B\n', source);
+    assertTrue(diagramCE.hasAttribute('rendered'));
+    const svg = diagramCE.querySelector('.diagram-rendered svg');
+    assertNotNull(svg);
+    assertFalse(svg.hasAttribute('onload'));
+    assertNull(svg.querySelector('script'));
+    const links = svg.querySelectorAll('a');
+    assertFalse(links[0].hasAttribute('href'));
+    assertEquals('#b', links[1].getAttribute('href'));
+  },
+
+  async testCodelabDiagramKeepsFallbackOnError() {
+    window['mermaid'] = {
+      'render': () => Promise.reject(new Error('parse error')),
+    };
+    document.body.appendChild(div);
+    await tick();
+    const diagramCE = div.querySelector('google-codelab-diagram');
+    assertFalse(diagramCE.hasAttribute('rendered'));
+    assertNull(diagramCE.querySelector('svg'));
+  },
+});
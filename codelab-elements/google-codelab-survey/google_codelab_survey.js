@@ -55,6 +55,30 @@ const OPTION_WRAPPER_CLASS = 'survey-option-wrapper';
 /** @const {string} */
 const RADIO_TEXT_CLASS = 'option-text';
 
+/**
+ * The attribute of quiz surveys and of their correct options.
+ * @const {string}
+ */
+const QUIZ_ATTR = 'quiz';
+
+/** @const {string} */
+const CORRECT_ATTR = 'correct';
+
+/** @const {string} */
+const QUESTION_WRAPPER_CLASS = 'survey-question-wrapper';
+
+/** @const {string} */
+const CHECK_ANSWER_CLASS = 'survey-check-answer';
+
+/** @const {string} */
+const EXPLANATION_CLASS = 'survey-explanation';
+
+/** @const {string} */
+const CORRECT_CLASS = 'correct';
+
+/** @const {string} */
+const INCORRECT_CLASS = 'incorrect';
+
 /**
  * @extends {HTMLElement}
  */
@@ -111,6 +135,8 @@ class CodelabSurvey extends HTMLElement {
   bindEvents_() {
     this.eventHandler_.listen(this, events.EventType.CHANGE,
       (event) => this.handleOptionSelected_(event));
+    this.eventHandler_.listen(this, events.EventType.CLICK,
+      (event) => this.handleCheckAnswer_(event));
   }
 
   /**
@@ -127,27 +153,116 @@ class CodelabSurvey extends HTMLElement {
     if (!(optionWrapperElement instanceof Element)) {
       return;
     }
-    const optionTextElement =
-        optionWrapperElement.querySelector(`.${RADIO_TEXT_CLASS}`);
+    const question = inputElement.name;
     let answer = '';
-    if (optionTextElement) {
-      answer = optionTextElement.textContent;
+    if (inputElement.type == 'checkbox') {
+      // Multi-select questions store all checked options.
+      const answers = this.checkedAnswers_(inputElement);
+      this.storedData_[this.surveyName_][question] = answers;
+      answer = answers.join(', ');
+    } else {
+      answer = this.optionText_(optionWrapperElement);
+      this.storedData_[this.surveyName_][question] = answer;
     }
-
-    const question = inputElement.name;
-    this.storedData_[this.surveyName_][question] = answer;
     this.storage_.set(
       this.storageKey_, JSON.stringify(this.storedData_[this.surveyName_]));
+    this.dispatchAction_('survey', question, answer);
+  }
+
+  /**
+   * Reveals the correct options of a quiz question
+   * and the explanation of its answer.
+   * @param {!Event} event
+   * @private
+   */
+  handleCheckAnswer_(event) {
+    if (!(event.target instanceof Element) ||
+        !event.target.classList.contains(CHECK_ANSWER_CLASS)) {
+      return;
+    }
+    const questionWrapperElement =
+        dom.getAncestorByClass(event.target, QUESTION_WRAPPER_CLASS);
+    if (!(questionWrapperElement instanceof Element)) {
+      return;
+    }
+    let correct = true;
+    const inputEls = questionWrapperElement.querySelectorAll('input');
+    inputEls.forEach(inputEl => {
+      const isCorrect = inputEl.hasAttribute(CORRECT_ATTR);
+      if (inputEl.checked != isCorrect) {
+        correct = false;
+      }
+      const optionWrapperElement =
+          dom.getAncestorByClass(inputEl, OPTION_WRAPPER_CLASS);
+      if (optionWrapperElement) {
+        optionWrapperElement.classList.toggle(CORRECT_CLASS, isCorrect);
+        optionWrapperElement.classList.toggle(
+            INCORRECT_CLASS, inputEl.checked && !isCorrect);
+      }
+    });
+    questionWrapperElement.classList.toggle(CORRECT_CLASS, correct);
+    questionWrapperElement.classList.toggle(INCORRECT_CLASS, !correct);
+    const explanationEl =
+        questionWrapperElement.querySelector(`.${EXPLANATION_CLASS}`);
+    if (explanationEl) {
+      explanationEl.hidden = false;
+    }
+    const question = inputEls.length ? inputEls[0].name : '';
+    this.dispatchAction_(
+        'quiz', question, correct ? CORRECT_CLASS : INCORRECT_CLASS);
+  }
+
+  /**
+   * @param {string} category
+   * @param {string} action
+   * @param {string} label
+   * @private
+   */
+  dispatchAction_(category, action, label) {
     const codelabEvent = new CustomEvent('google-codelab-action', {
       detail: {
-        'category': 'survey',
-        'action': question.substring(0, 500),
-        'label': answer.substring(0, 500)
+        'category': category,
+        'action': action.substring(0, 500),
+        'label': label.substring(0, 500)
       }
     });
     document.body.dispatchEvent(codelabEvent);
   }
 
+  /**
+   * @param {!Element} optionWrapperElement
+   * @return {string} The text of the option.
+   * @private
+   */
+  optionText_(optionWrapperElement) {
+    const optionTextElement =
+        optionWrapperElement.querySelector(`.${RADIO_TEXT_CLASS}`);
+    return optionTextElement ? optionTextElement.textContent : '';
+  }
+
+  /**
+   * @param {!HTMLInputElement} inputElement
+   * @return {!Array<string>} The checked options of the question
+   *     of inputElement.
+   * @private
+   */
+  checkedAnswers_(inputElement) {
+    const answers = [];
+    const questionWrapperElement =
+        dom.getAncestorByClass(inputElement, QUESTION_WRAPPER_CLASS);
+    if (!questionWrapperElement) {
+      return answers;
+    }
+    questionWrapperElement.querySelectorAll('input').forEach(inputEl => {
+      const optionWrapperElement =
+          dom.getAncestorByClass(inputEl, OPTION_WRAPPER_CLASS);
+      if (inputEl.checked && optionWrapperElement) {
+        answers.push(this.optionText_(optionWrapperElement));
+      }
+    });
+    return answers;
+  }
+
   /** @private */
   checkStoredData_() {
     const storedData = this.storage_.get(this.storageKey_);
@@ -161,30 +276,46 @@ class CodelabSurvey extends HTMLElement {
 
   /** @private */
   updateDom_() {
-    const radioGroupEls = this.querySelectorAll('paper-radio-group');
+    // Multi-select questions use checkbox groups.
+    const radioGroupEls = this.querySelectorAll(
+      'paper-radio-group, paper-checkbox-group');
     const questionEls = this.querySelectorAll('h4');
     const surveyQuestions = [];
     if (radioGroupEls.length && (questionEls.length == radioGroupEls.length)) {
       radioGroupEls.forEach((radioGroupEl, index) => {
         const surveyOptions = [];
         const polymerRadioEls = radioGroupEl.querySelectorAll(
-          'paper-radio-button');
+          'paper-radio-button, paper-checkbox');
+        const multi =
+            radioGroupEl.tagName.toLowerCase() == 'paper-checkbox-group';
+        // The explanation of a quiz answer follows its options.
+        let explanation = '';
+        const explanationEl = dom.getNextElementSibling(radioGroupEl);
+        if (explanationEl &&
+            explanationEl.classList.contains(EXPLANATION_CLASS)) {
+          explanation = explanationEl.textContent;
+          dom.removeNode(explanationEl);
+        }
         dom.removeNode(radioGroupEl);
         polymerRadioEls.forEach(radioEl => {
           const title = radioEl.textContent;
           surveyOptions.push({
             radioId: this.normalizeIdAttr_(questionEls[index].textContent, title),
-            radioTitle: title
+            radioTitle: title,
+            correct: radioEl.hasAttribute(CORRECT_ATTR)
           });
         });
         surveyQuestions.push({
           question: questionEls[index].textContent,
+          multi: multi,
+          explanation: explanation,
           options: surveyOptions
         });
         dom.removeNode(questionEls[index]);
       });
       const updatedDom = soy.renderAsElement(Templates.survey, {
         surveyName: this.surveyName_,
+        quiz: this.hasAttribute(QUIZ_ATTR),
         surveyQuestions: surveyQuestions
       });
       this.appendChild(updatedDom);
@@ -198,13 +329,18 @@ class CodelabSurvey extends HTMLElement {
     const surveyData = this.storedData_[this.surveyName_];
     if (surveyData) {
       Object.keys(surveyData).forEach(key => {
-        const id = this.normalizeIdAttr_(key, surveyData[key]);
-        /** @type {?HTMLInputElement} */
-        const inp = /** @type {?HTMLInputElement} */ (
-            this.querySelector(`#${id}`));
-        if (inp) {
-          inp.checked = true;
-        }
+        // Multi-select questions store an array of answers.
+        const answers = Array.isArray(surveyData[key]) ?
+            surveyData[key] : [surveyData[key]];
+        answers.forEach(answer => {
+          const id = this.normalizeIdAttr_(key, answer);
+          /** @type {?HTMLInputElement} */
+          const inp = /** @type {?HTMLInputElement} */ (
+              this.querySelector(`#${id}`));
+          if (inp) {
+            inp.checked = true;
+          }
+        });
       });
     }
   }
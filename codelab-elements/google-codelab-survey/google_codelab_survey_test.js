@@ -50,6 +50,18 @@ const polymerHtmlInvalid = '<google-codelab-survey survey-id="test"><paper-radio
   '<paper-radio-button>Title Text</paper-radio-button>' +
   '</paper-radio-group></google-codelab-survey>';
 
+const quizHtml = '<google-codelab-survey survey-id="quiz" quiz>' +
+  '<h4>Pick one?</h4><paper-radio-group>' +
+  '<paper-radio-button correct>Right</paper-radio-button>' +
+  '<paper-radio-button>Wrong</paper-radio-button>' +
+  '</paper-radio-group>' +
+  '<p class="survey-explanation">Because.</p>' +
+  '<h4>Pick many?</h4><paper-checkbox-group>' +
+  '<paper-checkbox correct>First</paper-checkbox>' +
+  '<paper-checkbox>Second</paper-checkbox>' +
+  '<paper-checkbox correct>Third</paper-checkbox>' +
+  '</paper-checkbox-group></google-codelab-survey>';
+
 testSuite({
 
   setUp() {
@@ -145,6 +157,53 @@ testSuite({
     assertEquals('{"Question?":"Second Option"}', localStorage.get('codelab-survey-test'));
   },
 
+  testCodelabSurveyQuizUpgraded() {
+    div.innerHTML = quizHtml;
+    document.body.appendChild(div);
+    const surveyCE = div.querySelector('google-codelab-survey');
+    assertTrue(surveyCE.hasAttribute('upgraded'));
+    assertEquals('radio',
+        surveyCE.querySelector('input#pick-one--right').type);
+    assertEquals('checkbox',
+        surveyCE.querySelector('input#pick-many--first').type);
+    assertEquals(2, surveyCE.querySelectorAll('.survey-check-answer').length);
+
+    // The explanation is hidden until the answer is checked.
+    const explanationEl = surveyCE.querySelector('.survey-explanation');
+    assertEquals('Because.', explanationEl.textContent);
+    assertTrue(explanationEl.hidden);
+  },
+
+  testCodelabSurveyQuizCheckAnswer() {
+    div.innerHTML = quizHtml;
+    document.body.appendChild(div);
+    const wrapperEls = div.querySelectorAll('.survey-question-wrapper');
+
+    div.querySelector('label#pick-one--wrong-label').click();
+    wrapperEls[0].querySelector('.survey-check-answer').click();
+    assertTrue(wrapperEls[0].classList.contains('incorrect'));
+    assertTrue(div.querySelector('label#pick-one--right-label')
+        .classList.contains('correct'));
+    assertFalse(div.querySelector('.survey-explanation').hidden);
+
+    div.querySelector('label#pick-many--first-label').click();
+    div.querySelector('label#pick-many--third-label').click();
+    assertEquals('{"Pick one?":"Wrong","Pick many?":["First","Third"]}',
+        localStorage.get('codelab-survey-quiz'));
+    wrapperEls[1].querySelector('.survey-check-answer').click();
+    assertTrue(wrapperEls[1].classList.contains('correct'));
+  },
+
+  testCodelabSurveyLoadsStoredMultiAnswers() {
+    localStorage.set('codelab-survey-quiz', '{"Pick many?":["Second","Third"]}');
+    div.innerHTML = quizHtml;
+    document.body.appendChild(div);
+
+    assertFalse(div.querySelector('input#pick-many--first').checked);
+    assertTrue(div.querySelector('input#pick-many--second').checked);
+    assertTrue(div.querySelector('input#pick-many--third').checked);
+  },
+
   testCodelabSurveyLoadsStoredAnswers() {
     localStorage.set('codelab-survey-test', '{"Question?":"Second Option"}');
     document.body.appendChild(div);
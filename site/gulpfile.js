@@ -436,6 +436,8 @@ const parseCodelabMetadata = (filepath) => {
   meta.mainCategory = meta.category[0] || DEFAULT_CATEGORY;
   meta.categoryClass = categoryClass(meta);
   meta.url = path.join(CODELABS_NAMESPACE, meta.id, 'index.html');
+  // Languages of exported translations, in <id>/<lang> subdirectories.
+  meta.locales = meta.locales || [];
 
   return meta;
 }